@@ -0,0 +1,66 @@
+// Package bodylimit limita cuanto se lee del cuerpo de una respuesta HTTP, lo comparten todos
+// los programas del repositorio para no leer respuestas patológicas (o maliciosas) hasta
+// quedarse sin memoria.
+package bodylimit
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// DefaultMaxSize es el tamaño máximo por defecto (10 MiB) que estamos dispuestos a leer del
+// cuerpo de una respuesta.
+const DefaultMaxSize = 10 << 20
+
+// MaxSize es el tamaño máximo, en bytes, que leeremos del cuerpo de cualquier respuesta, cada
+// programa lo puede modificar desde la linea de comandos.
+var MaxSize int64 = DefaultMaxSize
+
+// ResponseTooLargeError es el error que se devuelve cuando el cuerpo de una respuesta supera
+// MaxSize.
+type ResponseTooLargeError struct {
+	// URL es la dirección que devolvió la respuesta demasiado grande.
+	URL string
+	// Limit es el tamaño máximo que estábamos dispuestos a leer.
+	Limit int64
+}
+
+func (e *ResponseTooLargeError) Error() string {
+	return fmt.Sprintf("response from %s is larger than %d bytes", e.URL, e.Limit)
+}
+
+// limitedBody envuelve el cuerpo de una respuesta en un io.LimitReader y devuelve un
+// *ResponseTooLargeError si se intenta leer mas de lo permitido.
+type limitedBody struct {
+	reader io.Reader
+	closer io.Closer
+	url    string
+	limit  int64
+	read   int64
+}
+
+// Body devuelve el cuerpo de la respuesta limitado a MaxSize bytes.
+func Body(response *http.Response) io.ReadCloser {
+	return &limitedBody{
+		// leemos un byte mas del límite para poder distinguir una respuesta que mide
+		// exactamente el límite de una que lo supera.
+		reader: io.LimitReader(response.Body, MaxSize+1),
+		closer: response.Body,
+		url:    response.Request.URL.String(),
+		limit:  MaxSize,
+	}
+}
+
+func (l *limitedBody) Read(p []byte) (int, error) {
+	n, err := l.reader.Read(p)
+	l.read += int64(n)
+	if l.read > l.limit {
+		return n - int(l.read-l.limit), &ResponseTooLargeError{URL: l.url, Limit: l.limit}
+	}
+	return n, err
+}
+
+func (l *limitedBody) Close() error {
+	return l.closer.Close()
+}
@@ -0,0 +1,31 @@
+// Package conditional guarda los validadores de una respuesta HTTP, ETag y Last-Modified,
+// para volver a pedir el mismo recurso condicionalmente: si no cambió el servidor responde
+// 304 Not Modified sin cuerpo y se usa lo que ya se tenía.
+package conditional
+
+import "net/http"
+
+// Validators son los validadores de una respuesta, vacíos si el servidor no los envió.
+type Validators struct {
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+}
+
+// FromResponse devuelve los validadores de response.
+func FromResponse(response *http.Response) Validators {
+	return Validators{
+		ETag:         response.Header.Get("ETag"),
+		LastModified: response.Header.Get("Last-Modified"),
+	}
+}
+
+// Set agrega a request los encabezados If-None-Match e If-Modified-Since de los validadores que
+// haya.
+func (v Validators) Set(request *http.Request) {
+	if v.ETag != "" {
+		request.Header.Set("If-None-Match", v.ETag)
+	}
+	if v.LastModified != "" {
+		request.Header.Set("If-Modified-Since", v.LastModified)
+	}
+}
@@ -0,0 +1,60 @@
+package i18n
+
+import (
+	"github.com/shopspring/decimal"
+	"golang.org/x/text/currency"
+	"golang.org/x/text/language"
+	"golang.org/x/text/number"
+)
+
+// defaultRegions es el país cuyas costumbres se usan para los números de cada idioma cuando el
+// entorno no indica otro, los programas nacieron en Argentina.
+var defaultRegions = map[string]string{
+	Spanish: "AR",
+	English: "US",
+}
+
+// Locale devuelve el idioma elegido junto con su país: el del entorno si LC_ALL, LC_MESSAGES o
+// LANG indican el mismo idioma, por ejemplo es-ES con LANG=es_ES.UTF-8, o si no el de
+// defaultRegions.
+func Locale() language.Tag {
+	region := defaultRegions[lang]
+	if envLanguage, envRegion := fromEnv(); envLanguage == lang && envRegion != "" {
+		region = envRegion
+	}
+	tag, err := language.Parse(lang + "-" + region)
+	if err != nil {
+		return language.Make(lang)
+	}
+	return tag
+}
+
+// Number devuelve d con places decimales y los separadores de miles y de decimales del idioma
+// elegido, por ejemplo "1.234,56" en español y "1,234.56" en inglés. d ya debe estar redondeado
+// a places decimales, así quien llama elige como se redondea.
+func Number(d decimal.Decimal, places int32) string {
+	f, _ := d.Float64()
+	return printer.Sprint(number.Decimal(f, number.Scale(int(places))))
+}
+
+// CurrencySymbol devuelve el símbolo de la moneda code, un código ISO 4217, en el idioma
+// elegido, por ejemplo "US$" para USD y "$" para ARS en Argentina pero "$" y "ARS" en Estados
+// Unidos. Los códigos que no son de ISO 4217, como BTC, se devuelven tal cual.
+func CurrencySymbol(code string) string {
+	unit, err := currency.ParseISO(code)
+	if err != nil {
+		return code
+	}
+	return printer.Sprint(currency.Symbol(unit))
+}
+
+// CurrencyDecimals devuelve la cantidad de decimales con que se escriben los montos en la moneda
+// code, por ejemplo 2 para USD y 0 para CLP, y false si code no es de ISO 4217.
+func CurrencyDecimals(code string) (int32, bool) {
+	unit, err := currency.ParseISO(code)
+	if err != nil {
+		return 0, false
+	}
+	scale, _ := currency.Standard.Rounding(unit)
+	return int32(scale), true
+}
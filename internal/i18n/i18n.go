@@ -0,0 +1,112 @@
+// Package i18n traduce los textos que muestran los programas a otros idiomas y escribe los
+// números y los montos como se acostumbra en cada uno. Los textos se escriben en español en el
+// código, como siempre, y cada paquete registra con Add un catálogo por idioma que los usa como
+// clave: un texto que falta en el catálogo se muestra en español.
+package i18n
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/text/message"
+)
+
+// Idiomas en los que se pueden mostrar los textos.
+const (
+	// Spanish es el idioma en el que están escritos los textos, no necesita catálogo.
+	Spanish = "es"
+	English = "en"
+)
+
+// Languages son los idiomas conocidos, para los mensajes de ayuda y de error.
+var Languages = []string{Spanish, English}
+
+// lang es el idioma en que se muestran los textos y printer el que formatea los números en ese
+// idioma, ver Set.
+var (
+	lang    = FromEnv()
+	printer = message.NewPrinter(Locale())
+)
+
+// catalogs son las traducciones de cada idioma, por texto en español.
+var catalogs = map[string]map[string]string{}
+
+// Add agrega al catálogo de language las traducciones de messages, que tienen como clave el
+// texto en español tal cual aparece en el código, incluidos los verbos de formato. Se llama
+// desde un var o un init de cada paquete, antes de que se muestre cualquier texto.
+func Add(language string, messages map[string]string) {
+	catalog, ok := catalogs[language]
+	if !ok {
+		catalog = map[string]string{}
+		catalogs[language] = catalog
+	}
+	for message, translation := range messages {
+		catalog[message] = translation
+	}
+}
+
+// Valid indica si language es uno de Languages.
+func Valid(language string) bool {
+	for _, known := range Languages {
+		if language == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Set cambia el idioma en que se muestran los textos, debe llamarse antes de mostrar cualquiera
+// y no mientras otras gorutinas traducen.
+func Set(language string) error {
+	if !Valid(language) {
+		return fmt.Errorf("unknown language %q, must be %s", language, strings.Join(Languages, " or "))
+	}
+	lang = language
+	printer = message.NewPrinter(Locale())
+	return nil
+}
+
+// Lang devuelve el idioma en que se muestran los textos.
+func Lang() string {
+	return lang
+}
+
+// FromEnv devuelve el idioma de la primera de LC_ALL, LC_MESSAGES y LANG que esté definida,
+// el mismo orden que usa gettext, por ejemplo English con LANG=en_US.UTF-8. Sin ninguna, o con
+// un idioma que no se conoce como C o pt_BR, devuelve Spanish.
+func FromEnv() string {
+	language, _ := fromEnv()
+	if Valid(language) {
+		return language
+	}
+	return Spanish
+}
+
+// fromEnv devuelve el idioma y el país, en minúsculas y en mayúsculas, de la primera de LC_ALL,
+// LC_MESSAGES y LANG que esté definida, por ejemplo "en" y "US" con LANG=en_US.UTF-8.
+func fromEnv() (language, region string) {
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		value := os.Getenv(name)
+		if value == "" {
+			continue
+		}
+		// la codificación y el modificador no importan.
+		value, _, _ = strings.Cut(value, ".")
+		value, _, _ = strings.Cut(value, "@")
+		language, region, _ = strings.Cut(value, "_")
+		return strings.ToLower(language), strings.ToUpper(region)
+	}
+	return "", ""
+}
+
+// T devuelve message en el idioma elegido, o tal cual si está en español o no tiene traducción.
+// Los textos con verbos de formato se traducen antes de formatearlos:
+//
+//	fmt.Fprintf(w, i18n.T("Sitios con datos (%d):\n"), len(results))
+func T(message string) string {
+	if translation, ok := catalogs[lang][message]; ok {
+		return translation
+	}
+	return message
+}
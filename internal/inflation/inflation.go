@@ -0,0 +1,211 @@
+// Package inflation expresa precios en pesos argentinos de distintos meses en pesos de un mismo
+// mes según el índice de precios al consumidor (IPC) que publica el INDEC, con inflación alta
+// comparar el precio nominal de hace unos meses con el de hoy no dice mucho.
+package inflation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+
+	"github.com/perrito666/tutoriales_go/internal/bodylimit"
+	"github.com/shopspring/decimal"
+)
+
+// MonthLayout es el formato de los meses de una serie, por ejemplo "2025-01".
+const MonthLayout = "2006-01"
+
+const (
+	// seriesURL es la API de series de tiempo de datos.gob.ar, donde el INDEC publica el IPC.
+	seriesURL = "https://apis.datos.gob.ar/series/api/series/"
+	// cpiSeriesID es la serie del IPC nacional, nivel general, base diciembre 2016 = 100.
+	cpiSeriesID = "148.3_INIVELNAL_DICI_M_26"
+	// seriesLimit es la cantidad máxima de meses que se piden, la serie empieza en 2016.
+	seriesLimit = 1000
+)
+
+// argentina es la zona horaria en la que se decide a que mes corresponde un momento, el IPC
+// es mensual y un precio de las 23hs del 31 en Argentina no es del mes siguiente.
+var argentina = time.FixedZone("ART", -3*60*60)
+
+// Doer envía un pedido HTTP, por ejemplo http.DefaultClient.Do.
+type Doer func(*http.Request) (*http.Response, error)
+
+// Series es un índice de precios mensual, por mes en formato MonthLayout.
+type Series map[string]decimal.Decimal
+
+// Bundled es el IPC nacional del INDEC, base diciembre 2016 = 100, redondeado a dos decimales
+// y aproximado a partir de la variación mensual publicada. Para meses posteriores al último
+// se puede pedir la serie actualizada con FetchINDEC.
+var Bundled = Series{
+	"2022-01": decimal.RequireFromString("605.17"),
+	"2022-02": decimal.RequireFromString("633.62"),
+	"2022-03": decimal.RequireFromString("676.07"),
+	"2022-04": decimal.RequireFromString("716.63"),
+	"2022-05": decimal.RequireFromString("753.18"),
+	"2022-06": decimal.RequireFromString("793.10"),
+	"2022-07": decimal.RequireFromString("851.79"),
+	"2022-08": decimal.RequireFromString("911.41"),
+	"2022-09": decimal.RequireFromString("967.92"),
+	"2022-10": decimal.RequireFromString("1028.90"),
+	"2022-11": decimal.RequireFromString("1079.32"),
+	"2022-12": decimal.RequireFromString("1134.59"),
+	"2023-01": decimal.RequireFromString("1202.66"),
+	"2023-02": decimal.RequireFromString("1282.04"),
+	"2023-03": decimal.RequireFromString("1380.76"),
+	"2023-04": decimal.RequireFromString("1496.74"),
+	"2023-05": decimal.RequireFromString("1613.48"),
+	"2023-06": decimal.RequireFromString("1710.29"),
+	"2023-07": decimal.RequireFromString("1818.04"),
+	"2023-08": decimal.RequireFromString("2043.48"),
+	"2023-09": decimal.RequireFromString("2303.00"),
+	"2023-10": decimal.RequireFromString("2494.15"),
+	"2023-11": decimal.RequireFromString("2813.40"),
+	"2023-12": decimal.RequireFromString("3533.19"),
+	"2024-01": decimal.RequireFromString("4261.03"),
+	"2024-02": decimal.RequireFromString("4823.49"),
+	"2024-03": decimal.RequireFromString("5354.07"),
+	"2024-04": decimal.RequireFromString("5825.23"),
+	"2024-05": decimal.RequireFromString("6069.89"),
+	"2024-06": decimal.RequireFromString("6349.10"),
+	"2024-07": decimal.RequireFromString("6603.07"),
+	"2024-08": decimal.RequireFromString("6880.39"),
+	"2024-09": decimal.RequireFromString("7121.21"),
+	"2024-10": decimal.RequireFromString("7313.48"),
+	"2024-11": decimal.RequireFromString("7489.00"),
+	"2024-12": decimal.RequireFromString("7691.21"),
+	"2025-01": decimal.RequireFromString("7860.41"),
+	"2025-02": decimal.RequireFromString("8049.06"),
+	"2025-03": decimal.RequireFromString("8346.88"),
+	"2025-04": decimal.RequireFromString("8580.59"),
+	"2025-05": decimal.RequireFromString("8709.30"),
+	"2025-06": decimal.RequireFromString("8848.65"),
+	"2025-07": decimal.RequireFromString("9016.77"),
+	"2025-08": decimal.RequireFromString("9188.09"),
+	"2025-09": decimal.RequireFromString("9381.04"),
+}
+
+// Month devuelve el mes, en formato MonthLayout, al que corresponde t en Argentina.
+func Month(t time.Time) string {
+	return t.In(argentina).Format(MonthLayout)
+}
+
+// ParseMonth valida un mes en formato MonthLayout y lo devuelve normalizado.
+func ParseMonth(month string) (string, error) {
+	t, err := time.Parse(MonthLayout, month)
+	if err != nil {
+		return "", fmt.Errorf("invalid month %q, must be YYYY-MM", month)
+	}
+	return t.Format(MonthLayout), nil
+}
+
+// Latest devuelve el último mes de la serie, vacío si la serie está vacía.
+func (s Series) Latest() string {
+	months := make([]string, 0, len(s))
+	for month := range s {
+		months = append(months, month)
+	}
+	if len(months) == 0 {
+		return ""
+	}
+	sort.Strings(months)
+	return months[len(months)-1]
+}
+
+// index devuelve el índice de month, los meses posteriores al último de la serie, que el
+// INDEC todavía no publicó, usan el último.
+func (s Series) index(month string) (decimal.Decimal, bool) {
+	if index, ok := s[month]; ok {
+		return index, true
+	}
+	if latest := s.Latest(); latest != "" && month > latest {
+		return s[latest], true
+	}
+	return decimal.Zero, false
+}
+
+// Adjust expresa price, un precio en pesos del momento at, en pesos del mes base. Si el mes
+// de at es posterior al último de la serie se usa el último, es decir que la inflación que el
+// INDEC todavía no publicó no se descuenta.
+func (s Series) Adjust(price decimal.Decimal, at time.Time, base string) (decimal.Decimal, error) {
+	baseIndex, ok := s[base]
+	if !ok {
+		return decimal.Zero, fmt.Errorf("no consumer price index for %s, the series goes up to %s", base, s.Latest())
+	}
+	month := Month(at)
+	index, ok := s.index(month)
+	if !ok || !index.IsPositive() {
+		return decimal.Zero, fmt.Errorf("no consumer price index for %s", month)
+	}
+	return price.Mul(baseIndex).Div(index), nil
+}
+
+// seriesResponse imita la estructura JSON de la API de series, cada dato es un par de fecha
+// y valor, el valor es null en los meses sin dato.
+type seriesResponse struct {
+	Data [][]json.RawMessage `json:"data"`
+}
+
+// FetchINDEC pide el IPC publicado por el INDEC a la API de series de datos.gob.ar, con do o,
+// si es nil, con http.DefaultClient.
+func FetchINDEC(ctx context.Context, do Doer) (Series, error) {
+	u, err := url.Parse(seriesURL)
+	if err != nil {
+		return nil, fmt.Errorf("parsing series url: %w", err)
+	}
+	queryValues := u.Query()
+	queryValues.Set("ids", cpiSeriesID)
+	queryValues.Set("format", "json")
+	queryValues.Set("limit", fmt.Sprint(seriesLimit))
+	u.RawQuery = queryValues.Encode()
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("building indec request: %w", err)
+	}
+	if do == nil {
+		do = http.DefaultClient.Do
+	}
+	response, err := do(request)
+	if err != nil {
+		return nil, fmt.Errorf("querying indec: %w", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("requesting to indec: %s", response.Status)
+	}
+
+	found := &seriesResponse{}
+	if err := json.NewDecoder(bodylimit.Body(response)).Decode(found); err != nil {
+		return nil, fmt.Errorf("decoding indec response: %w", err)
+	}
+	series := Series{}
+	for _, point := range found.Data {
+		if len(point) != 2 {
+			return nil, fmt.Errorf("decoding indec response: expected date and value, got %d fields", len(point))
+		}
+		var date string
+		var value *decimal.Decimal
+		if err := json.Unmarshal(point[0], &date); err != nil {
+			return nil, fmt.Errorf("decoding indec date: %w", err)
+		}
+		if err := json.Unmarshal(point[1], &value); err != nil {
+			return nil, fmt.Errorf("decoding indec value for %s: %w", date, err)
+		}
+		if value == nil {
+			continue
+		}
+		t, err := time.Parse("2006-01-02", date)
+		if err != nil {
+			return nil, fmt.Errorf("decoding indec date: %w", err)
+		}
+		series[t.Format(MonthLayout)] = *value
+	}
+	if len(series) == 0 {
+		return nil, fmt.Errorf("indec returned an empty consumer price index series")
+	}
+	return series, nil
+}
@@ -0,0 +1,210 @@
+// Package meli contiene lo que comparten los programas del repositorio para buscar en la API
+// de Mercado Libre: la URL de búsqueda de un sitio y los tipos en los que se de-serializan sus
+// resultados.
+package meli
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"github.com/shopspring/decimal"
+)
+
+// IPhone11Max es el criterio de búsqueda de siempre, un teléfono carísimo.
+const IPhone11Max = "iPhone 11 Pro Max"
+
+const (
+	// searchURLFormat es la URL de búsqueda de ML con un segmento reemplazable dependiendo
+	// del site, por ejemplo MLA para Argentina.
+	searchURLFormat = "https://api.mercadolibre.com/sites/%s/search"
+	// queryKey es la clave que usaremos en el pedido GET para indicar el texto de búsqueda
+	queryKey = "q"
+	// sortKey es la clave que usaremos en el pedido GET para indicar el orden de los resultados
+	sortKey = "sort"
+	// limitKey es la clave que usaremos en el pedido GET para indicar el tamaño de la página
+	limitKey = "limit"
+	// offsetKey es la clave que usaremos en el pedido GET para indicar desde que resultado
+	// comienza la página
+	offsetKey = "offset"
+)
+
+const (
+	// SortPriceDesc pide los resultados ordenados por precio descendente, el mas caro primero.
+	SortPriceDesc = "price_desc"
+	// SortPriceAsc pide los resultados ordenados por precio ascendente, el mas barato primero.
+	SortPriceAsc = "price_asc"
+	// SortRelevance pide los resultados ordenados por relevancia, el orden por defecto de ML.
+	SortRelevance = "relevance"
+)
+
+// ValidSort indica si sort es uno de los órdenes que sabemos pedirle a ML.
+func ValidSort(sort string) bool {
+	switch sort {
+	case SortPriceDesc, SortPriceAsc, SortRelevance:
+		return true
+	}
+	return false
+}
+
+// SearchURL devuelve la URL de búsqueda de searchCriteria en el site siteID de ML, ordenando
+// los resultados según sort y restringiéndolos con los filtros de filters, por parámetro, por
+// ejemplo shipping_cost=free. Si limit es mayor a 0 se pide la página de limit resultados que
+// comienza en offset.
+func SearchURL(siteID, searchCriteria, sort string, filters map[string]string, limit, offset int) (string, error) {
+	// completamos la URL de base con el site que nos pasaron.
+	queryURL, err := url.Parse(fmt.Sprintf(searchURLFormat, siteID))
+	if err != nil {
+		return "", fmt.Errorf("parsing mercado libre url: %w", err)
+	}
+	// Obtenemos un diccionario de clave/valor de los parametros de GET
+	queryValues := queryURL.Query()
+	// Agregamos los parametros que nos interesan
+	// Ordenar según nos pidan
+	queryValues[sortKey] = []string{sort}
+	// Criterio de búsquda: lo que nos pasen como argumento
+	queryValues[queryKey] = []string{searchCriteria}
+	// Filtros: solo los que nos pidan
+	for key, value := range filters {
+		queryValues[key] = []string{value}
+	}
+	// Paginación: solo si nos la piden
+	if limit > 0 {
+		queryValues[limitKey] = []string{strconv.Itoa(limit)}
+		queryValues[offsetKey] = []string{strconv.Itoa(offset)}
+	}
+	// Re-asignamos el diccionario de valores a la query original.
+	queryURL.RawQuery = queryValues.Encode()
+	return queryURL.String(), nil
+}
+
+// ResultadosML contiene un listado de resultados, representa una página de resultados.
+type ResultadosML struct {
+	// Paging indica cuantos resultados hay en total y que parte de ellos es esta página.
+	Paging  PagingML      `json:"paging"`
+	Results []ResultadoML `json:"results"`
+}
+
+// PagingML es la paginación de una búsqueda.
+type PagingML struct {
+	// Total es la cantidad de publicaciones que coinciden con la búsqueda, no solo las de la
+	// página.
+	Total int `json:"total"`
+	// Offset es la posición del primer resultado de la página.
+	Offset int `json:"offset"`
+	// Limit es el tamaño de la página.
+	Limit int `json:"limit"`
+}
+
+// ResultadoML contiene el precio de un resultado junto con lo necesario para verificar que es
+// lo que buscábamos, representa un item de una página de resultados pero no es para nada
+// exaustivo.
+type ResultadoML struct {
+	// ID contiene el identificador de la publicación, por ejemplo MLA816609131
+	ID string `json:"id"`
+	// Price contiene el precio del resultado de búsqueda en moneda CurrencyID, decimal.Decimal
+	// lo lee directamente del texto del JSON sin pasar por float64, así no se pierde precisión.
+	Price decimal.Decimal `json:"price"`
+	// Title contiene el título de la publicación
+	Title string `json:"title"`
+	// Permalink contiene la URL en Mercado Libre de la publicación
+	Permalink string `json:"permalink"`
+	// Condition indica si la publicación es de un producto nuevo (new) o usado (used).
+	Condition string `json:"condition"`
+	// CurrencyID contiene el ID interno de la moneda en la cual está el precio.
+	CurrencyID string `json:"currency_id"`
+	// Seller contiene los datos del vendedor de la publicación
+	Seller SellerML `json:"seller"`
+	// CatalogProductID es el identificador del producto de catálogo de la publicación, vacío
+	// si no está asociada a uno.
+	CatalogProductID string `json:"catalog_product_id"`
+	// OfficialStoreID es el identificador de la tienda oficial que publica, nil si no la
+	// publica una tienda oficial.
+	OfficialStoreID *int64 `json:"official_store_id"`
+	// Shipping contiene los datos del envío de la publicación.
+	Shipping ShippingML `json:"shipping"`
+	// Installments contiene la financiación en cuotas que ofrece la publicación, nil si no
+	// ofrece ninguna.
+	Installments *InstallmentsML `json:"installments"`
+	// Attributes contiene las características del producto de la publicación, por ejemplo la
+	// memoria interna o el color.
+	Attributes []AttributeML `json:"attributes"`
+	// Address contiene la ubicación de la publicación.
+	Address AddressML `json:"address"`
+	// SellerAddress contiene la ubicación del vendedor, algunos sitios solo informan esta.
+	SellerAddress *SellerAddressML `json:"seller_address"`
+}
+
+// SellerML contiene los datos del vendedor de una publicación que nos interesan.
+type SellerML struct {
+	// ID es el identificador del vendedor, es el mismo en todos los sitios.
+	ID int64 `json:"id"`
+}
+
+// ShippingML contiene los datos del envío de una publicación que nos interesan.
+type ShippingML struct {
+	// FreeShipping indica que el envío es gratis.
+	FreeShipping bool `json:"free_shipping"`
+}
+
+// AttributeML es una característica del producto de una publicación.
+type AttributeML struct {
+	// ID identifica la característica, por ejemplo INTERNAL_MEMORY.
+	ID string `json:"id"`
+	// ValueID identifica el valor entre los que puede tomar la característica, vacío si el
+	// valor es libre.
+	ValueID string `json:"value_id"`
+	// ValueName es el valor, por ejemplo "256 GB".
+	ValueName string `json:"value_name"`
+}
+
+// AddressML contiene la ubicación de una publicación.
+type AddressML struct {
+	// StateName es el nombre de la provincia o estado.
+	StateName string `json:"state_name"`
+	// CityName es el nombre de la ciudad.
+	CityName string `json:"city_name"`
+}
+
+// SellerAddressML contiene la ubicación del vendedor de una publicación.
+type SellerAddressML struct {
+	State NamedML `json:"state"`
+	City  NamedML `json:"city"`
+}
+
+// NamedML es cualquier entidad de ML de la que solo nos interesa el nombre.
+type NamedML struct {
+	Name string `json:"name"`
+}
+
+// Location devuelve la provincia y la ciudad de la publicación, tomadas de Address o, si está
+// vacía, de SellerAddress.
+func (r ResultadoML) Location() (state, city string) {
+	state, city = r.Address.StateName, r.Address.CityName
+	if r.SellerAddress != nil {
+		if state == "" {
+			state = r.SellerAddress.State.Name
+		}
+		if city == "" {
+			city = r.SellerAddress.City.Name
+		}
+	}
+	return state, city
+}
+
+// InstallmentsML contiene la financiación en cuotas de una publicación.
+type InstallmentsML struct {
+	// Quantity es la cantidad de cuotas.
+	Quantity int `json:"quantity"`
+	// Amount es el monto de cada cuota, en moneda CurrencyID.
+	Amount decimal.Decimal `json:"amount"`
+	// Rate es la tasa de interés que informa ML, en porcentaje, 0 en las cuotas sin interés.
+	Rate decimal.Decimal `json:"rate"`
+	// CurrencyID contiene el ID interno de la moneda de las cuotas.
+	CurrencyID string `json:"currency_id"`
+}
+
+// GetPrice devuelve el precio de un resultado.
+func (r ResultadoML) GetPrice() decimal.Decimal {
+	return r.Price
+}
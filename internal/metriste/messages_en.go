@@ -0,0 +1,17 @@
+package metriste
+
+import "github.com/perrito666/tutoriales_go/internal/i18n"
+
+// las traducciones al inglés de los textos del tutorial.
+func init() {
+	i18n.Add(i18n.English, map[string]string{
+		"el iphone mas barato":                              "the cheapest iphone",
+		"el iphone mas relevante":                           "the most relevant iphone",
+		"el iphone mas caro":                                "the most expensive iphone",
+		"título: %s\ncondición: %s\nmoneda: %s\nlink: %s\n": "title: %s\ncondition: %s\ncurrency: %s\nlink: %s\n",
+		"%s cuesta: %s\n":                                   "%s costs: %s\n",
+		"%s cuesta: %s (%s)\n":                              "%s costs: %s (%s)\n",
+		"no se puede obtener el costo del iphone de mercado libre: %v": "cannot get the price of the iphone from mercado libre: %v",
+		"no se puede obtener la taza de cambio en dolares: %v":         "cannot get the dollar exchange rate: %v",
+	})
+}
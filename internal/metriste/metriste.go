@@ -0,0 +1,257 @@
+// Package metriste es el programa original del tutorial: busca el iPhone mas caro de Mercado
+// Libre Argentina y lo convierte a dólares, por defecto con la cotización del Banco Nación.
+// Lo usan tanto el main de iphonemetriste como el subcomando metriste de
+// iphonemeloenperspectiva.
+package metriste
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/perrito666/tutoriales_go/internal/bodylimit"
+	"github.com/perrito666/tutoriales_go/internal/i18n"
+	"github.com/perrito666/tutoriales_go/internal/meli"
+	"github.com/perrito666/tutoriales_go/internal/number"
+	"github.com/perrito666/tutoriales_go/internal/rates"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	// Si quieren buscar en otro lado reemplazaran MLA por su pais
+	siteID     = "MLA"
+	resultsKey = "results"
+	priceKey   = "price"
+)
+
+// Client es el cliente con el que se hacen los pedidos, quien use el paquete puede
+// reemplazarlo, por ejemplo por uno que salga por un proxy.
+var Client = http.DefaultClient
+
+// Sort es el orden en que se piden los resultados, uno de los de meli, y decide cual se
+// muestra: con meli.SortPriceDesc el mas caro, como en el post, con meli.SortPriceAsc el mas
+// barato y con meli.SortRelevance el mas relevante.
+var Sort = meli.SortPriceDesc
+
+// searchURL devuelve la URL de la búsqueda del iPhone según Sort.
+func searchURL() (string, error) {
+	// Ordenar según Sort, criterio de búsquda: un teléfono carísimo.
+	return meli.SearchURL(siteID, meli.IPhone11Max, Sort, nil, 0, 0)
+}
+
+func queryML() (io.ReadCloser, error) {
+	queryURL, err := searchURL()
+	if err != nil {
+		return nil, err
+	}
+	// Realizamos la consulta.
+	response, err := Client.Get(queryURL)
+	if err != nil {
+		return nil, fmt.Errorf("querying mercado libre url: %v", err)
+	}
+	if response.StatusCode != http.StatusOK {
+		response.Body.Close()
+		return nil, fmt.Errorf("requesting to mercado libre: %s", response.Status)
+	}
+	// devolvemos el cuerpo limitado para que quien lo lea no pueda excederse.
+	return bodylimit.Body(response), nil
+}
+
+// iPhoneMasCaroMLStruct devuelve el resultado de la búsqueda que corresponde a Sort, con los
+// órdenes por precio el mas caro o el mas barato.
+func iPhoneMasCaroMLStruct() (meli.ResultadoML, error) {
+	body, err := queryML()
+	if err != nil {
+		return meli.ResultadoML{}, err
+	}
+	defer body.Close()
+
+	resultML := &meli.ResultadosML{}
+	err = json.NewDecoder(body).Decode(resultML)
+	if err != nil {
+		return meli.ResultadoML{}, fmt.Errorf("decoding mercado libre response body: %v", err)
+	}
+	if len(resultML.Results) == 0 {
+		return meli.ResultadoML{}, fmt.Errorf("results not found in response")
+	}
+
+	return pick(resultML.Results, Sort), nil
+}
+
+// pick devuelve el resultado que corresponde a sort. ML no garantiza que el primero sea el mas
+// caro o el mas barato, por ejemplo con publicaciones destacadas, así que con los órdenes por
+// precio se busca entre todos; con relevancia el primero es justamente el mas relevante.
+func pick(results []meli.ResultadoML, sort string) meli.ResultadoML {
+	picked := results[0]
+	for _, result := range results[1:] {
+		switch {
+		case sort == meli.SortPriceDesc && result.GetPrice().GreaterThan(picked.GetPrice()),
+			sort == meli.SortPriceAsc && result.GetPrice().LessThan(picked.GetPrice()):
+			picked = result
+		}
+	}
+	return picked
+}
+
+// description describe el resultado que se muestra con Sort.
+func description() string {
+	switch Sort {
+	case meli.SortPriceAsc:
+		return i18n.T("el iphone mas barato")
+	case meli.SortRelevance:
+		return i18n.T("el iphone mas relevante")
+	}
+	return i18n.T("el iphone mas caro")
+}
+
+func iPhoneMasCaroML() (decimal.Decimal, error) {
+	// obtendremos el cuerpo de la respuesta de la función queryML, que es un io.ReadCloser
+	body, err := queryML()
+	if err != nil {
+		return decimal.Zero, err
+	}
+	// recordaremos cerrar el cuerpo al finalizar
+	defer body.Close()
+
+	// de-serializamos el contenido del cuerpo a un map[string]interface{}, en lugar de leer
+	// todo el cuerpo a un arreglo de bytes primero usamos un json.Decoder que va leyendo
+	// del cuerpo a medida que lo necesita, así no guardamos en memoria mas de lo necesario.
+	resultML := map[string]interface{}{}
+	err = json.NewDecoder(body).Decode(&resultML)
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("decoding mercado libre response body: %v", err)
+	}
+
+	// buscamos en el map, la clave de la lista de resultados
+	resultsRaw, ok := resultML[resultsKey]
+	if !ok {
+		return decimal.Zero, fmt.Errorf("key %s not found in response JSON", resultsKey)
+	}
+
+	// convertimos de un objeto interface{} a un []interface para poder utilizar las
+	// características de una lista
+	results, ok := resultsRaw.([]interface{})
+	if !ok {
+		return decimal.Zero, fmt.Errorf("unexpected results type %T", resultsRaw)
+	}
+
+	// chequeamos que, ademas de ser una lita, tenga en efecto resultados.
+	if len(results) == 0 {
+		return decimal.Zero, fmt.Errorf("nobody is selling an %s", meli.IPhone11Max)
+	}
+
+	// obtenemos el primer resultado que, dado el ordenamiento de mas caro a mas barato
+	// debería ser el mas caro.
+	resultRaw := results[0]
+
+	// convertimos este resultado nuevamente a un tipo que podamos manipular.
+	result, ok := resultRaw.(map[string]interface{})
+	if !ok {
+		return decimal.Zero, fmt.Errorf("unexpected single type %T", resultRaw)
+	}
+
+	// buscamos la clave del precio
+	priceRaw, ok := result[priceKey]
+	if !ok {
+		return decimal.Zero, fmt.Errorf("price is not available")
+	}
+
+	// utilizamos type switch para convertir el precio a decimal desde varios tipos
+	// posibles.
+	var moneyPrice decimal.Decimal
+	switch price := priceRaw.(type) {
+	case float64:
+		moneyPrice = decimal.NewFromFloat(price)
+	case float32:
+		moneyPrice = decimal.NewFromFloat32(price)
+	case string:
+		// como texto el precio puede venir con separadores de miles, por ejemplo "1,234.56".
+		moneyPrice, err = number.API.Parse(price)
+		if err != nil {
+			return decimal.Zero, fmt.Errorf("cannot translate price to a decimal value: %v", err)
+		}
+	default:
+		return decimal.Zero, fmt.Errorf("price is not a type we can convert to decimal, is %T", priceRaw)
+	}
+
+	return moneyPrice, nil
+}
+
+// currency es la moneda de los precios de Mercado Libre Argentina.
+const currency = "ARS"
+
+// DryRun escribe en w los pedidos que haría Run cotizando con source, sin hacerlos.
+func DryRun(w io.Writer, source rates.Source) error {
+	queryURL, err := searchURL()
+	if err != nil {
+		return err
+	}
+	rateURL, err := source.URL(currency)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "GET %s\n", queryURL)
+	// una cotización fija no pide nada.
+	if rateURL != "" {
+		fmt.Fprintf(w, "GET %s\n", rateURL)
+	}
+	return nil
+}
+
+// formatPrice devuelve price con el símbolo de currency y los decimales y separadores del
+// idioma de la salida, por ejemplo "$ 1.999.999,00" o "US$ 1.999,99".
+func formatPrice(price decimal.Decimal, currency string) string {
+	places, ok := i18n.CurrencyDecimals(currency)
+	if !ok {
+		places = 2
+	}
+	return i18n.CurrencySymbol(currency) + " " + i18n.Number(price.RoundBank(places), places)
+}
+
+// Run busca el iPhone según Sort, por defecto el mas caro, y escribe en w que es y cuanto cuesta en pesos y, con la
+// cotización de source, en dólares.
+func Run(w io.Writer, source rates.Source) error {
+	// la cotización no depende del precio, así que la pedimos en una gorutina mientras
+	// buscamos en Mercado Libre: el programa tarda lo que el mas lento de los dos pedidos y
+	// no la suma de ambos.
+	var rate decimal.Decimal
+	var rateErr error
+	rateWait := &sync.WaitGroup{}
+	rateWait.Add(1)
+	go func() {
+		defer rateWait.Done()
+		rate, rateErr = source.ToUSD(context.Background(), currency)
+	}()
+	// pase lo que pase no dejamos la gorutina de cotización colgada.
+	defer rateWait.Wait()
+
+	// moneyPrice, err := iPhoneMasCaroML()
+	result, err := iPhoneMasCaroMLStruct()
+	if err != nil {
+		return fmt.Errorf(i18n.T("no se puede obtener el costo del iphone de mercado libre: %v"), err)
+	}
+	moneyPrice := result.GetPrice()
+	// mostramos que encontramos antes que el precio, así se puede verificar que el número
+	// corresponde a un teléfono y no a un accesorio.
+	fmt.Fprintf(w, i18n.T("título: %s\ncondición: %s\nmoneda: %s\nlink: %s\n"),
+		result.Title, result.Condition, result.CurrencyID, result.Permalink)
+
+	// recién para convertir necesitamos la cotización, esperamos a que llegue.
+	rateWait.Wait()
+	// algunas publicaciones ya están en dólares, esas no hace falta convertirlas.
+	if result.CurrencyID == rates.USD {
+		fmt.Fprintf(w, i18n.T("%s cuesta: %s\n"), description(), formatPrice(moneyPrice, rates.USD))
+		return nil
+	}
+	if rateErr != nil {
+		fmt.Fprintf(w, i18n.T("%s cuesta: %s\n"), description(), formatPrice(moneyPrice, result.CurrencyID))
+		return fmt.Errorf(i18n.T("no se puede obtener la taza de cambio en dolares: %v"), rateErr)
+	}
+	usd := moneyPrice.Mul(rate)
+	fmt.Fprintf(w, i18n.T("%s cuesta: %s (%s)\n"), description(),
+		formatPrice(moneyPrice, result.CurrencyID), formatPrice(usd, rates.USD))
+	return nil
+}
@@ -0,0 +1,74 @@
+// Package number interpreta números escritos con los separadores de cada país, por ejemplo
+// "1.234,56" como los publica el Banco Nación o "1,234.56" como los devuelven las APIs, en
+// lugar de reemplazar comas por puntos a ciegas.
+package number
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// Format indica que caracteres separan los decimales y los miles de un número.
+type Format struct {
+	// Decimal separa la parte entera de la decimal.
+	Decimal rune
+	// Thousands separa los grupos de tres dígitos de la parte entera, es opcional en el
+	// texto: "1234,56" y "1.234,56" son el mismo número en Argentina.
+	Thousands rune
+}
+
+var (
+	// Argentina es el formato de los números en Argentina, "1.234,56".
+	Argentina = Format{Decimal: ',', Thousands: '.'}
+	// API es el formato de los números en las APIs y en inglés, "1,234.56".
+	API = Format{Decimal: '.', Thousands: ','}
+)
+
+// Parse interpreta text según el formato f, los espacios alrededor se ignoran. Los grupos de
+// miles, si los hay, tienen que ser de tres dígitos, así "1.2345" no pasa por un número en
+// Argentina.
+func (f Format) Parse(text string) (decimal.Decimal, error) {
+	text = strings.TrimSpace(text)
+	integer, fraction := text, ""
+	if i := strings.IndexRune(text, f.Decimal); i >= 0 {
+		integer, fraction = text[:i], text[i+len(string(f.Decimal)):]
+		if fraction == "" || !digits(fraction) {
+			return decimal.Zero, fmt.Errorf("invalid number %q", text)
+		}
+	}
+	sign := ""
+	if strings.HasPrefix(integer, "-") || strings.HasPrefix(integer, "+") {
+		sign, integer = integer[:1], integer[1:]
+	}
+	groups := strings.Split(integer, string(f.Thousands))
+	for i, group := range groups {
+		valid := digits(group)
+		if len(groups) > 1 {
+			// el primer grupo puede ser mas corto, los demás son de exactamente tres dígitos.
+			valid = valid && len(group) <= 3 && (i == 0 || len(group) == 3)
+		}
+		if !valid {
+			return decimal.Zero, fmt.Errorf("invalid number %q", text)
+		}
+	}
+	normalized := sign + strings.Join(groups, "")
+	if fraction != "" {
+		normalized += "." + fraction
+	}
+	return decimal.NewFromString(normalized)
+}
+
+// digits indica si s no está vacío y solo tiene dígitos.
+func digits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
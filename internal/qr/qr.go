@@ -0,0 +1,540 @@
+// Package qr genera códigos QR, para abrir en el teléfono los links que muestran los programas
+// sin tener que copiarlos. Solo codifica texto en modo byte con el nivel de corrección M, que
+// recupera el 15% del código: alcanza para links y no depende de ninguna biblioteca.
+package qr
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"strings"
+)
+
+// eccPerBlock y blocks son, por versión del código desde la 1, cuantos bytes de corrección
+// tiene cada bloque y cuantos bloques hay con el nivel de corrección M.
+var (
+	eccPerBlock = []int{10, 16, 26, 18, 24, 16, 18, 22, 22, 26, 30, 22, 22, 24, 24, 28, 28, 26, 26, 26,
+		26, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28}
+	blocks = []int{1, 1, 1, 2, 2, 4, 4, 4, 5, 5, 5, 8, 9, 9, 10, 10, 11, 13, 14, 16,
+		17, 17, 18, 20, 21, 23, 25, 26, 28, 29, 31, 33, 35, 37, 38, 40, 43, 45, 47, 49}
+)
+
+const (
+	minVersion = 1
+	maxVersion = 40
+	// quietZone es el margen claro, en módulos, que necesitan los lectores alrededor del código.
+	quietZone = 4
+	// formatM son los bits del nivel de corrección M en la información de formato.
+	formatM = 0
+)
+
+// Code es un código QR, un cuadrado de Size módulos de lado.
+type Code struct {
+	Size int
+	// dark indica, por fila y columna, los módulos oscuros.
+	dark [][]bool
+	// function indica los módulos de los patrones fijos, que no llevan datos ni se enmascaran.
+	function [][]bool
+}
+
+// Encode devuelve el código QR mas chico que contiene text.
+func Encode(text string) (*Code, error) {
+	data := []byte(text)
+	version := minVersion
+	for ; version <= maxVersion; version++ {
+		if 4+countBits(version)+8*len(data) <= 8*dataCodewords(version) {
+			break
+		}
+	}
+	if version > maxVersion {
+		return nil, fmt.Errorf("text too long for a qr code: %d bytes, at most %d", len(data),
+			(8*dataCodewords(maxVersion)-4-countBits(maxVersion))/8)
+	}
+
+	// modo byte, la cantidad de bytes y los bytes, completados hasta la capacidad de la versión.
+	var bits bitBuffer
+	bits.append(0x4, 4)
+	bits.append(len(data), countBits(version))
+	for _, b := range data {
+		bits.append(int(b), 8)
+	}
+	capacity := 8 * dataCodewords(version)
+	terminator := capacity - len(bits)
+	if terminator > 4 {
+		terminator = 4
+	}
+	bits.append(0, terminator)
+	bits.append(0, (8-len(bits)%8)%8)
+	for pad := 0xEC; len(bits) < capacity; pad ^= 0xEC ^ 0x11 {
+		bits.append(pad, 8)
+	}
+
+	c := newCode(version)
+	c.drawCodewords(withErrorCorrection(bits.bytes(), version))
+	c.applyBestMask()
+	return c, nil
+}
+
+// Dark indica si el módulo de la columna x y la fila y es oscuro.
+func (c *Code) Dark(x, y int) bool {
+	return c.dark[y][x]
+}
+
+// Image devuelve el código en blanco y negro, con scale pixeles por módulo y el margen que
+// necesitan los lectores, para guardar como PNG.
+func (c *Code) Image(scale int) image.Image {
+	side := (c.Size + 2*quietZone) * scale
+	img := image.NewPaletted(image.Rect(0, 0, side, side), color.Palette{color.White, color.Black})
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			if !c.dark[y][x] {
+				continue
+			}
+			for dy := 0; dy < scale; dy++ {
+				for dx := 0; dx < scale; dx++ {
+					img.SetColorIndex((x+quietZone)*scale+dx, (y+quietZone)*scale+dy, 1)
+				}
+			}
+		}
+	}
+	return img
+}
+
+// Text devuelve el código para mostrar en la terminal, dos filas de módulos por linea con
+// medios bloques. Como la mayoría de las terminales tienen fondo oscuro, los módulos claros y
+// el margen se dibujan con el color del texto y los oscuros quedan del fondo, igual que
+// qrencode -t UTF8.
+func (c *Code) Text() string {
+	light := func(x, y int) bool {
+		x, y = x-quietZone, y-quietZone
+		return x < 0 || y < 0 || x >= c.Size || y >= c.Size || !c.dark[y][x]
+	}
+	side := c.Size + 2*quietZone
+	var b strings.Builder
+	for y := 0; y < side; y += 2 {
+		for x := 0; x < side; x++ {
+			// la fila de abajo de la última linea es parte del margen.
+			top, bottom := light(x, y), y+1 >= side || light(x, y+1)
+			switch {
+			case top && bottom:
+				b.WriteString("█")
+			case top:
+				b.WriteString("▀")
+			case bottom:
+				b.WriteString("▄")
+			default:
+				b.WriteString(" ")
+			}
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// countBits es cuantos bits ocupa la cantidad de bytes en el modo byte.
+func countBits(version int) int {
+	if version < 10 {
+		return 8
+	}
+	return 16
+}
+
+// rawCodewords es cuantos bytes entran en una versión, sumando datos y corrección.
+func rawCodewords(version int) int {
+	modules := (16*version+128)*version + 64
+	if version >= 2 {
+		alignments := version/7 + 2
+		modules -= (25*alignments-10)*alignments - 55
+		if version >= 7 {
+			modules -= 36
+		}
+	}
+	return modules / 8
+}
+
+// dataCodewords es cuantos bytes de datos entran en una versión.
+func dataCodewords(version int) int {
+	return rawCodewords(version) - eccPerBlock[version-1]*blocks[version-1]
+}
+
+// withErrorCorrection divide data en los bloques de la versión, les agrega la corrección de
+// errores y los intercala como los espera el lector.
+func withErrorCorrection(data []byte, version int) []byte {
+	numBlocks, ecc := blocks[version-1], eccPerBlock[version-1]
+	raw := rawCodewords(version)
+	// los primeros bloques pueden tener un byte de datos menos que los últimos.
+	short := numBlocks - raw%numBlocks
+	shortLen := raw / numBlocks
+	divisor := rsDivisor(ecc)
+	split := make([][]byte, numBlocks)
+	for i, k := 0, 0; i < numBlocks; i++ {
+		size := shortLen - ecc
+		if i >= short {
+			size++
+		}
+		block := append([]byte{}, data[k:k+size]...)
+		k += size
+		correction := rsRemainder(block, divisor)
+		if i < short {
+			// el hueco alinea los bytes de corrección de todos los bloques al intercalar.
+			block = append(block, 0)
+		}
+		split[i] = append(block, correction...)
+	}
+	result := make([]byte, 0, raw)
+	for i := 0; i <= shortLen; i++ {
+		for j, block := range split {
+			if i != shortLen-ecc || j >= short {
+				result = append(result, block[i])
+			}
+		}
+	}
+	return result
+}
+
+// rsDivisor devuelve el polinomio generador de Reed-Solomon de grado degree, sin el
+// coeficiente principal que siempre es 1.
+func rsDivisor(degree int) []byte {
+	result := make([]byte, degree)
+	result[degree-1] = 1
+	root := byte(1)
+	for i := 0; i < degree; i++ {
+		// multiplica el polinomio por (x - r^i).
+		for j := range result {
+			result[j] = gfMultiply(result[j], root)
+			if j+1 < len(result) {
+				result[j] ^= result[j+1]
+			}
+		}
+		root = gfMultiply(root, 0x02)
+	}
+	return result
+}
+
+// rsRemainder devuelve los bytes de corrección de data, el resto de dividirlo por divisor.
+func rsRemainder(data, divisor []byte) []byte {
+	result := make([]byte, len(divisor))
+	for _, b := range data {
+		factor := b ^ result[0]
+		copy(result, result[1:])
+		result[len(result)-1] = 0
+		for i, coefficient := range divisor {
+			result[i] ^= gfMultiply(coefficient, factor)
+		}
+	}
+	return result
+}
+
+// gfMultiply multiplica x por y en el cuerpo de 256 elementos que usan los códigos QR.
+func gfMultiply(x, y byte) byte {
+	z := 0
+	for i := 7; i >= 0; i-- {
+		z = (z << 1) ^ ((z >> 7) * 0x11D)
+		z ^= int((y>>uint(i))&1) * int(x)
+	}
+	return byte(z)
+}
+
+// bitBuffer acumula los bits de los datos, uno por elemento.
+type bitBuffer []bool
+
+// append agrega los n bits menos significativos de value, del mas significativo al menos.
+func (b *bitBuffer) append(value, n int) {
+	for i := n - 1; i >= 0; i-- {
+		*b = append(*b, (value>>uint(i))&1 == 1)
+	}
+}
+
+// bytes devuelve los bits agrupados de a ocho, su largo tiene que ser múltiplo de ocho.
+func (b bitBuffer) bytes() []byte {
+	result := make([]byte, len(b)/8)
+	for i, bit := range b {
+		if bit {
+			result[i/8] |= 1 << uint(7-i%8)
+		}
+	}
+	return result
+}
+
+// newCode devuelve un código de la versión indicada con sus patrones fijos dibujados.
+func newCode(version int) *Code {
+	size := version*4 + 17
+	c := &Code{Size: size, dark: make([][]bool, size), function: make([][]bool, size)}
+	for i := range c.dark {
+		c.dark[i] = make([]bool, size)
+		c.function[i] = make([]bool, size)
+	}
+	// las lineas de sincronización, que los patrones de búsqueda tapan en los extremos.
+	for i := 0; i < size; i++ {
+		c.setFunction(6, i, i%2 == 0)
+		c.setFunction(i, 6, i%2 == 0)
+	}
+	c.drawFinder(3, 3)
+	c.drawFinder(size-4, 3)
+	c.drawFinder(3, size-4)
+	positions := alignmentPositions(version)
+	last := len(positions) - 1
+	for i, x := range positions {
+		for j, y := range positions {
+			// las esquinas de los patrones de búsqueda no llevan patrón de alineación.
+			if (i == 0 && j == 0) || (i == 0 && j == last) || (i == last && j == 0) {
+				continue
+			}
+			c.drawAlignment(x, y)
+		}
+	}
+	// el formato se reserva ahora y se escribe con la máscara elegida.
+	c.drawFormat(0)
+	c.drawVersion(version)
+	return c
+}
+
+// setFunction pinta un módulo de un patrón fijo.
+func (c *Code) setFunction(x, y int, dark bool) {
+	c.dark[y][x] = dark
+	c.function[y][x] = true
+}
+
+// drawFinder dibuja un patrón de búsqueda centrado en x, y con su separador claro.
+func (c *Code) drawFinder(x, y int) {
+	for dy := -4; dy <= 4; dy++ {
+		for dx := -4; dx <= 4; dx++ {
+			xx, yy := x+dx, y+dy
+			if xx < 0 || yy < 0 || xx >= c.Size || yy >= c.Size {
+				continue
+			}
+			distance := max(abs(dx), abs(dy))
+			c.setFunction(xx, yy, distance != 2 && distance != 4)
+		}
+	}
+}
+
+// drawAlignment dibuja un patrón de alineación centrado en x, y.
+func (c *Code) drawAlignment(x, y int) {
+	for dy := -2; dy <= 2; dy++ {
+		for dx := -2; dx <= 2; dx++ {
+			c.setFunction(x+dx, y+dy, max(abs(dx), abs(dy)) != 1)
+		}
+	}
+}
+
+// alignmentPositions devuelve las filas y columnas de los centros de los patrones de
+// alineación de la versión, de menor a mayor.
+func alignmentPositions(version int) []int {
+	if version == 1 {
+		return nil
+	}
+	count := version/7 + 2
+	step := (version*4 + count*2 + 1) / (count*2 - 2) * 2
+	if version == 32 {
+		step = 26
+	}
+	result := make([]int, count)
+	result[0] = 6
+	for i, position := count-1, version*4+17-7; i >= 1; i, position = i-1, position-step {
+		result[i] = position
+	}
+	return result
+}
+
+// drawFormat escribe dos veces el nivel de corrección y la máscara, con su corrección BCH.
+func (c *Code) drawFormat(mask int) {
+	data := formatM<<3 | mask
+	remainder := data
+	for i := 0; i < 10; i++ {
+		remainder = (remainder << 1) ^ ((remainder >> 9) * 0x537)
+	}
+	bits := (data<<10 | remainder) ^ 0x5412
+	bit := func(i int) bool { return (bits>>uint(i))&1 == 1 }
+
+	// alrededor del patrón de búsqueda de arriba a la izquierda.
+	for i := 0; i <= 5; i++ {
+		c.setFunction(8, i, bit(i))
+	}
+	c.setFunction(8, 7, bit(6))
+	c.setFunction(8, 8, bit(7))
+	c.setFunction(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		c.setFunction(14-i, 8, bit(i))
+	}
+	// repartida entre los otros dos patrones de búsqueda.
+	for i := 0; i < 8; i++ {
+		c.setFunction(c.Size-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		c.setFunction(8, c.Size-15+i, bit(i))
+	}
+	// el módulo que siempre es oscuro.
+	c.setFunction(8, c.Size-8, true)
+}
+
+// drawVersion escribe dos veces la versión con su corrección BCH, solo desde la versión 7.
+func (c *Code) drawVersion(version int) {
+	if version < 7 {
+		return
+	}
+	remainder := version
+	for i := 0; i < 12; i++ {
+		remainder = (remainder << 1) ^ ((remainder >> 11) * 0x1F25)
+	}
+	bits := version<<12 | remainder
+	for i := 0; i < 18; i++ {
+		dark := (bits>>uint(i))&1 == 1
+		a, b := c.Size-11+i%3, i/3
+		c.setFunction(a, b, dark)
+		c.setFunction(b, a, dark)
+	}
+}
+
+// drawCodewords ubica los bytes en zigzag, de a dos columnas desde abajo a la derecha,
+// salteando los patrones fijos.
+func (c *Code) drawCodewords(data []byte) {
+	i := 0
+	for right := c.Size - 1; right >= 1; right -= 2 {
+		// la columna de la linea de sincronización vertical no lleva datos.
+		if right == 6 {
+			right = 5
+		}
+		upward := (right+1)&2 == 0
+		for vertical := 0; vertical < c.Size; vertical++ {
+			y := vertical
+			if upward {
+				y = c.Size - 1 - vertical
+			}
+			for j := 0; j < 2; j++ {
+				x := right - j
+				if c.function[y][x] || i >= len(data)*8 {
+					continue
+				}
+				c.dark[y][x] = (data[i/8]>>uint(7-i%8))&1 == 1
+				i++
+			}
+		}
+	}
+}
+
+// masks son las ocho máscaras posibles, indican que módulos de datos se invierten.
+var masks = []func(x, y int) bool{
+	func(x, y int) bool { return (x+y)%2 == 0 },
+	func(x, y int) bool { return y%2 == 0 },
+	func(x, y int) bool { return x%3 == 0 },
+	func(x, y int) bool { return (x+y)%3 == 0 },
+	func(x, y int) bool { return (x/3+y/2)%2 == 0 },
+	func(x, y int) bool { return x*y%2+x*y%3 == 0 },
+	func(x, y int) bool { return (x*y%2+x*y%3)%2 == 0 },
+	func(x, y int) bool { return ((x+y)%2+x*y%3)%2 == 0 },
+}
+
+// applyMask invierte los módulos de datos según la máscara, aplicarla dos veces la quita.
+func (c *Code) applyMask(mask int) {
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			if !c.function[y][x] && masks[mask](x, y) {
+				c.dark[y][x] = !c.dark[y][x]
+			}
+		}
+	}
+}
+
+// applyBestMask aplica la máscara que deja menos patrones difíciles de leer.
+func (c *Code) applyBestMask() {
+	best, bestPenalty := 0, -1
+	for mask := range masks {
+		c.applyMask(mask)
+		c.drawFormat(mask)
+		if penalty := c.penalty(); bestPenalty < 0 || penalty < bestPenalty {
+			best, bestPenalty = mask, penalty
+		}
+		c.applyMask(mask)
+	}
+	c.applyMask(best)
+	c.drawFormat(best)
+}
+
+// penalty puntúa lo difícil que es leer el código según las cuatro reglas del estándar: las
+// tiras de cinco o mas módulos iguales, los cuadrados de dos por dos, lo que se parece a un
+// patrón de búsqueda y la proporción de módulos oscuros.
+func (c *Code) penalty() int {
+	result := 0
+	for i := 0; i < c.Size; i++ {
+		row := make([]bool, c.Size)
+		column := make([]bool, c.Size)
+		for j := 0; j < c.Size; j++ {
+			row[j], column[j] = c.dark[i][j], c.dark[j][i]
+		}
+		result += linePenalty(row) + linePenalty(column)
+	}
+	dark := 0
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			if c.dark[y][x] {
+				dark++
+			}
+			if x+1 < c.Size && y+1 < c.Size {
+				same := c.dark[y][x]
+				if c.dark[y][x+1] == same && c.dark[y+1][x] == same && c.dark[y+1][x+1] == same {
+					result += 3
+				}
+			}
+		}
+	}
+	total := c.Size * c.Size
+	k := (abs(dark*20-total*10)+total-1)/total - 1
+	return result + k*10
+}
+
+// finderLike es la proporción 1:1:3:1:1 de un patrón de búsqueda.
+var finderLike = []bool{true, false, true, true, true, false, true}
+
+// linePenalty puntúa una fila o columna según las tiras de módulos iguales y lo que se parece
+// a un patrón de búsqueda con cuatro módulos claros de un lado, fuera del código todo es claro.
+func linePenalty(line []bool) int {
+	result := 0
+	run := 1
+	for i := 1; i <= len(line); i++ {
+		if i < len(line) && line[i] == line[i-1] {
+			run++
+			continue
+		}
+		if run >= 5 {
+			result += 3 + run - 5
+		}
+		run = 1
+	}
+	light := func(i int) bool { return i < 0 || i >= len(line) || !line[i] }
+	for i := 0; i+len(finderLike) <= len(line); i++ {
+		matches := true
+		for j, dark := range finderLike {
+			if line[i+j] != dark {
+				matches = false
+				break
+			}
+		}
+		if !matches {
+			continue
+		}
+		before, after := true, true
+		for j := 1; j <= 4; j++ {
+			before = before && light(i-j)
+			after = after && light(i+len(finderLike)-1+j)
+		}
+		if before || after {
+			result += 40
+		}
+	}
+	return result
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,212 @@
+package qr
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestGFMultiply(t *testing.T) {
+	// 2 genera el cuerpo: 2^8 es el polinomio reducido, 0x1D, y 2^255 vuelve a 1.
+	power := byte(1)
+	for i := 1; i <= 255; i++ {
+		power = gfMultiply(power, 0x02)
+		if i == 8 && power != 0x1D {
+			t.Errorf("2^8 = %#x, want 0x1d", power)
+		}
+		if i < 255 && power == 1 {
+			t.Fatalf("2^%d = 1, want 2 to have order 255", i)
+		}
+	}
+	if power != 1 {
+		t.Errorf("2^255 = %#x, want 1", power)
+	}
+	if got := gfMultiply(0, 0xAB); got != 0 {
+		t.Errorf("gfMultiply(0, 0xab) = %#x, want 0", got)
+	}
+}
+
+func TestRSRemainder(t *testing.T) {
+	// los bytes de datos de HELLO WORLD en la versión 1-M, del ejemplo del estándar.
+	data := []byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17}
+	want := []byte{196, 35, 39, 119, 235, 215, 231, 226, 93, 23}
+	if got := rsRemainder(data, rsDivisor(len(want))); !bytes.Equal(got, want) {
+		t.Errorf("rsRemainder = %v, want %v", got, want)
+	}
+}
+
+// bit devuelve "1" para un módulo oscuro y "0" para uno claro.
+func bit(dark bool) string {
+	if dark {
+		return "1"
+	}
+	return "0"
+}
+
+// readFormat devuelve las dos copias de la información de formato de c, del bit 14 al 0.
+func readFormat(c *Code) (first, second string) {
+	var a, b [15]bool
+	for i := 0; i <= 5; i++ {
+		a[i] = c.dark[i][8]
+	}
+	a[6], a[7], a[8] = c.dark[7][8], c.dark[8][8], c.dark[8][7]
+	for i := 9; i < 15; i++ {
+		a[i] = c.dark[8][14-i]
+	}
+	for i := 0; i < 8; i++ {
+		b[i] = c.dark[8][c.Size-1-i]
+	}
+	for i := 8; i < 15; i++ {
+		b[i] = c.dark[c.Size-15+i][8]
+	}
+	bits := func(v [15]bool) string {
+		var s strings.Builder
+		for i := 14; i >= 0; i-- {
+			s.WriteString(bit(v[i]))
+		}
+		return s.String()
+	}
+	return bits(a), bits(b)
+}
+
+func TestDrawFormat(t *testing.T) {
+	// la información de formato del nivel M con cada máscara, de la tabla del estándar.
+	want := []string{
+		"101010000010010",
+		"101000100100101",
+		"101111001111100",
+		"101101101001011",
+		"100010111111001",
+		"100000011001110",
+		"100111110010111",
+		"100101010100000",
+	}
+	for mask, bits := range want {
+		c := newCode(2)
+		c.drawFormat(mask)
+		first, second := readFormat(c)
+		if first != bits || second != bits {
+			t.Errorf("format for mask %d = %s and %s, want %s", mask, first, second, bits)
+		}
+		if !c.dark[c.Size-8][8] {
+			t.Errorf("mask %d: the always dark module is light", mask)
+		}
+	}
+}
+
+func TestDrawVersion(t *testing.T) {
+	for _, tt := range []struct {
+		version int
+		want    string
+	}{
+		{7, "000111110010010100"},
+		{40, "101000110001101001"},
+	} {
+		c := newCode(tt.version)
+		var top, left strings.Builder
+		for i := 17; i >= 0; i-- {
+			top.WriteString(bit(c.dark[i/3][c.Size-11+i%3]))
+			left.WriteString(bit(c.dark[c.Size-11+i%3][i/3]))
+		}
+		if top.String() != tt.want || left.String() != tt.want {
+			t.Errorf("version %d info = %s and %s, want %s", tt.version, top.String(), left.String(), tt.want)
+		}
+	}
+}
+
+func TestAlignmentPositions(t *testing.T) {
+	for _, tt := range []struct {
+		version int
+		want    []int
+	}{
+		{1, nil},
+		{2, []int{6, 18}},
+		{7, []int{6, 22, 38}},
+		{15, []int{6, 26, 48, 70}},
+		{32, []int{6, 34, 60, 86, 112, 138}},
+		{36, []int{6, 24, 50, 76, 102, 128, 154}},
+		{40, []int{6, 30, 58, 86, 114, 142, 170}},
+	} {
+		if got := alignmentPositions(tt.version); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("alignmentPositions(%d) = %v, want %v", tt.version, got, tt.want)
+		}
+	}
+}
+
+func TestEncode(t *testing.T) {
+	// HELLO WORLD en modo byte entra en la versión 1 y queda con la máscara 3.
+	want := []string{
+		"#######.#...#.#######",
+		"#.....#.#...#.#.....#",
+		"#.###.#.......#.###.#",
+		"#.###.#.#.#.#.#.###.#",
+		"#.###.#..###..#.###.#",
+		"#.....#...###.#.....#",
+		"#######.#.#.#.#######",
+		"........#####........",
+		"#.##.###.#.##.#..#.##",
+		".##....#.#######.##..",
+		".....#####.#.#.#...##",
+		"#.#.##.##..#...#.#.#.",
+		"#...#.##.##.##....#.#",
+		"........#.##..##..#.#",
+		"#######.#.#######....",
+		"#.....#.###..#.#.####",
+		"#.###.#..#..#.#..#...",
+		"#.###.#.###...#..###.",
+		"#.###.#.##..#..#..#..",
+		"#.....#..###.####...#",
+		"#######.##.#.#.#.....",
+	}
+	c, err := Encode("HELLO WORLD")
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	if c.Size != len(want) {
+		t.Fatalf("size = %d, want %d", c.Size, len(want))
+	}
+	for y, row := range want {
+		var got strings.Builder
+		for x := 0; x < c.Size; x++ {
+			if c.Dark(x, y) {
+				got.WriteString("#")
+			} else {
+				got.WriteString(".")
+			}
+		}
+		if got.String() != row {
+			t.Errorf("row %d = %s, want %s", y, got.String(), row)
+		}
+	}
+	if first, _ := readFormat(c); first != "101101101001011" {
+		t.Errorf("format = %s, want M with mask 3", first)
+	}
+}
+
+func TestEncodeVersion(t *testing.T) {
+	// la mayor cantidad de bytes que entra en cada versión con el nivel M.
+	for _, tt := range []struct{ version, bytes int }{
+		{1, 14}, {2, 26}, {5, 84}, {10, 213}, {20, 666}, {40, 2331},
+	} {
+		for _, n := range []int{tt.bytes, tt.bytes + 1} {
+			c, err := Encode(strings.Repeat("a", n))
+			if n > 2331 {
+				if err == nil || !strings.Contains(err.Error(), "text too long for a qr code") {
+					t.Errorf("Encode(%d bytes) error = %v, want text too long", n, err)
+				}
+				continue
+			}
+			if err != nil {
+				t.Fatalf("Encode(%d bytes): %v", n, err)
+			}
+			want := tt.version
+			if n > tt.bytes {
+				want++
+			}
+			if got := (c.Size - 17) / 4; got != want {
+				t.Errorf("Encode(%d bytes) version = %d, want %d", n, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package rates
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/perrito666/tutoriales_go/internal/bodylimit"
+	"github.com/shopspring/decimal"
+)
+
+// dolarAPIURL es la API de DolarAPI que publica las cotizaciones del dólar en Argentina que
+// no publica el Banco Nación.
+const dolarAPIURL = "https://dolarapi.com/v1/dolares"
+
+const (
+	// DollarOficial es el dólar del Banco Nación.
+	DollarOficial = "oficial"
+	// DollarBlue es el dólar del mercado informal.
+	DollarBlue = "blue"
+	// DollarMEP es el dólar que resulta de comprar y vender bonos en pesos y en dólares.
+	DollarMEP = "mep"
+	// DollarCCL es el contado con liquidación, como el MEP pero con los dólares en el exterior.
+	DollarCCL = "ccl"
+	// DollarTarjeta es el dólar de las compras con tarjeta en el exterior, el oficial con los
+	// impuestos de CardTaxes.
+	DollarTarjeta = "tarjeta"
+)
+
+// ArgentineDollarNames son las cotizaciones del dólar en Argentina, en el orden en que se
+// muestran.
+var ArgentineDollarNames = []string{DollarOficial, DollarBlue, DollarMEP, DollarCCL, DollarTarjeta}
+
+// dolarAPINames relaciona el nombre de cada cotización en DolarAPI con el nuestro.
+var dolarAPINames = map[string]string{
+	"blue":            DollarBlue,
+	"bolsa":           DollarMEP,
+	"contadoconliqui": DollarCCL,
+}
+
+// DollarRate es una cotización del dólar en pesos argentinos.
+type DollarRate struct {
+	// Name es una de ArgentineDollarNames.
+	Name string `json:"name"`
+	// Source es de donde salió la cotización.
+	Source string `json:"source"`
+	// Buy y Sell son la compra y la venta, algunas cotizaciones como la tarjeta solo tienen
+	// venta y su compra es cero.
+	Buy  decimal.Decimal `json:"buy"`
+	Sell decimal.Decimal `json:"sell"`
+}
+
+// Pesos devuelve cuantos pesos vale un dólar, el promedio entre compra y venta o la venta si
+// no hay compra.
+func (r DollarRate) Pesos() decimal.Decimal {
+	if r.Buy.IsZero() {
+		return r.Sell
+	}
+	return r.Buy.Add(r.Sell).Div(decimal.NewFromFloat(2.0))
+}
+
+// ArgentineDollars pide a la vez la cotización oficial a bna y las demás a DolarAPI con do, el
+// dólar tarjeta es el oficial con los impuestos taxes. Devuelve las que consiguió en el orden
+// de ArgentineDollarNames y el error de cada una de las que no, por nombre.
+func ArgentineDollars(ctx context.Context, bna *BNA, do Doer, taxes CardTaxes) ([]DollarRate, map[string]error) {
+	found := map[string]DollarRate{}
+	failed := map[string]error{}
+	var mu sync.Mutex
+	wg := &sync.WaitGroup{}
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		// parseBNA garantiza que el dólar está entre las cotizaciones.
+		quotes, err := bna.quotes(ctx)
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil {
+			failed[DollarOficial] = err
+			failed[DollarTarjeta] = err
+			return
+		}
+		dollar := quotes[USD]
+		oficial := DollarRate{Name: DollarOficial, Source: BNAName, Buy: dollar.Buy, Sell: dollar.Sell}
+		found[DollarOficial] = oficial
+		found[DollarTarjeta] = cardRate(oficial, taxes)
+	}()
+	go func() {
+		defer wg.Done()
+		dollars, err := fetchDolarAPI(ctx, do)
+		mu.Lock()
+		defer mu.Unlock()
+		for _, name := range ArgentineDollarNames {
+			if name == DollarOficial || name == DollarTarjeta {
+				continue
+			}
+			rate, ok := dollars[name]
+			switch {
+			case err != nil:
+				failed[name] = err
+			case !ok:
+				failed[name] = fmt.Errorf("%s not found in dolarapi response", name)
+			default:
+				found[name] = rate
+			}
+		}
+	}()
+	wg.Wait()
+
+	rates := make([]DollarRate, 0, len(found))
+	for _, name := range ArgentineDollarNames {
+		if rate, ok := found[name]; ok {
+			rates = append(rates, rate)
+		}
+	}
+	return rates, failed
+}
+
+// dolarAPIRate imita un elemento de la respuesta de DolarAPI, la compra puede faltar.
+type dolarAPIRate struct {
+	Casa   string           `json:"casa"`
+	Compra *decimal.Decimal `json:"compra"`
+	Venta  *decimal.Decimal `json:"venta"`
+}
+
+// fetchDolarAPI pide las cotizaciones a DolarAPI y devuelve las que conocemos por nombre.
+func fetchDolarAPI(ctx context.Context, do Doer) (map[string]DollarRate, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, dolarAPIURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("building dolarapi request: %w", err)
+	}
+	response, err := send(do, request)
+	if err != nil {
+		return nil, fmt.Errorf("querying dolarapi: %w", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("requesting to dolarapi: %s", response.Status)
+	}
+
+	found := []dolarAPIRate{}
+	if err := json.NewDecoder(bodylimit.Body(response)).Decode(&found); err != nil {
+		return nil, fmt.Errorf("decoding dolarapi response: %w", err)
+	}
+	dollars := map[string]DollarRate{}
+	for _, r := range found {
+		name, ok := dolarAPINames[r.Casa]
+		if !ok || r.Venta == nil || !r.Venta.IsPositive() {
+			continue
+		}
+		rate := DollarRate{Name: name, Source: "dolarapi", Sell: *r.Venta}
+		if r.Compra != nil {
+			rate.Buy = *r.Compra
+		}
+		dollars[name] = rate
+	}
+	return dollars, nil
+}
@@ -0,0 +1,91 @@
+package rates
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/perrito666/tutoriales_go/internal/bodylimit"
+	"github.com/shopspring/decimal"
+)
+
+// bcraURL es la API de estadísticas cambiarias del Banco Central, con la cotización del día
+// de todas las monedas.
+const bcraURL = "https://api.bcra.gob.ar/estadisticascambiarias/v1.0/Cotizaciones"
+
+// bcraResponse imita la estructura JSON de la respuesta del BCRA, solo lo que nos interesa.
+type bcraResponse struct {
+	Results struct {
+		Detalle []struct {
+			CodigoMoneda string `json:"codigoMoneda"`
+			// TipoCotizacion es cuantos pesos vale una unidad de la moneda.
+			TipoCotizacion decimal.Decimal `json:"tipoCotizacion"`
+		} `json:"detalle"`
+	} `json:"results"`
+}
+
+// BCRA cotiza con el tipo de cambio oficial que publica el Banco Central el peso argentino y
+// todas las monedas que el BCRA cotiza, las demás devuelven ErrUnsupportedCurrency.
+type BCRA struct {
+	// Do envía los pedidos, si es nil se usa http.DefaultClient.
+	Do Doer
+}
+
+// URL devuelve la API del BCRA, nada para USD. Como el BCRA publica todas las monedas en un
+// mismo pedido, solo se sabe si cotiza una cuando llega la respuesta.
+func (b *BCRA) URL(currency string) (string, error) {
+	if currency == USD {
+		return "", nil
+	}
+	return bcraURL, nil
+}
+
+// ToUSD devuelve cuantos dólares vale una unidad de currency, su cotización en pesos dividida
+// por la del dólar, ambas de un mismo pedido.
+func (b *BCRA) ToUSD(ctx context.Context, currency string) (decimal.Decimal, error) {
+	if currency == USD {
+		return decimal.New(1, 0), nil
+	}
+	quotes, err := fetchBCRA(ctx, b.Do)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	dollar, ok := quotes[USD]
+	if !ok || !dollar.IsPositive() {
+		return decimal.Zero, fmt.Errorf("%s not found in bcra response", USD)
+	}
+	pesos := decimal.New(1, 0)
+	if currency != ars {
+		if pesos, ok = quotes[currency]; !ok || !pesos.IsPositive() {
+			return decimal.Zero, fmt.Errorf("%s not found in bcra response: %w", currency, ErrUnsupportedCurrency)
+		}
+	}
+	return pesos.Div(dollar), nil
+}
+
+// fetchBCRA pide las cotizaciones al BCRA y las devuelve en pesos, por código de moneda.
+func fetchBCRA(ctx context.Context, do Doer) (map[string]decimal.Decimal, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, bcraURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("building bcra request: %w", err)
+	}
+	response, err := send(do, request)
+	if err != nil {
+		return nil, fmt.Errorf("querying bcra: %w", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("requesting to bcra: %s", response.Status)
+	}
+
+	found := &bcraResponse{}
+	if err := json.NewDecoder(bodylimit.Body(response)).Decode(found); err != nil {
+		return nil, fmt.Errorf("decoding bcra response: %w", err)
+	}
+	quotes := map[string]decimal.Decimal{}
+	for _, quote := range found.Results.Detalle {
+		quotes[quote.CodigoMoneda] = quote.TipoCotizacion
+	}
+	return quotes, nil
+}
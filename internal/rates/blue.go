@@ -0,0 +1,42 @@
+package rates
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/shopspring/decimal"
+)
+
+// Blue cotiza el peso argentino con el promedio entre compra y venta del dólar blue que
+// publica DolarAPI. Las demás monedas devuelven ErrUnsupportedCurrency.
+type Blue struct {
+	// Do envía los pedidos, si es nil se usa http.DefaultClient.
+	Do Doer
+}
+
+// URL devuelve la API de DolarAPI para el peso, nada para USD.
+func (b *Blue) URL(currency string) (string, error) {
+	if err := pesosOnly(currency); err != nil || currency == USD {
+		return "", err
+	}
+	return dolarAPIURL, nil
+}
+
+// ToUSD devuelve cuantos dólares vale un peso al dólar blue, o uno si currency es el dólar.
+func (b *Blue) ToUSD(ctx context.Context, currency string) (decimal.Decimal, error) {
+	if err := pesosOnly(currency); err != nil {
+		return decimal.Zero, err
+	}
+	if currency == USD {
+		return decimal.New(1, 0), nil
+	}
+	dollars, err := fetchDolarAPI(ctx, b.Do)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	blue, ok := dollars[DollarBlue]
+	if !ok {
+		return decimal.Zero, fmt.Errorf("%s not found in dolarapi response", DollarBlue)
+	}
+	return decimal.New(1, 0).Div(blue.Pesos()), nil
+}
@@ -0,0 +1,234 @@
+package rates
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/perrito666/tutoriales_go/internal/bodylimit"
+	"github.com/perrito666/tutoriales_go/internal/conditional"
+	"github.com/shopspring/decimal"
+)
+
+const bnaURL = "http://www.bna.com.ar/Personas"
+
+// bnaCurrencies relaciona el identificador que utiliza la fuente de datos para indicar la
+// sección de cada moneda con su código.
+var bnaCurrencies = map[string]string{
+	"Dolar U.S.A": USD,
+	"Euro":        "EUR",
+	"Real":        "BRL",
+}
+
+// bnaPer100 es la marca que agrega la página a las monedas que cotiza cada 100 unidades, por
+// ejemplo "Real *".
+const bnaPer100 = " *"
+
+// ars es el código del peso argentino, la moneda en la que el Banco Nación cotiza todas las
+// demás.
+const ars = "ARS"
+
+const (
+	// BNABilletes es la tabla de la cotización de billetes, la del efectivo.
+	BNABilletes = "billetes"
+	// BNADivisas es la tabla de la cotización de divisas, la de las transferencias.
+	BNADivisas = "divisas"
+)
+
+// ValidBNATable indica si table es una de las tablas de la página del Banco Nación.
+func ValidBNATable(table string) bool {
+	return table == BNABilletes || table == BNADivisas
+}
+
+// BNA cotiza con el promedio entre compra y venta que publica el Banco Nación en su página el
+// peso argentino y las monedas de bnaCurrencies, las demás devuelven ErrUnsupportedCurrency.
+type BNA struct {
+	// Do envía los pedidos, si es nil se usa http.DefaultClient.
+	Do Doer
+	// Table es la tabla de la que se lee la cotización, BNABilletes o BNADivisas, si está
+	// vacía se usa BNABilletes. Cual es la correcta depende del uso: el efectivo se cambia a
+	// la de billetes y las transferencias a la de divisas.
+	Table string
+	// CachePath es el archivo donde se guardan las cotizaciones leídas para las próximas
+	// ejecuciones, si está vacío solo se guardan en memoria.
+	CachePath string
+	// TTL es el tiempo durante el cual se usan las cotizaciones guardadas sin volver a pedir
+	// la página, cero la pide siempre. La página cambia a lo sumo unas pocas veces por día.
+	TTL time.Duration
+	// Refresh pide la página la próxima vez aunque las cotizaciones guardadas no hayan
+	// vencido.
+	Refresh bool
+
+	mu     sync.Mutex
+	cached bnaCacheFile
+}
+
+// table devuelve la tabla de la que se lee la cotización.
+func (b *BNA) table() (string, error) {
+	if b.Table == "" {
+		return BNABilletes, nil
+	}
+	if !ValidBNATable(b.Table) {
+		return "", fmt.Errorf("unknown bna table %q, must be %s or %s", b.Table, BNABilletes, BNADivisas)
+	}
+	return b.Table, nil
+}
+
+// quoted indica si el Banco Nación cotiza currency.
+func quoted(currency string) bool {
+	if currency == ars {
+		return true
+	}
+	for _, code := range bnaCurrencies {
+		if code == currency {
+			return true
+		}
+	}
+	return false
+}
+
+// URL devuelve la página del Banco Nación para las monedas que cotiza, nada para USD.
+func (b *BNA) URL(currency string) (string, error) {
+	if _, err := b.table(); err != nil {
+		return "", err
+	}
+	if currency == USD {
+		return "", nil
+	}
+	if !quoted(currency) {
+		return "", fmt.Errorf("%s: %w", currency, ErrUnsupportedCurrency)
+	}
+	return bnaURL, nil
+}
+
+// Pesos devuelve cuantos pesos vale una unidad de currency, el promedio entre compra y venta
+// de la tabla b.Table.
+func (b *BNA) Pesos(ctx context.Context, currency string) (decimal.Decimal, error) {
+	if _, err := b.URL(currency); err != nil {
+		return decimal.Zero, err
+	}
+	if currency == ars {
+		return decimal.New(1, 0), nil
+	}
+	quotes, err := b.quotes(ctx)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	return quotes.pesos(currency)
+}
+
+// ToUSD devuelve cuantos dólares vale una unidad de currency, su cotización en pesos dividida
+// por la del dólar, ambas de un mismo pedido a la página.
+func (b *BNA) ToUSD(ctx context.Context, currency string) (decimal.Decimal, error) {
+	if _, err := b.URL(currency); err != nil {
+		return decimal.Zero, err
+	}
+	if currency == USD {
+		return decimal.New(1, 0), nil
+	}
+	quotes, err := b.quotes(ctx)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	dollar, err := quotes.pesos(USD)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	if dollar.IsZero() {
+		return decimal.Zero, fmt.Errorf("bna quoted the dollar at 0 pesos")
+	}
+	pesos := decimal.New(1, 0)
+	if currency != ars {
+		if pesos, err = quotes.pesos(currency); err != nil {
+			return decimal.Zero, err
+		}
+	}
+	return pesos.Div(dollar), nil
+}
+
+// quotes devuelve las cotizaciones de la tabla b.Table, las guardadas si tienen menos de
+// b.TTL o si no las de la página, que se guardan. Los pedidos simultáneos esperan al primero
+// en lugar de pedir la página cada uno.
+func (b *BNA) quotes(ctx context.Context) (bnaQuotes, error) {
+	table, err := b.table()
+	if err != nil {
+		return nil, err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.cached == nil {
+		b.cached = loadBNACache(b.CachePath)
+	}
+	entry, ok := b.cached[table]
+	if ok && !b.Refresh && time.Since(entry.Fetched) < b.TTL {
+		return entry.Quotes, nil
+	}
+
+	// si ya teníamos la tabla la pedimos condicionalmente, si no cambió no se descarga.
+	var cached *bnaCacheEntry
+	if ok {
+		cached = &entry
+	}
+	entry, err = fetchBNA(ctx, b.Do, table, cached)
+	if err != nil {
+		return nil, err
+	}
+	// solo se fuerza el primer pedido, un servidor no pide la página en cada búsqueda.
+	b.Refresh = false
+	b.cached[table] = entry
+	// si no se puede guardar la cotización igual sirve, la próxima ejecución pedirá la página.
+	_ = b.cached.save(b.CachePath)
+	return entry.Quotes, nil
+}
+
+// bnaQuote es la cotización en pesos de una moneda.
+type bnaQuote struct {
+	Buy  decimal.Decimal `json:"buy"`
+	Sell decimal.Decimal `json:"sell"`
+}
+
+// bnaQuotes son las cotizaciones de una tabla de la página, por código de moneda.
+type bnaQuotes map[string]bnaQuote
+
+// pesos devuelve el promedio entre compra y venta de currency.
+func (q bnaQuotes) pesos(currency string) (decimal.Decimal, error) {
+	quote, ok := q[currency]
+	if !ok {
+		return decimal.Zero, fmt.Errorf("%s not found in bna page: %w", currency, ErrUnsupportedCurrency)
+	}
+	return quote.Buy.Add(quote.Sell).Div(decimal.NewFromFloat(2.0)), nil
+}
+
+// fetchBNA pide la página del Banco Nación y devuelve las cotizaciones de la tabla table. Si
+// cached no es nil la página se pide con sus validadores y, si no cambió, se devuelven sus
+// cotizaciones.
+func fetchBNA(ctx context.Context, do Doer, table string, cached *bnaCacheEntry) (bnaCacheEntry, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, bnaURL, nil)
+	if err != nil {
+		return bnaCacheEntry{}, fmt.Errorf("building bna request: %v", err)
+	}
+	if cached != nil {
+		cached.Validators.Set(request)
+	}
+	res, err := send(do, request)
+	if err != nil {
+		return bnaCacheEntry{}, fmt.Errorf("getting bna website: %v", err)
+	}
+
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusNotModified && cached != nil {
+		entry := *cached
+		entry.Fetched = time.Now()
+		return entry, nil
+	}
+	if res.StatusCode != 200 {
+		return bnaCacheEntry{}, fmt.Errorf("código de estado de la petición inesperado: %d %s", res.StatusCode, res.Status)
+	}
+	quotes, err := parseBNA(bodylimit.Body(res), table)
+	if err != nil {
+		return bnaCacheEntry{}, err
+	}
+	return bnaCacheEntry{Fetched: time.Now(), Validators: conditional.FromResponse(res), Quotes: quotes}, nil
+}
@@ -0,0 +1,79 @@
+package rates
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/perrito666/tutoriales_go/internal/conditional"
+)
+
+// DefaultBNATTL es el tiempo por defecto durante el cual los programas usan las cotizaciones
+// del Banco Nación guardadas sin volver a pedir la página.
+const DefaultBNATTL = time.Hour
+
+// bnaCacheEntry son las cotizaciones de una tabla, cuando se leyeron y los validadores de la
+// respuesta de la que salieron.
+type bnaCacheEntry struct {
+	Fetched    time.Time              `json:"fetched"`
+	Validators conditional.Validators `json:"validators"`
+	Quotes     bnaQuotes              `json:"quotes"`
+}
+
+// bnaCacheFile es el contenido del archivo donde se guardan las cotizaciones, por tabla.
+type bnaCacheFile map[string]bnaCacheEntry
+
+// loadBNACache lee las cotizaciones guardadas en path, las que no pasan checkBNAQuotes se
+// descartan. Si no hay archivo o no se puede leer devuelve un bnaCacheFile vacío.
+func loadBNACache(path string) bnaCacheFile {
+	cached := bnaCacheFile{}
+	if path == "" {
+		return cached
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cached
+	}
+	if err := json.Unmarshal(data, &cached); err != nil {
+		return bnaCacheFile{}
+	}
+	for table, entry := range cached {
+		if checkBNAQuotes(entry.Quotes) != nil {
+			delete(cached, table)
+		}
+	}
+	return cached
+}
+
+// save guarda las cotizaciones en path, primero en un archivo temporal así otro proceso nunca
+// lee un archivo a medio escribir.
+func (c bnaCacheFile) save(path string) error {
+	if path == "" {
+		return nil
+	}
+	data, err := json.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("encoding bna cache: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("creating cache directory: %v", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("creating bna cache: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing bna cache: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("writing bna cache: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("writing bna cache: %v", err)
+	}
+	return nil
+}
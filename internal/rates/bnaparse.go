@@ -0,0 +1,210 @@
+package rates
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/perrito666/tutoriales_go/internal/number"
+	"github.com/shopspring/decimal"
+)
+
+// ErrLayoutChanged indica que no se pudo leer la cotización de la página del Banco Nación con
+// ninguna de las estrategias, probablemente porque cambió su HTML.
+var ErrLayoutChanged = errors.New("bna page layout changed")
+
+// LayoutError es el error de una página del Banco Nación que no se pudo leer, con lo que falló
+// en cada estrategia. errors.Is(err, ErrLayoutChanged) es verdadero para estos errores.
+type LayoutError struct {
+	// Table es la tabla que se intentó leer.
+	Table string
+	// Reasons es lo que falló con cada estrategia, en el orden en que se intentaron.
+	Reasons []string
+}
+
+func (e *LayoutError) Error() string {
+	return fmt.Sprintf("%v: table %s: %s", ErrLayoutChanged, e.Table, strings.Join(e.Reasons, "; "))
+}
+
+// Unwrap devuelve ErrLayoutChanged.
+func (e *LayoutError) Unwrap() error {
+	return ErrLayoutChanged
+}
+
+var (
+	// bnaMaxPesos es la cotización máxima en pesos que se considera razonable, una mayor
+	// seguramente es un número mal leído, por ejemplo dos celdas pegadas.
+	bnaMaxPesos = decimal.New(10000000, 0)
+	// bnaMaxSpread es la máxima relación entre venta y compra que se considera razonable, la
+	// del banco no suele pasar de unos pocos puntos.
+	bnaMaxSpread = decimal.NewFromFloat(1.2)
+)
+
+// bnaStrategy es una forma de leer las cotizaciones de una tabla de la página.
+type bnaStrategy struct {
+	name  string
+	parse func(page []byte, table string) (bnaQuotes, error)
+}
+
+// bnaStrategies son las estrategias con las que se lee la página, en orden: si una falla o lo
+// que lee no pasa checkBNAQuotes se intenta la siguiente.
+var bnaStrategies = []bnaStrategy{
+	{"selector", parseBNASelector},
+	{"loose selector", parseBNALooseSelector},
+	{"regexp", parseBNARegexp},
+}
+
+// parseBNA lee de la página del Banco Nación las cotizaciones de las monedas de
+// bnaCurrencies en la tabla table. Si ninguna estrategia puede devuelve un *LayoutError.
+func parseBNA(body io.Reader, table string) (bnaQuotes, error) {
+	page, err := io.ReadAll(body)
+	if err != nil {
+		return nil, fmt.Errorf("reading site body: %w", err)
+	}
+	layoutErr := &LayoutError{Table: table}
+	for _, strategy := range bnaStrategies {
+		quotes, err := strategy.parse(page, table)
+		if err == nil {
+			err = checkBNAQuotes(quotes)
+		}
+		if err == nil {
+			return quotes, nil
+		}
+		layoutErr.Reasons = append(layoutErr.Reasons, strategy.name+": "+err.Error())
+	}
+	return nil, layoutErr
+}
+
+// checkBNAQuotes verifica que las cotizaciones tengan sentido: el dólar tiene que estar, y en
+// todas la compra tiene que ser positiva y menor que la venta, y ambas razonables. Una
+// estrategia que lee celdas equivocadas suele fallar acá en lugar de devolver un número falso.
+func checkBNAQuotes(quotes bnaQuotes) error {
+	if _, ok := quotes[USD]; !ok {
+		return fmt.Errorf("dollar not found")
+	}
+	for currency, quote := range quotes {
+		if !quote.Buy.IsPositive() || !quote.Buy.LessThan(quote.Sell) {
+			return fmt.Errorf("%s buy %s is not positive and below sell %s", currency, quote.Buy, quote.Sell)
+		}
+		if quote.Sell.GreaterThan(bnaMaxPesos) || quote.Sell.GreaterThan(quote.Buy.Mul(bnaMaxSpread)) {
+			return fmt.Errorf("%s buy %s and sell %s out of plausible range", currency, quote.Buy, quote.Sell)
+		}
+	}
+	return nil
+}
+
+// addBNAQuote agrega a quotes la cotización de la fila de la moneda name, si es una de las de
+// bnaCurrencies.
+func addBNAQuote(quotes bnaQuotes, name, buyText, sellText string) error {
+	name = strings.TrimSpace(name)
+	per100 := strings.HasSuffix(name, bnaPer100)
+	currency, ok := bnaCurrencies[strings.TrimSuffix(name, bnaPer100)]
+	if !ok {
+		return nil
+	}
+	buy, err := number.Argentina.Parse(buyText)
+	if err != nil {
+		return fmt.Errorf("no se puede convertir el valor de compra de %s a Decimal: %v", currency, err)
+	}
+	sell, err := number.Argentina.Parse(sellText)
+	if err != nil {
+		return fmt.Errorf("no se puede convertir el valor de venta de %s a Decimal: %v", currency, err)
+	}
+	// algunas monedas se cotizan cada 100 unidades, las guardamos por unidad.
+	if per100 {
+		buy = buy.Div(decimal.New(100, 0))
+		sell = sell.Div(decimal.New(100, 0))
+	}
+	quotes[currency] = bnaQuote{Buy: buy, Sell: sell}
+	return nil
+}
+
+// parseBNASelector es la estrategia original del tutorial.
+func parseBNASelector(page []byte, table string) (bnaQuotes, error) {
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(page))
+	if err != nil {
+		return nil, fmt.Errorf("reading site body: %v", err)
+	}
+
+	quotes := bnaQuotes{}
+	var parseErr error
+	// Una selección es el resultado de un filtro o búsqueda dentro del DOM, cada fila de la
+	// tabla es una moneda, billetes y divisas son dos tablas con la misma estructura.
+	doc.Find("#" + table + " tr").EachWithBreak(func(_ int, s *goquery.Selection) bool {
+		cells := s.Find("td")
+		// Buscamos un elemento con la clase y cuyo texto tenga lo que buscamos, este criterio
+		// lo obtuvimos de analizar el código HTML de la pagina detenidamente el la
+		// sección que nos interesa. 0 es el título de la sección, 1 la cotización comprador
+		// y 2 vendedor, los demás nodos si los hubiese se ignoran.
+		title := cells.Eq(0)
+		if !title.HasClass("tit") {
+			return true
+		}
+		parseErr = addBNAQuote(quotes, title.Text(), cells.Eq(1).Text(), cells.Eq(2).Text())
+		return parseErr == nil
+	})
+	if parseErr != nil {
+		return nil, parseErr
+	}
+	return quotes, nil
+}
+
+// parseBNALooseSelector es como parseBNASelector pero no depende de la clase del título ni de
+// que sea la primera celda, ni de que la tabla tenga exactamente el ID table: busca en cada
+// fila de cualquier elemento cuyo ID o clase contenga table la celda con el nombre de una
+// moneda y toma las dos siguientes.
+func parseBNALooseSelector(page []byte, table string) (bnaQuotes, error) {
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(page))
+	if err != nil {
+		return nil, fmt.Errorf("reading site body: %v", err)
+	}
+
+	quotes := bnaQuotes{}
+	var parseErr error
+	doc.Find(fmt.Sprintf("[id*=%q] tr, [class*=%q] tr", table, table)).EachWithBreak(func(_ int, s *goquery.Selection) bool {
+		cells := s.Children().Filter("td, th")
+		cells.EachWithBreak(func(i int, cell *goquery.Selection) bool {
+			name := strings.TrimSuffix(strings.TrimSpace(cell.Text()), bnaPer100)
+			if _, ok := bnaCurrencies[name]; !ok {
+				return true
+			}
+			parseErr = addBNAQuote(quotes, cell.Text(), cells.Eq(i+1).Text(), cells.Eq(i+2).Text())
+			return false
+		})
+		return parseErr == nil
+	})
+	if parseErr != nil {
+		return nil, parseErr
+	}
+	return quotes, nil
+}
+
+// bnaRowRegexp encuentra en el HTML una celda con el nombre de una moneda seguida de dos
+// celdas con números.
+var bnaRowRegexp = regexp.MustCompile(`<t[dh][^>]*>\s*([^<]+?)\s*</t[dh]>\s*<t[dh][^>]*>\s*([\d.,]+)\s*</t[dh]>\s*<t[dh][^>]*>\s*([\d.,]+)\s*</t[dh]>`)
+
+// parseBNARegexp no interpreta el HTML, busca con una expresión regular las filas de las
+// monedas en el texto que sigue a la primera aparición del nombre de la tabla hasta el fin
+// de esa tabla. Sirve aunque la página deje de ser HTML válido para goquery.
+func parseBNARegexp(page []byte, table string) (bnaQuotes, error) {
+	start := bytes.Index(page, []byte(`"`+table+`"`))
+	if start < 0 {
+		return nil, fmt.Errorf("table %s not found", table)
+	}
+	section := page[start:]
+	if end := bytes.Index(section, []byte("</table>")); end >= 0 {
+		section = section[:end]
+	}
+
+	quotes := bnaQuotes{}
+	for _, match := range bnaRowRegexp.FindAllSubmatch(section, -1) {
+		if err := addBNAQuote(quotes, string(match[1]), string(match[2]), string(match[3])); err != nil {
+			return nil, err
+		}
+	}
+	return quotes, nil
+}
@@ -0,0 +1,40 @@
+package rates
+
+import (
+	"fmt"
+
+	"github.com/shopspring/decimal"
+)
+
+// CardTaxes son los impuestos que se suman al dólar oficial en las compras con tarjeta en el
+// exterior, en porcentaje. Cambian seguido, por eso los programas permiten configurarlos.
+type CardTaxes struct {
+	// PAIS es el impuesto PAIS.
+	PAIS decimal.Decimal
+	// Perception es la percepción a cuenta de ganancias y bienes personales.
+	Perception decimal.Decimal
+}
+
+// DefaultCardTaxes son los impuestos vigentes desde diciembre de 2024: sin impuesto PAIS y con
+// una percepción del 30%.
+var DefaultCardTaxes = CardTaxes{PAIS: decimal.Zero, Perception: decimal.New(30, 0)}
+
+// Total devuelve la suma de los impuestos, en porcentaje.
+func (t CardTaxes) Total() decimal.Decimal {
+	return t.PAIS.Add(t.Perception)
+}
+
+// Apply devuelve cuantos pesos cuesta un dólar pagado con tarjeta si el oficial vale pesos.
+func (t CardTaxes) Apply(pesos decimal.Decimal) decimal.Decimal {
+	return pesos.Add(pesos.Mul(t.Total()).Div(decimal.New(100, 0)))
+}
+
+// cardRate devuelve el dólar tarjeta a partir de la venta del oficial, que es a la que los
+// bancos liquidan los consumos.
+func cardRate(oficial DollarRate, taxes CardTaxes) DollarRate {
+	return DollarRate{
+		Name:   DollarTarjeta,
+		Source: fmt.Sprintf("%s +%s%%", oficial.Source, taxes.Total()),
+		Sell:   taxes.Apply(oficial.Sell),
+	}
+}
@@ -0,0 +1,88 @@
+package rates
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/perrito666/tutoriales_go/internal/bodylimit"
+	"github.com/shopspring/decimal"
+)
+
+// coinGeckoURL es la API de precios simples de CoinGecko, gratuita y sin clave.
+const coinGeckoURL = "https://api.coingecko.com/api/v3/simple/price"
+
+// CryptoCurrencies son las criptomonedas que cotiza CoinGecko, en el orden en que se muestran.
+var CryptoCurrencies = []string{"BTC", "ETH", "USDT"}
+
+// coinGeckoIDs es el ID de CoinGecko de cada criptomoneda de CryptoCurrencies.
+var coinGeckoIDs = map[string]string{
+	"BTC":  "bitcoin",
+	"ETH":  "ethereum",
+	"USDT": "tether",
+}
+
+// CoinGecko cotiza las criptomonedas de CryptoCurrencies con el precio en dólares que publica
+// CoinGecko, las demás monedas devuelven ErrUnsupportedCurrency. Sirve para mostrar un precio
+// en cripto, no como fuente de las monedas de los sitios.
+type CoinGecko struct {
+	// Do envía los pedidos, si es nil se usa http.DefaultClient.
+	Do Doer
+}
+
+// URL devuelve la API de CoinGecko para currency, nada para USD.
+func (c *CoinGecko) URL(currency string) (string, error) {
+	if currency == USD {
+		return "", nil
+	}
+	id, ok := coinGeckoIDs[currency]
+	if !ok {
+		return "", fmt.Errorf("%s: %w", currency, ErrUnsupportedCurrency)
+	}
+	u, err := url.Parse(coinGeckoURL)
+	if err != nil {
+		return "", fmt.Errorf("parsing coingecko url: %w", err)
+	}
+	queryValues := u.Query()
+	queryValues.Set("ids", id)
+	queryValues.Set("vs_currencies", "usd")
+	u.RawQuery = queryValues.Encode()
+	return u.String(), nil
+}
+
+// ToUSD devuelve cuantos dólares vale una unidad de la criptomoneda currency, o uno si currency
+// es el dólar.
+func (c *CoinGecko) ToUSD(ctx context.Context, currency string) (decimal.Decimal, error) {
+	if currency == USD {
+		return decimal.New(1, 0), nil
+	}
+	priceURL, err := c.URL(currency)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, priceURL, nil)
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("building coingecko request: %w", err)
+	}
+	response, err := send(c.Do, request)
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("querying coingecko: %w", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return decimal.Zero, fmt.Errorf("requesting to coingecko: %s", response.Status)
+	}
+
+	// la respuesta tiene el precio de cada ID en cada moneda pedida, {"bitcoin": {"usd": 1}}.
+	prices := map[string]map[string]decimal.Decimal{}
+	if err := json.NewDecoder(bodylimit.Body(response)).Decode(&prices); err != nil {
+		return decimal.Zero, fmt.Errorf("decoding coingecko response: %w", err)
+	}
+	price, ok := prices[coinGeckoIDs[currency]]["usd"]
+	if !ok || !price.IsPositive() {
+		return decimal.Zero, fmt.Errorf("%s not found in coingecko response", currency)
+	}
+	return price, nil
+}
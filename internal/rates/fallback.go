@@ -0,0 +1,67 @@
+package rates
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// Named es una fuente con su nombre, para los errores de Fallback.
+type Named struct {
+	Name   string
+	Source Source
+}
+
+// Fallback cotiza con la primera de sus fuentes que pueda, si una falla se prueba con la
+// siguiente, así una moneda que una fuente no cotiza no hace fallar todo un sitio.
+type Fallback []Named
+
+// URL devuelve la dirección de la primera fuente que cotiza currency.
+func (f Fallback) URL(currency string) (string, error) {
+	errs := make([]error, 0, len(f))
+	for _, named := range f {
+		rateURL, err := named.Source.URL(currency)
+		if err == nil {
+			return rateURL, nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", named.Name, err))
+	}
+	return "", fallbackError(errs)
+}
+
+// ToUSD devuelve la cotización de la primera fuente que la consigue. Si fallan todas el error
+// las incluye a todas, y solo es ErrUnsupportedCurrency si alguna lo fue. Un contexto vencido
+// no se sigue intentando.
+func (f Fallback) ToUSD(ctx context.Context, currency string) (decimal.Decimal, error) {
+	errs := make([]error, 0, len(f))
+	for _, named := range f {
+		rate, err := named.Source.ToUSD(ctx, currency)
+		if err == nil {
+			return rate, nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", named.Name, err))
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return decimal.Zero, fallbackError(errs)
+}
+
+// fallbackError son los errores de todas las fuentes de un Fallback, en una sola linea para
+// que entren en una tabla o en el reporte de un sitio.
+type fallbackError []error
+
+func (e fallbackError) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, err := range e {
+		messages = append(messages, err.Error())
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Unwrap permite que errors.Is encuentre el error de cualquiera de las fuentes.
+func (e fallbackError) Unwrap() []error {
+	return e
+}
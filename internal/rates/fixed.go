@@ -0,0 +1,50 @@
+package rates
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/shopspring/decimal"
+)
+
+// Fixed cotiza el peso argentino a una cantidad fija de pesos por dólar, sin pedir nada, para
+// ver cuanto costaría algo con otra cotización. Las demás monedas devuelven
+// ErrUnsupportedCurrency.
+type Fixed struct {
+	// Pesos es cuantos pesos vale un dólar.
+	Pesos decimal.Decimal
+}
+
+// NewFixed devuelve una cotización fija de value pesos por dólar, por ejemplo "1500".
+func NewFixed(value string) (*Fixed, error) {
+	pesos, err := decimal.NewFromString(value)
+	if err != nil || !pesos.IsPositive() {
+		return nil, fmt.Errorf("invalid fixed rate %q, must be a positive number of pesos per dollar", value)
+	}
+	return &Fixed{Pesos: pesos}, nil
+}
+
+// URL no devuelve nada, la cotización fija no pide nada.
+func (f *Fixed) URL(currency string) (string, error) {
+	return "", pesosOnly(currency)
+}
+
+// ToUSD devuelve cuantos dólares vale un peso, o uno si currency es el dólar.
+func (f *Fixed) ToUSD(_ context.Context, currency string) (decimal.Decimal, error) {
+	if err := pesosOnly(currency); err != nil {
+		return decimal.Zero, err
+	}
+	if currency == USD {
+		return decimal.New(1, 0), nil
+	}
+	return decimal.New(1, 0).Div(f.Pesos), nil
+}
+
+// pesosOnly devuelve ErrUnsupportedCurrency salvo para el peso argentino y el dólar, las
+// únicas monedas que cotizan las fuentes de un solo dólar.
+func pesosOnly(currency string) error {
+	if currency != ars && currency != USD {
+		return fmt.Errorf("%s: %w", currency, ErrUnsupportedCurrency)
+	}
+	return nil
+}
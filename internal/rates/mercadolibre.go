@@ -0,0 +1,130 @@
+package rates
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/perrito666/tutoriales_go/internal/bodylimit"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	// meliCurrencyConversionURL es la URL donde mercado libre publica una API de cambio de moneda
+	meliCurrencyConversionURL = "https://api.mercadolibre.com/currency_conversions/search"
+	// meliCurrencyFrom es la clave de pedido GET para indicarle cual es la moneda de origen a la API
+	meliCurrencyFrom = "from"
+	// meliCurrencyTo es la clave de pedido GET para indicarle cual es la moneda de destine a la API
+	meliCurrencyTo = "to"
+)
+
+// conversionRatio representa la estructura del resultado JSON de un pedido a la API de cambio.
+type conversionRatio struct {
+	// Ratio es la taza de cambio, como el precio se lee sin pasar por float64.
+	Ratio decimal.Decimal `json:"ratio"`
+}
+
+// MercadoLibre cotiza cualquier moneda de los sitios de Mercado Libre con su API de cambio. Si
+// la API no tiene el par de una moneda a dólares se pide el inverso, de dólares a la moneda.
+// Los campos permiten que quien lo use agregue su propio manejo de los pedidos, si son nil
+// se usa http.DefaultClient, el estado de la respuesta y encoding/json.
+type MercadoLibre struct {
+	// Do envía los pedidos.
+	Do Doer
+	// StatusError devuelve el error de una respuesta cuyo estado no es 200.
+	StatusError func(*http.Response) error
+	// Decode de-serializa el cuerpo de la respuesta en v.
+	Decode func(ctx context.Context, body io.Reader, v interface{}) error
+}
+
+// URL devuelve la URL de la cotización de una moneda de origen a Dolar EstadoUnidense.
+func (m *MercadoLibre) URL(currency string) (string, error) {
+	return pairURL(currency, USD)
+}
+
+// pairURL devuelve la URL de la cotización de from a to.
+func pairURL(from, to string) (string, error) {
+	// agregamos las claves del pedido GET como ya sabemos.
+	meliURL, err := url.Parse(meliCurrencyConversionURL)
+	if err != nil {
+		return "", fmt.Errorf("parsing mercado libre conversion api URL: %w", err)
+	}
+	queryValues := meliURL.Query()
+	queryValues[meliCurrencyFrom] = []string{from}
+	queryValues[meliCurrencyTo] = []string{to}
+	meliURL.RawQuery = queryValues.Encode()
+	return meliURL.String(), nil
+}
+
+// errNoPair indica que la API de cambio no tiene cotización para un par de monedas.
+var errNoPair = errors.New("no conversion pair")
+
+// ToUSD hace un pedido de una moneda de origen a Dolar EstadoUnidense y, si la API no tiene
+// ese par, del dólar a la moneda para invertirlo. El pedido se cancela si el contexto expira.
+func (m *MercadoLibre) ToUSD(ctx context.Context, currency string) (decimal.Decimal, error) {
+	ratio, err := m.ratio(ctx, currency, USD)
+	if !errors.Is(err, errNoPair) {
+		return ratio, err
+	}
+	inverse, err := m.ratio(ctx, USD, currency)
+	if errors.Is(err, errNoPair) {
+		return decimal.Zero, fmt.Errorf("%s: mercado libre has no conversion to %s: %w", currency, USD, ErrUnsupportedCurrency)
+	}
+	if err != nil {
+		return decimal.Zero, err
+	}
+	return decimal.New(1, 0).Div(inverse), nil
+}
+
+// ratio pide la cotización de from a to, devuelve errNoPair si la API no la tiene.
+func (m *MercadoLibre) ratio(ctx context.Context, from, to string) (decimal.Decimal, error) {
+	meliURL, err := pairURL(from, to)
+	if err != nil {
+		return decimal.Zero, err
+	}
+
+	// realizamos el pedido
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, meliURL, nil)
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("building mercado libre currency request: %w", err)
+	}
+	response, err := send(m.Do, request)
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("querying mercado libre currency url: %w", err)
+	}
+	defer response.Body.Close()
+	// la API responde así a los pares que no conoce.
+	if response.StatusCode == http.StatusNotFound || response.StatusCode == http.StatusBadRequest {
+		return decimal.Zero, fmt.Errorf("%s to %s: %w", from, to, errNoPair)
+	}
+	if response.StatusCode != http.StatusOK {
+		statusErr := errors.New(response.Status)
+		if m.StatusError != nil {
+			statusErr = m.StatusError(response)
+		}
+		return decimal.Zero, fmt.Errorf("requesting currency to mercado libre: %w", statusErr)
+	}
+
+	// de-serializamos el resultado directamente desde el cuerpo.
+	ratio := &conversionRatio{}
+	body := bodylimit.Body(response)
+	if m.Decode != nil {
+		err = m.Decode(ctx, body, ratio)
+	} else if err = json.NewDecoder(body).Decode(ratio); err != nil {
+		err = fmt.Errorf("decoding body from mercado libre currency url: %w", err)
+	}
+	if err != nil {
+		return decimal.Zero, err
+	}
+
+	// una cotización de cero tampoco sirve para convertir.
+	if !ratio.Ratio.IsPositive() {
+		return decimal.Zero, fmt.Errorf("%s to %s: %w", from, to, errNoPair)
+	}
+	// lo devolvemos convertido en Decimal.
+	return ratio.Ratio, nil
+}
@@ -0,0 +1,82 @@
+// Package rates obtiene la cotización de una moneda a dólares estadounidenses, con una misma
+// interfaz para las distintas fuentes: la API de cambio de Mercado Libre, la página del Banco
+// Nación, la API del BCRA, el dólar blue de DolarAPI o una cotización fija. Lo usan
+// iphonemetriste e iphonemeloenperspectiva, que eligen la fuente por configuración. CoinGecko
+// cotiza además algunas criptomonedas, para mostrar precios en cripto.
+package rates
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// USD es el código del Dolar EstadoUnidense, la moneda a la que se convierte todo.
+const USD = "USD"
+
+const (
+	// MercadoLibreName es el nombre de la fuente de la API de cambio de Mercado Libre.
+	MercadoLibreName = "mercadolibre"
+	// MeliName es otro nombre de la fuente de Mercado Libre, mas corto.
+	MeliName = "meli"
+	// BNAName es el nombre de la fuente de la página del Banco Nación.
+	BNAName = "bna"
+	// BlueName es el nombre de la fuente del dólar blue de DolarAPI.
+	BlueName = "blue"
+	// BCRAName es el nombre de la fuente de la API del Banco Central.
+	BCRAName = "bcra"
+	// FixedPrefix es el prefijo de la fuente de cotización fija, seguido de los pesos por
+	// dólar, por ejemplo "fixed:1500".
+	FixedPrefix = "fixed:"
+)
+
+// SourceNames describe los nombres que acepta New, para la ayuda y los errores.
+const SourceNames = "mercadolibre (o meli), bna, blue, bcra o fixed:<pesos por dólar>"
+
+// ErrUnsupportedCurrency indica que la fuente no cotiza la moneda pedida, por ejemplo el Banco
+// Nación no cotiza el peso uruguayo.
+var ErrUnsupportedCurrency = errors.New("currency not quoted by this rates source")
+
+// Source es una fuente de cotizaciones.
+type Source interface {
+	// ToUSD devuelve cuantos dólares vale una unidad de currency, el pedido se cancela si el
+	// contexto expira.
+	ToUSD(ctx context.Context, currency string) (decimal.Decimal, error)
+	// URL devuelve la dirección que ToUSD pide para cotizar currency, vacía si no hace falta
+	// pedir nada.
+	URL(currency string) (string, error)
+}
+
+// Doer envía un pedido HTTP, por ejemplo http.DefaultClient.Do.
+type Doer func(*http.Request) (*http.Response, error)
+
+// New devuelve la fuente llamada name, cuyos pedidos se envían con do o, si es nil, con
+// http.DefaultClient.
+func New(name string, do Doer) (Source, error) {
+	switch name {
+	case MercadoLibreName, MeliName:
+		return &MercadoLibre{Do: do}, nil
+	case BNAName:
+		return &BNA{Do: do}, nil
+	case BlueName:
+		return &Blue{Do: do}, nil
+	case BCRAName:
+		return &BCRA{Do: do}, nil
+	}
+	if value, ok := strings.CutPrefix(name, FixedPrefix); ok {
+		return NewFixed(value)
+	}
+	return nil, fmt.Errorf("unknown rates source %q, must be %s", name, SourceNames)
+}
+
+// send envía request con do o, si es nil, con http.DefaultClient.
+func send(do Doer, request *http.Request) (*http.Response, error) {
+	if do == nil {
+		do = http.DefaultClient.Do
+	}
+	return do(request)
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/perrito666/tutoriales_go/internal/i18n"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	// incomeMinWage mide cuanto cuesta un producto en meses de salario mínimo.
+	incomeMinWage = "min-wage"
+	// incomeGDP mide cuanto cuesta un producto en meses de PBI per cápita.
+	incomeGDP = "gdp"
+)
+
+// bundledGDPDate indica a que año corresponde el PBI de bundledGDPPerCapita.
+const bundledGDPDate = "2023"
+
+// bundledGDPPerCapita es el PBI per cápita anual aproximado en USD corrientes, según el Banco
+// Mundial, de los países de Mercado Libre, por ID de sitio.
+var bundledGDPPerCapita = map[string]decimal.Decimal{
+	"MLA": decimal.New(13730, 0),
+	"MLB": decimal.New(10040, 0),
+	"MLC": decimal.New(17070, 0),
+	"MCO": decimal.New(6980, 0),
+	"MLM": decimal.New(13790, 0),
+	"MLU": decimal.New(22800, 0),
+	"MPE": decimal.New(7790, 0),
+	"MEC": decimal.New(6530, 0),
+	"MPY": decimal.New(6150, 0),
+	"MBO": decimal.New(3690, 0),
+	"MCR": decimal.New(16600, 0),
+	"MGT": decimal.New(5800, 0),
+	"MSV": decimal.New(5370, 0),
+}
+
+// loadIncomes devuelve los ingresos mensuales incluidos de measure, incomeMinWage o incomeGDP,
+// reemplazados por los del CSV de path si no está vacío.
+func loadIncomes(measure, path string) (map[string]monthlyIncome, error) {
+	incomes := map[string]monthlyIncome{}
+	switch measure {
+	case incomeMinWage:
+		wages, err := loadWages("")
+		if err != nil {
+			return nil, err
+		}
+		incomes = wages
+	case incomeGDP:
+		for id, annual := range bundledGDPPerCapita {
+			incomes[id] = monthlyIncome{Currency: usdCurrencyCode, Monthly: annual.Div(decimal.New(12, 0))}
+		}
+	default:
+		return nil, fmt.Errorf("unknown -income %q, must be %s or %s", measure, incomeMinWage, incomeGDP)
+	}
+	if path == "" {
+		return incomes, nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening income file: %v", err)
+	}
+	defer f.Close()
+	overrides, err := readIncomeCSV(f)
+	if err != nil {
+		return nil, fmt.Errorf("invalid income file %s: %v", path, err)
+	}
+	for id, income := range overrides {
+		incomes[id] = income
+	}
+	return incomes, nil
+}
+
+// incomeCSVHeader son las columnas del CSV de ingresos: el ID del sitio, la moneda del ingreso y
+// el ingreso mensual, por ejemplo:
+//
+//	site_id,currency,monthly
+//	MLA,ARS,1200000
+//	MLB,USD,850
+var incomeCSVHeader = []string{"site_id", "currency", "monthly"}
+
+// readIncomeCSV lee ingresos mensuales por ID de sitio en formato CSV, con incomeCSVHeader
+// como encabezado.
+func readIncomeCSV(r io.Reader) (map[string]monthlyIncome, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = len(incomeCSVHeader)
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("empty file, the first line must be %s", strings.Join(incomeCSVHeader, ","))
+	}
+	if err != nil {
+		return nil, err
+	}
+	for i, column := range incomeCSVHeader {
+		if strings.ToLower(strings.TrimSpace(header[i])) != column {
+			return nil, fmt.Errorf("the first line must be %s", strings.Join(incomeCSVHeader, ","))
+		}
+	}
+	incomes := map[string]monthlyIncome{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return incomes, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+		id, currency := strings.ToUpper(strings.TrimSpace(record[0])), strings.ToUpper(strings.TrimSpace(record[1]))
+		monthly, err := decimal.NewFromString(strings.TrimSpace(record[2]))
+		if id == "" || currency == "" || err != nil || !monthly.IsPositive() {
+			return nil, fmt.Errorf("line %d: needs a site id, a currency and a positive monthly income", line)
+		}
+		if _, ok := incomes[id]; ok {
+			return nil, fmt.Errorf("line %d: %s given more than once", line, id)
+		}
+		incomes[id] = monthlyIncome{Currency: currency, Monthly: monthly}
+	}
+}
+
+// siteAffordability es lo que cuesta un producto en un sitio en relación al ingreso del país.
+type siteAffordability struct {
+	Rank     int             `json:"rank"`
+	SiteID   string          `json:"site_id"`
+	SiteName string          `json:"site_name"`
+	PriceUSD decimal.Decimal `json:"price_usd"`
+	// MonthlyIncomeUSD es el ingreso mensual del país convertido a USD.
+	MonthlyIncomeUSD decimal.Decimal `json:"monthly_income_usd"`
+	// Months es cuantos meses de ingreso cuesta el producto, el índice con el que se ordena.
+	Months decimal.Decimal `json:"months"`
+}
+
+// affordabilityReport es el resultado del subcomando afford, el "índice iPhone": los sitios
+// ordenados del país donde el producto es mas accesible al país donde lo es menos.
+type affordabilityReport struct {
+	Query  string              `json:"query"`
+	Income string              `json:"income"`
+	Sites  []siteAffordability `json:"sites"`
+	// Skipped son los sitios con precio pero sin ingreso conocido o sin cotización de su moneda,
+	// con el motivo.
+	Skipped map[string]string `json:"skipped,omitempty"`
+}
+
+// rankAffordability arma el índice de query: divide el precio en USD de cada sitio de prices por
+// el ingreso mensual del país en USD, con la cotización de rates (USD por unidad de cada
+// moneda), y ordena los sitios de menos a mas meses.
+func rankAffordability(query, income string, sites []mlSite, prices map[string]decimal.Decimal,
+	incomes map[string]monthlyIncome, rates map[string]decimal.Decimal) *affordabilityReport {
+	report := &affordabilityReport{Query: query, Income: income}
+	for _, site := range sites {
+		price, ok := prices[site.ID]
+		if !ok {
+			continue
+		}
+		siteIncome, ok := incomes[site.ID]
+		if !ok {
+			report.skip(site.ID, i18n.T("sin datos de ingreso"))
+			continue
+		}
+		rate := decimal.New(1, 0)
+		if siteIncome.Currency != usdCurrencyCode {
+			if rate, ok = rates[siteIncome.Currency]; !ok || !rate.IsPositive() {
+				report.skip(site.ID, fmt.Sprintf(i18n.T("sin cotización de %s"), siteIncome.Currency))
+				continue
+			}
+		}
+		incomeUSD := siteIncome.Monthly.Mul(rate)
+		report.Sites = append(report.Sites, siteAffordability{
+			SiteID:           site.ID,
+			SiteName:         site.Name,
+			PriceUSD:         price,
+			MonthlyIncomeUSD: incomeUSD,
+			Months:           price.Div(incomeUSD),
+		})
+	}
+	sort.SliceStable(report.Sites, func(i, j int) bool {
+		return report.Sites[i].Months.LessThan(report.Sites[j].Months)
+	})
+	for i := range report.Sites {
+		report.Sites[i].Rank = i + 1
+	}
+	return report
+}
+
+// skip anota que siteID quedó fuera del índice y por qué.
+func (r *affordabilityReport) skip(siteID, reason string) {
+	if r.Skipped == nil {
+		r.Skipped = map[string]string{}
+	}
+	r.Skipped[siteID] = reason
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/shopspring/decimal"
+)
+
+// checkAlerts evalúa las reglas de alerta del producto contra el reporte de su última
+// comparación y devuelve un mensaje por cada alerta que corresponde emitir.
+func checkAlerts(product watchedProduct, report *runReport) []string {
+	alerts := []string{}
+	min, _, ok := priceRange(report)
+	if !ok {
+		return alerts
+	}
+	if product.Below.IsPositive() && min.PriceUSD.LessThanOrEqual(product.Below) {
+		alerts = append(alerts, fmt.Sprintf("%q costs %s in %s, below the %s threshold (%s)",
+			product.Query, min.usd(), min.SiteName, NewMoney(product.Below, usdCurrencyCode), min.Permalink))
+	}
+	return alerts
+}
+
+// checkDropAlerts actualiza las referencias de precio de cada sitio en el historial y devuelve
+// un mensaje por cada sitio cuyo precio bajó mas de threshold por ciento respecto de la suya.
+func checkDropAlerts(history *historyStore, report *runReport, threshold decimal.Decimal) ([]string, error) {
+	drops, err := history.updateBaselines(report, threshold)
+	if err != nil {
+		return nil, err
+	}
+	alerts := make([]string, 0, len(drops))
+	for _, drop := range drops {
+		alerts = append(alerts, fmt.Sprintf("%q dropped %s%% in %s, from %s to %s (%s)",
+			report.Query, formatNumber(drop.percent), drop.site.SiteName,
+			NewMoney(drop.baseline, usdCurrencyCode), drop.site.usd(), drop.site.Permalink))
+	}
+	return alerts, nil
+}
+
+// emitAlerts muestra las alertas, por ahora simplemente en el log.
+func emitAlerts(alerts []string) {
+	for _, alert := range alerts {
+		log.Printf("ALERT: %s", alert)
+	}
+}
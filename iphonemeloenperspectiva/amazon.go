@@ -0,0 +1,306 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/perrito666/tutoriales_go/internal/bodylimit"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	// amazonSearchURL es la operación SearchItems de la Product Advertising API 5.0, el
+	// segmento reemplazable es el host del mercado.
+	amazonSearchURL = "https://%s/paapi5/searchitems"
+	// amazonSearchTarget es la operación que se indica en el encabezado x-amz-target.
+	amazonSearchTarget = "com.amazon.paapi5.v1.ProductAdvertisingAPIv1.SearchItems"
+	// amazonService es el nombre del servicio con el que se firman los pedidos.
+	amazonService = "ProductAdvertisingAPI"
+	// amazonItemsPerPage es la cantidad máxima de resultados por página de SearchItems.
+	amazonItemsPerPage = 10
+	// amazonMaxPages es la cantidad máxima de páginas que devuelve SearchItems.
+	amazonMaxPages = 10
+)
+
+// amazonMarketplace es un mercado de Amazon con los datos necesarios para consultarlo.
+type amazonMarketplace struct {
+	site mlSite
+	// host es el host de la Product Advertising API del mercado.
+	host string
+	// region es la región de AWS con la que se firman los pedidos al mercado.
+	region string
+	// marketplace es el nombre del mercado que espera la API.
+	marketplace string
+}
+
+// amazonMarketplaces contiene los mercados de Amazon que se pueden comparar.
+var amazonMarketplaces = map[string]amazonMarketplace{
+	"AMAZON_US": {
+		site: mlSite{ID: "AMAZON_US", Name: "Amazon Estados Unidos", DefaultCurrencyID: "USD"},
+		host: "webservices.amazon.com", region: "us-east-1", marketplace: "www.amazon.com",
+	},
+	"AMAZON_MX": {
+		site: mlSite{ID: "AMAZON_MX", Name: "Amazon México", DefaultCurrencyID: "MXN"},
+		host: "webservices.amazon.com.mx", region: "us-east-1", marketplace: "www.amazon.com.mx",
+	},
+	"AMAZON_BR": {
+		site: mlSite{ID: "AMAZON_BR", Name: "Amazon Brasil", DefaultCurrencyID: "BRL"},
+		host: "webservices.amazon.com.br", region: "us-east-1", marketplace: "www.amazon.com.br",
+	},
+	"AMAZON_ES": {
+		site: mlSite{ID: "AMAZON_ES", Name: "Amazon España", DefaultCurrencyID: "EUR"},
+		host: "webservices.amazon.es", region: "eu-west-1", marketplace: "www.amazon.es",
+	},
+}
+
+// amazonSites devuelve los mercados de Amazon con los IDs indicados.
+func amazonSites(ids []string) ([]mlSite, error) {
+	sites := make([]mlSite, 0, len(ids))
+	for _, id := range ids {
+		m, ok := amazonMarketplaces[strings.ToUpper(id)]
+		if !ok {
+			return nil, fmt.Errorf("unknown amazon marketplace %q", id)
+		}
+		sites = append(sites, m.site)
+	}
+	return sites, nil
+}
+
+// amazonClient busca en Amazon con la Product Advertising API, cada pedido se firma con las
+// claves de acceso y los resultados quedan asociados al partner tag del usuario.
+type amazonClient struct {
+	accessKey  string
+	secretKey  string
+	partnerTag string
+}
+
+// newAmazonClient devuelve un amazonClient con las claves de la Product Advertising API.
+func newAmazonClient(accessKey, secretKey, partnerTag string) *amazonClient {
+	return &amazonClient{accessKey: accessKey, secretKey: secretKey, partnerTag: partnerTag}
+}
+
+// amazonSearchRequest es el cuerpo de un pedido SearchItems.
+type amazonSearchRequest struct {
+	Keywords    string   `json:"Keywords"`
+	PartnerTag  string   `json:"PartnerTag"`
+	PartnerType string   `json:"PartnerType"`
+	Marketplace string   `json:"Marketplace"`
+	Resources   []string `json:"Resources"`
+	ItemCount   int      `json:"ItemCount"`
+	ItemPage    int      `json:"ItemPage"`
+	SortBy      string   `json:"SortBy,omitempty"`
+}
+
+// amazonSearchResponse imita la estructura JSON de la respuesta de SearchItems, solo lo que
+// nos interesa.
+type amazonSearchResponse struct {
+	SearchResult struct {
+		Items []struct {
+			ASIN          string `json:"ASIN"`
+			DetailPageURL string `json:"DetailPageURL"`
+			ItemInfo      struct {
+				Title struct {
+					DisplayValue string `json:"DisplayValue"`
+				} `json:"Title"`
+			} `json:"ItemInfo"`
+			Offers struct {
+				Listings []struct {
+					Price struct {
+						Amount   decimal.Decimal `json:"Amount"`
+						Currency string          `json:"Currency"`
+					} `json:"Price"`
+				} `json:"Listings"`
+			} `json:"Offers"`
+		} `json:"Items"`
+	} `json:"SearchResult"`
+}
+
+// amazonSort devuelve el orden de Amazon que corresponde a uno de nuestros órdenes.
+func amazonSort(sort string) string {
+	switch sort {
+	case sortPriceAsc:
+		return "Price:LowToHigh"
+	case sortPriceDesc:
+		return "Price:HighToLow"
+	}
+	return "Relevance"
+}
+
+// amazonProvider busca en un mercado de Amazon.
+type amazonProvider struct {
+	client *amazonClient
+	site   mlSite
+	opts   searchOptions
+}
+
+// amazonProviderSites devuelve los mercados de Amazon indicados con -amazon.
+func amazonProviderSites(opts searchOptions) ([]mlSite, error) {
+	sites := make([]mlSite, 0, len(opts.amazonSites))
+	for _, site := range opts.amazonSites {
+		site.provider = amazonProvider{client: opts.amazon, site: site, opts: opts}
+		sites = append(sites, site)
+	}
+	return sites, nil
+}
+
+// Name devuelve el nombre del mercado.
+func (p amazonProvider) Name() string {
+	return p.site.Name
+}
+
+// Search busca el criterio en el mercado de Amazon, las publicaciones que no tienen oferta (y
+// por lo tanto precio) se omiten.
+func (p amazonProvider) Search(ctx context.Context, searchCriteria string) ([]Listing, error) {
+	c, site, opts := p.client, p.site, p.opts
+	if opts.bestSellers {
+		return nil, notRetryableError{fmt.Errorf("best sellers are not supported on amazon")}
+	}
+	if opts.catalog {
+		return nil, notRetryableError{fmt.Errorf("catalog search is not supported on amazon")}
+	}
+	if opts.freeShipping {
+		return nil, notRetryableError{fmt.Errorf("free shipping filter is not supported on amazon")}
+	}
+	if opts.officialStore != "" {
+		return nil, notRetryableError{fmt.Errorf("official store filter is not supported on amazon")}
+	}
+	if len(opts.attributes) > 0 {
+		return nil, notRetryableError{fmt.Errorf("attribute filter is not supported on amazon")}
+	}
+	if opts.minRating.IsPositive() {
+		return nil, notRetryableError{fmt.Errorf("rating filter is not supported on amazon")}
+	}
+	marketplace := amazonMarketplaces[site.ID]
+	pages := opts.pages
+	if pages > amazonMaxPages {
+		pages = amazonMaxPages
+	}
+	listings := []Listing{}
+	for page := 1; page <= pages; page++ {
+		found, err := c.searchPage(ctx, searchCriteria, marketplace, opts.sort, page)
+		if err != nil {
+			return nil, fmt.Errorf("fetching page %d: %v", page, err)
+		}
+		for _, item := range found.SearchResult.Items {
+			if len(item.Offers.Listings) == 0 {
+				continue
+			}
+			price := item.Offers.Listings[0].Price
+			listings = append(listings, Listing{
+				// el ASIN es el mismo producto en todos los mercados pero cada uno tiene su
+				// propia oferta, así que no deben agruparse como la misma publicación.
+				ID:        site.ID + "/" + item.ASIN,
+				Title:     item.ItemInfo.Title.DisplayValue,
+				Permalink: item.DetailPageURL,
+				Price:     NewMoney(price.Amount, price.Currency),
+			})
+		}
+		// una página incompleta es la última.
+		if len(found.SearchResult.Items) < amazonItemsPerPage {
+			break
+		}
+	}
+	return listings, nil
+}
+
+// searchPage pide una página de resultados de SearchItems.
+func (c *amazonClient) searchPage(ctx context.Context, searchCriteria string, marketplace amazonMarketplace, sort string, page int) (*amazonSearchResponse, error) {
+	body, err := json.Marshal(amazonSearchRequest{
+		Keywords:    searchCriteria,
+		PartnerTag:  c.partnerTag,
+		PartnerType: "Associates",
+		Marketplace: marketplace.marketplace,
+		Resources:   []string{"ItemInfo.Title", "Offers.Listings.Price"},
+		ItemCount:   amazonItemsPerPage,
+		ItemPage:    page,
+		SortBy:      amazonSort(sort),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("encoding amazon request: %v", err)
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf(amazonSearchURL, marketplace.host), bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("building amazon request: %v", err)
+	}
+	request.Header.Set("Content-Encoding", "amz-1.0")
+	request.Header.Set("Content-Type", "application/json; charset=utf-8")
+	request.Header.Set("X-Amz-Target", amazonSearchTarget)
+	c.sign(request, body, marketplace.region, time.Now().UTC())
+
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("querying amazon url: %v", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("requesting to amazon: %s", response.Status)
+	}
+	found := &amazonSearchResponse{}
+	if err := json.NewDecoder(bodylimit.Body(response)).Decode(found); err != nil {
+		return nil, fmt.Errorf("decoding amazon response body: %v", err)
+	}
+	return found, nil
+}
+
+// sign firma el pedido con AWS Signature Version 4, como lo exige la Product Advertising API,
+// se firman todos los encabezados del pedido además del host.
+func (c *amazonClient) sign(request *http.Request, body []byte, region string, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	request.Header.Set("X-Amz-Date", amzDate)
+
+	headers := map[string]string{"host": request.URL.Host}
+	for name := range request.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(request.Header.Get(name))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	canonicalHeaders := &strings.Builder{}
+	for _, name := range names {
+		fmt.Fprintf(canonicalHeaders, "%s:%s\n", name, headers[name])
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	hash := func(data []byte) string {
+		sum := sha256.Sum256(data)
+		return hex.EncodeToString(sum[:])
+	}
+	mac := func(key []byte, data string) []byte {
+		h := hmac.New(sha256.New, key)
+		h.Write([]byte(data))
+		return h.Sum(nil)
+	}
+
+	canonicalRequest := strings.Join([]string{
+		request.Method,
+		(&url.URL{Path: request.URL.Path}).EscapedPath(),
+		request.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		hash(body),
+	}, "\n")
+	scope := strings.Join([]string{date, region, amazonService, "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, hash([]byte(canonicalRequest))}, "\n")
+
+	key := mac([]byte("AWS4"+c.secretKey), date)
+	key = mac(key, region)
+	key = mac(key, amazonService)
+	key = mac(key, "aws4_request")
+	signature := hex.EncodeToString(mac(key, stringToSign))
+
+	request.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		c.accessKey, scope, signedHeaders, signature))
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/perrito666/tutoriales_go/internal/i18n"
+	"github.com/perrito666/tutoriales_go/internal/rates"
+	"github.com/shopspring/decimal"
+)
+
+// argentineDollarsArg es el argumento de rates que muestra las cotizaciones del dólar en
+// Argentina, en minúsculas para distinguirlo de la moneda ARS.
+const argentineDollarsArg = "ars"
+
+// arsCurrencyCode es el ID de Mercado Libre del peso argentino.
+const arsCurrencyCode = "ARS"
+
+// paisTax y perceptionTax son los impuestos de las compras con tarjeta en el exterior que
+// se suman al dólar oficial para calcular el dólar tarjeta, se configuran con -pais-tax y
+// -perception-tax para no tener que recompilar cada vez que cambian.
+var (
+	paisTax       = &percentValue{percent: rates.DefaultCardTaxes.PAIS}
+	perceptionTax = &percentValue{percent: rates.DefaultCardTaxes.Perception}
+)
+
+// cardTaxes devuelve los impuestos configurados.
+func cardTaxes() rates.CardTaxes {
+	return rates.CardTaxes{PAIS: paisTax.percent, Perception: perceptionTax.percent}
+}
+
+// dollarsReport son las cotizaciones del dólar en Argentina que se consiguieron y los errores
+// de las demás, por nombre.
+type dollarsReport struct {
+	Rates  []rates.DollarRate `json:"rates"`
+	Failed map[string]string  `json:"failed,omitempty"`
+}
+
+// argentineDollars pide todas las cotizaciones del dólar en Argentina.
+func argentineDollars(ctx context.Context) dollarsReport {
+	found, failed := rates.ArgentineDollars(ctx, bnaSource(), doRates, cardTaxes())
+	report := dollarsReport{Rates: found}
+	for name, err := range failed {
+		if report.Failed == nil {
+			report.Failed = map[string]string{}
+		}
+		report.Failed[name] = err.Error()
+	}
+	return report
+}
+
+// writeArgentineDollars escribe las cotizaciones en w, en una tabla o en JSON.
+func writeArgentineDollars(w io.Writer, report dollarsReport, output string) error {
+	if output == outputJSON {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	}
+	found := map[string]rates.DollarRate{}
+	for _, rate := range report.Rates {
+		found[rate.Name] = rate
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, i18n.T("Dólar\tCompra\tVenta\tFuente"))
+	for _, name := range rates.ArgentineDollarNames {
+		if err, ok := report.Failed[name]; ok {
+			fmt.Fprintf(tw, "%s\terror: %s\t\t\n", name, err)
+			continue
+		}
+		rate, ok := found[name]
+		if !ok {
+			continue
+		}
+		buy := "-"
+		if !rate.Buy.IsZero() {
+			buy = formatAmount(rate.Buy, arsCurrencyCode)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", name, buy, formatAmount(rate.Sell, arsCurrencyCode), rate.Source)
+	}
+	return tw.Flush()
+}
+
+// reportDollar es el precio de una publicación en pesos convertido con una de las
+// cotizaciones del dólar en Argentina.
+type reportDollar struct {
+	Name     string          `json:"name"`
+	PriceUSD decimal.Decimal `json:"price_usd"`
+}
+
+// applyArgentineDollars agrega a las publicaciones en pesos argentinos su precio en cada una de
+// las cotizaciones del dólar de dollars, y a las de otros países lo que le cuestan en pesos a
+// alguien que paga con tarjeta desde Argentina.
+func applyArgentineDollars(report *runReport, dollars dollarsReport) {
+	var card decimal.Decimal
+	for _, rate := range dollars.Rates {
+		if rate.Name == rates.DollarTarjeta {
+			card = rate.Pesos()
+		}
+	}
+	for i, r := range report.Results {
+		if r.Currency != arsCurrencyCode {
+			if card.IsPositive() {
+				pesos := r.PriceUSD.Mul(card)
+				report.Results[i].CardARS = &pesos
+			}
+			continue
+		}
+		converted := make([]reportDollar, 0, len(dollars.Rates))
+		for _, rate := range dollars.Rates {
+			if !rate.Pesos().IsPositive() {
+				continue
+			}
+			converted = append(converted, reportDollar{Name: rate.Name, PriceUSD: r.Price.Div(rate.Pesos())})
+		}
+		report.Results[i].ARSDollars = converted
+	}
+}
+
+// formatARSDollars devuelve el precio en cada cotización del dólar, por ejemplo
+// "oficial US$ 200,00, blue US$ 150,00".
+func formatARSDollars(dollars []reportDollar) string {
+	parts := make([]string, 0, len(dollars))
+	for _, d := range dollars {
+		parts = append(parts, d.Name+" "+NewMoney(d.PriceUSD, usdCurrencyCode).String())
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"expvar"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// apiKeyHeader es el encabezado en el que se puede enviar la clave, también se acepta
+// "Authorization: Bearer <clave>" y, para EventSource y WebSocket que no permiten
+// encabezados, el parámetro api_key.
+const apiKeyHeader = "X-API-Key"
+
+// apiKeyRequests cuenta los pedidos aceptados por etiqueta de clave, y los rechazados bajo
+// "rejected", se muestra en /stats. No se publica en expvar porque /debug/vars también
+// muestra la linea de comandos, que puede tener las claves.
+var apiKeyRequests = new(expvar.Map)
+
+// apiKeys son las claves aceptadas por el servidor, cada una con una etiqueta que identifica
+// a quien la usa en los logs y métricas. Implementa flag.Value como etiqueta=clave separados
+// por coma, por ejemplo tablero=abc123,ci=def456.
+type apiKeys map[string]string
+
+func (k apiKeys) String() string {
+	// nunca mostramos las claves, solo sus etiquetas.
+	labels := make([]string, 0, len(k))
+	for label := range k {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+	return strings.Join(labels, ",")
+}
+
+// Set reemplaza las claves por las indicadas.
+func (k apiKeys) Set(value string) error {
+	for label := range k {
+		delete(k, label)
+	}
+	for _, pair := range parseKeywords(value) {
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return fmt.Errorf("invalid api key %q, must be label=key", pair)
+		}
+		k[parts[0]] = parts[1]
+	}
+	return nil
+}
+
+// label devuelve la etiqueta de la clave indicada. Compara contra todas las claves en tiempo
+// constante para no dar pistas sobre cuanto de la clave era correcto.
+func (k apiKeys) label(key string) (string, bool) {
+	found := ""
+	for label, valid := range k {
+		if subtle.ConstantTimeCompare([]byte(key), []byte(valid)) == 1 {
+			found = label
+		}
+	}
+	return found, found != ""
+}
+
+// requestAPIKey devuelve la clave enviada en un pedido HTTP.
+func requestAPIKey(r *http.Request) string {
+	if key := r.Header.Get(apiKeyHeader); key != "" {
+		return key
+	}
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		return strings.TrimPrefix(auth, "Bearer ")
+	}
+	return r.URL.Query().Get("api_key")
+}
+
+// apiKeyLabelKey es la clave del contexto con la etiqueta de la clave de API del pedido.
+type apiKeyLabelKey struct{}
+
+// apiKeyLabel devuelve la etiqueta de la clave de API con la que se autenticó el pedido.
+func apiKeyLabel(ctx context.Context) (string, bool) {
+	label, ok := ctx.Value(apiKeyLabelKey{}).(string)
+	return label, ok
+}
+
+// requireAPIKey rechaza con 401 los pedidos sin una clave válida y registra la etiqueta de
+// la clave de cada pedido aceptado. Sin claves configuradas no hace nada.
+func (k apiKeys) requireAPIKey(next http.Handler) http.Handler {
+	if len(k) == 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		label, ok := k.label(requestAPIKey(r))
+		if !ok {
+			apiKeyRequests.Add("rejected", 1)
+			log.Printf("rejected %s %s from %s: missing or invalid api key", r.Method, r.URL.Path, r.RemoteAddr)
+			w.Header().Set("WWW-Authenticate", `Bearer realm="iphonemelo"`)
+			writeError(w, http.StatusUnauthorized, fmt.Errorf("missing or invalid api key"))
+			return
+		}
+		apiKeyRequests.Add(label, 1)
+		log.Printf("%s: %s %s", label, r.Method, r.URL.Path)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), apiKeyLabelKey{}, label)))
+	})
+}
+
+// checkGRPC verifica la clave enviada en los metadatos de una llamada gRPC, en x-api-key o
+// authorization como en HTTP, y devuelve el contexto con su etiqueta.
+func (k apiKeys) checkGRPC(ctx context.Context, method string) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	key := ""
+	if values := md.Get(strings.ToLower(apiKeyHeader)); len(values) > 0 {
+		key = values[0]
+	} else if values := md.Get("authorization"); len(values) > 0 {
+		key = strings.TrimPrefix(values[0], "Bearer ")
+	}
+	label, ok := k.label(key)
+	if !ok {
+		apiKeyRequests.Add("rejected", 1)
+		log.Printf("rejected grpc %s: missing or invalid api key", method)
+		return nil, status.Error(codes.Unauthenticated, "missing or invalid api key")
+	}
+	apiKeyRequests.Add(label, 1)
+	log.Printf("%s: grpc %s", label, method)
+	return context.WithValue(ctx, apiKeyLabelKey{}, label), nil
+}
+
+// contextStream es un grpc.ServerStream con otro contexto, los interceptores de streams no
+// tienen otra forma de pasarle valores al handler.
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s contextStream) Context() context.Context {
+	return s.ctx
+}
+
+// grpcInterceptors devuelve los interceptores que exigen una clave válida en cada llamada
+// gRPC, nil si no hay claves configuradas.
+func (k apiKeys) grpcInterceptors() (grpc.UnaryServerInterceptor, grpc.StreamServerInterceptor) {
+	if len(k) == 0 {
+		return nil, nil
+	}
+	unary := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := k.checkGRPC(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+	stream := func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := k.checkGRPC(ss.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, contextStream{ss, ctx})
+	}
+	return unary, stream
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// siteBaselines contiene, por criterio de búsqueda normalizado y por ID de sitio, el precio en
+// USD contra el que se miden las bajas de precio.
+type siteBaselines map[string]map[string]decimal.Decimal
+
+// baselinesPath devuelve el archivo donde se guardan las referencias, junto al historial.
+func (h *historyStore) baselinesPath() string {
+	return strings.TrimSuffix(h.path, filepath.Ext(h.path)) + ".baselines.json"
+}
+
+// loadBaselines lee las referencias, si no existen devuelve unas vacías. Debe llamarse con
+// h.mu tomado.
+func (h *historyStore) loadBaselines() (siteBaselines, error) {
+	baselines := siteBaselines{}
+	data, err := ioutil.ReadFile(h.baselinesPath())
+	if os.IsNotExist(err) {
+		return baselines, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading baselines: %v", err)
+	}
+	if err := json.Unmarshal(data, &baselines); err != nil {
+		return nil, fmt.Errorf("decoding baselines %s: %v", h.baselinesPath(), err)
+	}
+	return baselines, nil
+}
+
+// saveBaselines guarda las referencias, primero en un archivo temporal para no dejarlas a medio
+// escribir. Debe llamarse con h.mu tomado.
+func (h *historyStore) saveBaselines(baselines siteBaselines) error {
+	if err := os.MkdirAll(filepath.Dir(h.path), 0755); err != nil {
+		return fmt.Errorf("creating history dir: %v", err)
+	}
+	data, err := json.MarshalIndent(baselines, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding baselines: %v", err)
+	}
+	tmp := h.baselinesPath() + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("writing baselines: %v", err)
+	}
+	if err := os.Rename(tmp, h.baselinesPath()); err != nil {
+		return fmt.Errorf("replacing baselines: %v", err)
+	}
+	return nil
+}
+
+// priceDrop es una baja de precio de un sitio respecto de su referencia.
+type priceDrop struct {
+	site     reportResult
+	baseline decimal.Decimal
+	percent  decimal.Decimal
+}
+
+// updateBaselines compara el precio mas barato de cada sitio del reporte con su referencia y
+// devuelve los sitios que bajaron mas de threshold por ciento. La referencia de cada sitio es
+// el precio mas alto visto desde la última baja informada: sube con el precio y, cuando se
+// informa una baja, pasa a ser el nuevo precio, así la misma baja no se informa dos veces.
+func (h *historyStore) updateBaselines(report *runReport, threshold decimal.Decimal) ([]priceDrop, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	all, err := h.loadBaselines()
+	if err != nil {
+		return nil, err
+	}
+	query := normalizeTitle(report.Query)
+	baselines := all[query]
+	if baselines == nil {
+		baselines = map[string]decimal.Decimal{}
+		all[query] = baselines
+	}
+
+	drops := []priceDrop{}
+	cheapest, order := cheapestBySite(report)
+	for _, id := range order {
+		current := cheapest[id]
+		baseline, ok := baselines[id]
+		if ok {
+			percent := percentChange(baseline, current.PriceUSD).Neg()
+			if percent.GreaterThan(threshold) {
+				drops = append(drops, priceDrop{site: current, baseline: baseline, percent: percent})
+				baselines[id] = current.PriceUSD
+				continue
+			}
+		}
+		if !ok || current.PriceUSD.GreaterThan(baseline) {
+			baselines[id] = current.PriceUSD
+		}
+	}
+	if err := h.saveBaselines(all); err != nil {
+		return nil, err
+	}
+	return drops, nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/perrito666/tutoriales_go/internal/i18n"
+)
+
+// readTerms lee un criterio de búsqueda por linea de path, ignorando lineas vacías y las que
+// comienzan con #.
+func readTerms(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening terms file: %v", err)
+	}
+	defer f.Close()
+
+	terms := []string{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		term := strings.TrimSpace(scanner.Text())
+		if term == "" || strings.HasPrefix(term, "#") {
+			continue
+		}
+		terms = append(terms, term)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading terms file: %v", err)
+	}
+	if len(terms) == 0 {
+		return nil, fmt.Errorf("terms file %s has no search terms", path)
+	}
+	return terms, nil
+}
+
+// writeBatchReport escribe los reportes de varias búsquedas: en JSON como una lista y en
+// texto cada reporte seguido de un resumen con el mas barato y el mas caro de cada búsqueda.
+func writeBatchReport(w io.Writer, reports []*runReport, output string) error {
+	if output == outputJSON {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(reports)
+	}
+	for _, report := range reports {
+		fmt.Fprintf(w, "=== %s\n", report.Query)
+		writeTextReport(w, report)
+		fmt.Fprintln(w)
+	}
+
+	fmt.Fprintln(w, i18n.T("Resumen:"))
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, i18n.T("Búsqueda\tMas barato (USD)\tMas caro (USD)\tSitios fallidos"))
+	for _, report := range reports {
+		cheapest, priciest := "-", "-"
+		if min, max, ok := priceRange(report); ok {
+			cheapest = fmt.Sprintf("%s %s", min.SiteName, min.usd().AmountString())
+			priciest = fmt.Sprintf("%s %s", max.SiteName, max.usd().AmountString())
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\n", report.Query, cheapest, priciest, len(report.Failed))
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/perrito666/tutoriales_go/internal/bodylimit"
+	"github.com/perrito666/tutoriales_go/internal/meli"
+)
+
+const (
+	// mlDomainDiscoveryURL es el endpoint que sugiere categorías de un sitio para un texto.
+	mlDomainDiscoveryURL = "https://api.mercadolibre.com/sites/%s/domain_discovery/search"
+	// mlHighlightsURL es el endpoint de los mas vendidos de una categoría de un sitio.
+	mlHighlightsURL = "https://api.mercadolibre.com/highlights/%s/category/%s"
+	// mlItemsURL es el endpoint que devuelve varias publicaciones por ID a la vez.
+	mlItemsURL = "https://api.mercadolibre.com/items"
+	// itemsPerRequest es la cantidad máxima de publicaciones que se pueden pedir a mlItemsURL
+	// en un mismo pedido.
+	itemsPerRequest = 20
+	// highlightItem es el tipo de los elementos de los mas vendidos que son publicaciones, los
+	// demás (por ejemplo productos de catálogo) no tienen precio propio y se ignoran.
+	highlightItem = "ITEM"
+)
+
+// getML hace un pedido GET a ML y de-serializa la respuesta en v, con -strict la valida contra
+// schema como decodeML. El pedido se cancela si el contexto expira.
+func getML(ctx context.Context, mlURL string, v, schema interface{}) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, mlURL, nil)
+	if err != nil {
+		return fmt.Errorf("building mercado libre request: %w", err)
+	}
+	response, err := doML(request)
+	if err != nil {
+		return fmt.Errorf("querying mercado libre url: %w", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("requesting to mercado libre: %w", newStatusError(response))
+	}
+	return decodeML(ctx, bodylimit.Body(response), "mercado libre response body", v, schema)
+}
+
+// isCategoryID indica si category es el ID de una categoría del sitio, por ejemplo MLA1055.
+func isCategoryID(category string, site mlSite) bool {
+	number := strings.TrimPrefix(category, site.ID)
+	if number == category || number == "" {
+		return false
+	}
+	for _, r := range number {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// domainDiscoveryURL devuelve la URL que sugiere categorías del sitio para category.
+func domainDiscoveryURL(category string, site mlSite) (string, error) {
+	discoveryURL, err := url.Parse(fmt.Sprintf(mlDomainDiscoveryURL, site.ID))
+	if err != nil {
+		return "", fmt.Errorf("parsing mercado libre domain discovery url: %w", err)
+	}
+	queryValues := discoveryURL.Query()
+	queryValues[queryKey] = []string{category}
+	discoveryURL.RawQuery = queryValues.Encode()
+	return discoveryURL.String(), nil
+}
+
+// categoryForSite devuelve el ID de la categoría del sitio que corresponde a category, que
+// puede ser directamente un ID de categoría del sitio o un texto como "celulares", en cuyo
+// caso se usa la primera categoría que sugiera ML.
+func categoryForSite(ctx context.Context, category string, site mlSite) (string, error) {
+	if isCategoryID(category, site) {
+		return category, nil
+	}
+	discoveryURL, err := domainDiscoveryURL(category, site)
+	if err != nil {
+		return "", err
+	}
+
+	domains := []struct {
+		CategoryID string `json:"category_id"`
+	}{}
+	if err := getML(ctx, discoveryURL, &domains, &[]mlDomainSchema{}); err != nil {
+		return "", fmt.Errorf("discovering category: %w", err)
+	}
+	if len(domains) == 0 || domains[0].CategoryID == "" {
+		return "", notRetryableError{fmt.Errorf("no category found for %q", category)}
+	}
+	return domains[0].CategoryID, nil
+}
+
+// mlHighlights imita la estructura JSON de los mas vendidos de una categoría.
+type mlHighlights struct {
+	Content []struct {
+		ID       string `json:"id"`
+		Position int    `json:"position"`
+		Type     string `json:"type"`
+	} `json:"content"`
+}
+
+// mlItem imita la estructura JSON de una publicación, que no es igual a la de un resultado de
+// búsqueda, por ejemplo el vendedor viene como seller_id.
+type mlItem struct {
+	meli.ResultadoML
+	SellerID int64 `json:"seller_id"`
+}
+
+// fetchItems pide las publicaciones con los IDs indicados y las devuelve en ese orden, las que
+// ML no devuelve se omiten.
+func fetchItems(ctx context.Context, ids []string) ([]meli.ResultadoML, error) {
+	items := make([]meli.ResultadoML, 0, len(ids))
+	for start := 0; start < len(ids); start += itemsPerRequest {
+		end := start + itemsPerRequest
+		if end > len(ids) {
+			end = len(ids)
+		}
+		itemsURL, err := url.Parse(mlItemsURL)
+		if err != nil {
+			return nil, fmt.Errorf("parsing mercado libre items url: %w", err)
+		}
+		queryValues := itemsURL.Query()
+		queryValues["ids"] = []string{strings.Join(ids[start:end], ",")}
+		itemsURL.RawQuery = queryValues.Encode()
+
+		// el pedido múltiple devuelve un código de estado por publicación, el cuerpo de las que
+		// fallaron es un error así que solo se de-serializa el de las demás.
+		responses := []struct {
+			Code int             `json:"code"`
+			Body json.RawMessage `json:"body"`
+		}{}
+		if err := getML(ctx, itemsURL.String(), &responses, nil); err != nil {
+			return nil, fmt.Errorf("fetching items: %w", err)
+		}
+		for _, r := range responses {
+			if r.Code != http.StatusOK {
+				continue
+			}
+			body := mlItem{}
+			if err := decodeML(ctx, bytes.NewReader(r.Body), "mercado libre item", &body, &mlItemSchema{}); err != nil {
+				return nil, fmt.Errorf("fetching items: %w", err)
+			}
+			item := body.ResultadoML
+			item.Seller.ID = body.SellerID
+			items = append(items, item)
+		}
+	}
+	return items, nil
+}
+
+// searchBestSellers devuelve las publicaciones mas vendidas de category en un sitio, ordenadas
+// según opts.sort, sortRelevance respeta la posición en la lista de mas vendidos.
+func searchBestSellers(ctx context.Context, category string, site mlSite, opts searchOptions) ([]meli.ResultadoML, error) {
+	categoryID, err := categoryForSite(ctx, category, site)
+	if err != nil {
+		return nil, err
+	}
+	highlights := &mlHighlights{}
+	if err := getML(ctx, fmt.Sprintf(mlHighlightsURL, site.ID, categoryID), highlights, &mlHighlightsSchema{}); err != nil {
+		return nil, fmt.Errorf("fetching best sellers of %s: %w", categoryID, err)
+	}
+	sort.SliceStable(highlights.Content, func(i, j int) bool {
+		return highlights.Content[i].Position < highlights.Content[j].Position
+	})
+	ids := []string{}
+	for _, h := range highlights.Content {
+		if h.Type == highlightItem {
+			ids = append(ids, h.ID)
+		}
+	}
+	items, err := fetchItems(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	switch opts.sort {
+	case sortPriceDesc:
+		sort.SliceStable(items, func(i, j int) bool {
+			return items[i].Price.GreaterThan(items[j].Price)
+		})
+	case sortPriceAsc:
+		sort.SliceStable(items, func(i, j int) bool {
+			return items[i].Price.LessThan(items[j].Price)
+		})
+	}
+	return items, nil
+}
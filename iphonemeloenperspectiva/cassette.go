@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/perrito666/tutoriales_go/internal/bodylimit"
+)
+
+// interaction es un pedido y la respuesta que recibió, tal como se guardan en un cassette.
+type interaction struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+	// RequestBody es el cuerpo del pedido, forma parte de lo que identifica al pedido.
+	RequestBody string      `json:"request_body,omitempty"`
+	Status      int         `json:"status"`
+	Header      http.Header `json:"header,omitempty"`
+	Body        string      `json:"body"`
+}
+
+// key identifica a los pedidos que se consideran el mismo al reproducir un cassette.
+func (i interaction) key() string {
+	return i.Method + " " + i.URL + "\n" + i.RequestBody
+}
+
+// cassette guarda todos los pedidos salientes y sus respuestas en un archivo JSON, o los
+// responde desde ese archivo sin salir a la red, así una comparación se puede repetir sin
+// conexión y siempre con los mismos datos.
+type cassette struct {
+	path   string
+	replay bool
+
+	mu           sync.Mutex
+	interactions []interaction
+	// pending contiene, por pedido, las respuestas que todavía no se reprodujeron en el
+	// orden en que se grabaron.
+	pending map[string][]interaction
+}
+
+// newRecorder devuelve un cassette que graba en path todos los pedidos que pasan por base.
+func newRecorder(path string) *cassette {
+	return &cassette{path: path}
+}
+
+// loadCassette carga el cassette de path para reproducirlo.
+func loadCassette(path string) (*cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading cassette: %v", err)
+	}
+	c := &cassette{path: path, replay: true, pending: map[string][]interaction{}}
+	if err := json.Unmarshal(data, &c.interactions); err != nil {
+		return nil, fmt.Errorf("decoding cassette %s: %v", path, err)
+	}
+	for _, i := range c.interactions {
+		c.pending[i.key()] = append(c.pending[i.key()], i)
+	}
+	return c, nil
+}
+
+// transport devuelve un RoundTripper que graba lo que pasa por base o reproduce el cassette.
+func (c *cassette) transport(base http.RoundTripper) http.RoundTripper {
+	return &cassetteTransport{cassette: c, base: base}
+}
+
+// cassetteTransport es el RoundTripper de un cassette.
+type cassetteTransport struct {
+	cassette *cassette
+	base     http.RoundTripper
+}
+
+func (t *cassetteTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	requestBody := ""
+	if request.Body != nil {
+		data, err := io.ReadAll(request.Body)
+		request.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("reading request body: %v", err)
+		}
+		requestBody = string(data)
+		request = request.Clone(request.Context())
+		request.Body = io.NopCloser(bytes.NewReader(data))
+	}
+	recorded := interaction{Method: request.Method, URL: request.URL.String(), RequestBody: requestBody}
+
+	if t.cassette.replay {
+		played, err := t.cassette.play(recorded.key())
+		if err != nil {
+			return nil, err
+		}
+		return played.response(request), nil
+	}
+
+	response, err := t.base.RoundTrip(request)
+	if err != nil {
+		return nil, err
+	}
+	// leemos la respuesta entera para guardarla y la devolvemos como si nada.
+	body, err := io.ReadAll(io.LimitReader(response.Body, bodylimit.MaxSize+1))
+	response.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("recording response from %s: %v", recorded.URL, err)
+	}
+	recorded.Status = response.StatusCode
+	recorded.Header = response.Header
+	recorded.Body = string(body)
+	if err := t.cassette.record(recorded); err != nil {
+		return nil, err
+	}
+	response.Body = io.NopCloser(bytes.NewReader(body))
+	return response, nil
+}
+
+// play devuelve la próxima respuesta grabada para el pedido. Si ya se reprodujeron todas se
+// repite la última, un pedido que se reintenta mas veces que al grabar igual obtiene algo.
+func (c *cassette) play(key string) (interaction, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	pending := c.pending[key]
+	if len(pending) == 0 {
+		return interaction{}, fmt.Errorf("request %s not found in cassette %s", strings.SplitN(key, "\n", 2)[0], c.path)
+	}
+	if len(pending) > 1 {
+		c.pending[key] = pending[1:]
+	}
+	return pending[0], nil
+}
+
+// record agrega la interacción al cassette y lo guarda, se guarda en cada pedido para no
+// perder nada si el programa termina de golpe, por ejemplo serve o watch.
+func (c *cassette) record(i interaction) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.interactions = append(c.interactions, i)
+	data, err := json.MarshalIndent(c.interactions, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding cassette: %v", err)
+	}
+	if err := os.WriteFile(c.path, data, 0o600); err != nil {
+		return fmt.Errorf("writing cassette: %v", err)
+	}
+	return nil
+}
+
+// response arma la respuesta grabada para request.
+func (i interaction) response(request *http.Request) *http.Response {
+	header := i.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", i.Status, http.StatusText(i.Status)),
+		StatusCode:    i.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(strings.NewReader(i.Body)),
+		ContentLength: int64(len(i.Body)),
+		Request:       request,
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+// testdata/search.cassette.json es una búsqueda de "iphone 11" en Argentina, Brasil y Ecuador
+// grabada con -record contra melitest.
+
+func TestReplayCassette(t *testing.T) {
+	played, err := loadCassette("testdata/search.cassette.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// sin transporte de base: cualquier pedido que no esté grabado falla.
+	useTransport(t, played.transport(nil))
+
+	results, failed := compareForTest(t, "iphone 11")
+	if len(failed) > 0 {
+		for id, f := range failed {
+			t.Errorf("site %s failed: %v", id, f.err)
+		}
+	}
+	want := map[string]struct {
+		itemID   string
+		price    string
+		priceUSD string
+	}{
+		"MLA": {"MLA1", "1500000", "1500"},
+		"MLB": {"MLB1", "7500", "1500"},
+		"MEC": {"MEC1", "1450", "1450"},
+	}
+	if len(results) != len(want) {
+		t.Fatalf("got %d results, want %d", len(results), len(want))
+	}
+	for id, w := range want {
+		r, ok := results[id]
+		if !ok {
+			t.Errorf("no result for %s", id)
+			continue
+		}
+		if r.itemID != w.itemID {
+			t.Errorf("%s item = %s, want %s", id, r.itemID, w.itemID)
+		}
+		if !r.price.Amount.Equal(decimal.RequireFromString(w.price)) {
+			t.Errorf("%s price = %s, want %s", id, r.price.Amount, w.price)
+		}
+		if !r.priceUSD.Amount.Equal(decimal.RequireFromString(w.priceUSD)) {
+			t.Errorf("%s price in USD = %s, want %s", id, r.priceUSD.Amount, w.priceUSD)
+		}
+	}
+}
+
+func TestReplayCassetteUnrecordedRequest(t *testing.T) {
+	played, err := loadCassette("testdata/search.cassette.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	useTransport(t, played.transport(nil))
+
+	results, failed := compareForTest(t, "iphone 12", "-retries", "0")
+	if len(results) > 0 {
+		t.Errorf("got %d results for a search that is not in the cassette", len(results))
+	}
+	for id, f := range failed {
+		if f.err == nil || !strings.Contains(f.err.Error(), "not found in cassette") {
+			t.Errorf("site %s error = %v, want a request not found in the cassette", id, f.err)
+		}
+	}
+	if len(failed) != 3 {
+		t.Errorf("got %d failed sites, want 3", len(failed))
+	}
+}
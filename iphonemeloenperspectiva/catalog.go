@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/perrito666/tutoriales_go/internal/meli"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	// mlProductsSearchURL es el endpoint de búsqueda en el catálogo de productos de ML.
+	mlProductsSearchURL = "https://api.mercadolibre.com/products/search"
+	// mlProductURL es el endpoint de un producto del catálogo.
+	mlProductURL = "https://api.mercadolibre.com/products/%s"
+)
+
+// mlProductsSearch imita la estructura JSON de la búsqueda en el catálogo.
+type mlProductsSearch struct {
+	Results []struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"results"`
+}
+
+// mlProduct imita la estructura JSON de un producto del catálogo.
+type mlProduct struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Permalink string `json:"permalink"`
+	// BuyBoxWinner es la publicación que ML elige para vender el producto, nil si ninguna
+	// publicación lo vende.
+	BuyBoxWinner *struct {
+		ItemID          string          `json:"item_id"`
+		Price           decimal.Decimal `json:"price"`
+		CurrencyID      string          `json:"currency_id"`
+		SellerID        int64           `json:"seller_id"`
+		OfficialStoreID *int64          `json:"official_store_id"`
+		Condition       string          `json:"condition"`
+		Shipping        meli.ShippingML `json:"shipping"`
+	} `json:"buy_box_winner"`
+}
+
+// productsSearchURL devuelve la URL que busca searchCriteria en el catálogo del sitio.
+func productsSearchURL(searchCriteria string, site mlSite) (string, error) {
+	productsURL, err := url.Parse(mlProductsSearchURL)
+	if err != nil {
+		return "", fmt.Errorf("parsing mercado libre products search url: %w", err)
+	}
+	queryValues := productsURL.Query()
+	queryValues.Set("status", "active")
+	queryValues.Set("site_id", site.ID)
+	queryValues[queryKey] = []string{searchCriteria}
+	productsURL.RawQuery = queryValues.Encode()
+	return productsURL.String(), nil
+}
+
+// searchCatalog busca searchCriteria en el catálogo del sitio y devuelve la publicación que
+// gana la buy box del primer producto, el que mejor corresponde al criterio. Así un criterio
+// como "iPhone 11 Pro Max 256GB" corresponde a un único producto con un único precio por
+// sitio en lugar de a publicaciones de variantes, accesorios y repuestos.
+func searchCatalog(ctx context.Context, searchCriteria string, site mlSite) ([]meli.ResultadoML, error) {
+	productsURL, err := productsSearchURL(searchCriteria, site)
+	if err != nil {
+		return nil, err
+	}
+	products := &mlProductsSearch{}
+	if err := getML(ctx, productsURL, products, &mlProductsSearchSchema{}); err != nil {
+		return nil, fmt.Errorf("searching catalog: %w", err)
+	}
+	if len(products.Results) == 0 {
+		return nil, nil
+	}
+	productID := products.Results[0].ID
+	product := &mlProduct{}
+	if err := getML(ctx, fmt.Sprintf(mlProductURL, url.PathEscape(productID)), product, &mlProductSchema{}); err != nil {
+		return nil, fmt.Errorf("fetching product %s: %w", productID, err)
+	}
+	winner := product.BuyBoxWinner
+	if winner == nil {
+		return nil, notRetryableError{fmt.Errorf("product %s (%s) is not being sold", product.ID, product.Name)}
+	}
+	return []meli.ResultadoML{{
+		ID:               winner.ItemID,
+		Price:            winner.Price,
+		Title:            product.Name,
+		Permalink:        product.Permalink,
+		Condition:        winner.Condition,
+		CurrencyID:       winner.CurrencyID,
+		Seller:           meli.SellerML{ID: winner.SellerID},
+		CatalogProductID: product.ID,
+		OfficialStoreID:  winner.OfficialStoreID,
+		Shipping:         winner.Shipping,
+	}}, nil
+}
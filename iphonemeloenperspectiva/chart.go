@@ -0,0 +1,213 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Dimensiones del gráfico en pixeles, los márgenes dejan lugar a los ejes y a la leyenda.
+const (
+	chartWidth        = 800
+	chartHeight       = 420
+	chartMarginLeft   = 90
+	chartMarginRight  = 80
+	chartMarginTop    = 60
+	chartMarginBottom = 50
+	// chartTicks es la cantidad de divisiones de cada eje.
+	chartTicks = 5
+)
+
+// chartPoint es una comparación del historial en el gráfico: el precio de la publicación mas
+// barata de un sitio en ese momento.
+type chartPoint struct {
+	Time     time.Time
+	Price    decimal.Decimal
+	Currency string
+	// ConstantARS es Price en pesos de otro mes, nil si no se pudo ajustar.
+	ConstantARS *decimal.Decimal
+	PriceUSD    decimal.Decimal
+}
+
+// chartPoints devuelve la publicación mas barata de siteID en cada reporte entre from y to,
+// cualquiera de los dos puede ser cero para no limitar ese extremo. Los reportes sin
+// publicaciones del sitio se omiten.
+func chartPoints(reports []*runReport, siteID string, from, to time.Time) []chartPoint {
+	points := []chartPoint{}
+	for _, report := range reports {
+		if (!from.IsZero() && report.Time.Before(from)) || (!to.IsZero() && !report.Time.Before(to)) {
+			continue
+		}
+		var cheapest *reportResult
+		for i, r := range report.Results {
+			if r.SiteID == siteID && (cheapest == nil || r.Price.LessThan(cheapest.Price)) {
+				cheapest = &report.Results[i]
+			}
+		}
+		if cheapest == nil {
+			continue
+		}
+		points = append(points, chartPoint{
+			Time:        report.Time,
+			Price:       cheapest.Price,
+			Currency:    cheapest.Currency,
+			ConstantARS: cheapest.ConstantARS,
+			PriceUSD:    cheapest.PriceUSD,
+		})
+	}
+	return points
+}
+
+// chartSeries es una línea del gráfico.
+type chartSeries struct {
+	Label string
+	Color string
+	// Dashed dibuja la línea punteada.
+	Dashed bool
+	// Right indica que la línea usa el eje derecho.
+	Right  bool
+	Times  []time.Time
+	Values []decimal.Decimal
+}
+
+// chartScale convierte valores en coordenadas de un eje, de min a max.
+type chartScale struct {
+	min, max       float64
+	start, end     float64
+	inverted       bool
+	formatTickText func(float64) string
+}
+
+// at devuelve la coordenada de v.
+func (s chartScale) at(v float64) float64 {
+	position := 0.5
+	if s.max > s.min {
+		position = (v - s.min) / (s.max - s.min)
+	}
+	if s.inverted {
+		position = 1 - position
+	}
+	return s.start + position*(s.end-s.start)
+}
+
+// valueScale devuelve la escala vertical de las series, de cero al valor máximo con algo de
+// aire arriba para que la línea no toque el borde.
+func valueScale(series []chartSeries) chartScale {
+	max := 0.0
+	for _, s := range series {
+		for _, v := range s.Values {
+			if f, _ := v.Float64(); f > max {
+				max = f
+			}
+		}
+	}
+	return chartScale{
+		min: 0, max: max * 1.1,
+		start: chartMarginTop, end: chartHeight - chartMarginBottom, inverted: true,
+		formatTickText: func(v float64) string {
+			return formatDecimal(decimal.NewFromFloat(v), 0)
+		},
+	}
+}
+
+// writeChartSVG dibuja las series como un gráfico SVG con title, una leyenda, las series con
+// Right en un eje a la derecha y las demás en uno a la izquierda.
+func writeChartSVG(w io.Writer, title string, series []chartSeries) error {
+	var left, right []chartSeries
+	var first, last time.Time
+	for _, s := range series {
+		if s.Right {
+			right = append(right, s)
+		} else {
+			left = append(left, s)
+		}
+		for _, t := range s.Times {
+			if first.IsZero() || t.Before(first) {
+				first = t
+			}
+			if t.After(last) {
+				last = t
+			}
+		}
+	}
+	if first.Equal(last) {
+		// con una sola comparación el punto queda en el medio.
+		first, last = first.Add(-12*time.Hour), last.Add(12*time.Hour)
+	}
+	x := chartScale{
+		min: float64(first.Unix()), max: float64(last.Unix()),
+		start: chartMarginLeft, end: chartWidth - chartMarginRight,
+		formatTickText: func(v float64) string {
+			return time.Unix(int64(v), 0).Local().Format("2006-01-02")
+		},
+	}
+	leftScale, rightScale := valueScale(left), valueScale(right)
+
+	b := &strings.Builder{}
+	fmt.Fprintf(b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="sans-serif" font-size="12">`+"\n",
+		chartWidth, chartHeight, chartWidth, chartHeight)
+	fmt.Fprintf(b, `<rect width="%d" height="%d" fill="white"/>`+"\n", chartWidth, chartHeight)
+	fmt.Fprintf(b, `<text x="%d" y="20" font-size="16">%s</text>`+"\n", chartMarginLeft, escapeXML(title))
+
+	bottom, top := float64(chartHeight-chartMarginBottom), float64(chartMarginTop)
+	for i := 0; i <= chartTicks; i++ {
+		fraction := float64(i) / chartTicks
+		y := bottom - fraction*(bottom-top)
+		fmt.Fprintf(b, `<line x1="%d" y1="%.1f" x2="%d" y2="%.1f" stroke="#ddd"/>`+"\n",
+			chartMarginLeft, y, chartWidth-chartMarginRight, y)
+		if len(left) > 0 {
+			fmt.Fprintf(b, `<text x="%d" y="%.1f" text-anchor="end">%s</text>`+"\n", chartMarginLeft-6, y+4,
+				leftScale.formatTickText(leftScale.min+fraction*(leftScale.max-leftScale.min)))
+		}
+		if len(right) > 0 {
+			fmt.Fprintf(b, `<text x="%d" y="%.1f">%s</text>`+"\n", chartWidth-chartMarginRight+6, y+4,
+				rightScale.formatTickText(rightScale.min+fraction*(rightScale.max-rightScale.min)))
+		}
+		xv := x.min + fraction*(x.max-x.min)
+		fmt.Fprintf(b, `<text x="%.1f" y="%d" text-anchor="middle">%s</text>`+"\n", x.at(xv), chartHeight-chartMarginBottom+20,
+			x.formatTickText(xv))
+	}
+	fmt.Fprintf(b, `<rect x="%d" y="%d" width="%d" height="%d" fill="none" stroke="#888"/>`+"\n", chartMarginLeft, chartMarginTop,
+		chartWidth-chartMarginLeft-chartMarginRight, chartHeight-chartMarginTop-chartMarginBottom)
+
+	for i, s := range series {
+		scale := leftScale
+		if s.Right {
+			scale = rightScale
+		}
+		dash := ""
+		if s.Dashed {
+			dash = ` stroke-dasharray="6 4"`
+		}
+		points := make([]string, 0, len(s.Values))
+		circles := &strings.Builder{}
+		for j, v := range s.Values {
+			f, _ := v.Float64()
+			px, py := x.at(float64(s.Times[j].Unix())), scale.at(f)
+			points = append(points, fmt.Sprintf("%.1f,%.1f", px, py))
+			fmt.Fprintf(circles, `<circle cx="%.1f" cy="%.1f" r="3" fill="%s"/>`+"\n", px, py, s.Color)
+		}
+		fmt.Fprintf(b, `<polyline points="%s" fill="none" stroke="%s" stroke-width="2"%s/>`+"\n", strings.Join(points, " "), s.Color, dash)
+		b.WriteString(circles.String())
+
+		// la leyenda va arriba, una serie al lado de la otra.
+		legendX := chartMarginLeft + i*220
+		fmt.Fprintf(b, `<line x1="%d" y1="40" x2="%d" y2="40" stroke="%s" stroke-width="2"%s/>`+"\n", legendX, legendX+24, s.Color, dash)
+		fmt.Fprintf(b, `<text x="%d" y="44">%s</text>`+"\n", legendX+30, escapeXML(s.Label))
+	}
+	b.WriteString("</svg>\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// escapeXML escapa text para incluirlo en un documento XML.
+func escapeXML(text string) string {
+	b := &strings.Builder{}
+	// strings.Builder nunca falla al escribir.
+	_ = xml.EscapeText(b, []byte(text))
+	return b.String()
+}
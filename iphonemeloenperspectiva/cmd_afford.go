@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/perrito666/tutoriales_go/internal/i18n"
+)
+
+// runAfford implementa el subcomando afford, que ordena los países según cuanto del ingreso
+// de cada uno cuesta un producto.
+func runAfford(args []string) error {
+	fs := newFlagSet("afford", "[opciones] <criterio de búsqueda>",
+		"Compara el producto en todos los sitios y divide el precio en dólares de cada uno por el\n"+
+			"ingreso mensual del país, también en dólares, el salario mínimo o el PBI per cápita, y\n"+
+			"ordena los países de donde es mas accesible a donde lo es menos, el \"índice iPhone\".\n"+
+			"Por ejemplo:\n"+
+			"  afford -income gdp \"iPhone 15\"")
+	search := addSearchFlags(fs)
+	income := fs.String("income", incomeMinWage, "ingreso con el que se compara el precio: min-wage, el salario mínimo de "+bundledWagesDate+", o gdp, el PBI per cápita de "+bundledGDPDate)
+	incomeFile := fs.String("income-file", "", "archivo CSV con columnas site_id,currency,monthly con ingresos mensuales que reemplazan a los incluidos")
+	output := fs.String("output", outputText, "formato de salida: text o json")
+	maxDuration := addMaxDurationFlag(fs)
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	deadline, err := runDeadline(*maxDuration)
+	if err != nil {
+		return err
+	}
+	opts, err := search.options()
+	if err != nil {
+		return err
+	}
+	if !validOutput(*output) {
+		return fmt.Errorf("unknown -output %q, must be %s or %s", *output, outputText, outputJSON)
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("afford needs a search criteria")
+	}
+	incomes, err := loadIncomes(*income, *incomeFile)
+	if err != nil {
+		return err
+	}
+	query := strings.Join(fs.Args(), " ")
+
+	sites, err := selectedSites(opts)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := withRunDeadline(context.Background(), deadline)
+	defer cancel()
+	results, _ := compareSites(ctx, query, sites, opts)
+	if ctx.Err() == context.DeadlineExceeded {
+		log.Printf("afford: -max-duration %s reached, showing partial results", *maxDuration)
+	}
+	prices := firstBySite(results)
+	if len(prices) == 0 {
+		return fmt.Errorf("no results for %q in any site", query)
+	}
+
+	// los ingresos en otra moneda que USD se convierten con la cotización del día.
+	currencies := []string{}
+	seen := map[string]bool{}
+	for id := range prices {
+		if siteIncome, ok := incomes[id]; ok && siteIncome.Currency != usdCurrencyCode && !seen[siteIncome.Currency] {
+			seen[siteIncome.Currency] = true
+			currencies = append(currencies, siteIncome.Currency)
+		}
+	}
+	sort.Strings(currencies)
+	ratesCtx, cancelRates := context.WithTimeout(ctx, opts.timeout)
+	rates, failed := fetchRates(ratesCtx, newRateCache(opts.timeout), currencies)
+	cancelRates()
+	for currency, err := range failed {
+		log.Printf("afford: %s rate: %s", currency, err)
+	}
+
+	report := rankAffordability(query, *income, sites, prices, incomes, rates)
+	if len(report.Sites) == 0 {
+		return fmt.Errorf("no site with results for %q has %s data", query, *income)
+	}
+	if *output == outputJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	}
+	return writeAffordability(os.Stdout, report)
+}
+
+// incomeNames es como se llama cada ingreso en el texto.
+var incomeNames = map[string]string{
+	incomeMinWage: "salario mínimo",
+	incomeGDP:     "PBI per cápita",
+}
+
+// writeAffordability escribe el índice como una tabla, del país donde el producto es mas
+// accesible al que lo es menos.
+func writeAffordability(w io.Writer, report *affordabilityReport) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, i18n.T("#\tSitio\tPrecio (USD)\tIngreso mensual (USD)\tMeses de %s\t\n"), i18n.T(incomeNames[report.Income]))
+	for _, s := range report.Sites {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t\n", s.Rank, s.SiteName, formatAmount(s.PriceUSD, usdCurrencyCode),
+			formatAmount(s.MonthlyIncomeUSD, usdCurrencyCode), formatNumber(s.Months))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	ids := make([]string, 0, len(report.Skipped))
+	for id := range report.Skipped {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		fmt.Fprintf(w, i18n.T("%s queda fuera del índice: %s\n"), id, report.Skipped[id])
+	}
+	return nil
+}
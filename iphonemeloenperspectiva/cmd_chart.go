@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/perrito666/tutoriales_go/internal/i18n"
+	"github.com/perrito666/tutoriales_go/internal/inflation"
+)
+
+// Colores de cada serie del gráfico.
+const (
+	nominalColor  = "#1f77b4"
+	constantColor = "#ff7f0e"
+	usdColor      = "#2ca02c"
+)
+
+// runChart implementa el subcomando chart, que dibuja la evolución del precio de un producto
+// según el historial.
+func runChart(args []string) error {
+	fs := newFlagSet("chart", "[opciones] <criterio de búsqueda>",
+		"Dibuja como un gráfico SVG la evolución del precio mas barato de un sitio según las\n"+
+			"comparaciones guardadas en el historial de un criterio, por ejemplo uno vigilado con watch:\n"+
+			"el precio nominal en pesos, en pesos de un mismo mes según la inflación y en dólares, este\n"+
+			"último en el eje derecho. Por ejemplo:\n"+
+			"  chart -from 2025-01-01 -out iphone.svg \"iPhone 15\"")
+	site := fs.String("site", "MLA", "ID del sitio cuyo precio mas barato se dibuja")
+	from := fs.String("from", "", "fecha, YYYY-MM-DD, desde la que se dibujan las comparaciones")
+	to := fs.String("to", "", "fecha, YYYY-MM-DD, hasta la que se dibujan las comparaciones, inclusive")
+	constantARS := fs.String("constant-ars", latestMonth, "mes, YYYY-MM o latest, en cuyos pesos se dibuja el precio ajustado por inflación, vacío para no dibujarlo")
+	cpiSource := fs.String("cpi-source", cpiSourceBundled, "fuente del índice de precios de -constant-ars: bundled, el incluido en el programa, o indec, el publicado hoy")
+	out := fs.String("out", "", "archivo donde se escribe el gráfico, si está vacío se escribe en la salida estándar")
+	historyPath := fs.String("history-file", defaultHistoryPath(), "archivo donde se guarda el historial")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("chart needs a search criteria")
+	}
+	query := strings.Join(fs.Args(), " ")
+	siteID := strings.ToUpper(*site)
+	start, err := parseChartDate("-from", *from)
+	if err != nil {
+		return err
+	}
+	end, err := parseChartDate("-to", *to)
+	if err != nil {
+		return err
+	}
+	if !end.IsZero() {
+		// -to incluye todo ese día.
+		end = end.AddDate(0, 0, 1)
+	}
+	if !start.IsZero() && !end.IsZero() && !start.Before(end) {
+		return fmt.Errorf("-from %s is after -to %s", *from, *to)
+	}
+
+	reports, err := newHistoryStore(*historyPath).load(query)
+	if err != nil {
+		return err
+	}
+	base := ""
+	if *constantARS != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), defaultSiteTimeout)
+		defer cancel()
+		var series inflation.Series
+		if series, err = loadCPI(ctx, *cpiSource); err != nil {
+			return fmt.Errorf("loading consumer price index: %v", err)
+		}
+		if base, err = constantARSMonth(*constantARS, series); err != nil {
+			return err
+		}
+		applyInflation(reports, series, base)
+	}
+	points := chartPoints(reports, siteID, start, end)
+	if len(points) == 0 {
+		return fmt.Errorf("no comparisons of %q with results from %s in the history", query, siteID)
+	}
+
+	title := fmt.Sprintf(i18n.T("%s en %s"), query, siteID)
+	series := historySeries(points, base)
+	if *out == "" {
+		return writeChartSVG(os.Stdout, title, series)
+	}
+	f, err := os.Create(*out)
+	if err != nil {
+		return fmt.Errorf("creating chart file: %v", err)
+	}
+	if err := writeChartSVG(f, title, series); err != nil {
+		f.Close()
+		return fmt.Errorf("writing chart: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("writing chart: %v", err)
+	}
+	return nil
+}
+
+// parseChartDate interpreta una fecha de -from o -to en la zona horaria local, vacía es la
+// fecha cero.
+func parseChartDate(flagName, date string) (time.Time, error) {
+	if date == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", date, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s %q, must be YYYY-MM-DD", flagName, date)
+	}
+	return t, nil
+}
+
+// historySeries arma las series del gráfico: el precio nominal, el ajustado por inflación a
+// pesos de base si hay alguno y el precio en dólares en el eje derecho.
+func historySeries(points []chartPoint, base string) []chartSeries {
+	nominal := chartSeries{Label: fmt.Sprintf(i18n.T("Precio nominal (%s)"), points[0].Currency), Color: nominalColor}
+	constant := chartSeries{Label: fmt.Sprintf(i18n.T("En pesos de %s"), base), Color: constantColor, Dashed: true}
+	usd := chartSeries{Label: i18n.T("USD (eje derecho)"), Color: usdColor, Right: true}
+	for _, p := range points {
+		nominal.Times = append(nominal.Times, p.Time)
+		nominal.Values = append(nominal.Values, p.Price)
+		usd.Times = append(usd.Times, p.Time)
+		usd.Values = append(usd.Values, p.PriceUSD)
+		if p.ConstantARS != nil {
+			constant.Times = append(constant.Times, p.Time)
+			constant.Values = append(constant.Values, *p.ConstantARS)
+		}
+	}
+	series := []chartSeries{nominal}
+	if len(constant.Values) > 0 {
+		series = append(series, constant)
+	}
+	return append(series, usd)
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/perrito666/tutoriales_go/internal/i18n"
+	"github.com/shopspring/decimal"
+)
+
+// runCompare implementa el subcomando compare, que compara dos productos sitio por sitio.
+func runCompare(args []string) error {
+	fs := newFlagSet("compare", "[opciones] <criterio A> <criterio B>",
+		"Compara dos productos en todos los sitios y muestra, sitio por sitio, el precio en dólares de\n"+
+			"cada uno y la diferencia. Cada criterio va entre comillas, por ejemplo:\n"+
+			"  compare \"iPhone 15\" \"Galaxy S24\"")
+	search := addSearchFlags(fs)
+	output := fs.String("output", outputText, "formato de salida: text o json")
+	maxDuration := addMaxDurationFlag(fs)
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	deadline, err := runDeadline(*maxDuration)
+	if err != nil {
+		return err
+	}
+	opts, err := search.options()
+	if err != nil {
+		return err
+	}
+	if !validOutput(*output) {
+		return fmt.Errorf("unknown -output %q, must be %s or %s", *output, outputText, outputJSON)
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return fmt.Errorf("compare needs exactly two search terms, got %d", fs.NArg())
+	}
+
+	sites, err := selectedSites(opts)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := withRunDeadline(context.Background(), deadline)
+	defer cancel()
+	// ambas comparaciones corren a la vez y comparten sitios y cotizaciones.
+	results := make([][]siteSearchResult, 2)
+	wg := &sync.WaitGroup{}
+	wg.Add(len(results))
+	for i := range results {
+		go func(i int) {
+			defer wg.Done()
+			results[i], _ = compareSites(ctx, fs.Arg(i), sites, opts)
+		}(i)
+	}
+	wg.Wait()
+	if ctx.Err() == context.DeadlineExceeded {
+		log.Printf("compare: -max-duration %s reached, showing partial results", *maxDuration)
+	}
+
+	comparison := correlateResults(sites, fs.Arg(0), fs.Arg(1), results[0], results[1])
+	if *output == outputJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(comparison)
+	}
+	return writeComparison(os.Stdout, comparison)
+}
+
+// siteComparison es la comparación de dos productos en un sitio.
+type siteComparison struct {
+	SiteID   string `json:"site_id"`
+	SiteName string `json:"site_name"`
+	// A y B son el precio en USD de cada producto, nil si el sitio no tiene datos.
+	A *decimal.Decimal `json:"a"`
+	B *decimal.Decimal `json:"b"`
+	// Delta es B - A en USD y DeltaPercent es cuanto mas caro es B que A en porcentaje.
+	Delta        *decimal.Decimal `json:"delta,omitempty"`
+	DeltaPercent *decimal.Decimal `json:"delta_percent,omitempty"`
+}
+
+// comparison es el resultado del subcomando compare.
+type comparison struct {
+	QueryA string           `json:"query_a"`
+	QueryB string           `json:"query_b"`
+	Sites  []siteComparison `json:"sites"`
+}
+
+// firstBySite devuelve el precio en USD de la primera publicación de cada sitio, por ID de
+// sitio.
+func firstBySite(results []siteSearchResult) map[string]decimal.Decimal {
+	prices := map[string]decimal.Decimal{}
+	for _, r := range results {
+		if _, ok := prices[r.site.ID]; !ok {
+			prices[r.site.ID] = r.priceUSD.Amount
+		}
+	}
+	// dedupResults deja una sola copia de las publicaciones repetidas entre sitios, los otros
+	// sitios donde aparece también tienen ese precio.
+	for _, r := range results {
+		for _, site := range r.alsoOn {
+			if _, ok := prices[site.ID]; !ok {
+				prices[site.ID] = r.priceUSD.Amount
+			}
+		}
+	}
+	return prices
+}
+
+// correlateResults junta por sitio los resultados de dos productos, tomando de cada sitio la
+// primera publicación de cada uno, en el orden de la lista de sitios.
+func correlateResults(sites []mlSite, queryA, queryB string, a, b []siteSearchResult) *comparison {
+	pricesA, pricesB := firstBySite(a), firstBySite(b)
+
+	c := &comparison{QueryA: queryA, QueryB: queryB}
+	for _, site := range sites {
+		sc := siteComparison{SiteID: site.ID, SiteName: site.Name}
+		if price, ok := pricesA[site.ID]; ok {
+			sc.A = &price
+		}
+		if price, ok := pricesB[site.ID]; ok {
+			sc.B = &price
+		}
+		if sc.A != nil && sc.B != nil {
+			delta := sc.B.Sub(*sc.A)
+			sc.Delta = &delta
+			if !sc.A.IsZero() {
+				percent := delta.Div(*sc.A).Mul(decimal.New(100, 0))
+				sc.DeltaPercent = &percent
+			}
+		}
+		c.Sites = append(c.Sites, sc)
+	}
+	return c
+}
+
+// writeComparison escribe la comparación como una tabla.
+func writeComparison(w io.Writer, c *comparison) error {
+	usd := func(d *decimal.Decimal) string {
+		if d == nil {
+			return "-"
+		}
+		return formatAmount(*d, usdCurrencyCode)
+	}
+	percent := func(d *decimal.Decimal) string {
+		if d == nil {
+			return "-"
+		}
+		return formatNumber(*d)
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, i18n.T("Sitio\t%s (USD)\t%s (USD)\tDiferencia (USD)\tDiferencia (%%)\t\n"), c.QueryA, c.QueryB)
+	for _, sc := range c.Sites {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t\n", sc.SiteName, usd(sc.A), usd(sc.B), usd(sc.Delta), percent(sc.DeltaPercent))
+	}
+	return tw.Flush()
+}
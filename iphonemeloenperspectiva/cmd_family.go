@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/perrito666/tutoriales_go/internal/i18n"
+)
+
+// runFamily implementa el subcomando family, que compara los modelos de una familia.
+func runFamily(args []string) error {
+	fs := newFlagSet("family", "[opciones] <familia>",
+		"Busca en el catálogo de Mercado Libre los modelos de una familia, por ejemplo los iPhone 15,\n"+
+			"15 Plus, 15 Pro y 15 Pro Max, compara cada uno en todos los sitios y muestra una tabla con\n"+
+			"el precio en dólares de cada modelo en cada país, por ejemplo:\n"+
+			"  family \"iPhone 15\"")
+	search := addSearchFlags(fs)
+	discoverSite := fs.String("discover-site", "MLA", "ID del sitio de Mercado Libre en cuyo catálogo se buscan los modelos de la familia")
+	maxModels := fs.Int("max-models", 6, "cantidad máxima de modelos que se comparan")
+	models := fs.String("models", "", "modelos separados por coma que se comparan en lugar de buscarlos en el catálogo")
+	output := fs.String("output", outputText, "formato de salida: text o json")
+	maxDuration := addMaxDurationFlag(fs)
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	deadline, err := runDeadline(*maxDuration)
+	if err != nil {
+		return err
+	}
+	opts, err := search.options()
+	if err != nil {
+		return err
+	}
+	if !validOutput(*output) {
+		return fmt.Errorf("unknown -output %q, must be %s or %s", *output, outputText, outputJSON)
+	}
+	if *maxModels < 1 {
+		return fmt.Errorf("-max-models must be at least 1, got %d", *maxModels)
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("family needs exactly one model family, got %d", fs.NArg())
+	}
+	if opts.catalog {
+		return fmt.Errorf("family cannot be used with -catalog")
+	}
+	prefix := fs.Arg(0)
+
+	sites, err := selectedSites(opts)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := withRunDeadline(context.Background(), deadline)
+	defer cancel()
+	familyModels := parseKeywords(*models)
+	if len(familyModels) == 0 {
+		discoverCtx, cancel := context.WithTimeout(ctx, opts.timeout)
+		familyModels, err = discoverFamily(discoverCtx, prefix, mlSite{ID: strings.ToUpper(*discoverSite)}, *maxModels)
+		cancel()
+		if err != nil {
+			return fmt.Errorf("discovering models of %q: %v", prefix, err)
+		}
+		if len(familyModels) == 0 {
+			return fmt.Errorf("no models of %q found in the %s catalog, list them with -models", prefix, *discoverSite)
+		}
+	}
+	results := compareQueries(ctx, familyModels, familyOptions(familyModels, opts), sites)
+	if ctx.Err() == context.DeadlineExceeded {
+		log.Printf("family: -max-duration %s reached, showing partial results", *maxDuration)
+	}
+
+	report := correlateFamily(prefix, sites, familyModels, results)
+	if len(report.Sites) == 0 {
+		return fmt.Errorf("no model of %q found in any site", prefix)
+	}
+	if *output == outputJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	}
+	return writeFamily(os.Stdout, report)
+}
+
+// writeFamily escribe la matriz de modelo por país como una tabla.
+func writeFamily(w io.Writer, report *familyReport) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprint(tw, i18n.T("Modelo (USD)\t"))
+	for _, site := range report.Sites {
+		fmt.Fprintf(tw, "%s\t", site.Name)
+	}
+	fmt.Fprintln(tw)
+	for _, m := range report.Models {
+		fmt.Fprintf(tw, "%s\t", m.Model)
+		for _, price := range m.Prices {
+			if price == nil {
+				fmt.Fprint(tw, "-\t")
+				continue
+			}
+			fmt.Fprintf(tw, "%s\t", formatAmount(*price, usdCurrencyCode))
+		}
+		fmt.Fprintln(tw)
+	}
+	return tw.Flush()
+}
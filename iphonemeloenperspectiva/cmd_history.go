@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/perrito666/tutoriales_go/internal/i18n"
+	"github.com/perrito666/tutoriales_go/internal/inflation"
+)
+
+// runHistory implementa el subcomando history, que muestra las comparaciones guardadas.
+func runHistory(args []string) error {
+	fs := newFlagSet("history", "[opciones] [criterio de búsqueda]",
+		"Muestra las comparaciones guardadas en el historial, solo las del criterio indicado si se indica uno.")
+	output := fs.String("output", outputText, "formato de salida: text o json")
+	limit := fs.Int("limit", 20, "cantidad máxima de comparaciones, las mas recientes, que se muestran (0 muestra todas)")
+	historyPath := fs.String("history-file", defaultHistoryPath(), "archivo donde se guarda el historial")
+	constantARS := fs.String("constant-ars", "", "mes, YYYY-MM o latest, en cuyos pesos se expresan los precios en pesos argentinos según la inflación")
+	cpiSource := fs.String("cpi-source", cpiSourceBundled, "fuente del índice de precios de -constant-ars: bundled, el incluido en el programa, o indec, el publicado hoy")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if !validOutput(*output) {
+		return fmt.Errorf("unknown -output %q, must be %s or %s", *output, outputText, outputJSON)
+	}
+	var series inflation.Series
+	base := ""
+	if *constantARS != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), defaultSiteTimeout)
+		defer cancel()
+		var err error
+		if series, err = loadCPI(ctx, *cpiSource); err != nil {
+			return fmt.Errorf("loading consumer price index: %v", err)
+		}
+		if base, err = constantARSMonth(*constantARS, series); err != nil {
+			return err
+		}
+	}
+
+	query := strings.Join(fs.Args(), " ")
+	reports, err := newHistoryStore(*historyPath).load(query)
+	if err != nil {
+		return err
+	}
+	reports = lastReports(reports, *limit)
+	if base != "" {
+		applyInflation(reports, series, base)
+	}
+
+	if *output == outputJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(reports)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprint(w, i18n.T("Fecha\tBúsqueda\tMas barato (USD)\tMas caro (USD)\tSitios fallidos"))
+	if base != "" {
+		fmt.Fprintf(w, i18n.T("\tMas barato (ARS)\tEn pesos de %s"), base)
+	}
+	fmt.Fprintln(w)
+	for _, report := range reports {
+		cheapest, priciest := "-", "-"
+		if min, max, ok := priceRange(report); ok {
+			cheapest = fmt.Sprintf("%s %s", min.SiteName, min.usd().AmountString())
+			priciest = fmt.Sprintf("%s %s", max.SiteName, max.usd().AmountString())
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d", report.Time.Local().Format("2006-01-02 15:04"),
+			report.Query, cheapest, priciest, len(report.Failed))
+		if base != "" {
+			nominal, constant := "-", "-"
+			if r, ok := cheapestARS(report); ok {
+				nominal = formatAmount(r.Price, arsCurrencyCode)
+				if r.ConstantARS != nil {
+					constant = formatAmount(*r.ConstantARS, arsCurrencyCode)
+				}
+			}
+			fmt.Fprintf(w, "\t%s\t%s", nominal, constant)
+		}
+		fmt.Fprintln(w)
+	}
+	return w.Flush()
+}
+
+// lastReports devuelve los últimos limit reportes, o todos si limit es 0.
+func lastReports(reports []*runReport, limit int) []*runReport {
+	if limit > 0 && len(reports) > limit {
+		return reports[len(reports)-limit:]
+	}
+	return reports
+}
+
+// priceRange devuelve el resultado mas barato y el mas caro en dólares de un reporte.
+func priceRange(report *runReport) (min, max reportResult, ok bool) {
+	for i, r := range report.Results {
+		if i == 0 || r.PriceUSD.LessThan(min.PriceUSD) {
+			min = r
+		}
+		if i == 0 || r.PriceUSD.GreaterThan(max.PriceUSD) {
+			max = r
+		}
+	}
+	return min, max, len(report.Results) > 0
+}
+
+// cheapestARS devuelve el resultado en pesos argentinos mas barato de un reporte.
+func cheapestARS(report *runReport) (cheapest reportResult, ok bool) {
+	for _, r := range report.Results {
+		if r.Currency != arsCurrencyCode {
+			continue
+		}
+		if !ok || r.Price.LessThan(cheapest.Price) {
+			cheapest, ok = r, true
+		}
+	}
+	return cheapest, ok
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/perrito666/tutoriales_go/internal/metriste"
+	"github.com/perrito666/tutoriales_go/internal/rates"
+)
+
+// runMetriste implementa el subcomando metriste, el programa original de iphonemetriste: el
+// iPhone mas caro de Argentina en pesos y en dólares, por defecto del Banco Nación.
+func runMetriste(args []string) error {
+	fs := newFlagSet("metriste", "[opciones]",
+		"Busca el iPhone mas caro en Mercado Libre Argentina y lo convierte a dólares con la cotización\n"+
+			"del Banco Nación, como el programa iphonemetriste. Con -rates-source mercadolibre usa la de\n"+
+			"Mercado Libre.")
+	dryRun := fs.Bool("dry-run", false, "muestra los pedidos que haría, sin hacerlos")
+	fs.StringVar(&metriste.Sort, "sort", sortPriceDesc, "orden de los resultados y cual se muestra: price_desc (el mas caro), price_asc (el mas barato) o relevance (el mas relevante)")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if !validSort(metriste.Sort) {
+		return fmt.Errorf("unknown -sort %q, must be one of %s, %s or %s", metriste.Sort, sortPriceDesc, sortPriceAsc, sortRelevance)
+	}
+	// a diferencia del resto de los comandos el tutorial cotiza con el Banco Nación.
+	if rateSourceName == "" {
+		var err error
+		if rateSource, err = newRateChain(rates.BNAName); err != nil {
+			return err
+		}
+	}
+	if *dryRun {
+		return metriste.DryRun(os.Stdout, rateSource)
+	}
+	// los pedidos salen con el mismo cliente que los del resto de los comandos, así respetan
+	// -proxy, -user-agent, -record y el resto de los flags comunes.
+	metriste.Client = httpClient
+	return metriste.Run(os.Stdout, rateSource)
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/perrito666/tutoriales_go/internal/i18n"
+	"github.com/shopspring/decimal"
+)
+
+// runRates implementa el subcomando rates, que muestra la cotización a dólares de las
+// monedas indicadas o, si no se indica ninguna, de las monedas de todos los sitios. "rates ars"
+// muestra en cambio las distintas cotizaciones del dólar en Argentina.
+func runRates(args []string) error {
+	fs := newFlagSet("rates", "[opciones] [moneda...|ars]",
+		"Muestra la cotización a dólares de las monedas indicadas, por ejemplo ARS BRL, o de todas\n"+
+			"las monedas de los sitios de Mercado Libre si no se indica ninguna. \"rates ars\", en\n"+
+			"minúsculas, muestra el dólar oficial, blue, MEP, CCL y tarjeta en pesos argentinos.")
+	output := fs.String("output", outputText, "formato de salida: text o json")
+	timeout := fs.Duration("site-timeout", defaultSiteTimeout, "plazo que tiene cada cotización para responder")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if !validOutput(*output) {
+		return fmt.Errorf("unknown -output %q, must be %s or %s", *output, outputText, outputJSON)
+	}
+	if fs.NArg() == 1 && fs.Arg(0) == argentineDollarsArg {
+		ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+		defer cancel()
+		return writeArgentineDollars(os.Stdout, argentineDollars(ctx), *output)
+	}
+
+	currencies := []string{}
+	for _, currency := range fs.Args() {
+		currencies = append(currencies, strings.ToUpper(currency))
+	}
+	if len(currencies) == 0 {
+		sites, err := fetchSites()
+		if err != nil {
+			return fmt.Errorf("could not obtain mercado libre sites: %v", err)
+		}
+		currencies = siteCurrencies(sites)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	rates, failed := fetchRates(ctx, newRateCache(*timeout), currencies)
+
+	if *output == outputJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(struct {
+			Rates  map[string]decimal.Decimal `json:"rates"`
+			Failed map[string]string          `json:"failed,omitempty"`
+		}{rates, failed})
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, i18n.T("Moneda\tUSD por unidad"))
+	for _, currency := range currencies {
+		if err, ok := failed[currency]; ok {
+			fmt.Fprintf(w, "%s\terror: %s\n", currency, err)
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%s\n", currency, formatRate(rates[currency]))
+	}
+	return w.Flush()
+}
+
+// siteCurrencies devuelve las monedas de los sitios, sin repetir y ordenadas.
+func siteCurrencies(sites []mlSite) []string {
+	seen := map[string]bool{}
+	currencies := []string{}
+	for _, site := range sites {
+		if !seen[site.DefaultCurrencyID] {
+			seen[site.DefaultCurrencyID] = true
+			currencies = append(currencies, site.DefaultCurrencyID)
+		}
+	}
+	sort.Strings(currencies)
+	return currencies
+}
+
+// fetchRates pide concurrentemente la cotización a USD de cada moneda y devuelve por un lado
+// las cotizaciones y por otro los errores, ambos por moneda.
+func fetchRates(ctx context.Context, cache *rateCache, currencies []string) (map[string]decimal.Decimal, map[string]string) {
+	rates := map[string]decimal.Decimal{}
+	failed := map[string]string{}
+	mu := &sync.Mutex{}
+	wg := &sync.WaitGroup{}
+	wg.Add(len(currencies))
+	for _, currency := range currencies {
+		go func(currency string) {
+			defer wg.Done()
+			rate, err := cache.get(ctx, currency)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				failed[currency] = err.Error()
+				return
+			}
+			rates[currency] = rate
+		}(currency)
+	}
+	wg.Wait()
+	return rates, failed
+}
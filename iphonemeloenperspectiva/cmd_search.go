@@ -0,0 +1,326 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// runSearch implementa el subcomando search, la comparación de precios de siempre.
+func runSearch(args []string) error {
+	fs := newFlagSet("search", "[opciones] [criterio de búsqueda]",
+		"Busca el criterio en todos los sitios de Mercado Libre y compara los precios en dólares.")
+	search := addSearchFlags(fs)
+	output := fs.String("output", outputText, "formato de salida: text, json, ndjson (una linea por resultado apenas llega) o stream (texto a medida que llega y al final ordenado)")
+	save := fs.Bool("save", false, "guarda el resultado en el historial")
+	historyPath := fs.String("history-file", defaultHistoryPath(), "archivo donde se guarda el historial")
+	costsFile := fs.String("costs-file", "", "archivo YAML con aranceles, impuestos y envíos por país para estimar el costo de traer cada publicación")
+	home := fs.String("home", "", "ID del sitio del país al que se trae la compra, reemplaza el home del archivo de costos")
+	minWage := fs.Bool("min-wage", false, "indica cuantos meses de salario mínimo del país cuesta cada publicación")
+	wagesFile := fs.String("wages-file", "", "archivo YAML con salarios mínimos que reemplazan a los incluidos")
+	alsoIn := fs.String("also-in", "", "monedas separadas por coma, por ejemplo EUR,BRL,CLP, en las que se muestra además el precio de cada publicación")
+	crypto := fs.String("crypto", "", "criptomonedas separadas por coma, BTC, ETH o USDT, en las que se muestra además el precio de cada publicación según CoinGecko")
+	arsDollars := fs.Bool("ars-dollars", false, "muestra el precio de las publicaciones en pesos argentinos en dólar oficial, blue, MEP, CCL y tarjeta, y el de las demás pagadas con tarjeta desde Argentina")
+	showInstallments := fs.Bool("show-installments", false, "indica lo que cuesta en total cada publicación pagada en las cuotas que ofrece y cuanto mas que de contado es")
+	describe := fs.Bool("describe", false, "muestra el comienzo de la descripción de cada publicación de Mercado Libre")
+	questions := fs.Bool("questions", false, "indica cuantas preguntas tiene cada publicación de Mercado Libre, cuantas sin responder y cuando fue la última actividad")
+	zip := fs.String("zip", "", "código postal del comprador, estima el envío de cada publicación de Mercado Libre hasta él y lo suma al costo puesto")
+	diff := fs.Bool("diff", false, "indica cuanto cambió el precio de cada sitio desde la última comparación del historial")
+	diffThreshold := &percentValue{}
+	fs.Var(diffThreshold, "diff-threshold", "con -diff, termina con error si algún precio cambió mas que este porcentaje, por ejemplo 10%")
+	export := addExportFlags(fs)
+	termsFile := fs.String("terms-file", "", "archivo con un criterio de búsqueda por linea, compara cada uno y emite un reporte combinado")
+	bestEffort := fs.Bool("best-effort", false, "termina sin error si algún sitio devolvió resultados, aunque otros hayan fallado")
+	showTimings := fs.Bool("timings", false, "muestra al final cuanto tardó cada sitio en la búsqueda, la cotización y la de-serialización")
+	groupBy := fs.String("group-by", "", "en un solo sitio, resume los precios por province o city del vendedor en lugar de mostrar cada publicación, considera todas las de -pages")
+	showQR := fs.Bool("qr", false, "muestra el código QR del link de la publicación elegida en cada sitio, para abrirla en el teléfono")
+	qrDir := fs.String("qr-dir", "", "directorio donde se guarda un PNG con el código QR del link de la publicación elegida en cada sitio")
+	open := &openValue{}
+	fs.Var(open, "open", "al terminar abre en el navegador la publicación mas barata, o con -open=<sitio> la elegida en ese sitio")
+	dryRun := fs.Bool("dry-run", false, "muestra los pedidos que haría la comparación, con todos sus parámetros, sin hacerlos")
+	maxDuration := addMaxDurationFlag(fs)
+	cfg, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	deadline, err := runDeadline(*maxDuration)
+	if err != nil {
+		return err
+	}
+	opts, err := search.options()
+	if err != nil {
+		return err
+	}
+	if !validOutput(*output) && *output != outputNDJSON && *output != outputStream {
+		return fmt.Errorf("unknown -output %q, must be %s, %s, %s or %s", *output, outputText, outputJSON, outputNDJSON, outputStream)
+	}
+	if *showQR && (*output == outputJSON || *output == outputNDJSON) {
+		return fmt.Errorf("-qr cannot be used with -output %s, use -qr-dir", *output)
+	}
+	if *groupBy != "" {
+		if !validGroupBy(*groupBy) {
+			return fmt.Errorf("unknown -group-by %q, must be %s or %s", *groupBy, groupByProvince, groupByCity)
+		}
+		if *output == outputNDJSON || *output == outputStream {
+			return fmt.Errorf("-group-by cannot be used with -output %s", *output)
+		}
+		// los grupos resumen todas las publicaciones que se vieron, no solo las primeras, y
+		// como dedupResults solo colapsa publicaciones de sitios distintos se cuentan todas.
+		opts.perSite = opts.pages * pageSize
+	}
+
+	exporters, err := export.exporters()
+	if err != nil {
+		return err
+	}
+
+	var costs *costModel
+	if *costsFile != "" {
+		if costs, err = loadCostModel(*costsFile); err != nil {
+			return err
+		}
+		if *home != "" {
+			costs.Home = *home
+		}
+		if costs.Home == "" {
+			return fmt.Errorf("-costs-file needs a home site, set it in the file or with -home")
+		}
+	}
+
+	var wages map[string]monthlyIncome
+	if *minWage {
+		if wages, err = loadWages(*wagesFile); err != nil {
+			return err
+		}
+	}
+
+	alsoInCurrencies, err := parseCurrencies(*alsoIn)
+	if err != nil {
+		return err
+	}
+	cryptoCurrencies, err := parseCryptoCurrencies(*crypto)
+	if err != nil {
+		return err
+	}
+
+	terms := []string{queryFromArgs(fs, cfg)}
+	if *termsFile != "" {
+		if terms, err = readTerms(*termsFile); err != nil {
+			return err
+		}
+	}
+
+	sitesRequested := false
+	if *dryRun {
+		// los sitios salen de la lista guardada y ningún pedido llega a la red.
+		for _, provider := range opts.providers {
+			sitesRequested = sitesRequested || strings.EqualFold(provider, "mercadolibre")
+		}
+		sitesRequested = sitesRequested && siteList.stale()
+		siteList.offline = true
+		outgoing.base = dryRunTransport{}
+	}
+
+	// la lista de sitios y el cache de cotizaciones se comparten entre todas las búsquedas.
+	sites, err := selectedSites(opts)
+	if err != nil {
+		return err
+	}
+	if open.site != "" && !hasSite(sites, open.site) {
+		return fmt.Errorf("-open: site %s is not among the compared sites", open.site)
+	}
+	if *groupBy != "" && len(sites) != 1 {
+		return fmt.Errorf("-group-by needs a single site, choose one with -sites")
+	}
+	if *dryRun {
+		return writeDryRun(os.Stdout, terms, sites, sitesRequested)
+	}
+	ctx, span := tracer.Start(context.Background(), "search")
+	defer span.End()
+	// el plazo de -max-duration solo alcanza a las búsquedas, lo que haya llegado se muestra y
+	// se exporta igual.
+	searchCtx, cancel := withRunDeadline(ctx, deadline)
+	defer cancel()
+	history := newHistoryStore(*historyPath)
+	reports := make([]*runReport, 0, len(terms))
+	var exceeded, exportErrs []string
+	var siteErrs []error
+	var dollars *dollarsReport
+	var cryptoQuotes map[string]decimal.Decimal
+	found := 0
+	for _, searchTerms := range terms {
+		termOpts := opts
+		switch *output {
+		case outputNDJSON:
+			termOpts.onResult = streamResults(os.Stdout, searchTerms)
+		case outputStream:
+			if *termsFile != "" {
+				fmt.Printf("=== %s\n", searchTerms)
+			}
+			termOpts.onResult = streamText(os.Stdout, time.Now())
+		}
+		timings := &timingCollector{}
+		if *showTimings {
+			termOpts.onResult = timings.collect(termOpts.onResult)
+		}
+		results, failed := compareSites(searchCtx, searchTerms, sites, termOpts)
+		report := newRunReport(searchTerms, results, failed)
+		if *showTimings {
+			report.Timings = timings.result()
+		}
+		if *groupBy != "" {
+			groupByLocation(report, *groupBy)
+		}
+		// la comparación anterior se busca antes de guardar la nueva.
+		if *diff {
+			previous, err := history.load(searchTerms)
+			if err != nil {
+				return err
+			}
+			if len(previous) > 0 {
+				report.Diff = diffReports(previous[len(previous)-1], report)
+				if change, ok := report.Diff.exceeding(diffThreshold.percent); ok && diffThreshold.percent.IsPositive() {
+					exceeded = append(exceeded, fmt.Sprintf("%s changed %s%% in %s", searchTerms,
+						change.ChangePercent.StringFixed(2), change.SiteName))
+				}
+			}
+		}
+		if *questions {
+			applyQuestions(searchCtx, report, opts.timeout)
+		}
+		if *describe {
+			applyDescriptions(searchCtx, report, opts.timeout)
+		}
+		// el envío va antes que los costos, que lo suman al costo puesto.
+		if *zip != "" {
+			applyShipping(searchCtx, report, *zip, opts.timeout)
+		}
+		if costs != nil {
+			applyCosts(report, costs)
+		}
+		if wages != nil {
+			applyWages(report, wages)
+		}
+		if *showInstallments {
+			applyInstallments(report)
+		}
+		if len(alsoInCurrencies) > 0 && len(report.Results) > 0 {
+			// el cache de cotizaciones de las búsquedas pide cada moneda una sola vez.
+			quotesCtx, cancel := context.WithTimeout(ctx, opts.timeout)
+			quotes, failed := fetchRates(quotesCtx, opts.rates, alsoInCurrencies)
+			cancel()
+			for currency, err := range failed {
+				log.Printf("search: -also-in %s: %s", currency, err)
+			}
+			applyAlsoIn(report, alsoInCurrencies, quotes)
+		}
+		if len(cryptoCurrencies) > 0 && len(report.Results) > 0 {
+			// como las del dólar, se piden una sola vez con el plazo de un sitio.
+			if cryptoQuotes == nil {
+				quotesCtx, cancel := context.WithTimeout(ctx, opts.timeout)
+				cryptoQuotes = fetchCryptoQuotes(quotesCtx, cryptoCurrencies)
+				cancel()
+			}
+			applyCrypto(report, cryptoCurrencies, cryptoQuotes)
+		}
+		if *arsDollars {
+			// las cotizaciones se piden una sola vez, recién cuando alguna búsqueda encontró
+			// publicaciones: las en pesos se convierten a cada dólar y las demás a pesos con el
+			// dólar tarjeta.
+			if dollars == nil && len(report.Results) > 0 {
+				// tienen el mismo plazo que un sitio.
+				dollarsCtx, cancel := context.WithTimeout(ctx, opts.timeout)
+				fetched := argentineDollars(dollarsCtx)
+				cancel()
+				for name, err := range fetched.Failed {
+					log.Printf("search: dollar %s: %s", name, err)
+				}
+				dollars = &fetched
+			}
+			if dollars != nil {
+				applyArgentineDollars(report, *dollars)
+			}
+		}
+		if *save {
+			if err := history.append(report); err != nil {
+				return fmt.Errorf("saving to history: %v", err)
+			}
+		}
+		// un destino que falla no debe impedir mostrar el resultado, el error se informa al final.
+		if err := exportReport(ctx, exporters, report); err != nil {
+			exportErrs = append(exportErrs, err.Error())
+		}
+		if *qrDir != "" {
+			if err := writeQRFiles(*qrDir, report); err != nil {
+				return err
+			}
+		}
+		reports = append(reports, report)
+		if *output == outputStream {
+			writeStreamSummary(os.Stdout, report)
+		}
+		found += len(report.Results)
+		for _, err := range report.siteErrs {
+			siteErrs = append(siteErrs, fmt.Errorf("%s: %w", searchTerms, err))
+		}
+	}
+
+	if searchCtx.Err() == context.DeadlineExceeded {
+		log.Printf("search: -max-duration %s reached, showing partial results", *maxDuration)
+	}
+
+	switch {
+	case *output == outputNDJSON, *output == outputStream:
+		// los resultados ya se escribieron a medida que llegaron.
+	case *termsFile != "":
+		err = writeBatchReport(os.Stdout, reports, *output)
+	default:
+		err = writeReport(os.Stdout, reports[0], *output)
+	}
+	if err != nil {
+		return err
+	}
+	if *showQR {
+		// después del reporte, que con varios criterios es una sola tabla.
+		for _, report := range reports {
+			if err := writeTextQR(os.Stdout, report); err != nil {
+				return err
+			}
+		}
+	}
+	var errs []error
+	if len(exportErrs) > 0 {
+		errs = append(errs, errors.New(strings.Join(exportErrs, "; ")))
+	}
+	if open.enabled {
+		// como los destinos, un navegador que no abre no invalida la comparación.
+		for _, report := range reports {
+			if err := openListing(open, report); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	if len(exceeded) > 0 {
+		errs = append(errs, fmt.Errorf("price change above -diff-threshold %s: %s", diffThreshold, strings.Join(exceeded, "; ")))
+	}
+	// los sitios que fallaron ya se mostraron con el resultado, pero también se devuelven para
+	// que quien llame pueda saber que la comparación no fue completa: si algún sitio respondió
+	// el programa termina con exitPartial, salvo con -best-effort donde solo se informan.
+	if siteErr := errors.Join(siteErrs...); siteErr != nil {
+		switch {
+		case found == 0 || len(errs) > 0:
+			errs = append([]error{siteErr}, errs...)
+		case *bestEffort:
+			log.Printf("search: %v", siteErr)
+		default:
+			return &exitError{code: exitPartial, err: siteErr}
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,434 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// defaultServeAddr es la dirección por defecto en la que escucha el servidor.
+const defaultServeAddr = "localhost:8080"
+
+// runServe implementa el subcomando serve, que expone las comparaciones como una API HTTP
+// con JSON:
+//
+//	GET /search?q=<criterio>  compara el criterio entre los sitios
+//	GET /search/stream?q=     la misma comparación como Server-Sent Events, sitio por sitio
+//	GET /sites                lista los sitios
+//	GET /rates?currency=ARS   cotización a dólares de una o mas monedas
+//	GET /history?q=<criterio> comparaciones guardadas en el historial
+//	GET /feed/<criterio>      feed Atom con los cambios de precio del criterio
+//	GET /ws?q=&since=         WebSocket con cada comparación nueva del historial
+//	GET /                     tablero web que usa los endpoints anteriores
+//	GET /stats                cantidad de pedidos por clave de API
+//
+// Al recibir Ctrl+C o SIGTERM deja de aceptar pedidos y espera hasta -drain-timeout a que
+// terminen los que están en curso.
+//
+// Si se indica -api-keys todos los endpoints salvo el tablero exigen una de las claves y si
+// se indica -rate-limit cada cliente tiene un límite de pedidos por minuto. Las páginas de
+// otros orígenes solo pueden llamar a la API si se los permite con -cors-origins.
+//
+// Si se indica -grpc-addr además atiende PriceService por gRPC (ver pricepb/price.proto).
+func runServe(args []string) error {
+	fs := newFlagSet("serve", "[opciones]", "Expone las comparaciones como una API HTTP que responde JSON.")
+	search := addSearchFlags(fs)
+	addr := fs.String("addr", defaultServeAddr, "dirección en la que escucha el servidor")
+	keys := apiKeys{}
+	fs.Var(keys, "api-keys", "claves de API aceptadas como etiqueta=clave separadas por coma, sin claves la API es abierta")
+	rateLimit := fs.Float64("rate-limit", 0, "pedidos por minuto que puede hacer cada cliente (clave de API o IP), 0 es sin límite")
+	rateBurst := fs.Int("rate-burst", 5, "pedidos seguidos que puede hacer un cliente antes de que se aplique -rate-limit")
+	resultTTL := fs.Duration("result-ttl", time.Minute, "tiempo durante el cual /search responde la misma comparación de un criterio sin volver a buscar")
+	rateTTL := fs.Duration("rate-ttl", 10*time.Minute, "tiempo durante el cual /rates responde las mismas cotizaciones sin volver a pedirlas")
+	corsOrigins := fs.String("cors-origins", "", "orígenes separados por coma desde los que un navegador puede llamar a la API, * permite todos")
+	corsMethods := fs.String("cors-methods", "GET", "métodos separados por coma permitidos a otros orígenes")
+	corsHeaders := fs.String("cors-headers", "Authorization,"+apiKeyHeader, "encabezados separados por coma que pueden enviar otros orígenes")
+	grpcAddr := fs.String("grpc-addr", "", "dirección en la que escucha el servicio gRPC, además de la API HTTP")
+	save := fs.Bool("save", false, "guarda cada búsqueda en el historial")
+	historyPath := fs.String("history-file", defaultHistoryPath(), "archivo donde se guarda el historial")
+	pprofEnabled := fs.Bool("pprof", false, "expone los perfiles de net/http/pprof en /debug/pprof/, con la misma clave de API que el resto")
+	drainTimeout := fs.Duration("drain-timeout", 30*time.Second, "al apagarse, tiempo que se espera a que terminen los pedidos en curso antes de cancelarlos")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	opts, err := search.options()
+	if err != nil {
+		return err
+	}
+	if *rateLimit < 0 || *rateBurst < 1 {
+		return fmt.Errorf("-rate-limit cannot be negative and -rate-burst must be at least 1")
+	}
+	if *resultTTL < 0 || *rateTTL < 0 {
+		return fmt.Errorf("-result-ttl and -rate-ttl cannot be negative")
+	}
+	if *drainTimeout < 0 {
+		return fmt.Errorf("-drain-timeout cannot be negative")
+	}
+
+	// al recibir Ctrl+C o SIGTERM el servidor se apaga ordenadamente.
+	stopping, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s := &server{
+		ctx:      ctx,
+		cancel:   cancel,
+		stopping: stopping.Done(),
+		stop:     stop,
+		opts:     opts,
+		history:  newHistoryStore(*historyPath),
+		hub:      newReportHub(),
+		keys:     keys,
+		limiter:  newClientLimiter(*rateLimit, *rateBurst),
+		cors:     newCORSPolicy(*corsOrigins, *corsMethods, *corsHeaders),
+		results:  newResponseCache(*resultTTL),
+		rates:    newResponseCache(*rateTTL),
+		save:     *save,
+		pprof:    *pprofEnabled,
+	}
+	// las comparaciones nuevas del historial, las guarde watch o este servidor, se reparten a
+	// los clientes conectados a /ws.
+	go s.hub.follow(s.ctx, s.history, historyPollInterval)
+
+	httpServer := &http.Server{
+		Addr:    *addr,
+		Handler: s.routes(),
+		// los pedidos derivan de s.ctx, así se cancelan las búsquedas que no terminen a tiempo
+		// al apagar el servidor.
+		BaseContext: func(net.Listener) context.Context { return s.ctx },
+	}
+	// si alguno de los dos servidores falla terminamos, sin esperar al otro.
+	errs := make(chan error, 2)
+	var grpcServer *grpc.Server
+	if *grpcAddr != "" {
+		listener, err := net.Listen("tcp", *grpcAddr)
+		if err != nil {
+			return fmt.Errorf("listening for grpc: %v", err)
+		}
+		grpcServer = newGRPCServer(s)
+		go func() {
+			log.Printf("listening for grpc on %s", *grpcAddr)
+			errs <- grpcServer.Serve(listener)
+		}()
+	}
+	go func() {
+		log.Printf("listening on http://%s", *addr)
+		errs <- httpServer.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-stopping.Done():
+	}
+	return s.shutdown(httpServer, grpcServer, *drainTimeout)
+}
+
+// shutdownGrace es lo que se espera a que respondan los pedidos cancelados al vencer el
+// plazo para apagar el servidor.
+const shutdownGrace = time.Second
+
+// shutdown deja de aceptar pedidos, termina las conexiones que no terminan solas (WebSocket
+// y Watch) y espera hasta drainTimeout a que terminen los pedidos en curso, después cancela
+// sus búsquedas. Por último espera a que termine de escribirse el historial.
+func (s *server) shutdown(httpServer *http.Server, grpcServer *grpc.Server, drainTimeout time.Duration) error {
+	log.Printf("shutting down, waiting up to %s for requests in progress", drainTimeout)
+	s.stop()
+	ctx, cancel := context.WithTimeout(context.Background(), drainTimeout)
+	defer cancel()
+
+	grpcDone := make(chan struct{})
+	if grpcServer != nil {
+		go func() {
+			defer close(grpcDone)
+			grpcServer.GracefulStop()
+		}()
+	}
+	err := httpServer.Shutdown(ctx)
+	if err == context.DeadlineExceeded {
+		// las búsquedas canceladas igual responden con los sitios que llegaron, les damos
+		// un momento para hacerlo antes de cortar las conexiones.
+		log.Printf("requests still in progress after %s, canceling them", drainTimeout)
+		s.cancel()
+		graceCtx, graceCancel := context.WithTimeout(context.Background(), shutdownGrace)
+		defer graceCancel()
+		if err = httpServer.Shutdown(graceCtx); err == context.DeadlineExceeded {
+			err = httpServer.Close()
+		}
+	}
+	if grpcServer != nil {
+		select {
+		case <-grpcDone:
+		case <-ctx.Done():
+			s.cancel()
+			grpcServer.Stop()
+		}
+	}
+	s.cancel()
+	s.history.wait()
+	return err
+}
+
+// server contiene lo que necesitan los handlers de la API.
+type server struct {
+	// ctx es el contexto de todos los pedidos, cancel lo cancela cuando vence el plazo para
+	// apagar el servidor.
+	ctx    context.Context
+	cancel context.CancelFunc
+	// stopping se cierra cuando el servidor se empieza a apagar, stop lo cierra.
+	stopping <-chan struct{}
+	stop     context.CancelFunc
+	// opts son las opciones de búsqueda por defecto, indicadas al iniciar el servidor.
+	opts    searchOptions
+	history *historyStore
+	hub     *reportHub
+	// keys son las claves de API aceptadas, si no hay ninguna la API es abierta.
+	keys apiKeys
+	// limiter limita los pedidos de cada cliente, nil es sin límite.
+	limiter *clientLimiter
+	// cors indica que otros orígenes pueden llamar a la API, nil es ninguno.
+	cors *corsPolicy
+	// results y rates guardan las respuestas de /search y /rates para no repetir búsquedas
+	// y cotizaciones cuando los clientes consultan seguido.
+	results *responseCache
+	rates   *responseCache
+	save    bool
+	// pprof indica que se exponen los endpoints de net/http/pprof.
+	pprof bool
+}
+
+// routes devuelve el http.Handler con todos los endpoints de la API.
+func (s *server) routes() http.Handler {
+	mux := http.NewServeMux()
+	api := func(pattern string, handler http.HandlerFunc) {
+		mux.Handle(pattern, s.keys.requireAPIKey(s.limiter.limitHTTP(handler)))
+	}
+	api("/search", s.handleSearch)
+	api("/search/stream", s.handleSearchStream)
+	api("/sites", s.handleSites)
+	api("/rates", s.handleRates)
+	api("/history", s.handleHistory)
+	api("/feed/", s.handleFeed)
+	api("/ws", s.handleWS)
+	api("/stats", s.handleStats)
+	// los perfiles exigen la clave de API pero no cuentan para el límite de pedidos, pedir
+	// un perfil de CPU tarda lo que dura el perfil.
+	if s.pprof {
+		registerPprof(mux, s.keys.requireAPIKey)
+	}
+	// todo lo que no es un endpoint de la API es un archivo del tablero, son archivos
+	// estáticos que no necesitan clave, la clave la envía el tablero en cada pedido.
+	mux.Handle("/", dashboard())
+	return s.cors.handler(mux)
+}
+
+// writeJSON responde v como JSON con el código de estado indicado.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("writing response: %v", err)
+	}
+}
+
+// writeError responde un error como JSON.
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// onlyGET responde 405 y devuelve false si el pedido no es un GET.
+func onlyGET(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+		return false
+	}
+	return true
+}
+
+func (s *server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	if !onlyGET(w, r) {
+		return
+	}
+	searchTerms := strings.TrimSpace(r.URL.Query().Get("q"))
+	if searchTerms == "" {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("missing q parameter"))
+		return
+	}
+	response, err := s.results.get(normalizeTitle(searchTerms), func() (interface{}, time.Time, error) {
+		// la búsqueda la pueden estar esperando varios clientes, no se corta si uno se va.
+		report, err := s.search(s.ctx, searchTerms, nil)
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+		return report, report.Time, nil
+	})
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	serveCached(w, r, response)
+}
+
+func (s *server) handleSearchStream(w http.ResponseWriter, r *http.Request) {
+	if !onlyGET(w, r) {
+		return
+	}
+	searchTerms := strings.TrimSpace(r.URL.Query().Get("q"))
+	if searchTerms == "" {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("missing q parameter"))
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("streaming is not supported"))
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	// cada sitio se envía apenas termina, desde la gorutina que junta los resultados.
+	events := newEventStream(w, flusher)
+	report, err := s.search(r.Context(), searchTerms, func(result siteSearchResult) {
+		if result.err != nil {
+			events.send(sseFailure, newReportFailure(result))
+			return
+		}
+		events.send(sseResult, newReportResult(result))
+	})
+	if err != nil {
+		events.send(sseError, map[string]string{"error": err.Error()})
+		return
+	}
+	if r.Context().Err() != nil {
+		return
+	}
+	events.send(sseSummary, report)
+}
+
+// search compara el criterio entre los sitios, llamando a onResult con cada resultado apenas
+// llega si no es nil, y guarda el reporte en el historial si se pidió.
+func (s *server) search(ctx context.Context, searchTerms string, onResult func(siteSearchResult)) (*runReport, error) {
+	sites, err := selectedSites(s.opts)
+	if err != nil {
+		return nil, err
+	}
+	// cada búsqueda pide cotizaciones nuevas, el servidor vive mucho y las cotizaciones cambian.
+	opts := s.opts
+	opts.rates = newRateCache(s.opts.timeout)
+	opts.onResult = onResult
+	results, failed := compareSites(ctx, searchTerms, sites, opts)
+	report := newRunReport(searchTerms, results, failed)
+	if s.save && ctx.Err() == nil {
+		if err := s.history.append(report); err != nil {
+			log.Printf("saving to history: %v", err)
+		}
+	}
+	return report, nil
+}
+
+func (s *server) handleSites(w http.ResponseWriter, r *http.Request) {
+	if !onlyGET(w, r) {
+		return
+	}
+	sites, err := fetchSites()
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, sites)
+}
+
+func (s *server) handleRates(w http.ResponseWriter, r *http.Request) {
+	if !onlyGET(w, r) {
+		return
+	}
+	currencies := []string{}
+	for _, currency := range r.URL.Query()["currency"] {
+		currencies = append(currencies, parseKeywords(strings.ToUpper(currency))...)
+	}
+	if len(currencies) == 0 {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("missing currency parameter"))
+		return
+	}
+	sort.Strings(currencies)
+	response, err := s.rates.get(strings.Join(currencies, ","), func() (interface{}, time.Time, error) {
+		// cada pedido usa su propio cache de cotizaciones, las que se reutilizan son las
+		// respuestas de s.rates mientras no vencen.
+		ctx, cancel := context.WithTimeout(s.ctx, s.opts.timeout)
+		defer cancel()
+		rates, failed := fetchRates(ctx, newRateCache(s.opts.timeout), currencies)
+		return map[string]interface{}{"rates": rates, "failed": failed}, time.Now(), nil
+	})
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	serveCached(w, r, response)
+}
+
+func (s *server) handleHistory(w http.ResponseWriter, r *http.Request) {
+	if !onlyGET(w, r) {
+		return
+	}
+	limit := 20
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		var err error
+		if limit, err = strconv.Atoi(raw); err != nil || limit < 0 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid limit %q", raw))
+			return
+		}
+	}
+	reports, err := s.history.load(r.URL.Query().Get("q"))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, lastReports(reports, limit))
+}
+
+func (s *server) handleStats(w http.ResponseWriter, r *http.Request) {
+	if !onlyGET(w, r) {
+		return
+	}
+	// expvar.Map ya sabe escribirse como JSON.
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprintf(w, "{\"api_key_requests\": %s}\n", apiKeyRequests)
+}
+
+func (s *server) handleFeed(w http.ResponseWriter, r *http.Request) {
+	if !onlyGET(w, r) {
+		return
+	}
+	// r.URL.Path ya viene sin escapar, "/feed/iphone%2011" es "/feed/iphone 11".
+	query := strings.TrimSpace(strings.TrimPrefix(r.URL.Path, "/feed/"))
+	if query == "" {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("missing query in path, use /feed/<query>"))
+		return
+	}
+	reports, err := s.history.load(query)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	selfURL := (&url.URL{Scheme: "http", Host: r.Host, Path: r.URL.Path}).String()
+	w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+	if err := writeFeed(w, priceChangesFeed(query, reports, selfURL)); err != nil {
+		log.Printf("writing feed: %v", err)
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/perrito666/tutoriales_go/internal/i18n"
+)
+
+// runSites implementa el subcomando sites, que lista los sitios de Mercado Libre.
+func runSites(args []string) error {
+	fs := newFlagSet("sites", "[opciones]", "Lista los sitios de Mercado Libre con su moneda por defecto.")
+	output := fs.String("output", outputText, "formato de salida: text o json")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if !validOutput(*output) {
+		return fmt.Errorf("unknown -output %q, must be %s or %s", *output, outputText, outputJSON)
+	}
+
+	sites, err := fetchSites()
+	if err != nil {
+		return fmt.Errorf("could not obtain mercado libre sites: %v", err)
+	}
+	if *output == outputJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(sites)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, i18n.T("ID\tNombre\tMoneda"))
+	for _, site := range sites {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", site.ID, site.Name, site.DefaultCurrencyID)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/perrito666/tutoriales_go/internal/i18n"
+)
+
+// runTrends implementa el subcomando trends, que muestra las búsquedas en tendencia de cada
+// sitio y opcionalmente compara los precios de la principal.
+func runTrends(args []string) error {
+	fs := newFlagSet("trends", "[opciones]",
+		"Muestra las búsquedas en tendencia de cada sitio de Mercado Libre, con -compare además compara\n"+
+			"entre sitios el precio de la tendencia principal (la primera del primer sitio, ver -sites).")
+	search := addSearchFlags(fs)
+	output := fs.String("output", outputText, "formato de salida: text o json")
+	limit := fs.Int("limit", 10, "cantidad de tendencias que se muestran por sitio")
+	compare := fs.Bool("compare", false, "compara entre sitios el precio de la tendencia principal")
+	maxDuration := addMaxDurationFlag(fs)
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	deadline, err := runDeadline(*maxDuration)
+	if err != nil {
+		return err
+	}
+	opts, err := search.options()
+	if err != nil {
+		return err
+	}
+	if !validOutput(*output) {
+		return fmt.Errorf("unknown -output %q, must be %s or %s", *output, outputText, outputJSON)
+	}
+	if *limit < 1 {
+		return fmt.Errorf("-limit must be at least 1, got %d", *limit)
+	}
+
+	// las tendencias son solo de Mercado Libre, la comparación incluye todos los sitios.
+	mlSites, err := mercadoLibreSites(opts)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := withRunDeadline(context.Background(), deadline)
+	defer cancel()
+	trends := fetchSitesTrends(ctx, mlSites, opts.timeout, *limit)
+
+	var report *runReport
+	if *compare {
+		query, ok := topTrend(trends)
+		if !ok {
+			return fmt.Errorf("no site returned trends to compare")
+		}
+		sites, err := selectedSites(opts)
+		if err != nil {
+			return err
+		}
+		results, failed := compareSites(ctx, query, sites, opts)
+		report = newRunReport(query, results, failed)
+	}
+
+	if ctx.Err() == context.DeadlineExceeded {
+		log.Printf("trends: -max-duration %s reached, showing partial results", *maxDuration)
+	}
+
+	if *output == outputJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(struct {
+			Sites      []siteTrends `json:"sites"`
+			Comparison *runReport   `json:"comparison,omitempty"`
+		}{trends, report})
+	}
+	for _, st := range trends {
+		fmt.Printf("%s:\n", st.SiteName)
+		if st.Error != "" {
+			fmt.Printf("  error: %s\n", st.Error)
+			continue
+		}
+		for i, trend := range st.Trends {
+			fmt.Printf("  %2d. %s\n", i+1, trend.Keyword)
+		}
+	}
+	if report != nil {
+		fmt.Printf(i18n.T("\nComparación de la tendencia principal %q:\n"), report.Query)
+		writeTextReport(os.Stdout, report)
+	}
+	return nil
+}
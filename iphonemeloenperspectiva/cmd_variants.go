@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	"github.com/perrito666/tutoriales_go/internal/i18n"
+)
+
+// runVariants implementa el subcomando variants, que compara las capacidades de un modelo.
+func runVariants(args []string) error {
+	fs := newFlagSet("variants", "[opciones] <modelo>",
+		"Busca cada capacidad de almacenamiento de un modelo en todos los sitios y muestra, sitio por\n"+
+			"sitio, el precio en dólares de cada una, el precio por GB y cuanto mas cuesta cada una que la\n"+
+			"anterior, por ejemplo:\n"+
+			"  variants -storage 128,256,512,1TB \"iPhone 15 Pro\"")
+	search := addSearchFlags(fs)
+	storage := fs.String("storage", "128,256,512", "capacidades separadas por coma, en GB o con unidad, por ejemplo 128,256,1TB")
+	output := fs.String("output", outputText, "formato de salida: text o json")
+	maxDuration := addMaxDurationFlag(fs)
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	deadline, err := runDeadline(*maxDuration)
+	if err != nil {
+		return err
+	}
+	opts, err := search.options()
+	if err != nil {
+		return err
+	}
+	if !validOutput(*output) {
+		return fmt.Errorf("unknown -output %q, must be %s or %s", *output, outputText, outputJSON)
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("variants needs exactly one model, got %d", fs.NArg())
+	}
+	if opts.catalog {
+		return fmt.Errorf("variants cannot be used with -catalog")
+	}
+	sizes, err := parseStorageSizes(*storage)
+	if err != nil {
+		return err
+	}
+	model := fs.Arg(0)
+	variants, err := expandStorageVariants(model, sizes, opts)
+	if err != nil {
+		return err
+	}
+
+	sites, err := selectedSites(opts)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := withRunDeadline(context.Background(), deadline)
+	defer cancel()
+	results := searchVariants(ctx, variants, sites)
+	if ctx.Err() == context.DeadlineExceeded {
+		log.Printf("variants: -max-duration %s reached, showing partial results", *maxDuration)
+	}
+
+	report := correlateVariants(model, sites, variants, results)
+	if len(report.Sites) == 0 {
+		return fmt.Errorf("no variant of %q found in any site", model)
+	}
+	if *output == outputJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	}
+	return writeVariants(os.Stdout, report)
+}
+
+// writeVariants escribe las variantes de cada sitio como una tabla.
+func writeVariants(w io.Writer, report *variantsReport) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, i18n.T("Sitio\tCapacidad\tPrecio (USD)\tPor GB (USD)\tSobreprecio (%)\t"))
+	for _, sv := range report.Sites {
+		for i, vp := range sv.Variants {
+			site := ""
+			if i == 0 {
+				site = sv.SiteName
+			}
+			premium := "-"
+			if vp.PremiumPercent != nil {
+				premium = formatNumber(*vp.PremiumPercent)
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t\n", site, vp.Storage, formatAmount(vp.PriceUSD, usdCurrencyCode),
+				formatDecimal(vp.PerGBUSD, perGBDecimals), premium)
+		}
+	}
+	return tw.Flush()
+}
+
+// perGBDecimals son los decimales del precio por GB, que suele ser de centavos.
+const perGBDecimals = 3
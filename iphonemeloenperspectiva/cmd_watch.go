@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/perrito666/tutoriales_go/iphonemeloenperspectiva/config"
+)
+
+// defaultWatchInterval es el tiempo por defecto entre comparaciones del modo watch.
+const defaultWatchInterval = 30 * time.Minute
+
+// runWatch implementa el subcomando watch, que repite una comparación de cada producto de la
+// lista de vigilados (o del criterio indicado) cada cierto intervalo, guardando cada
+// resultado en el historial hasta que se interrumpa con Ctrl+C.
+func runWatch(args []string) error {
+	fs := newFlagSet("watch", "[opciones] [criterio de búsqueda]",
+		"Repite la comparación de cada producto de la lista de vigilados (ver el comando watchlist) cada\n"+
+			"-interval y guarda cada resultado en el historial, se detiene con Ctrl+C. Si se indica un\n"+
+			"criterio de búsqueda se vigila solo ese.")
+	search := addSearchFlags(fs)
+	output := fs.String("output", outputText, "formato de salida: text o json")
+	interval := fs.Duration("interval", defaultWatchInterval, "tiempo entre comparaciones")
+	historyPath := fs.String("history-file", defaultHistoryPath(), "archivo donde se guarda el historial")
+	alertDrop := &percentValue{}
+	fs.Var(alertDrop, "alert-drop", "emite una alerta cuando el precio de un sitio baja mas de este porcentaje, por ejemplo 10%")
+	export := addExportFlags(fs)
+	watchlistPath := fs.String("watchlist-file", defaultWatchlistPath(), "archivo donde se guarda la lista de productos vigilados")
+	cfg, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	opts, err := search.options()
+	if err != nil {
+		return err
+	}
+	if !validOutput(*output) {
+		return fmt.Errorf("unknown -output %q, must be %s or %s", *output, outputText, outputJSON)
+	}
+	if *interval <= 0 {
+		return fmt.Errorf("-interval must be positive, got %s", *interval)
+	}
+	exporters, err := export.exporters()
+	if err != nil {
+		return err
+	}
+
+	// al recibir Ctrl+C se cancela el contexto, lo que corta las búsquedas en curso y la espera.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	history := newHistoryStore(*historyPath)
+	for {
+		// la lista se lee en cada vuelta, así los cambios hechos con watchlist se toman sin
+		// necesidad de reiniciar.
+		products, err := watchedProducts(fs, cfg, *watchlistPath)
+		if err != nil {
+			return err
+		}
+		for _, product := range products {
+			if ctx.Err() != nil {
+				break
+			}
+			report, err := watchOnce(ctx, product.Query, opts, history, *output)
+			if err != nil {
+				// un fallo en una vuelta no detiene el modo watch, la próxima puede funcionar.
+				log.Printf("watching %q: %v", product.Query, err)
+				continue
+			}
+			if report == nil {
+				continue
+			}
+			// exportar es un extra, si falla el resultado igual quedó en el historial.
+			if err := exportReport(ctx, exporters, report); err != nil {
+				log.Print(err)
+			}
+			emitAlerts(checkAlerts(product, report))
+			if alertDrop.percent.IsPositive() {
+				alerts, err := checkDropAlerts(history, report, alertDrop.percent)
+				if err != nil {
+					log.Printf("checking price drops of %q: %v", product.Query, err)
+				}
+				emitAlerts(alerts)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(*interval):
+		}
+	}
+}
+
+// watchedProducts devuelve los productos a vigilar: el criterio indicado en la linea de
+// comandos, o si no hay, la lista de vigilados, o si está vacía, el criterio por defecto.
+func watchedProducts(fs *flag.FlagSet, cfg *config.Config, watchlistPath string) ([]watchedProduct, error) {
+	if fs.NArg() > 0 {
+		return []watchedProduct{{Query: queryFromArgs(fs, cfg)}}, nil
+	}
+	list, err := loadWatchlist(watchlistPath)
+	if err != nil {
+		return nil, err
+	}
+	if len(list.Products) > 0 {
+		return list.Products, nil
+	}
+	return []watchedProduct{{Query: queryFromArgs(fs, cfg)}}, nil
+}
+
+// watchOnce realiza una vuelta del modo watch para un producto: compara, guarda e imprime.
+// Si la comparación se interrumpe no devuelve reporte.
+func watchOnce(ctx context.Context, searchTerms string, opts searchOptions, history *historyStore, output string) (*runReport, error) {
+	sites, err := selectedSites(opts)
+	if err != nil {
+		return nil, err
+	}
+	// cada vuelta pide cotizaciones nuevas.
+	opts.rates = newRateCache(opts.timeout)
+	results, failed := compareSites(ctx, searchTerms, sites, opts)
+	// si nos interrumpieron a mitad de camino no guardamos un resultado incompleto.
+	if ctx.Err() != nil {
+		return nil, nil
+	}
+	report := newRunReport(searchTerms, results, failed)
+	if err := history.append(report); err != nil {
+		return nil, fmt.Errorf("saving to history: %v", err)
+	}
+	return report, writeReport(os.Stdout, report, output)
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/perrito666/tutoriales_go/internal/i18n"
+	"github.com/shopspring/decimal"
+)
+
+// runWatchlist implementa el subcomando watchlist, con sus propios subcomandos add, remove
+// y list, que administran los productos que vigila el modo watch.
+func runWatchlist(args []string) error {
+	// la acción va primero así cada una puede tener sus opciones, por ejemplo
+	// "watchlist add -below 900 iPhone 15".
+	action := "help"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		action, args = args[0], args[1:]
+	}
+	fs := newFlagSet("watchlist "+action, "[opciones] [criterio de búsqueda]",
+		"Administra la lista de productos que compara el modo watch:\n"+
+			"  add [-below USD] <criterio>  agrega un producto, con -below se alerta si baja de ese precio\n"+
+			"  remove <criterio>            quita un producto\n"+
+			"  list                         lista los productos")
+	below := fs.String("below", "", "precio en USD por debajo del cual watch emite una alerta para el producto agregado")
+	watchlistPath := fs.String("watchlist-file", defaultWatchlistPath(), "archivo donde se guarda la lista de productos vigilados")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	query := strings.Join(fs.Args(), " ")
+
+	list, err := loadWatchlist(*watchlistPath)
+	if err != nil {
+		return err
+	}
+	switch action {
+	case "add":
+		if query == "" {
+			return fmt.Errorf("missing search terms to add")
+		}
+		product := watchedProduct{Query: query}
+		if *below != "" {
+			if product.Below, err = decimal.NewFromString(*below); err != nil || !product.Below.IsPositive() {
+				return fmt.Errorf("invalid -below %q, must be a positive USD amount", *below)
+			}
+		}
+		list.add(product)
+		return list.save()
+	case "remove":
+		if !list.remove(query) {
+			return fmt.Errorf("%q is not in the watchlist", query)
+		}
+		return list.save()
+	case "list":
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, i18n.T("Búsqueda\tAlerta debajo de (USD)"))
+		for _, product := range list.Products {
+			threshold := "-"
+			if product.Below.IsPositive() {
+				threshold = NewMoney(product.Below, usdCurrencyCode).AmountString()
+			}
+			fmt.Fprintf(w, "%s\t%s\n", product.Query, threshold)
+		}
+		return w.Flush()
+	}
+	fs.Usage()
+	return fmt.Errorf("unknown watchlist action %q, must be add, remove or list", action)
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// selectedSites devuelve los sitios de todos los tipos de Provider habilitados que le
+// interesan al usuario según opts.
+func selectedSites(opts searchOptions) ([]mlSite, error) {
+	sites, err := providerSites(opts)
+	if err != nil {
+		return nil, err
+	}
+	if len(sites) == 0 {
+		return nil, fmt.Errorf("no sites left to query after applying -providers, -sites and -exclude-sites")
+	}
+	return sites, nil
+}
+
+// compareSites busca searchTerms en todos los sitios concurrentemente y devuelve por un lado
+// los resultados, ya agrupados si se repiten entre sitios y ordenados según opts.sortBy, y por
+// otro los sitios que fallaron.
+func compareSites(ctx context.Context, searchTerms string, sites []mlSite, opts searchOptions) (results, failed []siteSearchResult) {
+	ctx, span := tracer.Start(ctx, "compare", trace.WithAttributes(
+		attribute.String("query", searchTerms),
+		attribute.Int("sites", len(sites)),
+	))
+	defer func() {
+		span.SetAttributes(attribute.Int("sites.failed", len(failed)))
+		span.End()
+	}()
+
+	// Hacemos una lista que contendrá los resultados de las búsquedas.
+	results = make([]siteSearchResult, 0, len(sites))
+
+	// creamos los WaitGroups para cada una de las go-rutinas que buscará.
+	wg := &sync.WaitGroup{}
+	wg.Add(len(sites))
+
+	// creamos un canal, sin buffer, para los resultados.
+	resultChannel := make(chan siteSearchResult)
+
+	// instanciamos una gorutina por cada sitio de Mercado Libre
+	for i := range sites {
+		go queryForSite(ctx, searchTerms, sites[i], opts, wg, resultChannel)
+	}
+
+	// creamos un WaitGroup para esperar la gorutina que procesa los resultados.
+	waitResultFetch := &sync.WaitGroup{}
+	waitResultFetch.Add(1)
+
+	// Hacemos un contexto cancelable para indicar cuando estemos listos
+	// para salir de la función de procesamiento de resultados.
+	processCtx, done := context.WithCancel(context.Background())
+
+	// invocamos la función anónima de procesamiento de resultados pasando
+	// el contexto como parámetro, notar el shadowing.
+	go func(ctx context.Context) {
+		for {
+			select {
+			case r := <-resultChannel:
+				// quien quiera puede ver cada resultado apenas llega.
+				if opts.onResult != nil {
+					opts.onResult(r)
+				}
+				// guardamos aparte los sitios que fallaron, o no respondieron a tiempo,
+				// para reportarlos al final separados de los que tienen datos.
+				if r.err != nil {
+					failed = append(failed, r)
+					break
+				}
+				results = append(results, r)
+			case <-ctx.Done():
+				waitResultFetch.Done()
+				return
+			}
+		}
+	}(processCtx)
+
+	// esperamos el wait group de todas las gorutinas de búsqueda, que no terminarán hasta
+	// que la funcion de procesamiento haya leido su resultado.
+	wg.Wait()
+
+	// indicamos a la función de procesamiento que ya no queda nada por procesar
+	done()
+
+	// esperamos que la función de procesamiento termine.
+	waitResultFetch.Wait()
+
+	// los resultados llegan en el orden en que respondieron los sitios, que cambia de una
+	// ejecución a otra, los ordenamos antes de agrupar así también es estable cual de las
+	// publicaciones repetidas se conserva.
+	sortResults(results, opts.sortBy)
+	sortFailures(failed)
+
+	// un mismo vendedor puede publicar lo mismo en varios sitios, los agrupamos.
+	results = dedupResults(results)
+	for _, r := range results {
+		sortSites(r.alsoOn)
+	}
+	return results, failed
+}
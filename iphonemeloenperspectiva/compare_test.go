@@ -0,0 +1,242 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/perrito666/tutoriales_go/internal/rates"
+	"github.com/perrito666/tutoriales_go/iphonemeloenperspectiva/melitest"
+)
+
+// useTransport hace que los pedidos de la prueba, incluidos los de la lista de sitios y las
+// cotizaciones, pasen por transport, y deja todo como estaba al terminar.
+func useTransport(t *testing.T, transport http.RoundTripper) {
+	t.Helper()
+	previousBase, previousSites, previousRates, previousBackoff := outgoing.base, siteList, rateSource, mlBackoff
+	t.Cleanup(func() {
+		outgoing.base, siteList, rateSource, mlBackoff = previousBase, previousSites, previousRates, previousBackoff
+	})
+	outgoing.base = transport
+	// ni la lista de sitios ni las pausas de Mercado Libre pasan de una prueba a otra.
+	siteList = &sitesCache{ttl: defaultSitesTTL}
+	mlBackoff = &mlPause{}
+	source, err := newRateSource(rates.MercadoLibreName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rateSource = source
+}
+
+// compareForTest compara searchTerms con los flags de search args y devuelve los resultados y
+// los sitios que fallaron, por ID de sitio.
+func compareForTest(t *testing.T, searchTerms string, args ...string) (results, failed map[string]siteSearchResult) {
+	t.Helper()
+	found, notFound := compareListForTest(t, searchTerms, args...)
+	results, failed = map[string]siteSearchResult{}, map[string]siteSearchResult{}
+	for _, r := range found {
+		results[r.site.ID] = r
+	}
+	for _, r := range notFound {
+		failed[r.site.ID] = r
+	}
+	return results, failed
+}
+
+// compareListForTest es como compareForTest pero devuelve los resultados en el orden de
+// compareSites, para las pruebas con varias publicaciones por sitio.
+func compareListForTest(t *testing.T, searchTerms string, args ...string) (results, failed []siteSearchResult) {
+	t.Helper()
+	fs := flag.NewFlagSet("search", flag.ContinueOnError)
+	search := addSearchFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	opts, err := search.options()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sites, err := selectedSites(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return compareSites(context.Background(), searchTerms, sites, opts)
+}
+
+func TestCompareSitesMeliTest(t *testing.T) {
+	server := melitest.NewServer()
+	defer server.Close()
+	useTransport(t, server.Transport())
+
+	results, failed := compareForTest(t, "iphone 11")
+	if len(failed) > 0 {
+		t.Fatalf("got %d failed sites, want none", len(failed))
+	}
+	if len(results) != 3 {
+		t.Fatalf("got %d results, want 3", len(results))
+	}
+	// la funda es la publicación mas barata y además se descarta por ser un accesorio.
+	for id, r := range results {
+		if r.itemID != id+"1" {
+			t.Errorf("%s item = %s, want %s1", id, r.itemID, id)
+		}
+	}
+}
+
+func TestCompareSitesRetriesFailedSite(t *testing.T) {
+	server := melitest.NewServer()
+	defer server.Close()
+	useTransport(t, server.Transport())
+	server.Inject(melitest.SearchPath("MLB"), melitest.Fault{Status: http.StatusInternalServerError, Times: 1})
+
+	results, failed := compareForTest(t, "iphone 11", "-retry-delay", "10ms")
+	if f, ok := failed["MLB"]; ok {
+		t.Fatalf("MLB failed: %v", f.err)
+	}
+	if r := results["MLB"]; r.attempts != 2 {
+		t.Errorf("MLB attempts = %d, want 2", r.attempts)
+	}
+	if calls := server.Calls(melitest.SearchPath("MLB")); calls != 2 {
+		t.Errorf("MLB searched %d times, want 2", calls)
+	}
+}
+
+func TestCompareSitesRespectsRetryAfter(t *testing.T) {
+	server := melitest.NewServer()
+	defer server.Close()
+	useTransport(t, server.Transport())
+	server.Inject(melitest.SearchPath("MLB"), melitest.Fault{Status: http.StatusTooManyRequests, RetryAfter: "1", Times: 1})
+
+	start := time.Now()
+	results, failed := compareForTest(t, "iphone 11", "-retry-delay", "10ms")
+	if f, ok := failed["MLB"]; ok {
+		t.Fatalf("MLB failed: %v", f.err)
+	}
+	if _, ok := results["MLB"]; !ok {
+		t.Fatal("no result for MLB")
+	}
+	// el reintento espera lo que pidió Mercado Libre y no el -retry-delay.
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("comparison took %s, want at least the 1s of Retry-After", elapsed)
+	}
+	if calls := server.Calls(melitest.SearchPath("MLB")); calls != 2 {
+		t.Errorf("MLB searched %d times, want 2", calls)
+	}
+}
+
+func TestCompareSitesMalformedJSON(t *testing.T) {
+	server := melitest.NewServer()
+	defer server.Close()
+	useTransport(t, server.Transport())
+	server.Inject(melitest.SearchPath("MLB"), melitest.Fault{Malformed: true})
+
+	results, failed := compareForTest(t, "iphone 11", "-retry-delay", "10ms")
+	f, ok := failed["MLB"]
+	if !ok {
+		t.Fatal("MLB did not fail with a malformed response")
+	}
+	if f.timedOut || !strings.Contains(f.err.Error(), "decoding") {
+		t.Errorf("MLB error = %v (timed out %v), want a decoding error", f.err, f.timedOut)
+	}
+	// el reintento no arregla un JSON roto, pero igual se intenta las veces de -retries.
+	if calls := server.Calls(melitest.SearchPath("MLB")); calls != 3 {
+		t.Errorf("MLB searched %d times, want 3", calls)
+	}
+	// los demás sitios no se ven afectados.
+	if len(results) != 2 {
+		t.Errorf("got %d results, want 2", len(results))
+	}
+}
+
+func TestCompareSitesTimeout(t *testing.T) {
+	server := melitest.NewServer()
+	defer server.Close()
+	useTransport(t, server.Transport())
+	server.Inject(melitest.SearchPath("MEC"), melitest.Fault{Latency: 5 * time.Second})
+
+	start := time.Now()
+	results, failed := compareForTest(t, "iphone 11", "-site-timeout", "300ms", "-retry-delay", "10ms")
+	f, ok := failed["MEC"]
+	if !ok {
+		t.Fatal("MEC did not fail with a slow response")
+	}
+	if !f.timedOut {
+		t.Errorf("MEC timedOut = false, want true: %v", f.err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("comparison took %s, want it to stop waiting at -site-timeout", elapsed)
+	}
+	if len(results) != 2 {
+		t.Errorf("got %d results, want 2", len(results))
+	}
+}
+
+// sameSellerItems devuelve dos publicaciones del mismo vendedor con el mismo título en
+// siteID, como las de un modelo en dos colores.
+func sameSellerItems(siteID, currency string, price float64) []melitest.Item {
+	items := []melitest.Item{}
+	for i, color := range []string{"negro", "blanco"} {
+		id := fmt.Sprintf("%s%d", siteID, 10+i)
+		items = append(items, melitest.Item{
+			ID:         id,
+			Title:      "Apple iPhone 11 Pro Max 256gb",
+			Price:      price - float64(i),
+			CurrencyID: currency,
+			Permalink:  "https://articulo.mercadolibre.com/" + id + "-" + color,
+			Seller:     melitest.Seller{ID: 42},
+		})
+	}
+	return items
+}
+
+// itemIDs devuelve los IDs de item de results y, por cada uno, los sitios de alsoOn.
+func itemIDs(results []siteSearchResult) map[string][]string {
+	ids := map[string][]string{}
+	for _, r := range results {
+		ids[r.itemID] = []string{}
+		for _, site := range r.alsoOn {
+			ids[r.itemID] = append(ids[r.itemID], site.ID)
+		}
+	}
+	return ids
+}
+
+func TestCompareSitesKeepsSameSiteDuplicates(t *testing.T) {
+	server := melitest.NewServer()
+	defer server.Close()
+	useTransport(t, server.Transport())
+	server.SetItems("MLA", sameSellerItems("MLA", "ARS", 1500000)...)
+
+	results, failed := compareListForTest(t, "iphone 11", "-sites", "MLA", "-per-site", "2")
+	if len(failed) > 0 {
+		t.Fatalf("got %d failed sites, want none", len(failed))
+	}
+	want := map[string][]string{"MLA10": {}, "MLA11": {}}
+	if got := itemIDs(results); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("results = %v, want %v", got, want)
+	}
+}
+
+func TestCompareSitesCollapsesOnlyAcrossSites(t *testing.T) {
+	server := melitest.NewServer()
+	defer server.Close()
+	useTransport(t, server.Transport())
+	server.SetItems("MLA", sameSellerItems("MLA", "ARS", 1500000)...)
+	server.SetItems("MLB", sameSellerItems("MLB", "BRL", 7500)[:1]...)
+
+	results, failed := compareListForTest(t, "iphone 11", "-sites", "MLA,MLB", "-per-site", "2",
+		"-sort-by", "site-id")
+	if len(failed) > 0 {
+		t.Fatalf("got %d failed sites, want none", len(failed))
+	}
+	// la publicación de Brasil es la misma que la primera de Argentina, la segunda se
+	// conserva aunque sea del mismo vendedor y nunca figura en su propio sitio.
+	want := map[string][]string{"MLA10": {"MLB"}, "MLA11": {}}
+	if got := itemIDs(results); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("results = %v, want %v", got, want)
+	}
+}
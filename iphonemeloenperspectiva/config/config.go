@@ -0,0 +1,490 @@
+// Package config carga la configuración de iphonemeloenperspectiva desde un archivo YAML,
+// por defecto ~/.config/iphonemelo/config.yaml, y la aplica por debajo de los flags de la
+// linea de comandos: un flag indicado explícitamente siempre gana sobre el archivo.
+package config
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// appDir es el nombre del directorio de la aplicación dentro del directorio de configuración.
+const appDir = "iphonemelo"
+
+// fileName es el nombre del archivo de configuración.
+const fileName = "config.yaml"
+
+// Config contiene los valores por defecto que el usuario quiere usar, cada campo se
+// corresponde con el flag del mismo nombre, los campos vacíos no modifican nada.
+type Config struct {
+	// Query es el criterio de búsqueda a usar si no se indica ninguno.
+	Query string `yaml:"query"`
+	// Sites restringe la comparación a estos IDs de sitio.
+	Sites []string `yaml:"sites"`
+	// ExcludeSites excluye estos IDs de sitio de la comparación.
+	ExcludeSites []string `yaml:"exclude_sites"`
+	// Providers son los tipos de sitio que participan de la comparación, por ejemplo
+	// mercadolibre, ebay o amazon.
+	Providers []string `yaml:"providers"`
+	// Ebay son los mercados de eBay que se suman a la comparación.
+	Ebay []string `yaml:"ebay"`
+	// EbayClientID y EbayClientSecret son las credenciales de la aplicación de eBay.
+	EbayClientID     string `yaml:"ebay_client_id"`
+	EbayClientSecret string `yaml:"ebay_client_secret"`
+	// Amazon son los mercados de Amazon que se suman a la comparación.
+	Amazon []string `yaml:"amazon"`
+	// AmazonAccessKey, AmazonSecretKey y AmazonPartnerTag son las claves de la Product
+	// Advertising API de Amazon.
+	AmazonAccessKey  string `yaml:"amazon_access_key"`
+	AmazonSecretKey  string `yaml:"amazon_secret_key"`
+	AmazonPartnerTag string `yaml:"amazon_partner_tag"`
+	// Sort es el orden de los resultados: price_desc, price_asc o relevance.
+	Sort string `yaml:"sort"`
+	// SortBy es el orden en que se muestran los resultados: price, site, site-id o rate.
+	SortBy string `yaml:"sort_by"`
+	// PerSite es la cantidad de publicaciones por sitio.
+	PerSite int `yaml:"per_site"`
+	// Exclude son las palabras que descartan un resultado, una lista vacía desactiva las
+	// exclusiones por defecto.
+	Exclude []string `yaml:"exclude"`
+	// SiteTimeout es el plazo de cada sitio, por ejemplo "5s".
+	SiteTimeout time.Duration `yaml:"site_timeout"`
+	// MaxDuration es el plazo de toda la ejecución de search, compare o trends, por ejemplo
+	// "30s".
+	MaxDuration time.Duration `yaml:"max_duration"`
+	// Retries es la cantidad de reintentos por sitio, es un puntero porque 0 es un valor válido.
+	Retries *int `yaml:"retries"`
+	// RetryDelay es la espera antes del primer reintento.
+	RetryDelay time.Duration `yaml:"retry_delay"`
+	// Pages es la cantidad de páginas que se piden por sitio.
+	Pages int `yaml:"pages"`
+	// PageConcurrency es la cantidad de páginas de un sitio que se piden a la vez.
+	PageConcurrency int `yaml:"page_concurrency"`
+	// SitesTTL es el tiempo durante el cual se usa la lista de sitios guardada.
+	SitesTTL time.Duration `yaml:"sites_ttl"`
+	// BNATTL es el tiempo durante el cual se usan las cotizaciones del Banco Nación guardadas.
+	BNATTL time.Duration `yaml:"bna_ttl"`
+	// UserAgent es el User-Agent de los pedidos salientes.
+	UserAgent string `yaml:"user_agent"`
+	// Headers son encabezados que se agregan a los pedidos salientes, por nombre.
+	Headers map[string]string `yaml:"headers"`
+	// Proxy es la URL del proxy por el que salen todos los pedidos.
+	Proxy string `yaml:"proxy"`
+	// MaxResponseSize es el tamaño máximo en bytes de cada respuesta.
+	MaxResponseSize int64 `yaml:"max_response_size"`
+	// Output es el formato de salida: text o json, y en search también ndjson o stream.
+	Output string `yaml:"output"`
+	// Decimals es la cantidad de decimales de los montos, es un puntero porque 0 es un valor
+	// válido.
+	Decimals *int `yaml:"decimals"`
+	// Rounding es el modo de redondeo de los montos: bank, half-up o truncate.
+	Rounding string `yaml:"rounding"`
+	// RatesSource es la fuente de las cotizaciones: mercadolibre (o meli), bna, blue, bcra o
+	// fixed:<pesos por dólar>.
+	RatesSource string `yaml:"rates_source"`
+	// RatesFallback son las fuentes que se prueban en orden si la de RatesSource no cotiza
+	// una moneda, una lista vacía (pero presente) no prueba ninguna.
+	RatesFallback []string `yaml:"rates_fallback"`
+	// BNATable es la tabla del Banco Nación de la que se leen las cotizaciones: billetes o
+	// divisas.
+	BNATable string `yaml:"bna_table"`
+	// PAISTax y PerceptionTax son los impuestos que se suman al dólar oficial para calcular
+	// el dólar tarjeta, en porcentaje, por ejemplo "30%".
+	PAISTax       string `yaml:"pais_tax"`
+	PerceptionTax string `yaml:"perception_tax"`
+	// CostsFile es el archivo con el modelo de costos de importación.
+	CostsFile string `yaml:"costs_file"`
+	// Home es el ID del sitio del país del usuario.
+	Home string `yaml:"home"`
+	// WagesFile es el archivo con los salarios mínimos que reemplazan a los incluidos.
+	WagesFile string `yaml:"wages_file"`
+	// SheetsID es la planilla de Google Sheets a la que se agregan los resultados.
+	SheetsID string `yaml:"sheets_id"`
+	// SheetsCredentials es el archivo con la clave de la cuenta de servicio de Google.
+	SheetsCredentials string `yaml:"sheets_credentials"`
+	// SheetsRange es la hoja o rango al final del cual se agregan las filas.
+	SheetsRange string `yaml:"sheets_range"`
+	// WebhookURL es la URL a la que se envía el reporte de cada comparación.
+	WebhookURL string `yaml:"webhook_url"`
+	// WebhookSecret es el secreto con el que se firman los envíos del webhook.
+	WebhookSecret string `yaml:"webhook_secret"`
+	// WebhookRetries es la cantidad de reintentos del webhook, es un puntero porque 0 es un
+	// valor válido.
+	WebhookRetries *int `yaml:"webhook_retries"`
+	// HistoryFile es el archivo donde se guarda el historial.
+	HistoryFile string `yaml:"history_file"`
+	// Interval es el tiempo entre comparaciones del modo watch.
+	Interval time.Duration `yaml:"interval"`
+	// Addr es la dirección en la que escucha el servidor.
+	Addr string `yaml:"addr"`
+	// GRPCAddr es la dirección en la que escucha el servicio gRPC.
+	GRPCAddr string `yaml:"grpc_addr"`
+	// APIKeys son las claves de API que acepta el servidor, por etiqueta.
+	APIKeys map[string]string `yaml:"api_keys"`
+	// ResultTTL es el tiempo durante el cual el servidor reutiliza la comparación de un criterio.
+	ResultTTL *time.Duration `yaml:"result_ttl"`
+	// RateTTL es el tiempo durante el cual el servidor reutiliza las cotizaciones.
+	RateTTL *time.Duration `yaml:"rate_ttl"`
+	// CORSOrigins son los orígenes desde los que un navegador puede llamar a la API.
+	CORSOrigins []string `yaml:"cors_origins"`
+	// CORSMethods son los métodos permitidos a otros orígenes.
+	CORSMethods []string `yaml:"cors_methods"`
+	// CORSHeaders son los encabezados que pueden enviar otros orígenes.
+	CORSHeaders []string `yaml:"cors_headers"`
+	// RateLimit es la cantidad de pedidos por minuto que puede hacer cada cliente del servidor.
+	RateLimit float64 `yaml:"rate_limit"`
+	// RateBurst es la cantidad de pedidos seguidos que puede hacer un cliente del servidor.
+	RateBurst int `yaml:"rate_burst"`
+	// DrainTimeout es el tiempo que el servidor espera a los pedidos en curso al apagarse.
+	DrainTimeout time.Duration `yaml:"drain_timeout"`
+}
+
+// DefaultPath devuelve la ubicación por defecto del archivo de configuración.
+func DefaultPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("finding user config dir: %v", err)
+	}
+	return filepath.Join(dir, appDir, fileName), nil
+}
+
+// Load lee y valida el archivo de configuración en path. Si el archivo no existe y optional
+// es verdadero se devuelve una configuración vacía.
+func Load(path string, optional bool) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) && optional {
+		return &Config{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading config file: %v", err)
+	}
+
+	c := &Config{}
+	if err := yaml.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("parsing config file %s: %v", path, err)
+	}
+	if err := c.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %v", path, err)
+	}
+	return c, nil
+}
+
+// Validate verifica que los valores presentes tengan sentido, los campos vacíos son válidos.
+func (c *Config) Validate() error {
+	switch c.Sort {
+	case "", "price_desc", "price_asc", "relevance":
+	default:
+		return fmt.Errorf("unknown sort %q", c.Sort)
+	}
+	switch c.SortBy {
+	case "", "price", "site", "site-id", "rate":
+	default:
+		return fmt.Errorf("unknown sort_by %q", c.SortBy)
+	}
+	if c.PerSite < 0 {
+		return fmt.Errorf("per_site must be at least 1, got %d", c.PerSite)
+	}
+	if c.Pages < 0 || c.PageConcurrency < 0 {
+		return fmt.Errorf("pages and page_concurrency must be at least 1")
+	}
+	if c.Retries != nil && *c.Retries < 0 {
+		return fmt.Errorf("retries cannot be negative, got %d", *c.Retries)
+	}
+	if c.SiteTimeout < 0 || c.RetryDelay < 0 || c.MaxDuration < 0 {
+		return fmt.Errorf("site_timeout, retry_delay and max_duration cannot be negative")
+	}
+	// ndjson y stream son solo de search, los demás comandos los rechazan al leer -output.
+	switch c.Output {
+	case "", "text", "json", "ndjson", "stream":
+	default:
+		return fmt.Errorf("unknown output %q", c.Output)
+	}
+	if c.Decimals != nil && *c.Decimals < 0 {
+		return fmt.Errorf("decimals cannot be negative, got %d", *c.Decimals)
+	}
+	switch c.Rounding {
+	case "", "bank", "half-up", "truncate":
+	default:
+		return fmt.Errorf("unknown rounding %q", c.Rounding)
+	}
+	if c.RatesSource != "" && !validRatesSource(c.RatesSource) {
+		return fmt.Errorf("unknown rates_source %q", c.RatesSource)
+	}
+	for _, name := range c.RatesFallback {
+		if !validRatesSource(name) {
+			return fmt.Errorf("unknown rates source %q in rates_fallback", name)
+		}
+	}
+	switch c.BNATable {
+	case "", "billetes", "divisas":
+	default:
+		return fmt.Errorf("unknown bna_table %q", c.BNATable)
+	}
+	if c.WebhookRetries != nil && *c.WebhookRetries < 0 {
+		return fmt.Errorf("webhook_retries cannot be negative, got %d", *c.WebhookRetries)
+	}
+	if c.Interval < 0 {
+		return fmt.Errorf("interval cannot be negative")
+	}
+	if (c.ResultTTL != nil && *c.ResultTTL < 0) || (c.RateTTL != nil && *c.RateTTL < 0) {
+		return fmt.Errorf("result_ttl and rate_ttl cannot be negative")
+	}
+	if c.RateLimit < 0 || c.RateBurst < 0 {
+		return fmt.Errorf("rate_limit and rate_burst cannot be negative")
+	}
+	if c.DrainTimeout < 0 {
+		return fmt.Errorf("drain_timeout cannot be negative")
+	}
+	if c.SitesTTL < 0 || c.BNATTL < 0 {
+		return fmt.Errorf("sites_ttl and bna_ttl cannot be negative")
+	}
+	for _, tax := range []string{c.PAISTax, c.PerceptionTax} {
+		if tax == "" {
+			continue
+		}
+		percent, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(tax), "%"), 64)
+		if err != nil || percent < 0 {
+			return fmt.Errorf("invalid tax percentage %q in pais_tax or perception_tax", tax)
+		}
+	}
+	if c.MaxResponseSize < 0 {
+		return fmt.Errorf("max_response_size cannot be negative, got %d", c.MaxResponseSize)
+	}
+	for label, key := range c.APIKeys {
+		if label == "" || key == "" || strings.ContainsAny(label+key, ",=") {
+			return fmt.Errorf("invalid api key %q, labels and keys cannot be empty or contain , or =", label)
+		}
+	}
+	for name, value := range c.Headers {
+		if strings.TrimSpace(name) == "" || strings.ContainsAny(name, ",=") || strings.Contains(value, ",") {
+			return fmt.Errorf("invalid header %q, names cannot be empty or contain , or = and values cannot contain ,", name)
+		}
+	}
+	for _, id := range append(append([]string{}, c.Sites...), c.ExcludeSites...) {
+		if strings.TrimSpace(id) == "" || strings.Contains(id, ",") {
+			return fmt.Errorf("invalid site ID %q", id)
+		}
+	}
+	return nil
+}
+
+// validRatesSource indica si name es una fuente de cotizaciones, la cotización fija se valida
+// al armar la fuente.
+func validRatesSource(name string) bool {
+	switch name {
+	case "mercadolibre", "meli", "bna", "blue", "bcra":
+		return true
+	}
+	return strings.HasPrefix(name, "fixed:")
+}
+
+// flagValues devuelve, por nombre de flag, el valor textual de cada campo que tiene valor.
+func (c *Config) flagValues() map[string]string {
+	values := map[string]string{}
+	if len(c.Sites) > 0 {
+		values["sites"] = strings.Join(c.Sites, ",")
+	}
+	if len(c.ExcludeSites) > 0 {
+		values["exclude-sites"] = strings.Join(c.ExcludeSites, ",")
+	}
+	if len(c.Providers) > 0 {
+		values["providers"] = strings.Join(c.Providers, ",")
+	}
+	if len(c.Ebay) > 0 {
+		values["ebay"] = strings.Join(c.Ebay, ",")
+	}
+	if c.EbayClientID != "" {
+		values["ebay-client-id"] = c.EbayClientID
+	}
+	if c.EbayClientSecret != "" {
+		values["ebay-client-secret"] = c.EbayClientSecret
+	}
+	if len(c.Amazon) > 0 {
+		values["amazon"] = strings.Join(c.Amazon, ",")
+	}
+	if c.AmazonAccessKey != "" {
+		values["amazon-access-key"] = c.AmazonAccessKey
+	}
+	if c.AmazonSecretKey != "" {
+		values["amazon-secret-key"] = c.AmazonSecretKey
+	}
+	if c.AmazonPartnerTag != "" {
+		values["amazon-partner-tag"] = c.AmazonPartnerTag
+	}
+	if c.Sort != "" {
+		values["sort"] = c.Sort
+	}
+	if c.SortBy != "" {
+		values["sort-by"] = c.SortBy
+	}
+	if c.PerSite > 0 {
+		values["per-site"] = strconv.Itoa(c.PerSite)
+	}
+	// una lista vacía (pero presente) es distinta de no indicar nada.
+	if c.Exclude != nil {
+		values["exclude"] = strings.Join(c.Exclude, ",")
+	}
+	if c.SiteTimeout > 0 {
+		values["site-timeout"] = c.SiteTimeout.String()
+	}
+	if c.MaxDuration > 0 {
+		values["max-duration"] = c.MaxDuration.String()
+	}
+	if c.Retries != nil {
+		values["retries"] = strconv.Itoa(*c.Retries)
+	}
+	if c.RetryDelay > 0 {
+		values["retry-delay"] = c.RetryDelay.String()
+	}
+	if c.Pages > 0 {
+		values["pages"] = strconv.Itoa(c.Pages)
+	}
+	if c.PageConcurrency > 0 {
+		values["page-concurrency"] = strconv.Itoa(c.PageConcurrency)
+	}
+	if c.SitesTTL != 0 {
+		values["sites-ttl"] = c.SitesTTL.String()
+	}
+	if c.BNATTL != 0 {
+		values["bna-ttl"] = c.BNATTL.String()
+	}
+	if c.UserAgent != "" {
+		values["user-agent"] = c.UserAgent
+	}
+	if len(c.Headers) > 0 {
+		headers := make([]string, 0, len(c.Headers))
+		for name, value := range c.Headers {
+			headers = append(headers, name+"="+value)
+		}
+		sort.Strings(headers)
+		values["headers"] = strings.Join(headers, ",")
+	}
+	if c.Proxy != "" {
+		values["proxy"] = c.Proxy
+	}
+	if c.MaxResponseSize > 0 {
+		values["max-response-size"] = strconv.FormatInt(c.MaxResponseSize, 10)
+	}
+	if c.Output != "" {
+		values["output"] = c.Output
+	}
+	if c.Decimals != nil {
+		values["decimals"] = strconv.Itoa(*c.Decimals)
+	}
+	if c.Rounding != "" {
+		values["rounding"] = c.Rounding
+	}
+	if c.RatesSource != "" {
+		values["rates-source"] = c.RatesSource
+	}
+	if c.RatesFallback != nil {
+		values["rates-fallback"] = strings.Join(c.RatesFallback, ",")
+	}
+	if c.BNATable != "" {
+		values["bna-table"] = c.BNATable
+	}
+	if c.PAISTax != "" {
+		values["pais-tax"] = c.PAISTax
+	}
+	if c.PerceptionTax != "" {
+		values["perception-tax"] = c.PerceptionTax
+	}
+	if c.CostsFile != "" {
+		values["costs-file"] = c.CostsFile
+	}
+	if c.Home != "" {
+		values["home"] = c.Home
+	}
+	if c.WagesFile != "" {
+		values["wages-file"] = c.WagesFile
+	}
+	if c.SheetsID != "" {
+		values["sheets-id"] = c.SheetsID
+	}
+	if c.SheetsCredentials != "" {
+		values["sheets-credentials"] = c.SheetsCredentials
+	}
+	if c.SheetsRange != "" {
+		values["sheets-range"] = c.SheetsRange
+	}
+	if c.WebhookURL != "" {
+		values["webhook-url"] = c.WebhookURL
+	}
+	if c.WebhookSecret != "" {
+		values["webhook-secret"] = c.WebhookSecret
+	}
+	if c.WebhookRetries != nil {
+		values["webhook-retries"] = strconv.Itoa(*c.WebhookRetries)
+	}
+	if c.HistoryFile != "" {
+		values["history-file"] = c.HistoryFile
+	}
+	if c.Interval > 0 {
+		values["interval"] = c.Interval.String()
+	}
+	if c.Addr != "" {
+		values["addr"] = c.Addr
+	}
+	if c.GRPCAddr != "" {
+		values["grpc-addr"] = c.GRPCAddr
+	}
+	if c.ResultTTL != nil {
+		values["result-ttl"] = c.ResultTTL.String()
+	}
+	if c.RateTTL != nil {
+		values["rate-ttl"] = c.RateTTL.String()
+	}
+	if c.DrainTimeout != 0 {
+		values["drain-timeout"] = c.DrainTimeout.String()
+	}
+	if len(c.CORSOrigins) > 0 {
+		values["cors-origins"] = strings.Join(c.CORSOrigins, ",")
+	}
+	if len(c.CORSMethods) > 0 {
+		values["cors-methods"] = strings.Join(c.CORSMethods, ",")
+	}
+	if len(c.CORSHeaders) > 0 {
+		values["cors-headers"] = strings.Join(c.CORSHeaders, ",")
+	}
+	if c.RateLimit > 0 {
+		values["rate-limit"] = strconv.FormatFloat(c.RateLimit, 'f', -1, 64)
+	}
+	if c.RateBurst > 0 {
+		values["rate-burst"] = strconv.Itoa(c.RateBurst)
+	}
+	if len(c.APIKeys) > 0 {
+		keys := make([]string, 0, len(c.APIKeys))
+		for label, key := range c.APIKeys {
+			keys = append(keys, label+"="+key)
+		}
+		sort.Strings(keys)
+		values["api-keys"] = strings.Join(keys, ",")
+	}
+	return values
+}
+
+// Apply asigna los valores de la configuración a los flags de fs que no fueron indicados
+// explícitamente en la linea de comandos, debe llamarse luego de fs.Parse.
+func (c *Config) Apply(fs *flag.FlagSet) error {
+	explicit := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+	for name, value := range c.flagValues() {
+		if explicit[name] || fs.Lookup(name) == nil {
+			continue
+		}
+		if err := fs.Set(name, value); err != nil {
+			return fmt.Errorf("applying config value %q to -%s: %v", value, name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,16 @@
+package config
+
+import "testing"
+
+func TestValidateOutput(t *testing.T) {
+	for _, output := range []string{"", "text", "json", "ndjson", "stream"} {
+		c := &Config{Output: output}
+		if err := c.Validate(); err != nil {
+			t.Errorf("Validate() with output %q = %v, want nil", output, err)
+		}
+	}
+	c := &Config{Output: "xml"}
+	if err := c.Validate(); err == nil {
+		t.Error("Validate() with output \"xml\" = nil, want error")
+	}
+}
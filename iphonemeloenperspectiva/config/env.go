@@ -0,0 +1,48 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// EnvPrefix es el prefijo de las variables de entorno que configuran la aplicación.
+const EnvPrefix = "MELO_"
+
+// EnvName devuelve el nombre de la variable de entorno que corresponde a un flag, por ejemplo
+// MELO_SITE_TIMEOUT para -site-timeout.
+func EnvName(flagName string) string {
+	return EnvPrefix + strings.ToUpper(strings.Replace(flagName, "-", "_", -1))
+}
+
+// ApplyEnv asigna a cada flag de fs que no fue indicado explícitamente el valor de su
+// variable de entorno, si existe. Debe llamarse luego de fs.Parse y antes de Config.Apply,
+// así el orden de prioridad queda: flags, entorno, archivo y por último valores por defecto.
+func ApplyEnv(fs *flag.FlagSet) error {
+	explicit := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		if err != nil || explicit[f.Name] {
+			return
+		}
+		value, ok := os.LookupEnv(EnvName(f.Name))
+		if !ok {
+			return
+		}
+		if setErr := fs.Set(f.Name, value); setErr != nil {
+			err = fmt.Errorf("applying %s=%q: %v", EnvName(f.Name), value, setErr)
+		}
+	})
+	return err
+}
+
+// QueryFromEnv devuelve el criterio de búsqueda de MELO_QUERY si está definido.
+func QueryFromEnv() (string, bool) {
+	query, ok := os.LookupEnv(EnvName("query"))
+	return query, ok && query != ""
+}
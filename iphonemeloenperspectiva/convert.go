@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// convertedPrice es el precio de una publicación convertido a otra moneda, por ejemplo la de
+// quien lee la comparación o una criptomoneda.
+type convertedPrice struct {
+	Currency string          `json:"currency"`
+	Amount   decimal.Decimal `json:"amount"`
+}
+
+// convertPrice convierte priceUSD a cada moneda de currencies que tiene cotización en quotes,
+// en dólares por unidad, en el orden de currencies.
+func convertPrice(priceUSD decimal.Decimal, currencies []string, quotes map[string]decimal.Decimal) []convertedPrice {
+	var prices []convertedPrice
+	for _, currency := range currencies {
+		quote, ok := quotes[currency]
+		if !ok || !quote.IsPositive() {
+			continue
+		}
+		prices = append(prices, convertedPrice{Currency: currency, Amount: priceUSD.Div(quote)})
+	}
+	return prices
+}
+
+// formatConverted describe los precios convertidos, por ejemplo "BTC 0.00290000, USDT 199.05".
+func formatConverted(prices []convertedPrice) string {
+	parts := make([]string, 0, len(prices))
+	for _, p := range prices {
+		parts = append(parts, NewMoney(p.Amount, p.Currency).String())
+	}
+	return strings.Join(parts, ", ")
+}
+
+// parseCurrencies lee la lista de -also-in, códigos de moneda separados por coma, y los
+// devuelve en mayúsculas y sin repetir.
+func parseCurrencies(list string) ([]string, error) {
+	currencies := []string{}
+	seen := map[string]bool{}
+	for _, currency := range parseKeywords(list) {
+		currency = strings.ToUpper(currency)
+		if len(currency) != 3 || strings.Trim(currency, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") != "" {
+			return nil, fmt.Errorf("invalid -also-in currency %q, must be a three letter code like EUR", currency)
+		}
+		if !seen[currency] {
+			seen[currency] = true
+			currencies = append(currencies, currency)
+		}
+	}
+	return currencies, nil
+}
+
+// applyAlsoIn completa el precio de cada resultado del reporte en cada moneda de currencies
+// que tiene cotización en quotes, en el orden de currencies.
+func applyAlsoIn(report *runReport, currencies []string, quotes map[string]decimal.Decimal) {
+	for i, r := range report.Results {
+		report.Results[i].AlsoIn = convertPrice(r.PriceUSD, currencies, quotes)
+	}
+}
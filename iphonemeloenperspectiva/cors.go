@@ -0,0 +1,83 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// corsPolicy indica desde que orígenes un navegador puede llamar a la API, por defecto
+// ninguno salvo el del propio servidor (el tablero).
+type corsPolicy struct {
+	// origins son los orígenes permitidos, por ejemplo https://tablero.example.com, "*" los
+	// permite a todos.
+	origins []string
+	methods string
+	headers string
+}
+
+// newCORSPolicy devuelve la política para los orígenes, métodos y encabezados indicados como
+// listas separadas por coma. Sin orígenes devuelve nil, que no permite ninguno.
+func newCORSPolicy(origins, methods, headers string) *corsPolicy {
+	allowed := parseKeywords(origins)
+	if len(allowed) == 0 {
+		return nil
+	}
+	return &corsPolicy{
+		origins: allowed,
+		methods: strings.Join(parseKeywords(methods), ", "),
+		headers: strings.Join(parseKeywords(headers), ", "),
+	}
+}
+
+// allowed indica si la política permite el origen, una política nil no permite ninguno.
+func (c *corsPolicy) allowed(origin string) bool {
+	if c == nil || origin == "" {
+		return false
+	}
+	for _, allowed := range c.origins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// handler agrega los encabezados CORS a las respuestas a orígenes permitidos y responde los
+// pedidos preflight sin llegar a la API, que además no exigen clave porque el navegador no
+// la envía en ellos. Una política nil no modifica nada.
+func (c *corsPolicy) handler(next http.Handler) http.Handler {
+	if c == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		// la respuesta depende del origen, los caches no deben mezclarlas.
+		w.Header().Add("Vary", "Origin")
+		if !c.allowed(origin) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			w.Header().Set("Access-Control-Allow-Methods", c.methods)
+			w.Header().Set("Access-Control-Allow-Headers", c.headers)
+			w.Header().Set("Access-Control-Max-Age", "600")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// checkWebSocketOrigin permite conexiones WebSocket desde el mismo origen que el servidor y
+// desde los orígenes que permite la política CORS, los navegadores no aplican CORS a los
+// WebSocket así que lo tiene que verificar el servidor.
+func (c *corsPolicy) checkWebSocketOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" || c.allowed(origin) {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, r.Host)
+}
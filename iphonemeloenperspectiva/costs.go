@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/shopspring/decimal"
+	"gopkg.in/yaml.v3"
+)
+
+// countryCosts son los costos de comprar en un país o de traer una compra a él, todos los
+// campos son opcionales.
+type countryCosts struct {
+	// DutyPercent es el arancel de importación que cobra el país sobre lo que entra.
+	DutyPercent decimal.Decimal `yaml:"duty_percent"`
+	// VATPercent es el impuesto al valor agregado que cobra el país sobre lo que entra,
+	// se calcula sobre el precio con envío y arancel.
+	VATPercent decimal.Decimal `yaml:"vat_percent"`
+	// DutyFreeUSD es la franquicia del país, las compras de hasta este valor no pagan arancel
+	// ni IVA.
+	DutyFreeUSD decimal.Decimal `yaml:"duty_free_usd"`
+	// CourierUSD es lo que cuesta enviar una compra desde el país.
+	CourierUSD decimal.Decimal `yaml:"courier_usd"`
+}
+
+// costModel estima el costo real de comprar en un país y traer la compra al país del usuario,
+// se carga de un archivo YAML como este:
+//
+//	home: MLA
+//	countries:
+//	  MLA:
+//	    duty_percent: 50
+//	    vat_percent: 21
+//	    duty_free_usd: 0
+//	  MLC:
+//	    courier_usd: 45
+type costModel struct {
+	// Home es el ID del sitio del país del usuario, adonde se trae la compra.
+	Home string `yaml:"home"`
+	// Countries contiene los costos de cada país por ID de sitio.
+	Countries map[string]countryCosts `yaml:"countries"`
+}
+
+// loadCostModel lee el modelo de costos de path.
+func loadCostModel(path string) (*costModel, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading costs file: %v", err)
+	}
+	model := &costModel{}
+	if err := yaml.Unmarshal(data, model); err != nil {
+		return nil, fmt.Errorf("parsing costs file %s: %v", path, err)
+	}
+	for id, c := range model.Countries {
+		if c.DutyPercent.IsNegative() || c.VATPercent.IsNegative() || c.DutyFreeUSD.IsNegative() || c.CourierUSD.IsNegative() {
+			return nil, fmt.Errorf("invalid costs file %s: costs for %s cannot be negative", path, id)
+		}
+	}
+	return model, nil
+}
+
+// landedCost devuelve lo que cuesta en USD comprar a priceUSD en el sitio origin y traerlo al
+// país del usuario: el envío desde origin y, si se supera la franquicia, el arancel y el IVA
+// del país del usuario. Comprar en el propio país no tiene costos extra.
+func (m *costModel) landedCost(priceUSD decimal.Decimal, origin string) decimal.Decimal {
+	if origin == m.Home {
+		return priceUSD
+	}
+	hundred := decimal.New(100, 0)
+	cost := priceUSD.Add(m.Countries[origin].CourierUSD)
+	home := m.Countries[m.Home]
+	if cost.LessThanOrEqual(home.DutyFreeUSD) {
+		return cost
+	}
+	cost = cost.Add(cost.Mul(home.DutyPercent).Div(hundred))
+	return cost.Add(cost.Mul(home.VATPercent).Div(hundred))
+}
+
+// applyCosts completa el costo puesto en el país del usuario de cada resultado del reporte,
+// incluido el envío si ya se estimó con applyShipping.
+func applyCosts(report *runReport, model *costModel) {
+	report.Home = model.Home
+	for i := range report.Results {
+		landed := model.landedCost(report.Results[i].withShippingUSD(), report.Results[i].SiteID)
+		report.Results[i].LandedUSD = &landed
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/perrito666/tutoriales_go/internal/rates"
+	"github.com/shopspring/decimal"
+)
+
+// parseCryptoCurrencies lee la lista de -crypto, criptomonedas separadas por coma, y las
+// devuelve en mayúsculas y sin repetir.
+func parseCryptoCurrencies(list string) ([]string, error) {
+	currencies := []string{}
+	seen := map[string]bool{}
+	for _, currency := range parseKeywords(list) {
+		currency = strings.ToUpper(currency)
+		supported := false
+		for _, c := range rates.CryptoCurrencies {
+			supported = supported || c == currency
+		}
+		if !supported {
+			return nil, fmt.Errorf("unknown -crypto currency %q, must be one of %s", currency, strings.Join(rates.CryptoCurrencies, ", "))
+		}
+		if !seen[currency] {
+			seen[currency] = true
+			currencies = append(currencies, currency)
+		}
+	}
+	return currencies, nil
+}
+
+// fetchCryptoQuotes pide a la vez la cotización en USD de cada criptomoneda de currencies, las
+// que fallan se informan en el log y no se incluyen.
+func fetchCryptoQuotes(ctx context.Context, currencies []string) map[string]decimal.Decimal {
+	source := &rates.CoinGecko{Do: doRates}
+	quotes := map[string]decimal.Decimal{}
+	mu := &sync.Mutex{}
+	wg := &sync.WaitGroup{}
+	wg.Add(len(currencies))
+	for _, currency := range currencies {
+		go func(currency string) {
+			defer wg.Done()
+			quote, err := source.ToUSD(ctx, currency)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				log.Printf("search: crypto %s: %v", currency, err)
+				return
+			}
+			quotes[currency] = quote
+		}(currency)
+	}
+	wg.Wait()
+	return quotes
+}
+
+// applyCrypto completa el precio de cada resultado del reporte en cada criptomoneda de
+// currencies que tiene cotización en quotes, en el orden de currencies.
+func applyCrypto(report *runReport, currencies []string, quotes map[string]decimal.Decimal) {
+	for i, r := range report.Results {
+		report.Results[i].Crypto = convertPrice(r.PriceUSD, currencies, quotes)
+	}
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"time"
+)
+
+// addMaxDurationFlag registra en fs el flag -max-duration de los subcomandos que terminan
+// luego de comparar, no tiene sentido en watch ni en serve.
+func addMaxDurationFlag(fs *flag.FlagSet) *time.Duration {
+	return fs.Duration("max-duration", 0,
+		"plazo para toda la ejecución, al vencer se cancelan los sitios que faltan y se muestra lo que haya llegado, por ejemplo 30s (0 sin plazo)")
+}
+
+// runDeadline devuelve el momento en que vence una ejecución que empieza ahora y puede durar
+// max, el instante cero si max es 0.
+func runDeadline(max time.Duration) (time.Time, error) {
+	if max < 0 {
+		return time.Time{}, fmt.Errorf("-max-duration cannot be negative, got %s", max)
+	}
+	if max == 0 {
+		return time.Time{}, nil
+	}
+	return time.Now().Add(max), nil
+}
+
+// withRunDeadline devuelve un contexto que vence en deadline, o que solo se cancela con cancel
+// si deadline es cero. Cada sitio deriva de él el plazo de -site-timeout, así cuando vence
+// la ejecución los sitios que faltan se cancelan y se reportan como vencidos.
+func withRunDeadline(ctx context.Context, deadline time.Time) (context.Context, context.CancelFunc) {
+	if deadline.IsZero() {
+		return context.WithCancel(ctx)
+	}
+	return context.WithDeadline(ctx, deadline)
+}
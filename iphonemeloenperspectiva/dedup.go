@@ -0,0 +1,81 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+)
+
+// titleReplacer quita los acentos mas comunes para que "Año" y "Ano" se consideren iguales.
+var titleReplacer = strings.NewReplacer(
+	"á", "a", "é", "e", "í", "i", "ó", "o", "ú", "u", "ü", "u", "ñ", "n", "ã", "a", "õ", "o", "ç", "c",
+)
+
+// normalizeTitle devuelve una versión del título apta para comparar publicaciones, en minúsculas
+// sin acentos, sin signos de puntuación y con los espacios colapsados.
+func normalizeTitle(title string) string {
+	title = titleReplacer.Replace(strings.ToLower(title))
+	return strings.Join(strings.FieldsFunc(title, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	}), " ")
+}
+
+// dedupKeys devuelve las claves con las que consideramos que dos resultados son la misma
+// publicación: el mismo ID de item o el mismo vendedor publicando el mismo título.
+func dedupKeys(r siteSearchResult) []string {
+	keys := []string{}
+	if r.itemID != "" {
+		keys = append(keys, "id|"+r.itemID)
+	}
+	// sin vendedor conocido no podemos afirmar que dos títulos iguales sean la misma publicación.
+	if r.sellerID != "" && r.sellerID != "0" {
+		keys = append(keys, "seller|"+r.sellerID+"|"+normalizeTitle(r.item))
+	}
+	return keys
+}
+
+// dedupResults colapsa los resultados que son la misma publicación en varios sitios, ya sea
+// porque comparten el ID de item o porque el mismo vendedor la publicó con el mismo título.
+// Se conserva el primer resultado de cada grupo y en alsoOn se anotan los demás sitios. Solo
+// se colapsan resultados de sitios distintos: con -per-site o -group-by un vendedor puede
+// tener varias publicaciones iguales en un sitio, por ejemplo en distintos colores, y todas
+// cuentan.
+func dedupResults(results []siteSearchResult) []siteSearchResult {
+	deduped := make([]siteSearchResult, 0, len(results))
+	// seen indica, para cada clave, las posiciones en deduped de los resultados que
+	// conservamos, puede haber varios del mismo sitio.
+	seen := map[string][]int{}
+	// listed indica las publicaciones que ya vimos en cada sitio, las páginas que se piden a
+	// la vez pueden repetir alguna.
+	listed := map[string]bool{}
+	for _, r := range results {
+		if r.itemID != "" {
+			if listed[r.site.ID+"|"+r.itemID] {
+				continue
+			}
+			listed[r.site.ID+"|"+r.itemID] = true
+		}
+		keys := dedupKeys(r)
+		if kept, ok := keptFromOtherSite(deduped, seen, keys, r.site.ID); ok {
+			deduped[kept].alsoOn = append(deduped[kept].alsoOn, r.site)
+			continue
+		}
+		for _, key := range keys {
+			seen[key] = append(seen[key], len(deduped))
+		}
+		deduped = append(deduped, r)
+	}
+	return deduped
+}
+
+// keptFromOtherSite devuelve la posición en deduped del primer resultado conservado que
+// comparte alguna de keys, no es del sitio siteID y no colapsó ya otro resultado de ese sitio.
+func keptFromOtherSite(deduped []siteSearchResult, seen map[string][]int, keys []string, siteID string) (int, bool) {
+	for _, key := range keys {
+		for _, i := range seen[key] {
+			if deduped[i].site.ID != siteID && !hasSite(deduped[i].alsoOn, siteID) {
+				return i, true
+			}
+		}
+	}
+	return 0, false
+}
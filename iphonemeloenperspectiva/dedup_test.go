@@ -0,0 +1,21 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestDedupResultsRepeatedListing(t *testing.T) {
+	mla := mlSite{ID: "MLA", Name: "Argentina"}
+	mlb := mlSite{ID: "MLB", Name: "Brasil"}
+	// las páginas de MLA se superpusieron y devolvieron dos veces la misma publicación.
+	results := dedupResults([]siteSearchResult{
+		{site: mla, itemID: "MLA10", sellerID: "42", item: "Apple iPhone 11"},
+		{site: mla, itemID: "MLA10", sellerID: "42", item: "Apple iPhone 11"},
+		{site: mlb, itemID: "MLB10", sellerID: "42", item: "Apple iPhone 11"},
+	})
+	want := map[string][]string{"MLA10": {"MLB"}}
+	if got := itemIDs(results); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("dedupResults = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+const (
+	// mlDescriptionURL es el endpoint de la descripción de una publicación.
+	mlDescriptionURL = "https://api.mercadolibre.com/items/%s/description"
+	// descriptionLength es la cantidad máxima de caracteres de la descripción que se muestran,
+	// la de ML puede ser de varias páginas.
+	descriptionLength = 400
+)
+
+// descriptionBlocks son los elementos HTML que terminan una linea en el texto de una
+// descripción.
+const descriptionBlocks = "p, div, li, tr, h1, h2, h3, h4, h5, h6, blockquote, pre, table, ul, ol"
+
+// mlDescription imita la estructura JSON de la descripción de una publicación, ML la devuelve
+// como texto, como HTML o como ambos.
+type mlDescription struct {
+	Text      string `json:"text"`
+	PlainText string `json:"plain_text"`
+}
+
+// htmlToText convierte el HTML de una descripción en texto: cada párrafo o elemento de una
+// lista en su linea, sin etiquetas, scripts ni espacios de mas.
+func htmlToText(description string) (string, error) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(description))
+	if err != nil {
+		return "", fmt.Errorf("parsing description html: %v", err)
+	}
+	doc.Find("script, style").Remove()
+	doc.Find("br").ReplaceWithHtml("\n")
+	doc.Find(descriptionBlocks).Each(func(_ int, s *goquery.Selection) {
+		s.AppendHtml("\n")
+	})
+	return cleanText(doc.Text()), nil
+}
+
+// cleanText junta los espacios de cada linea de text y descarta las lineas vacías.
+func cleanText(text string) string {
+	lines := []string{}
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.Join(strings.Fields(line), " "); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// trimText recorta text a length caracteres, terminando en una palabra completa y con "..."
+// si se recortó.
+func trimText(text string, length int) string {
+	runes := []rune(text)
+	if len(runes) <= length {
+		return text
+	}
+	trimmed := string(runes[:length])
+	if space := strings.LastIndexAny(trimmed, " \n"); space > 0 {
+		trimmed = trimmed[:space]
+	}
+	return trimmed + "..."
+}
+
+// fetchDescription devuelve la descripción de itemID en texto, recortada a descriptionLength.
+func fetchDescription(ctx context.Context, itemID string) (string, error) {
+	descriptionURL := fmt.Sprintf(mlDescriptionURL, url.PathEscape(itemID))
+	description := mlDescription{}
+	if err := getML(ctx, descriptionURL, &description, &mlDescriptionSchema{}); err != nil {
+		return "", fmt.Errorf("fetching description: %w", err)
+	}
+	text := cleanText(description.PlainText)
+	if text == "" && description.Text != "" {
+		var err error
+		if text, err = htmlToText(description.Text); err != nil {
+			return "", err
+		}
+	}
+	return trimText(text, descriptionLength), nil
+}
+
+// applyDescriptions completa la descripción de cada resultado de Mercado Libre del reporte,
+// cada pedido tiene timeout de plazo. Una descripción que no se pudo obtener se informa en el
+// log y el resultado queda sin descripción.
+func applyDescriptions(ctx context.Context, report *runReport, timeout time.Duration) {
+	forEachMLResult(ctx, report, timeout, func(ctx context.Context, r *reportResult) {
+		description, err := fetchDescription(ctx, r.ItemID)
+		if err != nil {
+			log.Printf("search: description of %s: %v", r.ItemID, err)
+			return
+		}
+		r.Description = description
+	})
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/perrito666/tutoriales_go/internal/i18n"
+	"github.com/shopspring/decimal"
+)
+
+// siteChange es como cambió el precio de un sitio entre dos comparaciones, se compara el
+// precio mas barato en USD de cada sitio.
+type siteChange struct {
+	SiteID   string `json:"site_id"`
+	SiteName string `json:"site_name"`
+	// Previous y Current son nil si el sitio no tenía datos en la comparación respectiva.
+	Previous *decimal.Decimal `json:"previous,omitempty"`
+	Current  *decimal.Decimal `json:"current,omitempty"`
+	// Change y ChangePercent solo están si el sitio tiene datos en ambas comparaciones.
+	Change        *decimal.Decimal `json:"change,omitempty"`
+	ChangePercent *decimal.Decimal `json:"change_percent,omitempty"`
+	// NewCheapest indica que el sitio es el mas barato y antes no lo era.
+	NewCheapest bool `json:"new_cheapest,omitempty"`
+}
+
+// reportDiff son los cambios de un reporte respecto de la comparación anterior del historial.
+type reportDiff struct {
+	// Since es el momento de la comparación anterior.
+	Since   time.Time    `json:"since"`
+	Changes []siteChange `json:"changes"`
+}
+
+// cheapestBySite devuelve el precio mas barato en USD de cada sitio del reporte y los IDs de
+// sitio en el orden en que aparecen.
+func cheapestBySite(report *runReport) (map[string]reportResult, []string) {
+	cheapest := map[string]reportResult{}
+	order := []string{}
+	for _, r := range report.Results {
+		current, ok := cheapest[r.SiteID]
+		if !ok {
+			order = append(order, r.SiteID)
+		}
+		if !ok || r.PriceUSD.LessThan(current.PriceUSD) {
+			cheapest[r.SiteID] = r
+		}
+	}
+	return cheapest, order
+}
+
+// diffReports compara report con previous sitio por sitio.
+func diffReports(previous, report *runReport) *reportDiff {
+	before, _ := cheapestBySite(previous)
+	now, order := cheapestBySite(report)
+	// los sitios que ya no tienen datos van al final.
+	for _, r := range previous.Results {
+		if _, ok := now[r.SiteID]; !ok && !containsString(order, r.SiteID) {
+			order = append(order, r.SiteID)
+		}
+	}
+
+	previousMin, _, hadResults := priceRange(previous)
+	currentMin, _, hasResults := priceRange(report)
+
+	diff := &reportDiff{Since: previous.Time}
+	for _, id := range order {
+		change := siteChange{SiteID: id}
+		if r, ok := before[id]; ok {
+			change.SiteName = r.SiteName
+			price := r.PriceUSD
+			change.Previous = &price
+		}
+		if r, ok := now[id]; ok {
+			change.SiteName = r.SiteName
+			price := r.PriceUSD
+			change.Current = &price
+		}
+		if change.Previous != nil && change.Current != nil {
+			delta := change.Current.Sub(*change.Previous)
+			percent := percentChange(*change.Previous, *change.Current)
+			change.Change, change.ChangePercent = &delta, &percent
+		}
+		change.NewCheapest = hasResults && currentMin.SiteID == id && (!hadResults || previousMin.SiteID != id)
+		diff.Changes = append(diff.Changes, change)
+	}
+	return diff
+}
+
+// containsString indica si values contiene value.
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// exceeding devuelve el primer cambio cuyo porcentaje, en valor absoluto, supera threshold.
+func (d *reportDiff) exceeding(threshold decimal.Decimal) (siteChange, bool) {
+	for _, change := range d.Changes {
+		if change.ChangePercent != nil && change.ChangePercent.Abs().GreaterThan(threshold) {
+			return change, true
+		}
+	}
+	return siteChange{}, false
+}
+
+// writeTextDiff escribe los cambios de manera legible.
+func writeTextDiff(w io.Writer, diff *reportDiff) {
+	fmt.Fprintf(w, i18n.T("\nCambios desde %s:\n"), diff.Since.Local().Format("2006-01-02 15:04"))
+	for _, change := range diff.Changes {
+		writeTextChange(w, change)
+	}
+}
+
+// writeTextChange escribe el cambio de precio de un sitio en una linea.
+func writeTextChange(w io.Writer, change siteChange) {
+	usd := func(d *decimal.Decimal) string {
+		if d == nil {
+			return i18n.T("sin datos")
+		}
+		return NewMoney(*d, usdCurrencyCode).String()
+	}
+	fmt.Fprintf(w, "%s: %s -> %s", change.SiteName, usd(change.Previous), usd(change.Current))
+	if change.Change != nil {
+		fmt.Fprintf(w, " (%s, %s%%)", formatAmount(*change.Change, usdCurrencyCode), formatNumber(*change.ChangePercent))
+	}
+	if change.NewCheapest {
+		fmt.Fprint(w, i18n.T(" <-- nuevo mas barato"))
+	}
+	fmt.Fprintln(w)
+}
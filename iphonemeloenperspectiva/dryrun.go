@@ -0,0 +1,125 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+
+	"github.com/perrito666/tutoriales_go/internal/i18n"
+	"github.com/perrito666/tutoriales_go/internal/rates"
+)
+
+// errDryRun es el error de cualquier pedido que se intente hacer con -dry-run, que no debe
+// tocar la red.
+var errDryRun = errors.New("not performing requests in a dry run")
+
+// dryRunTransport rechaza todos los pedidos, es una garantía de que -dry-run no sale a la red
+// aunque algún camino del código se olvide de chequearlo.
+type dryRunTransport struct{}
+
+func (dryRunTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	return nil, errDryRun
+}
+
+// requestPlanner lo implementan los Provider que saben indicar que pedidos harían para
+// buscar un criterio, sin hacerlos.
+type requestPlanner interface {
+	plannedRequests(query string) ([]string, error)
+}
+
+// plannedRequests devuelve las URLs que pediría Search. Con -best-sellers los pedidos que
+// siguen al descubrimiento de la categoría dependen de su respuesta, así que solo se indica
+// ese.
+func (p mlProvider) plannedRequests(query string) ([]string, error) {
+	if p.opts.bestSellers {
+		if isCategoryID(query, p.site) {
+			return []string{fmt.Sprintf(mlHighlightsURL, p.site.ID, query)}, nil
+		}
+		discoveryURL, err := domainDiscoveryURL(query, p.site)
+		if err != nil {
+			return nil, err
+		}
+		return []string{discoveryURL}, nil
+	}
+	// el pedido del producto depende de la respuesta de la búsqueda en el catálogo.
+	if p.opts.catalog {
+		productsURL, err := productsSearchURL(query, p.site)
+		if err != nil {
+			return nil, err
+		}
+		return []string{productsURL}, nil
+	}
+	// sin paginación se hace un único pedido, igual que en searchPages.
+	if p.opts.pages <= 1 {
+		pageURL, err := searchURL(query, p.site, p.opts.sort, p.opts.filters, 0, 0)
+		if err != nil {
+			return nil, err
+		}
+		return []string{pageURL}, nil
+	}
+	urls := make([]string, 0, p.opts.pages)
+	for i := 0; i < p.opts.pages; i++ {
+		pageURL, err := searchURL(query, p.site, p.opts.sort, p.opts.filters, pageSize, i*pageSize)
+		if err != nil {
+			return nil, err
+		}
+		urls = append(urls, pageURL)
+	}
+	return urls, nil
+}
+
+// writeDryRun escribe los pedidos que haría la comparación de cada criterio entre los
+// sitios: la lista de sitios si hay que pedirla, la búsqueda en cada sitio y la cotización
+// de cada moneda, que se pide una sola vez.
+func writeDryRun(w io.Writer, terms []string, sites []mlSite, sitesRequested bool) error {
+	if sitesRequested {
+		fmt.Fprintf(w, "GET %s\n", mlSiteFetchEndpoint)
+	}
+	currencies := map[string]bool{}
+	for _, searchTerms := range terms {
+		fmt.Fprintf(w, "# %s\n", searchTerms)
+		for _, site := range sites {
+			currencies[site.DefaultCurrencyID] = true
+			planner, ok := site.provider.(requestPlanner)
+			if !ok {
+				fmt.Fprintf(w, i18n.T("# %s (%s): este proveedor no puede mostrar sus pedidos\n"), site.Name, site.ID)
+				continue
+			}
+			urls, err := planner.plannedRequests(searchTerms)
+			if err != nil {
+				return fmt.Errorf("planning requests for %s: %v", site.ID, err)
+			}
+			for _, u := range urls {
+				fmt.Fprintf(w, "GET %s\n", u)
+			}
+		}
+	}
+	sorted := make([]string, 0, len(currencies))
+	for currency := range currencies {
+		sorted = append(sorted, currency)
+	}
+	sort.Strings(sorted)
+	// varias monedas pueden salir del mismo pedido, como la página del Banco Nación.
+	seen := map[string]bool{}
+	for _, currency := range sorted {
+		rateURL, err := rateSource.URL(currency)
+		// una moneda que la fuente no cotiza no impide mostrar el resto, la búsqueda fallará
+		// solo en esos sitios.
+		if errors.Is(err, rates.ErrUnsupportedCurrency) {
+			fmt.Fprintf(w, "# %v\n", err)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		// la fuente no necesita pedir nada para cotizar esta moneda.
+		if rateURL == "" || seen[rateURL] {
+			continue
+		}
+		seen[rateURL] = true
+		fmt.Fprintf(w, "GET %s\n", rateURL)
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/perrito666/tutoriales_go/internal/bodylimit"
+)
+
+// mlAPIHost es el host de la API de Mercado Libre.
+const mlAPIHost = "api.mercadolibre.com"
+
+// dumpTransport guarda en dir el cuerpo de cada respuesta que pasa por base, comprimido con
+// gzip, para poder adjuntarlo al reportar un resultado inesperado.
+type dumpTransport struct {
+	dir  string
+	base http.RoundTripper
+	// seq numera los archivos, dos respuestas del mismo endpoint pueden llegar a la vez.
+	seq int64
+}
+
+// newDumpTransport devuelve un RoundTripper que guarda en dir las respuestas de base, crea dir
+// si no existe.
+func newDumpTransport(dir string, base http.RoundTripper) (*dumpTransport, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("creating dump dir: %v", err)
+	}
+	return &dumpTransport{dir: dir, base: base}, nil
+}
+
+func (t *dumpTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	response, err := t.base.RoundTrip(request)
+	if err != nil {
+		return nil, err
+	}
+	// leemos la respuesta hasta el límite para guardarla, si lo supera quien la lea igual
+	// recibe el resto y el *ResponseTooLargeError de siempre.
+	body, err := io.ReadAll(io.LimitReader(response.Body, bodylimit.MaxSize+1))
+	if err != nil {
+		response.Body.Close()
+		return nil, fmt.Errorf("reading response from %s: %v", request.URL, err)
+	}
+	// un error al guardar no debe impedir la comparación, es solo para depurar.
+	if err := t.dump(request, body); err != nil {
+		logf(request.Context(), "could not dump response from %s: %v", request.URL.Redacted(), err)
+	}
+	response.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(body), response.Body), response.Body}
+	return response, nil
+}
+
+// dump guarda body en un archivo de t.dir.
+func (t *dumpTransport) dump(request *http.Request, body []byte) error {
+	site, endpoint := dumpNameParts(request)
+	name := fmt.Sprintf("%s_%s_%s_%04d.gz", site, endpoint,
+		time.Now().UTC().Format("20060102T150405.000Z"), atomic.AddInt64(&t.seq, 1))
+	f, err := os.OpenFile(filepath.Join(t.dir, name), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	w := gzip.NewWriter(f)
+	// el comentario del encabezado de gzip guarda la URL del pedido.
+	w.Comment = request.URL.Redacted()
+	if _, err := w.Write(body); err != nil {
+		f.Close()
+		return err
+	}
+	if err := w.Close(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// dumpNameParts devuelve el sitio y el endpoint con los que se nombra el archivo de una
+// respuesta, por ejemplo MLA y search para /sites/MLA/search. Las respuestas que no son de un
+// sitio de Mercado Libre usan ml o el host como sitio.
+func dumpNameParts(request *http.Request) (string, string) {
+	parts := strings.Split(strings.Trim(request.URL.Path, "/"), "/")
+	site, endpoint := "ml", strings.Join(parts, "_")
+	switch {
+	case request.URL.Host != mlAPIHost:
+		site = request.URL.Host
+	case len(parts) >= 3 && parts[0] == "sites":
+		site, endpoint = parts[1], strings.Join(parts[2:], "_")
+	case len(parts) >= 2 && (parts[0] == "trends" || parts[0] == "highlights"):
+		site, endpoint = parts[1], parts[0]
+	}
+	return safeFileName(site), safeFileName(endpoint)
+}
+
+// safeFileName reemplaza lo que no conviene tener en el nombre de un archivo.
+func safeFileName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '.':
+			return r
+		}
+		return '-'
+	}, name)
+}
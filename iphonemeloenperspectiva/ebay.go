@@ -0,0 +1,236 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/perrito666/tutoriales_go/internal/bodylimit"
+	"github.com/perrito666/tutoriales_go/internal/number"
+)
+
+const (
+	// ebayTokenURL es donde se obtiene un token de aplicación de eBay.
+	ebayTokenURL = "https://api.ebay.com/identity/v1/oauth2/token"
+	// ebayTokenScope es el alcance que necesita el token para usar la Browse API.
+	ebayTokenScope = "https://api.ebay.com/oauth/api_scope"
+	// ebaySearchURL es la búsqueda de la Browse API de eBay.
+	ebaySearchURL = "https://api.ebay.com/buy/browse/v1/item_summary/search"
+	// ebayMarketplaceHeader es el encabezado que indica a eBay en que mercado buscar.
+	ebayMarketplaceHeader = "X-EBAY-C-MARKETPLACE-ID"
+	// ebayMaxLimit es la cantidad máxima de resultados que devuelve una búsqueda de eBay.
+	ebayMaxLimit = 200
+)
+
+// ebayMarketplaces contiene los mercados de eBay que se pueden comparar, como si fueran sitios
+// de Mercado Libre, con la moneda en la que publican.
+var ebayMarketplaces = map[string]mlSite{
+	"EBAY_US": {ID: "EBAY_US", Name: "eBay Estados Unidos", DefaultCurrencyID: "USD"},
+	"EBAY_CA": {ID: "EBAY_CA", Name: "eBay Canadá", DefaultCurrencyID: "CAD"},
+	"EBAY_GB": {ID: "EBAY_GB", Name: "eBay Reino Unido", DefaultCurrencyID: "GBP"},
+	"EBAY_DE": {ID: "EBAY_DE", Name: "eBay Alemania", DefaultCurrencyID: "EUR"},
+	"EBAY_ES": {ID: "EBAY_ES", Name: "eBay España", DefaultCurrencyID: "EUR"},
+	"EBAY_FR": {ID: "EBAY_FR", Name: "eBay Francia", DefaultCurrencyID: "EUR"},
+	"EBAY_IT": {ID: "EBAY_IT", Name: "eBay Italia", DefaultCurrencyID: "EUR"},
+	"EBAY_AU": {ID: "EBAY_AU", Name: "eBay Australia", DefaultCurrencyID: "AUD"},
+}
+
+// ebaySites devuelve los mercados de eBay con los IDs indicados.
+func ebaySites(ids []string) ([]mlSite, error) {
+	sites := make([]mlSite, 0, len(ids))
+	for _, id := range ids {
+		site, ok := ebayMarketplaces[strings.ToUpper(id)]
+		if !ok {
+			return nil, fmt.Errorf("unknown ebay marketplace %q", id)
+		}
+		sites = append(sites, site)
+	}
+	return sites, nil
+}
+
+// ebayClient busca en eBay con la Browse API, obtiene un token de aplicación con las
+// credenciales y lo reutiliza hasta que vence.
+type ebayClient struct {
+	clientID     string
+	clientSecret string
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// newEbayClient devuelve un ebayClient con las credenciales de la aplicación.
+func newEbayClient(clientID, clientSecret string) *ebayClient {
+	return &ebayClient{clientID: clientID, clientSecret: clientSecret}
+}
+
+// accessToken devuelve un token válido, pidiendo uno nuevo si no hay o está por vencer.
+func (c *ebayClient) accessToken(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.token != "" && time.Now().Before(c.expires) {
+		return c.token, nil
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "client_credentials")
+	form.Set("scope", ebayTokenScope)
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, ebayTokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("building ebay token request: %v", err)
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.SetBasicAuth(c.clientID, c.clientSecret)
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return "", fmt.Errorf("requesting ebay token: %v", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("requesting ebay token: %s", response.Status)
+	}
+	token := struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}{}
+	if err := json.NewDecoder(bodylimit.Body(response)).Decode(&token); err != nil {
+		return "", fmt.Errorf("decoding ebay token: %v", err)
+	}
+	c.token = token.AccessToken
+	// renovamos el token un minuto antes de que venza.
+	c.expires = time.Now().Add(time.Duration(token.ExpiresIn)*time.Second - time.Minute)
+	return c.token, nil
+}
+
+// ebaySearchResponse imita la estructura JSON de la búsqueda de la Browse API, solo lo que
+// nos interesa.
+type ebaySearchResponse struct {
+	ItemSummaries []struct {
+		ItemID string `json:"itemId"`
+		Title  string `json:"title"`
+		Price  struct {
+			// Value viene como texto, por ejemplo "1234.56", y se lee con number.API.
+			Value    string `json:"value"`
+			Currency string `json:"currency"`
+		} `json:"price"`
+		ItemWebURL string `json:"itemWebUrl"`
+	} `json:"itemSummaries"`
+}
+
+// ebaySort devuelve el orden de eBay que corresponde a uno de nuestros órdenes.
+func ebaySort(sort string) string {
+	switch sort {
+	case sortPriceAsc:
+		return "price"
+	case sortPriceDesc:
+		return "-price"
+	}
+	// sin orden eBay ordena por relevancia.
+	return ""
+}
+
+// ebayProvider busca en un mercado de eBay.
+type ebayProvider struct {
+	client *ebayClient
+	site   mlSite
+	opts   searchOptions
+}
+
+// ebayProviderSites devuelve los mercados de eBay indicados con -ebay.
+func ebayProviderSites(opts searchOptions) ([]mlSite, error) {
+	sites := make([]mlSite, 0, len(opts.ebaySites))
+	for _, site := range opts.ebaySites {
+		site.provider = ebayProvider{client: opts.ebay, site: site, opts: opts}
+		sites = append(sites, site)
+	}
+	return sites, nil
+}
+
+// Name devuelve el nombre del mercado.
+func (p ebayProvider) Name() string {
+	return p.site.Name
+}
+
+// Search busca el criterio en el mercado de eBay, eBay no indica un vendedor numérico así que
+// las publicaciones no se agrupan por vendedor.
+func (p ebayProvider) Search(ctx context.Context, searchCriteria string) ([]Listing, error) {
+	site, opts := p.site, p.opts
+	if opts.bestSellers {
+		return nil, notRetryableError{fmt.Errorf("best sellers are not supported on ebay")}
+	}
+	if opts.catalog {
+		return nil, notRetryableError{fmt.Errorf("catalog search is not supported on ebay")}
+	}
+	if opts.freeShipping {
+		return nil, notRetryableError{fmt.Errorf("free shipping filter is not supported on ebay")}
+	}
+	if opts.officialStore != "" {
+		return nil, notRetryableError{fmt.Errorf("official store filter is not supported on ebay")}
+	}
+	if len(opts.attributes) > 0 {
+		return nil, notRetryableError{fmt.Errorf("attribute filter is not supported on ebay")}
+	}
+	if opts.minRating.IsPositive() {
+		return nil, notRetryableError{fmt.Errorf("rating filter is not supported on ebay")}
+	}
+	token, err := p.client.accessToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	searchURL, err := url.Parse(ebaySearchURL)
+	if err != nil {
+		return nil, fmt.Errorf("parsing ebay search url: %v", err)
+	}
+	limit := opts.pages * pageSize
+	if limit > ebayMaxLimit {
+		limit = ebayMaxLimit
+	}
+	queryValues := searchURL.Query()
+	queryValues[queryKey] = []string{searchCriteria}
+	queryValues[limitKey] = []string{strconv.Itoa(limit)}
+	if sort := ebaySort(opts.sort); sort != "" {
+		queryValues[sortKey] = []string{sort}
+	}
+	searchURL.RawQuery = queryValues.Encode()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, searchURL.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("building ebay request: %v", err)
+	}
+	request.Header.Set("Authorization", "Bearer "+token)
+	request.Header.Set(ebayMarketplaceHeader, site.ID)
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("querying ebay url: %v", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("requesting to ebay: %s", response.Status)
+	}
+
+	found := &ebaySearchResponse{}
+	if err := json.NewDecoder(bodylimit.Body(response)).Decode(found); err != nil {
+		return nil, fmt.Errorf("decoding ebay response body: %v", err)
+	}
+	listings := make([]Listing, 0, len(found.ItemSummaries))
+	for _, item := range found.ItemSummaries {
+		amount, err := number.API.Parse(item.Price.Value)
+		if err != nil {
+			return nil, fmt.Errorf("decoding price of ebay item %s: %v", item.ItemID, err)
+		}
+		listings = append(listings, Listing{
+			ID:        item.ItemID,
+			Title:     item.Title,
+			Permalink: item.ItemWebURL,
+			Price:     NewMoney(amount, item.Price.Currency),
+		})
+	}
+	return listings, nil
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/perrito666/tutoriales_go/internal/rates"
+)
+
+// ErrNoResults es el error de una búsqueda que funcionó pero no devolvió publicaciones.
+var ErrNoResults = errors.New("results not found in response")
+
+// ErrRateLimited indica que Mercado Libre rechazó el pedido por exceso de pedidos, un
+// *StatusError con estado 429 lo satisface con errors.Is.
+var ErrRateLimited = errors.New("rate limited")
+
+// ErrSchemaDrift indica que con -strict una respuesta de Mercado Libre no coincide con la
+// estructura conocida, por ejemplo porque la API agregó o cambió el tipo de un campo.
+var ErrSchemaDrift = errors.New("response does not match the known schema")
+
+// StatusError es el error de una respuesta de Mercado Libre con un estado distinto de 200.
+type StatusError struct {
+	// Code es el estado HTTP de la respuesta y Status su texto, por ejemplo "404 Not Found".
+	Code   int
+	Status string
+	// RetryAfter es lo que Mercado Libre pidió esperar antes de reintentar, 0 si no lo indicó.
+	RetryAfter time.Duration
+}
+
+// newStatusError devuelve el *StatusError de una respuesta que falló.
+func newStatusError(response *http.Response) *StatusError {
+	e := &StatusError{Code: response.StatusCode, Status: response.Status}
+	if delay, ok := parseRetryAfter(response.Header.Get("Retry-After"), time.Now()); ok {
+		e.RetryAfter = delay
+	}
+	return e
+}
+
+func (e *StatusError) Error() string {
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("%s, retry after %s", e.Status, e.RetryAfter.Round(time.Second))
+	}
+	return e.Status
+}
+
+// Is hace que errors.Is(err, ErrRateLimited) sea cierto para un 429.
+func (e *StatusError) Is(target error) bool {
+	return target == ErrRateLimited && e.Code == http.StatusTooManyRequests
+}
+
+// Retryable indica si tiene sentido reintentar el pedido: los 4xx indican un problema del
+// pedido que no se soluciona repitiéndolo, salvo que sean por exceso de pedidos o demora.
+func (e *StatusError) Retryable() bool {
+	switch {
+	case e.Code == http.StatusTooManyRequests, e.Code == http.StatusRequestTimeout:
+		return true
+	case e.Code >= 400 && e.Code < 500:
+		return false
+	}
+	return true
+}
+
+// DecodeError es el error de una respuesta de Mercado Libre que no se pudo de-serializar.
+type DecodeError struct {
+	// What indica que se estaba de-serializando, por ejemplo "mercado libre response body".
+	What string
+	Err  error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("decoding %s: %v", e.What, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// SiteError es el error de un sitio que falló en una comparación.
+type SiteError struct {
+	SiteID   string
+	SiteName string
+	// RequestID es el ID de la búsqueda en el sitio, el mismo de sus lineas de log y del
+	// encabezado X-Request-Id de sus pedidos.
+	RequestID string
+	Err       error
+}
+
+func (e *SiteError) Error() string {
+	if e.RequestID != "" {
+		return fmt.Sprintf("%s (%s, req=%s): %v", e.SiteName, e.SiteID, e.RequestID, e.Err)
+	}
+	return fmt.Sprintf("%s (%s): %v", e.SiteName, e.SiteID, e.Err)
+}
+
+func (e *SiteError) Unwrap() error {
+	return e.Err
+}
+
+// newSiteError devuelve el *SiteError de un sitio que falló.
+func newSiteError(f siteSearchResult) *SiteError {
+	return &SiteError{SiteID: f.site.ID, SiteName: f.site.Name, RequestID: f.requestID, Err: f.err}
+}
+
+// notRetryableError envuelve errores que no tiene sentido reintentar, por ejemplo cuando la
+// búsqueda funcionó pero no devolvió nada útil.
+type notRetryableError struct {
+	error
+}
+
+func (e notRetryableError) Unwrap() error {
+	return e.error
+}
+
+// retryable indica si un sitio que falló con err se debe reintentar.
+func retryable(err error) bool {
+	// una respuesta que cambió de forma no se arregla repitiendo el pedido.
+	// tampoco una moneda que la fuente de cotizaciones no conoce ni una página del Banco Nación
+	// que ya no se puede leer.
+	if errors.As(err, &notRetryableError{}) || errors.Is(err, ErrSchemaDrift) ||
+		errors.Is(err, rates.ErrUnsupportedCurrency) || errors.Is(err, rates.ErrLayoutChanged) {
+		return false
+	}
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.Retryable()
+	}
+	return true
+}
+
+const (
+	// exitFailure es el código de salida de un comando que falló, también el de una búsqueda
+	// en la que no respondió ningún sitio.
+	exitFailure = 1
+	// exitPartial es el código de salida de una búsqueda en la que fallaron algunos sitios,
+	// así un cron o un CI pueden distinguir una comparación incompleta de una que no funcionó.
+	exitPartial = 2
+)
+
+// exitError es un error con el que el programa termina con un código distinto de exitFailure.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error {
+	return e.err
+}
+
+// exitCode devuelve el código de salida que corresponde a err.
+func exitCode(err error) int {
+	var exitErr *exitError
+	if errors.As(err, &exitErr) {
+		return exitErr.code
+	}
+	return exitFailure
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+)
+
+// reportExporter envía el reporte de cada comparación a algún lado fuera del programa, por
+// ejemplo una planilla o un webhook.
+type reportExporter interface {
+	export(ctx context.Context, report *runReport) error
+}
+
+// exportFlags contiene los flags de todos los destinos a los que se pueden exportar los
+// reportes, los registran search y watch.
+type exportFlags struct {
+	sheets  *sheetsFlags
+	webhook *webhookFlags
+}
+
+// addExportFlags registra en fs los flags de exportación.
+func addExportFlags(fs *flag.FlagSet) *exportFlags {
+	return &exportFlags{
+		sheets:  addSheetsFlags(fs),
+		webhook: addWebhookFlags(fs),
+	}
+}
+
+// exporters devuelve los destinos indicados por los flags, puede no haber ninguno.
+func (f *exportFlags) exporters() ([]reportExporter, error) {
+	exporters := []reportExporter{}
+	sheets, err := f.sheets.exporter()
+	if err != nil {
+		return nil, err
+	}
+	if sheets != nil {
+		exporters = append(exporters, sheets)
+	}
+	hook, err := f.webhook.exporter()
+	if err != nil {
+		return nil, err
+	}
+	if hook != nil {
+		exporters = append(exporters, hook)
+	}
+	return exporters, nil
+}
+
+// exportReport envía el reporte a todos los destinos, que un destino falle no impide que se
+// intente con los demás.
+func exportReport(ctx context.Context, exporters []reportExporter, report *runReport) error {
+	var failed []string
+	for _, exporter := range exporters {
+		if err := exporter.export(ctx, report); err != nil {
+			failed = append(failed, err.Error())
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("exporting %q: %s", report.Query, strings.Join(failed, "; "))
+	}
+	return nil
+}
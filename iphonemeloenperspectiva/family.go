@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// familyProductsLimit es la cantidad de productos del catálogo de los que se toman los modelos
+// de una familia.
+const familyProductsLimit = 50
+
+// modelName devuelve el modelo de un producto del catálogo, su nombre sin la capacidad ni el
+// color, que ML escribe después, por ejemplo "Apple iPhone 15 Pro (256 GB) - Titanio negro"
+// es "Apple iPhone 15 Pro".
+func modelName(product string) string {
+	for _, separator := range []string{"(", " - "} {
+		if i := strings.Index(product, separator); i >= 0 {
+			product = product[:i]
+		}
+	}
+	return strings.Join(strings.Fields(product), " ")
+}
+
+// discoverFamily busca prefix en el catálogo de site y devuelve los modelos distintos cuyo
+// nombre contiene prefix, por ejemplo para "iPhone 15" los modelos 15, 15 Plus, 15 Pro y 15
+// Pro Max, del de nombre mas corto al mas largo y a lo sumo max. Los productos con palabras
+// que exclusionsFor descarta para prefix, como fundas, no son modelos de la familia.
+func discoverFamily(ctx context.Context, prefix string, site mlSite, max int) ([]string, error) {
+	productsURL, err := productsSearchURL(prefix, site)
+	if err != nil {
+		return nil, err
+	}
+	limitedURL, err := url.Parse(productsURL)
+	if err != nil {
+		return nil, fmt.Errorf("parsing mercado libre products search url: %w", err)
+	}
+	queryValues := limitedURL.Query()
+	queryValues.Set("limit", strconv.Itoa(familyProductsLimit))
+	limitedURL.RawQuery = queryValues.Encode()
+
+	products := &mlProductsSearch{}
+	if err := getML(ctx, limitedURL.String(), products, &mlProductsSearchSchema{}); err != nil {
+		return nil, fmt.Errorf("searching catalog: %w", err)
+	}
+	normalizedPrefix := " " + normalizeTitle(prefix) + " "
+	accessory := map[string]bool{}
+	for _, word := range exclusionsFor(prefix) {
+		accessory[normalizeTitle(word)] = true
+	}
+	seen := map[string]bool{}
+	models := []string{}
+	for _, product := range products.Results {
+		model := modelName(product.Name)
+		key := normalizeTitle(model)
+		if seen[key] || !strings.Contains(" "+key+" ", normalizedPrefix) || anyWord(key, accessory) {
+			continue
+		}
+		seen[key] = true
+		models = append(models, model)
+	}
+	sort.SliceStable(models, func(i, j int) bool {
+		wi, wj := len(strings.Fields(models[i])), len(strings.Fields(models[j]))
+		if wi != wj {
+			return wi < wj
+		}
+		return models[i] < models[j]
+	})
+	if len(models) > max {
+		models = models[:max]
+	}
+	return models, nil
+}
+
+// anyWord dice si alguna de las palabras de text está en words.
+func anyWord(text string, words map[string]bool) bool {
+	for _, word := range strings.Fields(text) {
+		if words[word] {
+			return true
+		}
+	}
+	return false
+}
+
+// familyOptions devuelve las opciones con las que se busca cada modelo de models, cada una
+// excluye las palabras que distinguen a los demás modelos, así la búsqueda de "iPhone 15" no
+// encuentra publicaciones del "iPhone 15 Pro".
+func familyOptions(models []string, opts searchOptions) []searchOptions {
+	words := map[string]bool{}
+	for _, model := range models {
+		for _, word := range strings.Fields(normalizeTitle(model)) {
+			words[word] = true
+		}
+	}
+	modelOpts := make([]searchOptions, 0, len(models))
+	for _, model := range models {
+		own := map[string]bool{}
+		for _, word := range strings.Fields(normalizeTitle(model)) {
+			own[word] = true
+		}
+		exclude := opts.exclude
+		if opts.autoExclude {
+			exclude = exclusionsFor(model)
+		}
+		exclude = append([]string{}, exclude...)
+		for word := range words {
+			if !own[word] {
+				exclude = append(exclude, word)
+			}
+		}
+		sort.Strings(exclude)
+		o := opts
+		o.exclude, o.autoExclude = exclude, false
+		modelOpts = append(modelOpts, o)
+	}
+	return modelOpts
+}
+
+// familySite es un sitio, una columna de la matriz de una familia.
+type familySite struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// familyModel es un modelo, una fila de la matriz de una familia.
+type familyModel struct {
+	Model string `json:"model"`
+	// Prices es el precio en USD del modelo en cada sitio de familyReport.Sites, en el mismo
+	// orden, nil si el sitio no tiene datos.
+	Prices []*decimal.Decimal `json:"prices_usd"`
+}
+
+// familyReport es el resultado del subcomando family, una matriz de modelo por país.
+type familyReport struct {
+	Prefix string        `json:"prefix"`
+	Sites  []familySite  `json:"sites"`
+	Models []familyModel `json:"models"`
+}
+
+// correlateFamily arma la matriz de precios de models por sitio, tomando de cada sitio la
+// primera publicación de cada modelo. Los sitios sin ningún modelo se omiten.
+func correlateFamily(prefix string, sites []mlSite, models []string, results [][]siteSearchResult) *familyReport {
+	prices := make([]map[string]decimal.Decimal, len(results))
+	for i := range results {
+		prices[i] = firstBySite(results[i])
+	}
+	report := &familyReport{Prefix: prefix}
+	for _, site := range sites {
+		for i := range models {
+			if _, ok := prices[i][site.ID]; ok {
+				report.Sites = append(report.Sites, familySite{ID: site.ID, Name: site.Name})
+				break
+			}
+		}
+	}
+	for i, model := range models {
+		row := familyModel{Model: model, Prices: make([]*decimal.Decimal, len(report.Sites))}
+		for j, site := range report.Sites {
+			if price, ok := prices[i][site.ID]; ok {
+				row.Prices[j] = &price
+			}
+		}
+		report.Models = append(report.Models, row)
+	}
+	return report
+}
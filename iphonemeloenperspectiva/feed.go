@@ -0,0 +1,118 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/perrito666/tutoriales_go/internal/i18n"
+)
+
+// atomNamespace es el espacio de nombres XML de Atom.
+const atomNamespace = "http://www.w3.org/2005/Atom"
+
+// atomLink es un enlace de un feed o una entrada Atom.
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+}
+
+// atomEntry es una entrada de un feed Atom.
+type atomEntry struct {
+	Title   string    `xml:"title"`
+	ID      string    `xml:"id"`
+	Updated time.Time `xml:"updated"`
+	Link    *atomLink `xml:"link,omitempty"`
+	Content struct {
+		Type string `xml:"type,attr"`
+		Body string `xml:",chardata"`
+	} `xml:"content"`
+}
+
+// atomFeed es un feed Atom, solo con los elementos que usamos.
+type atomFeed struct {
+	XMLName xml.Name    `xml:"feed"`
+	XMLNS   string      `xml:"xmlns,attr"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated time.Time   `xml:"updated"`
+	Author  string      `xml:"author>name"`
+	Link    atomLink    `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+// feedID devuelve un identificador estable para el feed de un criterio de búsqueda.
+func feedID(query string, parts ...string) string {
+	sum := sha1.Sum([]byte(strings.Join(append([]string{normalizeTitle(query)}, parts...), "|")))
+	return "urn:iphonemelo:" + hex.EncodeToString(sum[:8])
+}
+
+// priceChangesFeed arma un feed Atom con una entrada por cada comparación del historial en la
+// que cambió algún precio respecto de la anterior, de la mas nueva a la mas vieja. La primera
+// comparación no tiene con que compararse pero también es una entrada, con los precios de ese
+// momento. selfURL es la dirección del feed.
+func priceChangesFeed(query string, reports []*runReport, selfURL string) *atomFeed {
+	feed := &atomFeed{
+		XMLNS:  atomNamespace,
+		Title:  fmt.Sprintf(i18n.T("Precios de %q"), query),
+		ID:     feedID(query),
+		Author: programName,
+		Link:   atomLink{Href: selfURL, Rel: "self"},
+	}
+	for i, report := range reports {
+		var body strings.Builder
+		title := ""
+		if i == 0 {
+			title = fmt.Sprintf(i18n.T("Primera comparación de %q"), report.Query)
+			for _, r := range report.Results {
+				fmt.Fprintf(&body, "%s: %s\n", r.SiteName, r.usd())
+			}
+		} else {
+			diff := diffReports(reports[i-1], report)
+			changed := 0
+			for _, change := range diff.Changes {
+				if change.Change != nil && change.Change.IsZero() {
+					continue
+				}
+				changed++
+				writeTextChange(&body, change)
+			}
+			if changed == 0 {
+				continue
+			}
+			title = fmt.Sprintf(i18n.T("Cambió el precio de %q en %d sitios"), report.Query, changed)
+		}
+		entry := atomEntry{
+			Title:   title,
+			ID:      feedID(query, report.Time.UTC().Format(time.RFC3339Nano)),
+			Updated: report.Time,
+		}
+		if min, _, ok := priceRange(report); ok {
+			entry.Title += fmt.Sprintf(i18n.T(", el mas barato es %s a %s"), min.SiteName, min.usd())
+			entry.Link = &atomLink{Href: min.Permalink}
+		}
+		entry.Content.Type = "text"
+		entry.Content.Body = body.String()
+		feed.Entries = append([]atomEntry{entry}, feed.Entries...)
+	}
+	if len(feed.Entries) > 0 {
+		feed.Updated = feed.Entries[0].Updated
+	} else {
+		feed.Updated = time.Now()
+	}
+	return feed
+}
+
+// writeFeed escribe el feed como XML.
+func writeFeed(w io.Writer, feed *atomFeed) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	return encoder.Encode(feed)
+}
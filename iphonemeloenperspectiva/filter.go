@@ -0,0 +1,238 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// productTypeHints relaciona un tipo de producto con palabras que, si aparecen en el criterio
+// de búsqueda, nos hacen pensar que se está buscando ese tipo de producto.
+var productTypeHints = map[string][]string{
+	"phone":    {"iphone", "galaxy", "celular", "smartphone", "telefono", "motorola", "xiaomi", "pixel"},
+	"notebook": {"notebook", "macbook", "laptop", "ultrabook"},
+	"console":  {"playstation", "ps5", "ps4", "xbox", "nintendo", "switch"},
+}
+
+// defaultExclusions contiene, por tipo de producto, las palabras que suelen aparecer en los
+// títulos de accesorios que ensucian los extremos de los resultados (en español y portugués,
+// que son los idiomas de los sitios de ML). Se comparan palabras enteras, por eso están
+// también los plurales.
+var defaultExclusions = map[string][]string{
+	"phone": {
+		"funda", "fundas", "vidrio", "vidrios", "templado", "film", "films", "protector", "protectores",
+		"carcasa", "carcasas", "cable", "cables", "cargador", "cargadores", "estuche", "estuches",
+		"mica", "micas", "soporte", "soportes", "capa", "capas", "capinha", "capinhas", "pelicula",
+		"peliculas", "carregador", "carregadores", "case", "cases",
+	},
+	"notebook": {
+		"funda", "fundas", "maletin", "mochila", "mochilas", "cargador", "cargadores", "bateria",
+		"baterias", "teclado", "teclados", "skin", "skins", "carregador", "carregadores",
+	},
+	"console": {
+		"joystick", "joysticks", "control", "controles", "juego", "juegos", "funda", "fundas", "skin",
+		"skins", "soporte", "soportes", "jogo", "jogos", "controle",
+	},
+}
+
+// exclusionsFor devuelve las palabras a excluir por defecto según el tipo de producto que
+// parece estar buscándose, si no se reconoce el tipo no se excluye nada.
+func exclusionsFor(searchCriteria string) []string {
+	normalized := normalizeTitle(searchCriteria)
+	for _, word := range strings.Fields(normalized) {
+		for productType, hints := range productTypeHints {
+			for _, hint := range hints {
+				if word == hint {
+					return defaultExclusions[productType]
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// parseKeywords separa una lista de palabras separadas por comas, ignorando las vacías.
+func parseKeywords(list string) []string {
+	keywords := []string{}
+	for _, keyword := range strings.Split(list, ",") {
+		keyword = strings.TrimSpace(keyword)
+		if keyword != "" {
+			keywords = append(keywords, keyword)
+		}
+	}
+	return keywords
+}
+
+// excludeByKeywords devuelve los resultados cuyo título no contiene ninguna de las palabras
+// excluidas. La comparación se hace por palabras enteras de los títulos normalizados, así
+// "capa" descarta "Capa para iPhone" pero no "iPhone 13 128GB capacidad", y una exclusión de
+// varias palabras como "vidrio templado" tiene que aparecer tal cual.
+func excludeByKeywords(results []Listing, excluded []string) []Listing {
+	if len(excluded) == 0 {
+		return results
+	}
+	normalizedExcluded := make([][]string, 0, len(excluded))
+	for _, keyword := range excluded {
+		if words := strings.Fields(normalizeTitle(keyword)); len(words) > 0 {
+			normalizedExcluded = append(normalizedExcluded, words)
+		}
+	}
+	kept := make([]Listing, 0, len(results))
+	for _, r := range results {
+		title := strings.Fields(normalizeTitle(r.Title))
+		excludedResult := false
+		for _, keyword := range normalizedExcluded {
+			if containsWords(title, keyword) {
+				excludedResult = true
+				break
+			}
+		}
+		if !excludedResult {
+			kept = append(kept, r)
+		}
+	}
+	return kept
+}
+
+// containsWords indica si words aparece seguido dentro de title, palabra por palabra.
+func containsWords(title, words []string) bool {
+	for i := 0; i+len(words) <= len(title); i++ {
+		matches := true
+		for j, word := range words {
+			if title[i+j] != word {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return true
+		}
+	}
+	return false
+}
+
+// filterSites devuelve los sitios cuyo ID está en only (o todos si only está vacío) y no está
+// en excluded, los IDs se comparan sin importar mayúsculas.
+func filterSites(sites []mlSite, only, excluded []string) []mlSite {
+	contains := func(ids []string, id string) bool {
+		for _, candidate := range ids {
+			if strings.EqualFold(candidate, id) {
+				return true
+			}
+		}
+		return false
+	}
+	filtered := make([]mlSite, 0, len(sites))
+	for _, site := range sites {
+		if len(only) > 0 && !contains(only, site.ID) {
+			continue
+		}
+		if contains(excluded, site.ID) {
+			continue
+		}
+		filtered = append(filtered, site)
+	}
+	return filtered
+}
+
+// freeShippingFilter es el filtro de la búsqueda de ML que deja solo las publicaciones con
+// envío gratis.
+const freeShippingFilter, freeShippingValue = "shipping_cost", "free"
+
+// freeShippingOnly devuelve los resultados con envío gratis.
+func freeShippingOnly(results []Listing) []Listing {
+	free := make([]Listing, 0, len(results))
+	for _, result := range results {
+		if result.FreeShipping {
+			free = append(free, result)
+		}
+	}
+	return free
+}
+
+// officialStoreFilter es el filtro de la búsqueda de ML que deja solo las publicaciones de una
+// tienda oficial, con el ID de la tienda o allOfficialStores para cualquiera.
+const officialStoreFilter, allOfficialStores = "official_store", "all"
+
+// officialStoreOnly devuelve los resultados publicados por la tienda oficial store, o por
+// cualquiera si store es allOfficialStores.
+func officialStoreOnly(results []Listing, store string) []Listing {
+	official := make([]Listing, 0, len(results))
+	for _, result := range results {
+		if result.OfficialStoreID != "" && (store == allOfficialStores || result.OfficialStoreID == store) {
+			official = append(official, result)
+		}
+	}
+	return official
+}
+
+// attributeFilter es una característica que deben tener las publicaciones, con el ID de la
+// característica de ML como Name, por ejemplo INTERNAL_MEMORY, y su valor o el ID del valor.
+type attributeFilter struct {
+	Name, Value string
+}
+
+func (a attributeFilter) String() string {
+	return a.Name + "=" + a.Value
+}
+
+// attributeFilters implementa flag.Value para -attr, que se puede indicar varias veces, cada
+// una como NOMBRE=valor, por ejemplo -attr INTERNAL_MEMORY=256GB -attr COLOR=Negro.
+type attributeFilters []attributeFilter
+
+func (f *attributeFilters) String() string {
+	if f == nil {
+		return ""
+	}
+	pairs := make([]string, 0, len(*f))
+	for _, a := range *f {
+		pairs = append(pairs, a.String())
+	}
+	return strings.Join(pairs, ",")
+}
+
+// Set agrega una característica a las indicadas.
+func (f *attributeFilters) Set(value string) error {
+	parts := strings.SplitN(value, "=", 2)
+	if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" || strings.TrimSpace(parts[1]) == "" {
+		return fmt.Errorf("invalid attribute %q, must be NAME=value", value)
+	}
+	name := strings.ToUpper(strings.TrimSpace(parts[0]))
+	for _, a := range *f {
+		if a.Name == name {
+			return fmt.Errorf("attribute %s given more than once", name)
+		}
+	}
+	*f = append(*f, attributeFilter{Name: name, Value: strings.TrimSpace(parts[1])})
+	return nil
+}
+
+// hasAttributes indica si la publicación tiene todas las características de filters, los
+// valores se comparan normalizados así "256GB" coincide con "256 GB".
+func hasAttributes(listing Listing, filters []attributeFilter) bool {
+	for _, filter := range filters {
+		want := strings.ReplaceAll(normalizeTitle(filter.Value), " ", "")
+		found := false
+		for _, value := range listing.Attributes[filter.Name] {
+			if strings.ReplaceAll(normalizeTitle(value), " ", "") == want {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// attributesOnly devuelve los resultados que tienen todas las características de filters, los
+// que no las indican se descartan porque no se puede saber si son la misma variante.
+func attributesOnly(results []Listing, filters []attributeFilter) []Listing {
+	matching := make([]Listing, 0, len(results))
+	for _, result := range results {
+		if hasAttributes(result, filters) {
+			matching = append(matching, result)
+		}
+	}
+	return matching
+}
@@ -0,0 +1,33 @@
+package main
+
+import "testing"
+
+func TestExcludeByKeywords(t *testing.T) {
+	phone := exclusionsFor("iphone 13")
+	tests := []struct {
+		name     string
+		title    string
+		excluded []string
+		kept     bool
+	}{
+		{"capacidad no es capa", "iPhone 13 128GB capacidad", phone, true},
+		{"cerámica no es mica", "iPhone 13 Pro Cerámica Blanco", phone, true},
+		{"cable dentro de otra palabra", "iPhone 13 Inalámbrico Desbloqueado Cableado Original", phone, true},
+		{"showcase no es case", "iPhone 13 Showcase Edition", phone, true},
+		{"funda", "Funda Silicona iPhone 13", phone, false},
+		{"plural", "Fundas para iPhone 13 x3", phone, false},
+		{"acentos y mayúsculas", "Película Vidrio iPhone 13", phone, false},
+		{"puntuación", "iPhone 13 (case) transparente", phone, false},
+		{"varias palabras seguidas", "Vidrio Templado iPhone 13", []string{"vidrio templado"}, false},
+		{"varias palabras separadas", "Vidrio trasero iPhone 13 templado", []string{"vidrio templado"}, true},
+		{"sin exclusiones", "Funda iPhone 13", nil, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			kept := excludeByKeywords([]Listing{{Title: test.title}}, test.excluded)
+			if got := len(kept) == 1; got != test.kept {
+				t.Errorf("excludeByKeywords(%q) kept = %v, want %v", test.title, got, test.kept)
+			}
+		})
+	}
+}
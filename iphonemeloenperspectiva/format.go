@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/perrito666/tutoriales_go/internal/i18n"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	// roundBank redondea al par mas cercano, como lo hace un banco.
+	roundBank = "bank"
+	// roundHalfUp redondea la mitad hacia arriba (alejándose del cero).
+	roundHalfUp = "half-up"
+	// roundTruncate descarta los decimales que sobran.
+	roundTruncate = "truncate"
+)
+
+// validRounding indica si rounding es un modo de redondeo conocido.
+func validRounding(rounding string) bool {
+	switch rounding {
+	case roundBank, roundHalfUp, roundTruncate:
+		return true
+	}
+	return false
+}
+
+// defaultDecimals es la cantidad de decimales de las monedas que no conoce ni ISO 4217 ni
+// currencyDecimals, y de los números que no son montos.
+const defaultDecimals = 2
+
+// currencyDecimals contiene los decimales de las monedas que no son de ISO 4217, un iPhone cuesta
+// una fracción de bitcoin. Los de las demás, por ejemplo ninguno en Chile y Colombia donde los
+// precios no tienen centavos, salen de golang.org/x/text/currency.
+var currencyDecimals = map[string]int32{
+	"BTC": 8,
+	"ETH": 8,
+}
+
+// numberFormat indica como se muestran los montos, se puede modificar desde la linea de
+// comandos.
+var numberFormat = struct {
+	// decimals es la cantidad de decimales, si es negativo se usan los de cada moneda.
+	decimals int
+	// rounding es el modo de redondeo, uno de roundBank, roundHalfUp o roundTruncate.
+	rounding string
+}{decimals: -1, rounding: roundBank}
+
+// formatDecimal devuelve d con places decimales, redondeado según numberFormat y con los
+// separadores de miles y de decimales del idioma de la salida, por ejemplo "1.234,56".
+func formatDecimal(d decimal.Decimal, places int32) string {
+	switch numberFormat.rounding {
+	case roundHalfUp:
+		d = d.Round(places)
+	case roundTruncate:
+		d = d.Truncate(places)
+	default:
+		d = d.RoundBank(places)
+	}
+	return i18n.Number(d, places)
+}
+
+// decimalsFor devuelve la cantidad de decimales con que se muestran los montos en currency.
+func decimalsFor(currency string) int32 {
+	if numberFormat.decimals >= 0 {
+		return int32(numberFormat.decimals)
+	}
+	if places, ok := currencyDecimals[currency]; ok {
+		return places
+	}
+	if places, ok := i18n.CurrencyDecimals(currency); ok {
+		return places
+	}
+	return defaultDecimals
+}
+
+// formatAmount devuelve amount como se muestran los montos en currency.
+func formatAmount(amount decimal.Decimal, currency string) string {
+	return formatDecimal(amount, decimalsFor(currency))
+}
+
+// formatNumber devuelve d, que no es un monto (por ejemplo un porcentaje), con los decimales
+// indicados en la linea de comandos o dos si no se indicó nada.
+func formatNumber(d decimal.Decimal) string {
+	places := int32(defaultDecimals)
+	if numberFormat.decimals >= 0 {
+		places = int32(numberFormat.decimals)
+	}
+	return formatDecimal(d, places)
+}
+
+// formatRate devuelve la cotización d con todos sus decimales, que en monedas con mucha
+// inflación son varios, y el separador de decimales del idioma de la salida.
+func formatRate(d decimal.Decimal) string {
+	places := -d.Exponent()
+	if places < 0 {
+		places = 0
+	}
+	return i18n.Number(d, places)
+}
+
+// checkNumberFormat valida los flags de formato.
+func checkNumberFormat() error {
+	if !validRounding(numberFormat.rounding) {
+		return fmt.Errorf("unknown -rounding %q, must be one of %s, %s or %s",
+			numberFormat.rounding, roundBank, roundHalfUp, roundTruncate)
+	}
+	return nil
+}
@@ -1,5 +0,0 @@
-module github.com/perrito666/tutoriales_go
-
-go 1.12
-
-require github.com/shopspring/decimal v0.0.0-20190905144223-a36b5d85f337
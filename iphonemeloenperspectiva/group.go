@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/perrito666/tutoriales_go/internal/i18n"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	// groupByProvince agrupa las publicaciones por provincia (o estado) del vendedor.
+	groupByProvince = "province"
+	// groupByCity agrupa las publicaciones por ciudad del vendedor, dentro de su provincia.
+	groupByCity = "city"
+)
+
+// unknownLocation es el nombre del grupo de las publicaciones que no indican su ubicación.
+const unknownLocation = "sin ubicación"
+
+// validGroupBy indica si by es un criterio de agrupación conocido.
+func validGroupBy(by string) bool {
+	switch by {
+	case groupByProvince, groupByCity:
+		return true
+	}
+	return false
+}
+
+// reportGroup resume los precios de las publicaciones de una misma ubicación dentro de un
+// sitio, los precios están en la moneda del sitio salvo MedianUSD.
+type reportGroup struct {
+	Name      string          `json:"name"`
+	Count     int             `json:"count"`
+	Currency  string          `json:"currency"`
+	Min       decimal.Decimal `json:"min"`
+	Median    decimal.Decimal `json:"median"`
+	Max       decimal.Decimal `json:"max"`
+	MedianUSD decimal.Decimal `json:"median_usd"`
+}
+
+// locationName devuelve el nombre del grupo al que pertenece r según by.
+func locationName(r reportResult, by string) string {
+	if r.Province == "" {
+		return unknownLocation
+	}
+	if by == groupByCity && r.City != "" {
+		return fmt.Sprintf("%s, %s", r.City, r.Province)
+	}
+	return r.Province
+}
+
+// groupByLocation agrupa los resultados del reporte según by y completa report.Groups, de la
+// ubicación con la mediana mas barata a la mas cara.
+func groupByLocation(report *runReport, by string) {
+	byName := map[string][]reportResult{}
+	for _, r := range report.Results {
+		name := locationName(r, by)
+		byName[name] = append(byName[name], r)
+	}
+	groups := make([]reportGroup, 0, len(byName))
+	for name, results := range byName {
+		local := make([]decimal.Decimal, 0, len(results))
+		for _, r := range results {
+			local = append(local, r.Price)
+		}
+		sortDecimals(local)
+		groups = append(groups, reportGroup{
+			Name:      name,
+			Count:     len(results),
+			Currency:  results[0].Currency,
+			Min:       local[0],
+			Median:    median(local),
+			Max:       local[len(local)-1],
+			MedianUSD: medianUSD(results),
+		})
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if !groups[i].MedianUSD.Equal(groups[j].MedianUSD) {
+			return groups[i].MedianUSD.LessThan(groups[j].MedianUSD)
+		}
+		return groups[i].Name < groups[j].Name
+	})
+	report.Groups = groups
+}
+
+// writeTextGroups escribe los grupos del reporte en una tabla, en lugar de cada publicación.
+func writeTextGroups(w io.Writer, report *runReport) {
+	fmt.Fprintf(w, i18n.T("Precios por ubicación en %s:\n"), report.Results[0].SiteName)
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, i18n.T("Ubicación\tPublicaciones\tMínimo\tMediana\tMáximo\tMediana (USD)"))
+	for _, g := range report.Groups {
+		// el nombre del grupo sin ubicación es el único que no sale de Mercado Libre.
+		name := g.Name
+		if name == unknownLocation {
+			name = i18n.T(unknownLocation)
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\t%s\n", name, g.Count,
+			formatAmount(g.Min, g.Currency), formatAmount(g.Median, g.Currency),
+			formatAmount(g.Max, g.Currency), formatAmount(g.MedianUSD, usdCurrencyCode))
+	}
+	tw.Flush()
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/perrito666/tutoriales_go/iphonemeloenperspectiva/melitest"
+)
+
+func TestGroupByLocationCountsSameSellerListings(t *testing.T) {
+	server := melitest.NewServer()
+	defer server.Close()
+	useTransport(t, server.Transport())
+	server.SetItems("MLA", sameSellerItems("MLA", "ARS", 1500000)...)
+
+	// -group-by compara un solo sitio con todas las publicaciones de las páginas.
+	results, failed := compareListForTest(t, "iphone 11", "-sites", "MLA", "-per-site", "50")
+	report := newRunReport("iphone 11", results, failed)
+	groupByLocation(report, groupByProvince)
+	if len(report.Groups) != 1 {
+		t.Fatalf("got %d groups, want 1", len(report.Groups))
+	}
+	group := report.Groups[0]
+	if group.Name != unknownLocation || group.Count != 2 {
+		t.Errorf("group = %s with %d listings, want %s with 2", group.Name, group.Count, unknownLocation)
+	}
+	if group.Min.String() != "1499999" || group.Max.String() != "1500000" {
+		t.Errorf("group prices = %s to %s, want 1499999 to 1500000", group.Min, group.Max)
+	}
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/perrito666/tutoriales_go/iphonemeloenperspectiva/pricepb"
+)
+
+// priceService implementa pricepb.PriceService con las mismas búsquedas que la API HTTP.
+type priceService struct {
+	pricepb.UnimplementedPriceServiceServer
+	s *server
+}
+
+// newGRPCServer devuelve un servidor gRPC que atiende PriceService.
+func newGRPCServer(s *server) *grpc.Server {
+	// primero se verifica la clave, así el límite se aplica por clave y no por IP.
+	var unary []grpc.UnaryServerInterceptor
+	var stream []grpc.StreamServerInterceptor
+	for _, interceptors := range []func() (grpc.UnaryServerInterceptor, grpc.StreamServerInterceptor){
+		s.keys.grpcInterceptors, s.limiter.grpcInterceptors,
+	} {
+		if u, st := interceptors(); u != nil {
+			unary = append(unary, u)
+			stream = append(stream, st)
+		}
+	}
+	grpcServer := grpc.NewServer(grpc.ChainUnaryInterceptor(unary...), grpc.ChainStreamInterceptor(stream...))
+	pricepb.RegisterPriceServiceServer(grpcServer, &priceService{s: s})
+	return grpcServer
+}
+
+// search compara el criterio con las búsquedas del servidor HTTP, convirtiendo los errores
+// en errores de gRPC.
+func (p *priceService) search(ctx context.Context, query string, onResult func(siteSearchResult)) (*runReport, error) {
+	report, err := p.s.search(ctx, query, onResult)
+	if err != nil {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+	if ctx.Err() != nil {
+		return nil, status.FromContextError(ctx.Err()).Err()
+	}
+	return report, nil
+}
+
+// Search compara el criterio entre los sitios.
+func (p *priceService) Search(ctx context.Context, request *pricepb.SearchRequest) (*pricepb.Report, error) {
+	query := strings.TrimSpace(request.GetQuery())
+	if query == "" {
+		return nil, status.Error(codes.InvalidArgument, "missing query")
+	}
+	report, err := p.search(ctx, query, nil)
+	if err != nil {
+		return nil, err
+	}
+	return reportToProto(report), nil
+}
+
+// Watch repite la comparación cada intervalo hasta que el cliente corte, enviando cada
+// resultado a medida que llega y el reporte al final de cada vuelta.
+func (p *priceService) Watch(request *pricepb.WatchRequest, stream pricepb.PriceService_WatchServer) error {
+	query := strings.TrimSpace(request.GetQuery())
+	if query == "" {
+		return status.Error(codes.InvalidArgument, "missing query")
+	}
+	interval := defaultWatchInterval
+	if request.GetInterval() != nil {
+		interval = request.GetInterval().AsDuration()
+	}
+	if interval <= 0 {
+		return status.Errorf(codes.InvalidArgument, "interval must be positive, got %s", interval)
+	}
+
+	// si un envío falla el cliente ya no escucha, cancelamos la búsqueda en curso.
+	ctx, cancel := context.WithCancel(stream.Context())
+	go func() {
+		// si vence el plazo para apagar el servidor cancelamos también la búsqueda.
+		select {
+		case <-p.s.ctx.Done():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	defer cancel()
+	var sendErr error
+	send := func(event *pricepb.WatchEvent) {
+		if sendErr != nil {
+			return
+		}
+		if sendErr = stream.Send(event); sendErr != nil {
+			cancel()
+		}
+	}
+	// onResult siempre se llama desde la misma gorutina, no hace falta proteger sendErr.
+	onResult := func(r siteSearchResult) {
+		if r.err != nil {
+			send(&pricepb.WatchEvent{Event: &pricepb.WatchEvent_Failure{Failure: failureToProto(newReportFailure(r))}})
+			return
+		}
+		send(&pricepb.WatchEvent{Event: &pricepb.WatchEvent_Result{Result: resultToProto(newReportResult(r))}})
+	}
+	for {
+		report, err := p.search(ctx, query, onResult)
+		if sendErr != nil {
+			return sendErr
+		}
+		if err != nil {
+			return err
+		}
+		send(&pricepb.WatchEvent{Event: &pricepb.WatchEvent_Report{Report: reportToProto(report)}})
+		if sendErr != nil {
+			return sendErr
+		}
+		// al apagarse el servidor se termina la vuelta en curso pero no se empieza otra.
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-p.s.stopping:
+			return nil
+		case <-time.After(interval):
+		}
+	}
+}
+
+// Rates devuelve la cotización a USD de las monedas pedidas.
+func (p *priceService) Rates(ctx context.Context, request *pricepb.RatesRequest) (*pricepb.RatesReply, error) {
+	currencies := []string{}
+	for _, currency := range request.GetCurrencies() {
+		currencies = append(currencies, parseKeywords(strings.ToUpper(currency))...)
+	}
+	if len(currencies) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "missing currencies")
+	}
+	// cada pedido usa su propio cache, las cotizaciones cambian.
+	rates, failed := fetchRates(ctx, newRateCache(p.s.opts.timeout), currencies)
+	reply := &pricepb.RatesReply{Rates: map[string]string{}, Failed: failed}
+	for currency, rate := range rates {
+		reply.Rates[currency] = rate.String()
+	}
+	return reply, nil
+}
+
+// reportToProto convierte un runReport en su mensaje de PriceService.
+func reportToProto(report *runReport) *pricepb.Report {
+	message := &pricepb.Report{
+		Query: report.Query,
+		Time:  timestamppb.New(report.Time),
+	}
+	for _, r := range report.Results {
+		message.Results = append(message.Results, resultToProto(r))
+	}
+	for _, f := range report.Failed {
+		message.Failed = append(message.Failed, failureToProto(f))
+	}
+	if summary := report.Summary; summary != nil {
+		message.Summary = &pricepb.Summary{
+			Cheapest:      summarySiteToProto(summary.Cheapest),
+			MostExpensive: summarySiteToProto(summary.MostExpensive),
+			Spread:        summary.Spread.String(),
+			SpreadPercent: summary.SpreadPercent.String(),
+			Median:        summary.Median.String(),
+		}
+	}
+	return message
+}
+
+// resultToProto convierte una publicación de un reporte en su mensaje de PriceService.
+func resultToProto(r reportResult) *pricepb.Result {
+	return &pricepb.Result{
+		SiteId:    r.SiteID,
+		SiteName:  r.SiteName,
+		Currency:  r.Currency,
+		Rank:      int32(r.Rank),
+		Price:     r.Price.String(),
+		PriceUsd:  r.PriceUSD.String(),
+		Ratio:     r.Ratio.String(),
+		Title:     r.Title,
+		ItemId:    r.ItemID,
+		Permalink: r.Permalink,
+		AlsoOn:    r.AlsoOn,
+	}
+}
+
+// failureToProto convierte un sitio que falló en su mensaje de PriceService.
+func failureToProto(f reportFailure) *pricepb.Failure {
+	return &pricepb.Failure{
+		SiteId:   f.SiteID,
+		SiteName: f.SiteName,
+		Error:    f.Error,
+		TimedOut: f.TimedOut,
+		Attempts: int32(f.Attempts),
+	}
+}
+
+// summarySiteToProto convierte un sitio del resumen en su mensaje de PriceService.
+func summarySiteToProto(site summarySite) *pricepb.SummarySite {
+	return &pricepb.SummarySite{
+		SiteId:   site.SiteID,
+		SiteName: site.SiteName,
+		PriceUsd: site.PriceUSD.String(),
+	}
+}
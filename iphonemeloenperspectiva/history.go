@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// historyFileName es el nombre del archivo donde se guarda el historial de comparaciones.
+const historyFileName = "history.jsonl"
+
+// dataPath devuelve la ubicación de un archivo de datos de la aplicación, dentro de
+// $XDG_DATA_HOME/iphonemelo o, si no está definido, de ~/.local/share/iphonemelo.
+func dataPath(fileName string) string {
+	dataDir := os.Getenv("XDG_DATA_HOME")
+	if dataDir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			// sin directorio personal usamos el directorio actual.
+			return fileName
+		}
+		dataDir = filepath.Join(home, ".local", "share")
+	}
+	return filepath.Join(dataDir, "iphonemelo", fileName)
+}
+
+// defaultHistoryPath devuelve la ubicación por defecto del historial.
+func defaultHistoryPath() string {
+	return dataPath(historyFileName)
+}
+
+// historyStore guarda los reportes de cada comparación en un archivo de JSON por linea, no es
+// una base de datos pero alcanza para consultar como cambian los precios en el tiempo.
+type historyStore struct {
+	path string
+	// mu evita que dos escrituras concurrentes (por ejemplo desde el servidor) se mezclen.
+	mu sync.Mutex
+}
+
+// newHistoryStore devuelve un historial guardado en path.
+func newHistoryStore(path string) *historyStore {
+	return &historyStore{path: path}
+}
+
+// wait espera a que termine la escritura que esté en curso, el historial se escribe
+// directamente en el archivo así que una vez que terminó no queda nada por escribir.
+func (h *historyStore) wait() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+}
+
+// sameQuery indica si dos criterios de búsqueda son el mismo a los fines del historial.
+func sameQuery(a, b string) bool {
+	return normalizeTitle(a) == normalizeTitle(b)
+}
+
+// append agrega un reporte al final del historial.
+func (h *historyStore) append(report *runReport) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(h.path), 0755); err != nil {
+		return fmt.Errorf("creating history dir: %v", err)
+	}
+	f, err := os.OpenFile(h.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("opening history file: %v", err)
+	}
+	defer f.Close()
+
+	// json.Encoder agrega un salto de linea luego de cada valor, justo lo que necesitamos.
+	if err := json.NewEncoder(f).Encode(report); err != nil {
+		return fmt.Errorf("writing history entry: %v", err)
+	}
+	return nil
+}
+
+// load devuelve los reportes guardados, del mas viejo al mas nuevo, solo los de query si
+// no está vacío. Un historial inexistente es un historial vacío.
+func (h *historyStore) load(query string) ([]*runReport, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	f, err := os.Open(h.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("opening history file: %v", err)
+	}
+	defer f.Close()
+
+	reports := []*runReport{}
+	scanner := bufio.NewScanner(f)
+	// cada linea es un reporte completo, pueden ser bastante mas largas que el límite por
+	// defecto de bufio.Scanner.
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		report := &runReport{}
+		if err := json.Unmarshal(scanner.Bytes(), report); err != nil {
+			return nil, fmt.Errorf("decoding history line %d: %v", line, err)
+		}
+		if query != "" && !sameQuery(report.Query, query) {
+			continue
+		}
+		reports = append(reports, report)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading history file: %v", err)
+	}
+	return reports, nil
+}
+
+// loadFrom devuelve los reportes que empiezan a partir del byte offset del historial y el
+// offset desde el cual seguir leyendo la próxima vez, sirve para seguir el historial mientras
+// otro proceso (por ejemplo watch) lo va escribiendo. Una linea sin terminar se deja para la
+// próxima vez y si el archivo se achicó se vuelve a leer desde el principio.
+func (h *historyStore) loadFrom(offset int64) ([]*runReport, int64, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	f, err := os.Open(h.path)
+	if os.IsNotExist(err) {
+		return nil, 0, nil
+	}
+	if err != nil {
+		return nil, offset, fmt.Errorf("opening history file: %v", err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, offset, fmt.Errorf("reading history file: %v", err)
+	}
+	if info.Size() < offset {
+		offset = 0
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return nil, offset, fmt.Errorf("reading history file: %v", err)
+	}
+
+	reports := []*runReport{}
+	reader := bufio.NewReader(f)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			// lo que quedó sin salto de linea todavía se está escribiendo.
+			return reports, offset, nil
+		}
+		if err != nil {
+			return nil, offset, fmt.Errorf("reading history file: %v", err)
+		}
+		offset += int64(len(line))
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		report := &runReport{}
+		if err := json.Unmarshal(line, report); err != nil {
+			// devolvemos lo leído hasta acá y salteamos la linea rota, reintentarla no la arregla.
+			return reports, offset, fmt.Errorf("decoding history entry at byte %d: %v", offset-int64(len(line)), err)
+		}
+		reports = append(reports, report)
+	}
+}
+
+// size devuelve el tamaño actual del historial, 0 si todavía no existe.
+func (h *historyStore) size() (int64, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	info, err := os.Stat(h.path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("reading history file: %v", err)
+	}
+	return info.Size(), nil
+}
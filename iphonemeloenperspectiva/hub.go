@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	// historyPollInterval es cada cuanto se revisa si el historial tiene reportes nuevos.
+	historyPollInterval = 2 * time.Second
+	// subscriberBuffer es cuantos reportes puede tener pendientes un suscriptor antes de que
+	// se le empiecen a descartar.
+	subscriberBuffer = 16
+)
+
+// reportHub reparte los reportes que se agregan al historial, los escriba watch, otro proceso
+// o el mismo servidor, entre quienes estén suscriptos.
+type reportHub struct {
+	mu sync.Mutex
+	// subscribers contiene el criterio de cada suscriptor, vacío para recibir todos.
+	subscribers map[chan *runReport]string
+}
+
+// newReportHub devuelve un reportHub sin suscriptores.
+func newReportHub() *reportHub {
+	return &reportHub{subscribers: map[chan *runReport]string{}}
+}
+
+// subscribe devuelve un canal por el que llegan los reportes nuevos de query, o de todos si
+// está vacío. Se debe llamar a unsubscribe al terminar.
+func (h *reportHub) subscribe(query string) chan *runReport {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	ch := make(chan *runReport, subscriberBuffer)
+	h.subscribers[ch] = query
+	return ch
+}
+
+// unsubscribe deja de enviar reportes a ch.
+func (h *reportHub) unsubscribe(ch chan *runReport) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.subscribers, ch)
+}
+
+// publish envía el reporte a los suscriptores de su criterio, a quien no lo esté leyendo
+// se le descarta en lugar de frenar a los demás.
+func (h *reportHub) publish(report *runReport) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch, query := range h.subscribers {
+		if query != "" && !sameQuery(query, report.Query) {
+			continue
+		}
+		select {
+		case ch <- report:
+		default:
+			log.Printf("subscriber to %q is not keeping up, dropping report", query)
+		}
+	}
+}
+
+// follow revisa cada interval si se agregaron reportes al historial y los publica, empieza
+// por el final del historial actual y termina cuando se cancela ctx.
+func (h *reportHub) follow(ctx context.Context, history *historyStore, interval time.Duration) {
+	offset, err := history.size()
+	if err != nil {
+		log.Printf("following history: %v", err)
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		var reports []*runReport
+		reports, offset, err = history.loadFrom(offset)
+		if err != nil {
+			log.Printf("following history: %v", err)
+		}
+		for _, report := range reports {
+			h.publish(report)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/perrito666/tutoriales_go/internal/inflation"
+)
+
+const (
+	// cpiSourceBundled usa el IPC incluido en el programa, no hace pedidos.
+	cpiSourceBundled = "bundled"
+	// cpiSourceINDEC pide el IPC actualizado al INDEC.
+	cpiSourceINDEC = "indec"
+	// latestMonth como mes de -constant-ars indica el último mes con IPC publicado.
+	latestMonth = "latest"
+)
+
+// loadCPI devuelve el IPC de source, cpiSourceBundled o cpiSourceINDEC.
+func loadCPI(ctx context.Context, source string) (inflation.Series, error) {
+	switch source {
+	case cpiSourceBundled:
+		return inflation.Bundled, nil
+	case cpiSourceINDEC:
+		return inflation.FetchINDEC(ctx, doRates)
+	}
+	return nil, fmt.Errorf("unknown -cpi-source %q, must be %s or %s", source, cpiSourceBundled, cpiSourceINDEC)
+}
+
+// constantARSMonth devuelve el mes de month, en formato YYYY-MM o latestMonth, en cuyos pesos
+// se expresan los precios, tiene que tener IPC en series.
+func constantARSMonth(month string, series inflation.Series) (string, error) {
+	if month == latestMonth {
+		return series.Latest(), nil
+	}
+	month, err := inflation.ParseMonth(month)
+	if err != nil {
+		return "", fmt.Errorf("-constant-ars: %v", err)
+	}
+	if _, ok := series[month]; !ok {
+		return "", fmt.Errorf("-constant-ars: no consumer price index for %s, the series goes up to %s", month, series.Latest())
+	}
+	return month, nil
+}
+
+// applyInflation completa, para cada resultado en pesos argentinos de los reportes, su precio
+// en pesos del mes base según el IPC de series. Los reportes anteriores al comienzo de la serie
+// quedan sin precio ajustado.
+func applyInflation(reports []*runReport, series inflation.Series, base string) {
+	for _, report := range reports {
+		for i, r := range report.Results {
+			if r.Currency != arsCurrencyCode {
+				continue
+			}
+			adjusted, err := series.Adjust(r.Price, report.Time, base)
+			if err != nil {
+				continue
+			}
+			report.Results[i].ConstantARS = &adjusted
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/perrito666/tutoriales_go/internal/i18n"
+	"github.com/shopspring/decimal"
+)
+
+// Installments es la financiación en cuotas que ofrece una publicación.
+type Installments struct {
+	// Quantity es la cantidad de cuotas.
+	Quantity int
+	// Amount es el monto de cada cuota.
+	Amount Money
+	// Rate es la tasa de interés que informa el sitio, en porcentaje.
+	Rate decimal.Decimal
+}
+
+// total devuelve lo que se termina pagando en cuotas.
+func (i Installments) total() Money {
+	return NewMoney(i.Amount.Amount.Mul(decimal.New(int64(i.Quantity), 0)), i.Amount.Currency)
+}
+
+// reportInstallments es la financiación en cuotas de una publicación dentro de un runReport.
+type reportInstallments struct {
+	Quantity int             `json:"quantity"`
+	Amount   decimal.Decimal `json:"amount"`
+	Currency string          `json:"currency"`
+	Rate     decimal.Decimal `json:"rate"`
+	// Total es lo que se termina pagando en cuotas.
+	Total decimal.Decimal `json:"total"`
+	// InterestPercent es cuanto mas que el precio de contado cuesta en cuotas, en porcentaje,
+	// no está si las cuotas están en otra moneda que el precio.
+	InterestPercent *decimal.Decimal `json:"interest_percent,omitempty"`
+}
+
+// applyInstallments agrega a cada publicación que ofrece cuotas lo que cuesta en total
+// financiada y cuanto mas que de contado es, en Argentina ambos precios pueden ser muy
+// distintos.
+func applyInstallments(report *runReport) {
+	for i, r := range report.Results {
+		if r.installments == nil || r.installments.Quantity <= 0 {
+			continue
+		}
+		total := r.installments.total()
+		installments := &reportInstallments{
+			Quantity: r.installments.Quantity,
+			Amount:   r.installments.Amount.Amount,
+			Currency: total.Currency,
+			Rate:     r.installments.Rate,
+			Total:    total.Amount,
+		}
+		// el precio de contado se compara en la moneda de las cuotas.
+		cash := decimal.Zero
+		switch total.Currency {
+		case r.Currency:
+			cash = r.Price
+		case usdCurrencyCode:
+			cash = r.PriceUSD
+		}
+		if cash.IsPositive() {
+			interest := percentChange(cash, total.Amount)
+			installments.InterestPercent = &interest
+		}
+		report.Results[i].Installments = installments
+	}
+}
+
+// formatInstallments describe la financiación, por ejemplo "12 cuotas de $ 25.000,00, en
+// total $ 300.000,00 (50,00% mas que de contado)".
+func formatInstallments(i reportInstallments) string {
+	text := fmt.Sprintf(i18n.T("%d cuotas de %s, en total %s"), i.Quantity, NewMoney(i.Amount, i.Currency), NewMoney(i.Total, i.Currency))
+	switch {
+	case i.InterestPercent == nil:
+	case i.InterestPercent.IsPositive():
+		text += fmt.Sprintf(i18n.T(" (%s%% mas que de contado)"), formatNumber(*i.InterestPercent))
+	default:
+		text += i18n.T(" (sin interés)")
+	}
+	return text
+}
@@ -0,0 +1,328 @@
+// Package melitest levanta un servidor HTTP que imita los endpoints de sitios, búsqueda y
+// cotizaciones de la API de Mercado Libre, con datos configurables y la posibilidad de
+// inyectar fallas (demoras, 429, 5xx y JSON mal formado), para probar de punta a punta el
+// código concurrente sin depender de la API real.
+//
+// Uso típico:
+//
+//	server := melitest.NewServer()
+//	defer server.Close()
+//	server.Inject(melitest.SearchPath("MLB"), melitest.Fault{Status: http.StatusTooManyRequests, RetryAfter: "1", Times: 1})
+//	client := &http.Client{Transport: server.Transport()}
+//
+// Transport redirige al servidor los pedidos a api.mercadolibre.com, así el código bajo
+// prueba no necesita conocer la URL del servidor.
+package melitest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// APIHost es el host de la API de Mercado Libre, los pedidos a este host se redirigen al
+	// servidor de prueba.
+	APIHost = "api.mercadolibre.com"
+	// SitesPath es el endpoint de la lista de sitios.
+	SitesPath = "/sites"
+	// CurrencyPath es el endpoint de cotizaciones.
+	CurrencyPath = "/currency_conversions/search"
+	// defaultPageSize es la cantidad de resultados que devuelve la búsqueda si no se indica
+	// limit, como la API real.
+	defaultPageSize = 50
+)
+
+// SearchPath devuelve el endpoint de búsqueda de un sitio.
+func SearchPath(siteID string) string {
+	return "/sites/" + siteID + "/search"
+}
+
+// Site es un sitio de Mercado Libre.
+type Site struct {
+	ID                string `json:"id"`
+	Name              string `json:"name"`
+	DefaultCurrencyID string `json:"default_currency_id"`
+}
+
+// Seller es el vendedor de una publicación.
+type Seller struct {
+	ID int64 `json:"id"`
+}
+
+// Item es una publicación que devuelve la búsqueda de un sitio.
+type Item struct {
+	ID         string  `json:"id"`
+	Title      string  `json:"title"`
+	Price      float64 `json:"price"`
+	CurrencyID string  `json:"currency_id"`
+	Permalink  string  `json:"permalink"`
+	Seller     Seller  `json:"seller"`
+}
+
+// Fault es una falla que se inyecta en un endpoint.
+type Fault struct {
+	// Latency es la demora antes de responder, se respeta la cancelación del pedido.
+	Latency time.Duration
+	// Status, si no es 0, es el estado con que se responde en lugar de los datos.
+	Status int
+	// RetryAfter, si no está vacío, es el encabezado Retry-After de la respuesta con Status.
+	RetryAfter string
+	// Malformed responde un 200 con un JSON que no se puede de-serializar.
+	Malformed bool
+	// Times es la cantidad de pedidos a los que se aplica la falla, 0 es a todos.
+	Times int
+}
+
+// Server es un servidor que imita a la API de Mercado Libre, se puede modificar mientras
+// atiende pedidos.
+type Server struct {
+	*httptest.Server
+
+	mu     sync.Mutex
+	sites  []Site
+	items  map[string][]Item
+	rates  map[string]float64
+	faults map[string]*Fault
+	calls  map[string]int
+}
+
+// NewServer levanta un servidor con datos de ejemplo: Argentina (MLA), Brasil (MLB) y
+// Ecuador (MEC) con algunas publicaciones cada uno y la cotización de sus monedas.
+func NewServer() *Server {
+	s := &Server{
+		sites: []Site{
+			{ID: "MLA", Name: "Argentina", DefaultCurrencyID: "ARS"},
+			{ID: "MLB", Name: "Brasil", DefaultCurrencyID: "BRL"},
+			{ID: "MEC", Name: "Ecuador", DefaultCurrencyID: "USD"},
+		},
+		items:  map[string][]Item{},
+		rates:  map[string]float64{"ARS": 0.001, "BRL": 0.2, "USD": 1},
+		faults: map[string]*Fault{},
+		calls:  map[string]int{},
+	}
+	s.items["MLA"] = sampleItems("MLA", "ARS", 1500000, 1000)
+	s.items["MLB"] = sampleItems("MLB", "BRL", 7500, 2000)
+	s.items["MEC"] = sampleItems("MEC", "USD", 1450, 3000)
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// sampleItems devuelve publicaciones de ejemplo de un sitio, la mas cara a price. Cada sitio
+// usa vendedores distintos, si no las publicaciones se considerarían la misma en todos.
+func sampleItems(siteID, currency string, price float64, sellers int64) []Item {
+	items := make([]Item, 0, 3)
+	for i, title := range []string{"Apple iPhone 11 Pro Max 256gb", "Apple iPhone 11 Pro Max 64gb", "Funda iPhone 11 Pro Max"} {
+		id := fmt.Sprintf("%s%d", siteID, i+1)
+		items = append(items, Item{
+			ID:         id,
+			Title:      title,
+			Price:      price / float64(i*i+1),
+			CurrencyID: currency,
+			Permalink:  "https://articulo.mercadolibre.com/" + id,
+			Seller:     Seller{ID: sellers + int64(i)},
+		})
+	}
+	return items
+}
+
+// SetSites reemplaza la lista de sitios.
+func (s *Server) SetSites(sites ...Site) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sites = sites
+}
+
+// SetItems reemplaza las publicaciones que devuelve la búsqueda de un sitio.
+func (s *Server) SetItems(siteID string, items ...Item) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.items[siteID] = items
+}
+
+// SetRate indica la cotización a USD de una moneda.
+func (s *Server) SetRate(currency string, ratio float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rates[currency] = ratio
+}
+
+// Inject aplica una falla a los pedidos a path, por ejemplo SitesPath, CurrencyPath o
+// SearchPath("MLA"). Reemplaza la falla anterior del mismo endpoint.
+func (s *Server) Inject(path string, fault Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults[path] = &fault
+}
+
+// Clear quita la falla de path.
+func (s *Server) Clear(path string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.faults, path)
+}
+
+// Calls devuelve la cantidad de pedidos que recibió path.
+func (s *Server) Calls(path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls[path]
+}
+
+// Transport devuelve un RoundTripper que envía al servidor los pedidos a APIHost y el resto
+// por http.DefaultTransport.
+func (s *Server) Transport() http.RoundTripper {
+	target, err := url.Parse(s.URL)
+	if err != nil {
+		// httptest siempre devuelve una URL válida.
+		panic(err)
+	}
+	return &redirectTransport{host: APIHost, target: target, base: http.DefaultTransport}
+}
+
+// redirectTransport cambia el esquema y el host de los pedidos a host por los de target.
+type redirectTransport struct {
+	host   string
+	target *url.URL
+	base   http.RoundTripper
+}
+
+func (t *redirectTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	if request.URL.Host == t.host {
+		request = request.Clone(request.Context())
+		request.URL.Scheme = t.target.Scheme
+		request.URL.Host = t.target.Host
+		request.Host = t.target.Host
+	}
+	return t.base.RoundTrip(request)
+}
+
+// fault registra el pedido a path y devuelve la falla que le corresponde, nil si no hay.
+func (s *Server) fault(path string) *Fault {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls[path]++
+	fault, ok := s.faults[path]
+	if !ok {
+		return nil
+	}
+	applied := *fault
+	if fault.Times > 0 {
+		fault.Times--
+		if fault.Times == 0 {
+			delete(s.faults, path)
+		}
+	}
+	return &applied
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if fault := s.fault(r.URL.Path); fault != nil {
+		if fault.Latency > 0 {
+			select {
+			case <-time.After(fault.Latency):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		if fault.Status != 0 {
+			if fault.RetryAfter != "" {
+				w.Header().Set("Retry-After", fault.RetryAfter)
+			}
+			writeJSON(w, fault.Status, map[string]string{"message": http.StatusText(fault.Status)})
+			return
+		}
+		if fault.Malformed {
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"results": [`)
+			return
+		}
+	}
+
+	switch {
+	case r.URL.Path == SitesPath:
+		s.mu.Lock()
+		sites := append([]Site(nil), s.sites...)
+		s.mu.Unlock()
+		writeJSON(w, http.StatusOK, sites)
+	case r.URL.Path == CurrencyPath:
+		s.serveRate(w, r)
+	case strings.HasPrefix(r.URL.Path, "/sites/") && strings.HasSuffix(r.URL.Path, "/search"):
+		s.serveSearch(w, r, strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/sites/"), "/search"))
+	default:
+		writeJSON(w, http.StatusNotFound, map[string]string{"message": "resource not found"})
+	}
+}
+
+// serveRate responde la cotización de la moneda from a USD.
+func (s *Server) serveRate(w http.ResponseWriter, r *http.Request) {
+	from := r.URL.Query().Get("from")
+	s.mu.Lock()
+	ratio, ok := s.rates[from]
+	s.mu.Unlock()
+	if !ok {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"message": "invalid currency " + from})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"currency_base":  from,
+		"currency_quote": r.URL.Query().Get("to"),
+		"ratio":          ratio,
+	})
+}
+
+// serveSearch responde la búsqueda de un sitio, ordenada según sort y paginada según limit y
+// offset como la API real. El criterio no filtra, se devuelven todas las publicaciones.
+func (s *Server) serveSearch(w http.ResponseWriter, r *http.Request, siteID string) {
+	s.mu.Lock()
+	items, ok := s.items[siteID]
+	items = append([]Item(nil), items...)
+	s.mu.Unlock()
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"message": "site " + siteID + " not found"})
+		return
+	}
+
+	query := r.URL.Query()
+	switch query.Get("sort") {
+	case "price_asc":
+		sort.SliceStable(items, func(i, j int) bool { return items[i].Price < items[j].Price })
+	case "price_desc":
+		sort.SliceStable(items, func(i, j int) bool { return items[i].Price > items[j].Price })
+	}
+	limit, offset := defaultPageSize, 0
+	if value, err := strconv.Atoi(query.Get("limit")); err == nil && value > 0 {
+		limit = value
+	}
+	if value, err := strconv.Atoi(query.Get("offset")); err == nil && value > 0 {
+		offset = value
+	}
+	total := len(items)
+	if offset > total {
+		offset = total
+	}
+	end := offset + limit
+	if end > total {
+		end = total
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"site_id": siteID,
+		"query":   query.Get("q"),
+		"paging":  map[string]int{"total": total, "offset": offset, "limit": limit},
+		"results": items[offset:end],
+	})
+}
+
+// writeJSON responde v como JSON con el estado indicado.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -1,15 +1,21 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"net/http"
-	"net/url"
+	"sort"
 	"sync"
+	"time"
 
+	"github.com/perrito666/tutoriales_go/internal/bodylimit"
+	"github.com/perrito666/tutoriales_go/internal/conditional"
+	"github.com/perrito666/tutoriales_go/internal/meli"
+	"github.com/perrito666/tutoriales_go/internal/rates"
 	"github.com/shopspring/decimal"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // mlSite imita la estructura JSON que devuelve la búsqueda de Sites de Mercado Libre
@@ -18,196 +24,395 @@ type mlSite struct {
 	DefaultCurrencyID string `json:"default_currency_id"`
 	ID                string `json:"id"`
 	Name              string `json:"name"`
+	// provider es quien sabe buscar en el sitio, aunque se llame mlSite también representa
+	// sitios de otros mercados.
+	provider Provider
 }
 
 // mlSiteFetchEndpoint es el endpoint de listado de sites de Mercado Libre
 const mlSiteFetchEndpoint = "https://api.mercadolibre.com/sites"
 
-
-// fetchSites devuelve una lista de sites de Mercado Libre, los sites son los diferentes
-// paises donde ML tiene sitios, por ejemplo Argentina es MLA
-func fetchSites() ([]mlSite, error) {
-	// llamamos directamente al endpoint de Sitios
-	response, err := http.Get(mlSiteFetchEndpoint)
+// requestSites pide a Mercado Libre la lista de sites, los sites son los diferentes paises
+// donde ML tiene sitios, por ejemplo Argentina es MLA. Normalmente se usa fetchSites, que
+// guarda la lista. El pedido es condicional según cached, los validadores de la lista que ya
+// se tiene: si no cambió devuelve notModified y ninguna lista.
+func requestSites(cached conditional.Validators) (sites []mlSite, current conditional.Validators, notModified bool, err error) {
+	request, err := http.NewRequest(http.MethodGet, mlSiteFetchEndpoint, nil)
 	if err != nil {
-		return nil, fmt.Errorf("querying mercado libre sites endpoint: %v", err)
+		return nil, current, false, fmt.Errorf("building mercado libre sites request: %w", err)
 	}
-
-	// Fallaremos a menos que el estado sea 200
-	if response.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("requesting to mercado libre sites list: %s", response.Status)
+	cached.Set(request)
+	// llamamos directamente al endpoint de Sitios
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return nil, current, false, fmt.Errorf("querying mercado libre sites endpoint: %w", err)
 	}
 	// no olvidar cerrar el cuerpo de la respuesta.
 	defer response.Body.Close()
 
-	// leemos todo el Cuerpo, algo no recomendable a menos que estemos seguro que no es un
-	// stream de datos infinito y que no va a ocupar demasiado.
-	bodyData, err := ioutil.ReadAll(response.Body)
-	if err != nil {
-		return nil, fmt.Errorf("reading mercado libre sites list body: %v", err)
+	if response.StatusCode == http.StatusNotModified {
+		return nil, cached, true, nil
+	}
+	// Fallaremos a menos que el estado sea 200
+	if response.StatusCode != http.StatusOK {
+		return nil, current, false, fmt.Errorf("requesting to mercado libre sites list: %w", newStatusError(response))
 	}
 
 	// Instanciamos el slice de mlSite que va a recibir los resultados de-serializados
 	// del JSON que devuelve el endpoint
 	availableSites := []mlSite{}
 
-	// de-serializamos la respuesta en nuestro slice.
-	err = json.Unmarshal(bodyData, &availableSites)
+	// de-serializamos la respuesta en nuestro slice a medida que la leemos del cuerpo,
+	// sin necesidad de cargarla entera en memoria primero.
+	err = decodeML(context.Background(), bodylimit.Body(response), "mercado libre sites list", &availableSites, nil)
 	if err != nil {
-		return nil, fmt.Errorf("unmarshaling mercado libre sites list: %v", err)
+		return nil, current, false, err
 	}
 
-	return availableSites, nil
+	return availableSites, conditional.FromResponse(response), false, nil
 }
 
 const (
-	// baseMeLiURL es la URL de búsqueda de ML con un segmento reemplazable dependiendo del site
-	baseMeLiURL = "https://api.mercadolibre.com/sites/%s/search"
 	// queryKey es la clave que usaremos en el pedido GET para indicar el texto de búsqueda
-	queryKey    = "q"
+	queryKey = "q"
 	// sortKey es la clave que usaremos en el pedido GET para indicar el orden de los resultados
-	sortKey     = "sort"
-
-	// sortID es el valor de la clave sortKey que indica que queremos los resultados ordenados por 
-	// precio descendente
-	sortID = "price_desc"
+	sortKey = "sort"
+	// limitKey es la clave que usaremos en el pedido GET para indicar el tamaño de la página
+	limitKey = "limit"
+
+	// sortPriceDesc, sortPriceAsc y sortRelevance son los ordenamientos que sabemos pedirle a
+	// ML: por precio descendente, ascendente o por relevancia, el orden por defecto de ML.
+	sortPriceDesc = meli.SortPriceDesc
+	sortPriceAsc  = meli.SortPriceAsc
+	sortRelevance = meli.SortRelevance
 )
 
-// queryML busca un determinado término en un determinado site de ML
-func queryML(searchCriteria string, site mlSite) (io.ReadCloser, error) {
-	// completamos la URL de base con el site que nos pasaron.
-	queryURL, err := url.Parse(fmt.Sprintf(baseMeLiURL, site.ID))
+// validSort indica si sort es uno de los ordenamientos que sabemos pedirle a ML.
+func validSort(sort string) bool {
+	return meli.ValidSort(sort)
+}
+
+// searchURL devuelve la URL de búsqueda de un determinado término en un determinado site de
+// ML, ordenando los resultados según sort y con los filtros de filters. Si limit es mayor a 0
+// se pide la página de limit resultados que comienza en offset.
+func searchURL(searchCriteria string, site mlSite, sort string, filters map[string]string, limit, offset int) (string, error) {
+	return meli.SearchURL(site.ID, searchCriteria, sort, filters, limit, offset)
+}
+
+// queryML busca un determinado término en un determinado site de ML, ordenando los resultados
+// según sort y con los filtros de filters, el pedido se cancela si el contexto expira. Si
+// limit es mayor a 0 se pide la página de limit resultados que comienza en offset.
+func queryML(ctx context.Context, searchCriteria string, site mlSite, sort string, filters map[string]string, limit, offset int) (io.ReadCloser, error) {
+	queryURL, err := searchURL(searchCriteria, site, sort, filters, limit, offset)
 	if err != nil {
-		return nil, fmt.Errorf("parsing mercado libre url: %v", err)
-	}
-	// Obtenemos un diccionario de clave/valor de los parametros de GET
-	queryValues := queryURL.Query()
-	// Agregamos los parametros que nos interesan
-	// Ordenar por mas caro primero
-	queryValues[sortKey] = []string{sortID}
-	// Criterio de búsquda: lo que nos pasen como argumento
-	queryValues[queryKey] = []string{searchCriteria}
-	// Re-asignamos el diccionario de valores a la query original.
-	queryURL.RawQuery = queryValues.Encode()
+		return nil, err
+	}
+	// Armamos el pedido atado al contexto, de esta manera si el contexto vence se abandona.
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, queryURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("building mercado libre request: %w", err)
+	}
 	// Realizamos la consulta.
-	response, err := http.Get(queryURL.String())
+	response, err := doML(request)
 	if err != nil {
-		return nil, fmt.Errorf("querying mercado libre url: %v", err)
+		return nil, fmt.Errorf("querying mercado libre url: %w", err)
 	}
 	if response.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("requesting to mercado libre: %s", response.Status)
+		response.Body.Close()
+		return nil, fmt.Errorf("requesting to mercado libre: %w", newStatusError(response))
 	}
-	return response.Body, nil
-}
-
-// ResultadosML contiene un listado de resultados, representa una página de resultados.
-type ResultadosML struct {
-	Results []ResultadoML `json:"results"`
-}
-
-// ResultadoML contiene el precio de un resultado, representa un item de una página de resultados
-// pero no es para nada exaustivo.
-type ResultadoML struct {
-	// Price contiene el precio del resultado de búsqueda en moneda CurrencyID
-	Price      float64 `json:"price"`
-	// Title contiene el título de la publicación
-	Title      string  `json:"title"`
-	// Permalink contiene la URL en Mercado Libre de la publicación
-	Permalink  string  `json:"permalink"`
-	// CurrencyID contiene el ID interno de la moneda en la cual está el precio.
-	CurrencyID string  `json:"currency_id"`
-}
-
-// GetPrice devuelve el precio de un resultado convertido a decimal.Decimal.
-func (r ResultadoML) GetPrice() decimal.Decimal {
-	return decimal.NewFromFloat(r.Price)
+	// devolvemos el cuerpo limitado para que quien lo lea no pueda excederse.
+	return bodylimit.Body(response), nil
 }
 
 // siteSearchResult contiene un resultado de búsqueda, es para uso interno, lo utilizaremos
 // para enviar resultados de la gorutina a la rutina principal, contiene todo lo relevante
 // que la rutina podria devolver, incluyendo un error por si esta fallara.
 type siteSearchResult struct {
-	site     mlSite
-	price    decimal.Decimal
-	priceUSD decimal.Decimal
-	ratio    decimal.Decimal
-	item     string
-	err      error
+	site mlSite
+	// rank es la posición del resultado dentro de los de su sitio, empezando por 1.
+	rank int
+	// price es el precio en la moneda del sitio y priceUSD el mismo precio en USD.
+	price    Money
+	priceUSD Money
+	// ratio es la cotización de la moneda del sitio a USD.
+	ratio     Rate
+	item      string
+	itemID    string
+	permalink string
+	sellerID  string
+	// installments es la financiación en cuotas, nil si el sitio no la indica.
+	installments *Installments
+	// catalogProductID es el producto de catálogo de la publicación, vacío si no tiene.
+	catalogProductID string
+	// rating es la calificación del producto, solo si se pidió.
+	rating *Rating
+	// province y city son la ubicación de la publicación, vacías si el sitio no la indica.
+	province, city string
+	// total es cuantas publicaciones del sitio coinciden con el criterio, cero si el sitio no
+	// lo indica, y seen cuantas de ellas se analizaron.
+	total, seen int
+	// alsoOn contiene otros sitios donde se encontró la misma publicación.
+	alsoOn []mlSite
+	err    error
+	// timedOut indica que el sitio no respondió dentro del plazo asignado.
+	timedOut bool
+	// attempts es la cantidad de intentos que fueron necesarios para este sitio.
+	attempts int
+	// timing son los tiempos del último intento.
+	timing siteTiming
+	// requestID identifica la búsqueda en el sitio en el log y en los pedidos salientes.
+	requestID string
 }
 
-const (
-	// meliCurrencyConversionURL es la URL donde mercado libre publica una API de cambio de moneda
-	meliCurrencyConversionURL = "https://api.mercadolibre.com/currency_conversions/search"
-	// meliCurrencyFrom es la clave de pedido GET para indicarle cual es la moneda de origen a la API
-	meliCurrencyFrom          = "from"
-	// meliCurrencyTo es la clave de pedido GET para indicarle cual es la moneda de destine a la API
-	meliCurrencyTo            = "to"
-)
-
 // usdCurrencyCode es el ID de Mercado Libre para el Dolar EstadoUnidense.
-const usdCurrencyCode = "USD"
-
-// conversionRatio representa la estructura del resultado JSON de un pedido a la API de cambio.
-type conversionRatio struct {
-	// Ratio es la taza de cambio
-	Ratio float64 `json:"ratio"`
-}
-
-// Decimal devuelve la taza de cambio en decimal.Decimal.
-func (c conversionRatio) Decimal() decimal.Decimal {
-	return decimal.NewFromFloat(c.Ratio)
-}
+const usdCurrencyCode = rates.USD
+
+// rateSourceName es la fuente de las cotizaciones elegida con -rates-source, vacía para usar
+// la de cada comando, y rateSource la fuente en si, que parseFlags arma a partir del nombre
+// y de las fuentes de rateFallback, separadas por coma, que se prueban si la primera falla.
+// bnaTable es la tabla del Banco Nación elegida con -bna-table, sus cotizaciones se guardan
+// en bnaCachePath y se usan durante bnaTTL salvo con -refresh-rate.
+var (
+	rateSourceName string
+	rateSource     rates.Source
+	rateFallback   = rates.BCRAName
+	bnaTable       = rates.BNABilletes
+	bnaCachePath   = cachePath(bnaCacheFileName)
+	bnaTTL         = rates.DefaultBNATTL
+	refreshRate    bool
+)
 
+// bnaCacheFileName es el nombre del archivo donde se guardan las cotizaciones del Banco
+// Nación.
+const bnaCacheFileName = "bna.json"
 
-// fetchCurrencyRate hace un pedido de una moneda de origen a Dolar EstadoUnidense.
-func fetchCurrencyRate(sourceCurrency string) (decimal.Decimal, error) {
-	// agregamos las claves del pedido GET como ya sabemos.
-	meliURL, err := url.Parse(meliCurrencyConversionURL)
+// newRateSource devuelve la fuente de cotizaciones llamada name. La de Mercado Libre hace sus
+// pedidos como los demás pedidos a ML, respetando Retry-After y validando el cuerpo con
+// -strict, la del Banco Nación sale con httpClient, lee la tabla bnaTable y guarda lo leído
+// en bnaCachePath.
+func newRateSource(name string) (rates.Source, error) {
+	source, err := rates.New(name, doRates)
 	if err != nil {
-		return decimal.Zero, fmt.Errorf("parsing mercado libre conversion api URL: %v", err)
+		return nil, err
 	}
-	queryValues := meliURL.Query()
-	queryValues[meliCurrencyFrom] = []string{sourceCurrency}
-	queryValues[meliCurrencyTo] = []string{usdCurrencyCode}
-	meliURL.RawQuery = queryValues.Encode()
-
-	// realizamos el pedido
-	response, err := http.Get(meliURL.String())
-	if err != nil {
-		return decimal.Zero, fmt.Errorf("querying mercado libre currency url: %v", err)
+	if bna, ok := source.(*rates.BNA); ok {
+		bna.Table = bnaTable
+		bna.CachePath, bna.TTL, bna.Refresh = bnaCachePath, bnaTTL, refreshRate
 	}
-	if response.StatusCode != http.StatusOK {
-		return decimal.Zero, fmt.Errorf("requesting currency to mercado libre: %s", response.Status)
+	if ml, ok := source.(*rates.MercadoLibre); ok {
+		ml.Do = doML
+		ml.StatusError = func(response *http.Response) error { return newStatusError(response) }
+		ml.Decode = func(ctx context.Context, body io.Reader, v interface{}) error {
+			return decodeML(ctx, body, "body from mercado libre currency url", v, &mlRateSchema{})
+		}
 	}
+	return source, nil
+}
 
-	// leemos el resultado
-	bodyData, err := ioutil.ReadAll(response.Body)
+// newRateChain devuelve la fuente llamada name seguida de las de rateFallback, salvo que no
+// haya ninguna distinta de name.
+func newRateChain(name string) (rates.Source, error) {
+	source, err := newRateSource(name)
 	if err != nil {
-		return decimal.Zero, fmt.Errorf("reading body from mercado libre currency url: %v", err)
+		return nil, err
 	}
+	chain := rates.Fallback{{Name: name, Source: source}}
+	for _, fallbackName := range parseKeywords(rateFallback) {
+		if fallbackName == name {
+			continue
+		}
+		fallback, err := newRateSource(fallbackName)
+		if err != nil {
+			return nil, fmt.Errorf("-rates-fallback: %v", err)
+		}
+		chain = append(chain, rates.Named{Name: fallbackName, Source: fallback})
+	}
+	if len(chain) == 1 {
+		return source, nil
+	}
+	return chain, nil
+}
 
-	// de-serializamos el resultado.
-	ratio := &conversionRatio{}
-	err = json.Unmarshal(bodyData, ratio)
-	if err != nil {
-		return decimal.Zero, fmt.Errorf("unmarshaling body from mercado libre currency url: %v", err)
+// doRates envía los pedidos de las fuentes de cotizaciones que no son Mercado Libre.
+func doRates(request *http.Request) (*http.Response, error) {
+	return httpClient.Do(request)
+}
+
+// bnaSource devuelve la fuente del Banco Nación, la de -rates-source o -rates-fallback si es
+// esa, así se comparten su cache y -refresh-rate, o una nueva con los mismos flags.
+func bnaSource() *rates.BNA {
+	if bna, ok := rateSource.(*rates.BNA); ok {
+		return bna
+	}
+	if chain, ok := rateSource.(rates.Fallback); ok {
+		for _, named := range chain {
+			if bna, ok := named.Source.(*rates.BNA); ok {
+				return bna
+			}
+		}
 	}
+	return &rates.BNA{Do: doRates, Table: bnaTable, CachePath: bnaCachePath, TTL: bnaTTL, Refresh: refreshRate}
+}
 
-	// lo devolvemos convertido en Decimal.
-	return ratio.Decimal(), nil
+// searchOptions agrupa las opciones que modifican como se busca en cada sitio.
+type searchOptions struct {
+	// timeout es el plazo que tiene cada sitio para completar la búsqueda y la cotización.
+	timeout time.Duration
+	// perSite es la cantidad de resultados que se devolverán por sitio.
+	perSite int
+	// sort es el orden en que le pedimos los resultados a ML, uno de sortPriceDesc,
+	// sortPriceAsc o sortRelevance.
+	sort string
+	// filters son los filtros que se agregan a la búsqueda de ML, por parámetro, por ejemplo
+	// shipping_cost=free.
+	filters map[string]string
+	// freeShipping indica que solo interesan las publicaciones con envío gratis.
+	freeShipping bool
+	// officialStore indica que solo interesan las publicaciones de tiendas oficiales: la de
+	// este ID o cualquiera si es allOfficialStores, vacío para todas las publicaciones.
+	officialStore string
+	// attributes son las características que deben tener las publicaciones, se agregan a
+	// filters y además se verifican en cada publicación.
+	attributes []attributeFilter
+	// ratings indica que se completa la calificación de cada resultado de Mercado Libre y
+	// minRating, si es positivo, la mínima que debe tener.
+	ratings   bool
+	minRating decimal.Decimal
+	// sortBy es como se ordenan los resultados de todos los sitios antes de mostrarlos, uno
+	// de sortByPrice, sortBySite o sortByRate.
+	sortBy string
+	// exclude contiene palabras que, si aparecen en el título, descartan el resultado.
+	exclude []string
+	// autoExclude indica que en lugar de exclude se usen las exclusiones por defecto para
+	// el tipo de producto que se está buscando.
+	autoExclude bool
+	// rates es donde se obtienen las cotizaciones, es compartido por todos los sitios.
+	rates *rateCache
+	// bestSellers indica que el criterio de búsqueda es una categoría y que se busca entre sus
+	// publicaciones mas vendidas en lugar de buscar el texto.
+	bestSellers bool
+	// catalog indica que el criterio se busca en el catálogo de productos de ML y se usa el
+	// precio de la buy box del producto en lugar de buscar publicaciones.
+	catalog bool
+	// pages es la cantidad de páginas de resultados que se piden por sitio.
+	pages int
+	// pageConcurrency es la cantidad máxima de páginas de un sitio que se piden a la vez.
+	pageConcurrency int
+	// retries es la cantidad de veces que se reintenta un sitio que falló.
+	retries int
+	// retryDelay es la espera antes del primer reintento, cada reintento espera un poco mas.
+	retryDelay time.Duration
+	// providers son los nombres de los tipos de Provider cuyos sitios participan de la
+	// comparación.
+	providers []string
+	// ebaySites son los mercados de eBay que se suman a la comparación.
+	ebaySites []mlSite
+	// ebay es el cliente con el que se busca en ebaySites.
+	ebay *ebayClient
+	// amazonSites son los mercados de Amazon que se suman a la comparación.
+	amazonSites []mlSite
+	// amazon es el cliente con el que se busca en amazonSites.
+	amazon *amazonClient
+	// onlySites restringe la comparación a estos IDs de sitio, si está vacío se usan todos.
+	onlySites []string
+	// excludeSites excluye estos IDs de sitio de la comparación.
+	excludeSites []string
+	// onResult, si no es nil, se llama con cada resultado (o sitio que falló) apenas llega,
+	// antes de ordenar y agrupar, siempre desde la misma gorutina.
+	onResult func(siteSearchResult)
 }
 
-// queryForSite hara un pedido de búsqueda y devolverá el resultado mas caro para un site
-// determinado de Mercado Libre. El resultado se devolverá en Dólares EstadoUnidenses si es
-// posible por una cuestión de uniformidad de los resultados (ademas de la moneda de origen)
-// esta pensado para ser llamado dentro de una gorutina, concurrentemente con otros sites.
-func queryForSite(searchCriteria string, site mlSite,
+// queryForSite hara un pedido de búsqueda y devolverá los primeros opts.perSite resultados
+// según opts.sort (por defecto los mas caros) para un site determinado de Mercado Libre, cada
+// uno como un envío separado por el canal.
+// Los resultados se devolverán en Dólares EstadoUnidenses si es posible por una cuestión de
+// uniformidad de los resultados (ademas de la moneda de origen) esta pensado para ser llamado
+// dentro de una gorutina, concurrentemente con otros sites.
+// Si la búsqueda falla se reintenta hasta opts.retries veces, pero todos los intentos deben
+// completarse antes de opts.timeout, de lo contrario el sitio se reporta como vencido.
+func queryForSite(ctx context.Context, searchCriteria string, site mlSite, opts searchOptions,
 	callerWaiting *sync.WaitGroup, result chan siteSearchResult) {
 	// lo primero que haremos es encolar la llamada a Done, del wait group, así cuando
 	// esta función salga, sin importar el resultado se avisará que terminó a quien esté
 	// esperando.
 	defer callerWaiting.Done()
 
+	// derivamos un contexto con plazo para este sitio, todos los pedidos lo comparten.
+	ctx, cancel := context.WithTimeout(ctx, opts.timeout)
+	defer cancel()
+	// todos los pedidos y lineas de log de este sitio llevan su ID de pedido.
+	requestID := newRequestID(site.ID)
+	ctx = withRequestID(ctx, requestID)
+	ctx, span := tracer.Start(ctx, "site "+site.ID, trace.WithAttributes(
+		attribute.String("site.id", site.ID),
+		attribute.String("site.name", site.Name),
+		attribute.String("request.id", requestID),
+	))
+
+	var results []siteSearchResult
+	var err error
+	timing := &siteTiming{}
+	attempts := 0
+	for attempts <= opts.retries {
+		// esperamos un poco mas antes de cada reintento para no insistirle a un sitio con
+		// problemas, salvo que se nos termine el plazo.
+		if attempts > 0 {
+			// si Mercado Libre nos pidió esperar mas, esperamos lo que pidió.
+			delay := time.Duration(attempts) * opts.retryDelay
+			if remaining := mlBackoff.remaining(); remaining > delay {
+				delay = remaining
+			}
+			// si no llegamos a reintentar antes del plazo nos quedamos con el último error.
+			if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+				break
+			}
+			logf(ctx, "%s attempt %d failed, retrying in %s: %v", site.ID, attempts, delay, err)
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+			}
+			if ctx.Err() != nil {
+				break
+			}
+		}
+		attempts++
+		timing = &siteTiming{}
+		results, err = searchSite(withSiteTiming(ctx, timing), searchCriteria, site, opts)
+		if err == nil || !retryable(err) || ctx.Err() != nil {
+			break
+		}
+	}
+
+	span.SetAttributes(attribute.Int("site.attempts", attempts))
+	endSpan(span, err)
+
+	// si fallamos lo indicamos, si el plazo venció lo indicamos para que se reporte como tal
+	// y no como un error cualquiera.
+	if err != nil {
+		result <- siteSearchResult{
+			site:      site,
+			err:       err,
+			attempts:  attempts,
+			timedOut:  ctx.Err() == context.DeadlineExceeded,
+			timing:    *timing,
+			requestID: requestID,
+		}
+		return
+	}
+
+	// enviamos cada resultado por el canal de resultados.
+	for _, r := range results {
+		r.attempts = attempts
+		r.timing = *timing
+		r.requestID = requestID
+		result <- r
+	}
+}
+
+// searchSite realiza un intento de búsqueda en un sitio, junto con la cotización de su moneda,
+// y devuelve los resultados ya convertidos.
+func searchSite(ctx context.Context, searchCriteria string, site mlSite, opts searchOptions) ([]siteSearchResult, error) {
 	// creamos un wait group para la gorutina que obtendrá la cotización.
 	currencyWait := &sync.WaitGroup{}
 	currencyWait.Add(1)
@@ -215,91 +420,138 @@ func queryForSite(searchCriteria string, site mlSite,
 	// para facilitar
 	var currencyRatio decimal.Decimal
 	var currencyError error
+	timing := siteTimingFrom(ctx)
 
 	// llamamos concurrentemente a la función de búsqueda de cotización, cuando termine
 	// lo indicará al wait group.
 	go func() {
 		defer currencyWait.Done()
-		currencyRatio, currencyError = fetchCurrencyRate(site.DefaultCurrencyID)
+		ctx, span := tracer.Start(ctx, "currency", trace.WithAttributes(attribute.String("currency", site.DefaultCurrencyID)))
+		start := time.Now()
+		currencyRatio, currencyError = opts.rates.get(ctx, site.DefaultCurrencyID)
+		timing.currency = time.Since(start)
+		endSpan(span, currencyError)
 	}()
+	// pase lo que pase no dejamos la gorutina de cotización colgada.
+	defer currencyWait.Wait()
 
-	// realizamos la función principal de esta función, buscar el item mas caro
-	body, err := queryML(searchCriteria, site)
-	// si fallamos retornamos enseguida.
-	if err != nil {
-		result <- siteSearchResult{
-			site: site,
-			err:  err,
-		}
-		return
+	// realizamos la función principal de esta función, buscar los items, cada mercado sabe
+	// como hacerlo.
+	if site.provider == nil {
+		return nil, notRetryableError{fmt.Errorf("site %s has no provider", site.ID)}
 	}
-
-	// leemos el cuerpo de la respuesa
-	bodyData, err := ioutil.ReadAll(body)
-		// si fallamos retornamos enseguida.
-	if err != nil {
-		result <- siteSearchResult{
-			site: site,
-			err:  fmt.Errorf("reading mercado libre response body: %v", err),
-		}
-		return
+	searchCtx, span := tracer.Start(ctx, "search")
+	start := time.Now()
+	var searchResults []Listing
+	var total int
+	var err error
+	if searcher, ok := site.provider.(totalSearcher); ok {
+		searchResults, total, err = searcher.searchWithTotal(searchCtx, searchCriteria)
+	} else {
+		searchResults, err = site.provider.Search(searchCtx, searchCriteria)
 	}
-
-	// de-serializamos el cuerpo en un ResultadosML
-	resultML := &ResultadosML{}
-	err = json.Unmarshal(bodyData, &resultML)
-			// si fallamos retornamos enseguida.
+	timing.search = time.Since(start)
+	endSpan(span, err)
+	// si fallamos retornamos enseguida.
 	if err != nil {
-		result <- siteSearchResult{
-			site: site,
-			err:  fmt.Errorf("unmarshaling mercado libre response body: %v", err),
-		}
-		return
+		return nil, err
 	}
-			// si no encontramos resultados retornamos enseguida.
-	if len(resultML.Results) == 0 {
-		result <- siteSearchResult{
-			site: site,
-			err:  fmt.Errorf("results not found in response"),
-		}
-		return
+	// si no encontramos resultados retornamos enseguida.
+	if len(searchResults) == 0 {
+		return nil, notRetryableError{ErrNoResults}
 	}
 
 	// esperamos a la función de cotización para poder hacer la conversión de moneda.
 	currencyWait.Wait()
 	// si la función de cotización falló, retornaremos enseguida
 	if currencyError != nil {
-		result <- siteSearchResult{
-			site: site,
-			err:  fmt.Errorf("getting currency ratio: %v", currencyError),
-		}
-		return
+		return nil, fmt.Errorf("getting currency ratio: %w", currencyError)
 	}
 
 	// Algunos prints útiles para entender la función y como se ejecuta.
 	//fmt.Println(site.Name)
-	//fmt.Println(resultML.Results[0].Title)
-	//fmt.Println(resultML.Results[0].Permalink)
-	mlResult := resultML.Results[0]
-	var price, priceUSD decimal.Decimal
-	// si el precio esta en Dólares EstadoUnidenses originalmente agregaremos la otra
-	// cotización dividiendo el precio en USD / cotización
-	// de lo contrario multiplicaremos el precio en moneda de origen por cotización para
-	// rellenar el precio en USD.
-	if mlResult.CurrencyID == usdCurrencyCode {
-		priceUSD = mlResult.GetPrice()
-		price = priceUSD.Div(currencyRatio)
-	} else {
-		price = mlResult.GetPrice()
-		priceUSD = price.Mul(currencyRatio)
+	//fmt.Println(searchResults[0].Title)
+	//fmt.Println(searchResults[0].Permalink)
+	// descartamos fundas, vidrios y demás accesorios que suelen aparecer en los extremos.
+	exclude := opts.exclude
+	if opts.autoExclude {
+		exclude = exclusionsFor(searchCriteria)
+	}
+	listings := excludeByKeywords(searchResults, exclude)
+	if len(listings) == 0 {
+		return nil, notRetryableError{fmt.Errorf("all %d results were excluded by keyword", len(searchResults))}
 	}
+	// los filtros de ML no son garantía, se verifica cada publicación.
+	if opts.freeShipping {
+		if listings = freeShippingOnly(listings); len(listings) == 0 {
+			return nil, notRetryableError{fmt.Errorf("no results with free shipping")}
+		}
+	}
+	if opts.officialStore != "" {
+		if listings = officialStoreOnly(listings, opts.officialStore); len(listings) == 0 {
+			return nil, notRetryableError{fmt.Errorf("no results from official stores")}
+		}
+	}
+	if len(opts.attributes) > 0 {
+		if listings = attributesOnly(listings, opts.attributes); len(listings) == 0 {
+			return nil, notRetryableError{fmt.Errorf("no results with attributes %s", (*attributeFilters)(&opts.attributes))}
+		}
+	}
+	rate := Rate{From: site.DefaultCurrencyID, To: usdCurrencyCode, Ratio: currencyRatio}
+	results := make([]siteSearchResult, 0, len(listings))
+	for _, listing := range listings {
+		// la publicación puede estar en la moneda del sitio o en Dólares EstadoUnidenses,
+		// en ambos casos completamos el precio en la otra moneda con la cotización.
+		price, err := listing.Price.In(site.DefaultCurrencyID, rate)
+		if err != nil {
+			return nil, notRetryableError{fmt.Errorf("converting price of %s: %v", listing.ID, err)}
+		}
+		priceUSD, err := listing.Price.In(usdCurrencyCode, rate)
+		if err != nil {
+			return nil, notRetryableError{fmt.Errorf("converting price of %s: %v", listing.ID, err)}
+		}
 
-	// enviamos el struct que contiene el resultado por el canal de resultados.
-	result <- siteSearchResult{
-		site:     site,
-		priceUSD: priceUSD,
-		price:    price,
-		item:     resultML.Results[0].Title,
-		ratio:    currencyRatio,
+		results = append(results, siteSearchResult{
+			site:         site,
+			priceUSD:     priceUSD,
+			price:        price,
+			item:         listing.Title,
+			itemID:       listing.ID,
+			permalink:    listing.Permalink,
+			sellerID:     listing.SellerID,
+			installments: listing.Installments,
+			province:     listing.Province,
+
+			catalogProductID: listing.CatalogProductID,
+			city:             listing.City,
+			ratio:            rate,
+		})
+	}
+	// nos quedamos con los primeros opts.perSite resultados según opts.sort: con los órdenes
+	// por precio los mas caros o los mas baratos, que no siempre son los primeros que devuelve
+	// el sitio (por ejemplo por las publicaciones destacadas o las que están en dólares), y
+	// con relevancia los primeros.
+	switch opts.sort {
+	case sortPriceDesc:
+		sort.SliceStable(results, func(i, j int) bool {
+			return results[i].priceUSD.Amount.GreaterThan(results[j].priceUSD.Amount)
+		})
+	case sortPriceAsc:
+		sort.SliceStable(results, func(i, j int) bool {
+			return results[i].priceUSD.Amount.LessThan(results[j].priceUSD.Amount)
+		})
+	}
+	// las opiniones solo están en Mercado Libre.
+	if _, ok := site.provider.(mlProvider); ok && opts.ratings {
+		if results, err = rateResults(ctx, results, opts); err != nil {
+			return nil, err
+		}
+	} else if len(results) > opts.perSite {
+		results = results[:opts.perSite]
+	}
+	for i := range results {
+		results[i].rank = i + 1
+		results[i].total, results[i].seen = total, len(searchResults)
 	}
+	return results, nil
 }
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/perrito666/tutoriales_go/internal/i18n"
+	"github.com/shopspring/decimal"
+)
+
+// Money es un monto junto con su moneda, usar Money en lugar de un decimal.Decimal suelto evita
+// sumar o convertir montos en monedas que no corresponden.
+type Money struct {
+	// Amount es el monto.
+	Amount decimal.Decimal
+	// Currency es el ID de Mercado Libre de la moneda del monto, por ejemplo ARS.
+	Currency string
+}
+
+// NewMoney devuelve un Money de amount en currency.
+func NewMoney(amount decimal.Decimal, currency string) Money {
+	return Money{Amount: amount, Currency: currency}
+}
+
+// Rate es una cotización: una unidad de From equivale a Ratio unidades de To.
+type Rate struct {
+	From  string
+	To    string
+	Ratio decimal.Decimal
+}
+
+// ConvertTo convierte m a la otra moneda de rate: si m está en rate.From se multiplica por la
+// cotización y si está en rate.To se divide. Falla si m está en una moneda que no es ninguna
+// de las dos de rate.
+func (m Money) ConvertTo(rate Rate) (Money, error) {
+	switch m.Currency {
+	case rate.From:
+		return Money{Amount: m.Amount.Mul(rate.Ratio), Currency: rate.To}, nil
+	case rate.To:
+		if rate.Ratio.IsZero() {
+			return Money{}, fmt.Errorf("cannot convert %s to %s with a zero rate", m, rate.From)
+		}
+		return Money{Amount: m.Amount.Div(rate.Ratio), Currency: rate.From}, nil
+	}
+	return Money{}, fmt.Errorf("cannot convert %s with a %s to %s rate", m, rate.From, rate.To)
+}
+
+// In devuelve m expresado en currency, convirtiéndolo con rate solo si hace falta.
+func (m Money) In(currency string, rate Rate) (Money, error) {
+	if m.Currency == currency {
+		return m, nil
+	}
+	converted, err := m.ConvertTo(rate)
+	if err != nil {
+		return Money{}, err
+	}
+	if converted.Currency != currency {
+		return Money{}, fmt.Errorf("cannot convert %s to %s with a %s to %s rate", m, currency, rate.From, rate.To)
+	}
+	return converted, nil
+}
+
+// AmountString devuelve el monto con los decimales de su moneda, ver formatAmount.
+func (m Money) AmountString() string {
+	return formatAmount(m.Amount, m.Currency)
+}
+
+// String devuelve el monto precedido por el símbolo de la moneda en el idioma de la salida, por
+// ejemplo "US$ 1.199,00" en español y "$ 1,199.00" en inglés.
+func (m Money) String() string {
+	return fmt.Sprintf("%s %s", i18n.CurrencySymbol(m.Currency), m.AmountString())
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// openValue es el flag.Value de -open, que se puede usar solo, para abrir la publicación mas
+// barata, o con un sitio, -open=MLC, para abrir la elegida en ese sitio.
+type openValue struct {
+	enabled bool
+	// site es el ID del sitio cuya publicación se abre, vacío para la mas barata.
+	site string
+}
+
+// String devuelve el sitio, "true" si se abre la mas barata o vacío si no se pidió.
+func (o *openValue) String() string {
+	switch {
+	case o == nil || !o.enabled:
+		return ""
+	case o.site == "":
+		return "true"
+	}
+	return o.site
+}
+
+// Set recibe "true" cuando el flag se usa solo, como los booleanos, o el ID del sitio.
+func (o *openValue) Set(value string) error {
+	switch strings.TrimSpace(value) {
+	case "":
+		return fmt.Errorf("-open needs a site ID, for example -open=MLC")
+	case "true":
+		o.enabled, o.site = true, ""
+	case "false":
+		o.enabled, o.site = false, ""
+	default:
+		o.enabled, o.site = true, strings.ToUpper(strings.TrimSpace(value))
+	}
+	return nil
+}
+
+// IsBoolFlag permite usar -open sin valor, el sitio va con =.
+func (o *openValue) IsBoolFlag() bool {
+	return true
+}
+
+// hasSite indica si el sitio con ID id está entre sites.
+func hasSite(sites []mlSite, id string) bool {
+	for _, site := range sites {
+		if strings.EqualFold(site.ID, id) {
+			return true
+		}
+	}
+	return false
+}
+
+// listingToOpen devuelve la publicación del reporte que se abre: la elegida en o.site, la
+// primera según -sort, o la mas barata de todas si no se indicó sitio.
+func (o *openValue) listingToOpen(report *runReport) (reportResult, error) {
+	if o.site == "" {
+		cheapest, _, ok := priceRange(report)
+		if !ok {
+			return reportResult{}, fmt.Errorf("-open: no listings found for %q", report.Query)
+		}
+		return cheapest, nil
+	}
+	sites := make([]string, 0, len(report.Results))
+	for _, r := range report.Results {
+		if r.Rank != 1 {
+			continue
+		}
+		if strings.EqualFold(r.SiteID, o.site) {
+			return r, nil
+		}
+		sites = append(sites, r.SiteID)
+	}
+	return reportResult{}, fmt.Errorf("-open: no listing from %s for %q, sites with results: %s", o.site,
+		report.Query, strings.Join(sites, ", "))
+}
+
+// openListing abre en el navegador la publicación del reporte que indica o.
+func openListing(o *openValue, report *runReport) error {
+	listing, err := o.listingToOpen(report)
+	if err != nil {
+		return err
+	}
+	if listing.Permalink == "" {
+		return fmt.Errorf("-open: the listing from %s has no link", listing.SiteName)
+	}
+	log.Printf("search: opening %s from %s in the browser", listing.Permalink, listing.SiteName)
+	return openInBrowser(listing.Permalink)
+}
+
+// openInBrowser abre url en el navegador predeterminado del sistema.
+func openInBrowser(url string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", url)
+	case "windows":
+		// start interpreta los & de la URL como separadores de comandos de cmd, rundll32 es
+		// lo que usa start para abrir una URL sin pasar por cmd.
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	default:
+		cmd = exec.Command("xdg-open", url)
+	}
+	out, err := cmd.CombinedOutput()
+	if err == nil {
+		return nil
+	}
+	if detail := strings.TrimSpace(string(out)); detail != "" {
+		err = fmt.Errorf("%v: %s", err, detail)
+	}
+	return fmt.Errorf("opening %s with %s: %v", url, cmd.Path, err)
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// defaultSiteTimeout es el plazo por defecto que tiene cada sitio para responder.
+const defaultSiteTimeout = 10 * time.Second
+
+// searchFlags contiene los flags comunes a todos los subcomandos que comparan precios entre
+// sitios, cada subcomando los registra en su propio flag.FlagSet.
+type searchFlags struct {
+	fs *flag.FlagSet
+
+	siteTimeout     *time.Duration
+	perSite         *int
+	sort            *string
+	cheapest        *bool
+	bestSellers     *bool
+	catalog         *bool
+	sortBy          *string
+	freeShipping    *bool
+	officialOnly    *bool
+	officialStore   *string
+	attributes      *attributeFilters
+	ratings         *bool
+	minRating       *float64
+	exclude         *string
+	pages           *int
+	pageConcurrency *int
+	onlySites       *string
+	excludeSites    *string
+	retries         *int
+	retryDelay      *time.Duration
+
+	providers *string
+
+	ebay             *string
+	ebayClientID     *string
+	ebayClientSecret *string
+
+	amazon           *string
+	amazonAccessKey  *string
+	amazonSecretKey  *string
+	amazonPartnerTag *string
+}
+
+// addSearchFlags registra en fs los flags de búsqueda.
+func addSearchFlags(fs *flag.FlagSet) *searchFlags {
+	attributes := &attributeFilters{}
+	fs.Var(attributes, "attr", "característica NOMBRE=valor que deben tener las publicaciones, por ejemplo INTERNAL_MEMORY=256GB, se puede repetir, solo en Mercado Libre")
+	return &searchFlags{
+		fs: fs,
+		siteTimeout: fs.Duration("site-timeout", defaultSiteTimeout,
+			"plazo que tiene cada sitio para responder la búsqueda y la cotización"),
+		perSite:  fs.Int("per-site", 1, "cantidad de publicaciones, en el orden de -sort, que se muestran por sitio"),
+		sort:     fs.String("sort", sortPriceDesc, "orden de los resultados: price_desc, price_asc o relevance"),
+		cheapest: fs.Bool("cheapest", false, "atajo para -sort price_asc, busca donde es mas barato"),
+		bestSellers: fs.Bool("best-sellers", false,
+			"el criterio es una categoría, por ejemplo celulares o MLA1055, y se busca entre sus mas vendidos"),
+		catalog: fs.Bool("catalog", false,
+			"busca el criterio en el catálogo de productos de Mercado Libre y compara el precio de la buy box del producto en cada sitio"),
+		sortBy: fs.String("sort-by", sortByPrice, "orden en que se muestran los resultados: price (en USD), site, site-id o rate"),
+		freeShipping: fs.Bool("free-shipping", false,
+			"solo considera publicaciones con envío gratis, sin costos de entrega escondidos, solo en Mercado Libre"),
+		officialOnly: fs.Bool("official-only", false,
+			"solo considera publicaciones de tiendas oficiales, no de revendedores, solo en Mercado Libre"),
+		officialStore: fs.String("official-store", "", "ID de la tienda oficial de Mercado Libre a la que se restringe la comparación, implica -official-only"),
+		attributes:    attributes,
+		ratings:       fs.Bool("ratings", false, "indica la calificación promedio y la cantidad de opiniones del producto de cada publicación de Mercado Libre"),
+		minRating: fs.Float64("min-rating", 0,
+			"calificación mínima, de 1 a 5, del producto de las publicaciones que se consideran, implica -ratings, solo en Mercado Libre"),
+		exclude: fs.String("exclude", "",
+			"palabras separadas por coma que descartan un resultado si aparecen en su título, "+
+				"por defecto se eligen según el tipo de producto buscado"),
+		pages:           fs.Int("pages", 1, fmt.Sprintf("cantidad de páginas de %d resultados que se piden por sitio", pageSize)),
+		pageConcurrency: fs.Int("page-concurrency", 4, "cantidad máxima de páginas de un mismo sitio que se piden a la vez"),
+		onlySites:       fs.String("sites", "", "IDs de sitio separados por coma a los que se restringe la comparación, por ejemplo MLA,MLB,MLC"),
+		excludeSites:    fs.String("exclude-sites", "", "IDs de sitio separados por coma que se excluyen de la comparación, por ejemplo MCU"),
+		retries:         fs.Int("retries", 2, "cantidad de veces que se reintenta un sitio que falló"),
+		retryDelay:      fs.Duration("retry-delay", 500*time.Millisecond, "espera antes del primer reintento, cada reintento espera un poco mas"),
+
+		providers: fs.String("providers", strings.Join(providerNames(), ","),
+			"tipos de sitio separados por coma que participan de la comparación, por ejemplo mercadolibre,ebay"),
+
+		ebay:             fs.String("ebay", "", "mercados de eBay separados por coma que se suman a la comparación, por ejemplo EBAY_US,EBAY_DE"),
+		ebayClientID:     fs.String("ebay-client-id", "", "client ID de la aplicación de eBay, necesario para -ebay"),
+		ebayClientSecret: fs.String("ebay-client-secret", "", "client secret de la aplicación de eBay, necesario para -ebay"),
+
+		amazon:           fs.String("amazon", "", "mercados de Amazon separados por coma que se suman a la comparación, por ejemplo AMAZON_US,AMAZON_MX"),
+		amazonAccessKey:  fs.String("amazon-access-key", "", "access key de la Product Advertising API, necesaria para -amazon"),
+		amazonSecretKey:  fs.String("amazon-secret-key", "", "secret key de la Product Advertising API, necesaria para -amazon"),
+		amazonPartnerTag: fs.String("amazon-partner-tag", "", "partner tag de Amazon Associates, necesario para -amazon"),
+	}
+}
+
+// options valida los flags de búsqueda y devuelve las searchOptions correspondientes, debe
+// llamarse luego de aplicar el entorno y la configuración.
+func (f *searchFlags) options() (searchOptions, error) {
+	if *f.perSite < 1 {
+		return searchOptions{}, fmt.Errorf("-per-site must be at least 1, got %d", *f.perSite)
+	}
+	if *f.pages < 1 || *f.pageConcurrency < 1 {
+		return searchOptions{}, fmt.Errorf("-pages and -page-concurrency must be at least 1")
+	}
+	if *f.retries < 0 {
+		return searchOptions{}, fmt.Errorf("-retries cannot be negative, got %d", *f.retries)
+	}
+	sort := *f.sort
+	if *f.cheapest {
+		sort = sortPriceAsc
+	}
+	if !validSort(sort) {
+		return searchOptions{}, fmt.Errorf("unknown -sort %q, must be one of %s, %s or %s",
+			sort, sortPriceDesc, sortPriceAsc, sortRelevance)
+	}
+	if !validSortBy(*f.sortBy) {
+		return searchOptions{}, fmt.Errorf("unknown -sort-by %q, must be one of %s, %s, %s or %s",
+			*f.sortBy, sortByPrice, sortBySite, sortBySiteID, sortByRate)
+	}
+	if *f.catalog && *f.bestSellers {
+		return searchOptions{}, fmt.Errorf("-catalog and -best-sellers cannot be used together")
+	}
+	if *f.catalog && len(*f.attributes) > 0 {
+		return searchOptions{}, fmt.Errorf("-attr cannot be used with -catalog, include the attributes in the search criteria")
+	}
+	if *f.minRating < 0 || *f.minRating > maxRating {
+		return searchOptions{}, fmt.Errorf("-min-rating must be between 0 and %d, got %v", maxRating, *f.minRating)
+	}
+	providers := parseKeywords(*f.providers)
+	if err := validProviders(providers); err != nil {
+		return searchOptions{}, err
+	}
+	opts := searchOptions{
+		timeout:     *f.siteTimeout,
+		perSite:     *f.perSite,
+		sort:        sort,
+		sortBy:      *f.sortBy,
+		bestSellers: *f.bestSellers,
+		catalog:     *f.catalog,
+		rates:       newRateCache(*f.siteTimeout),
+
+		freeShipping:  *f.freeShipping,
+		officialStore: *f.officialStore,
+		attributes:    *f.attributes,
+		ratings:       *f.ratings || *f.minRating > 0,
+		minRating:     decimal.NewFromFloat(*f.minRating),
+
+		pages:           *f.pages,
+		pageConcurrency: *f.pageConcurrency,
+		retries:         *f.retries,
+		retryDelay:      *f.retryDelay,
+
+		providers:    providers,
+		onlySites:    parseKeywords(*f.onlySites),
+		excludeSites: parseKeywords(*f.excludeSites),
+	}
+
+	if opts.officialStore != "" {
+		if _, err := strconv.ParseInt(opts.officialStore, 10, 64); err != nil {
+			return searchOptions{}, fmt.Errorf("invalid -official-store %q, must be a numeric store ID", opts.officialStore)
+		}
+	} else if *f.officialOnly {
+		opts.officialStore = allOfficialStores
+	}
+	opts.filters = map[string]string{}
+	if opts.freeShipping {
+		opts.filters[freeShippingFilter] = freeShippingValue
+	}
+	if opts.officialStore != "" {
+		opts.filters[officialStoreFilter] = opts.officialStore
+	}
+	for _, a := range opts.attributes {
+		opts.filters[a.Name] = a.Value
+	}
+
+	if *f.ebay != "" {
+		if *f.ebayClientID == "" || *f.ebayClientSecret == "" {
+			return searchOptions{}, fmt.Errorf("-ebay needs -ebay-client-id and -ebay-client-secret")
+		}
+		sites, err := ebaySites(parseKeywords(*f.ebay))
+		if err != nil {
+			return searchOptions{}, err
+		}
+		opts.ebaySites = sites
+		opts.ebay = newEbayClient(*f.ebayClientID, *f.ebayClientSecret)
+	}
+
+	if *f.amazon != "" {
+		if *f.amazonAccessKey == "" || *f.amazonSecretKey == "" || *f.amazonPartnerTag == "" {
+			return searchOptions{}, fmt.Errorf("-amazon needs -amazon-access-key, -amazon-secret-key and -amazon-partner-tag")
+		}
+		sites, err := amazonSites(parseKeywords(*f.amazon))
+		if err != nil {
+			return searchOptions{}, err
+		}
+		opts.amazonSites = sites
+		opts.amazon = newAmazonClient(*f.amazonAccessKey, *f.amazonSecretKey, *f.amazonPartnerTag)
+	}
+
+	// si no nos indicaron que excluir usamos las exclusiones por defecto para lo que se
+	// esté buscando, pasar -exclude "" desactiva las exclusiones.
+	opts.autoExclude = true
+	f.fs.Visit(func(fl *flag.Flag) {
+		if fl.Name == "exclude" {
+			opts.autoExclude = false
+			opts.exclude = parseKeywords(*f.exclude)
+		}
+	})
+	return opts, nil
+}
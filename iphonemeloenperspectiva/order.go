@@ -0,0 +1,76 @@
+package main
+
+import (
+	"sort"
+)
+
+const (
+	// sortByPrice ordena los resultados por precio en USD, del mas barato al mas caro.
+	sortByPrice = "price"
+	// sortBySite ordena los resultados por nombre de sitio.
+	sortBySite = "site"
+	// sortBySiteID ordena los resultados por ID de sitio, por ejemplo MLA antes que MLB.
+	sortBySiteID = "site-id"
+	// sortByRate ordena los resultados por la cotización de la moneda del sitio a USD.
+	sortByRate = "rate"
+)
+
+// validSortBy indica si by es un orden de resultados conocido.
+func validSortBy(by string) bool {
+	switch by {
+	case sortByPrice, sortBySite, sortBySiteID, sortByRate:
+		return true
+	}
+	return false
+}
+
+// sortResults ordena los resultados según by, los empates se desempatan por sitio y posición
+// dentro del sitio, así el resultado no depende del orden en que respondieron los sitios.
+func sortResults(results []siteSearchResult, by string) {
+	bySite := func(a, b siteSearchResult) bool {
+		if a.site.ID != b.site.ID {
+			return lessSite(a.site, b.site)
+		}
+		return a.rank < b.rank
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		switch by {
+		case sortBySiteID:
+			if a.site.ID != b.site.ID {
+				return a.site.ID < b.site.ID
+			}
+		case sortByPrice:
+			if !a.priceUSD.Amount.Equal(b.priceUSD.Amount) {
+				return a.priceUSD.Amount.LessThan(b.priceUSD.Amount)
+			}
+		case sortByRate:
+			if !a.ratio.Ratio.Equal(b.ratio.Ratio) {
+				return a.ratio.Ratio.LessThan(b.ratio.Ratio)
+			}
+		}
+		return bySite(a, b)
+	})
+}
+
+// lessSite ordena sitios por nombre y, si se llaman igual, por ID.
+func lessSite(a, b mlSite) bool {
+	if a.Name != b.Name {
+		return a.Name < b.Name
+	}
+	return a.ID < b.ID
+}
+
+// sortSites ordena sitios por nombre.
+func sortSites(sites []mlSite) {
+	sort.SliceStable(sites, func(i, j int) bool {
+		return lessSite(sites[i], sites[j])
+	})
+}
+
+// sortFailures ordena los sitios que fallaron por nombre.
+func sortFailures(failed []siteSearchResult) {
+	sort.SliceStable(failed, func(i, j int) bool {
+		return lessSite(failed[i].site, failed[j].site)
+	})
+}
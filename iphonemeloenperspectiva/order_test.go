@@ -0,0 +1,38 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestSortResultsSameSite(t *testing.T) {
+	// el provider de un sitio de Mercado Libre tiene slices y un mapa, los sitios no se pueden
+	// comparar enteros.
+	site := mlSite{ID: "MLA", Name: "Argentina", DefaultCurrencyID: "ARS"}
+	site.provider = mlProvider{site: site, opts: searchOptions{exclude: []string{"funda"}, filters: map[string]string{}}}
+	other := mlSite{ID: "MLB", Name: "Brasil", DefaultCurrencyID: "BRL"}
+	price := NewMoney(decimal.New(1000, 0), usdCurrencyCode)
+	ratio := Rate{From: "ARS", To: usdCurrencyCode, Ratio: decimal.New(1, -3)}
+
+	for _, by := range []string{sortByPrice, sortBySite, sortBySiteID, sortByRate} {
+		t.Run(by, func(t *testing.T) {
+			results := []siteSearchResult{
+				{site: site, rank: 2, priceUSD: price, ratio: ratio},
+				{site: other, rank: 1, priceUSD: price, ratio: ratio},
+				{site: site, rank: 1, priceUSD: price, ratio: ratio},
+			}
+			sortResults(results, by)
+			got := []string{}
+			for _, r := range results {
+				got = append(got, r.site.ID+"#"+string(rune('0'+r.rank)))
+			}
+			want := []string{"MLA#1", "MLA#2", "MLB#1"}
+			for i := range want {
+				if got[i] != want[i] {
+					t.Fatalf("sortResults(%s) = %v, want %v", by, got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/perrito666/tutoriales_go/internal/meli"
+	"golang.org/x/sync/errgroup"
+)
+
+// pageSize es la cantidad máxima de resultados por página que devuelve la búsqueda de ML,
+// es el valor que usamos como limit cuando pedimos mas de una página.
+const pageSize = 50
+
+// searchPage pide a ML una página de resultados con los filtros de filters y la de-serializa,
+// limit 0 indica que no nos interesa paginar y se usa el tamaño de página por defecto de ML.
+// Además de los resultados devuelve cuantas publicaciones coinciden en total.
+func searchPage(ctx context.Context, searchCriteria string, site mlSite, sort string, filters map[string]string, limit, offset int) ([]meli.ResultadoML, int, error) {
+	body, err := queryML(ctx, searchCriteria, site, sort, filters, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	// recordaremos cerrar el cuerpo al finalizar
+	defer body.Close()
+
+	// de-serializamos el cuerpo en un ResultadosML a medida que lo leemos.
+	resultML := &meli.ResultadosML{}
+	err = decodeML(ctx, body, "mercado libre response body", resultML, &mlSearchSchema{})
+	if err != nil {
+		return nil, 0, err
+	}
+	return resultML.Results, resultML.Paging.Total, nil
+}
+
+// searchPages pide opts.pages páginas de resultados de un sitio, como mucho
+// opts.pageConcurrency a la vez, y las devuelve unidas respetando el orden de las páginas
+// junto con cuantas publicaciones coinciden en total según la primera página.
+// Si alguna página falla se cancelan las demás y se devuelve el error.
+func searchPages(ctx context.Context, searchCriteria string, site mlSite, opts searchOptions) ([]meli.ResultadoML, int, error) {
+	// sin paginación hacemos un único pedido, tal como lo haría ML por defecto.
+	if opts.pages <= 1 {
+		return searchPage(ctx, searchCriteria, site, opts.sort, opts.filters, 0, 0)
+	}
+
+	// cada gorutina escribe solo su posición del slice, así no necesitamos sincronizar el
+	// acceso y al final unimos las páginas en orden.
+	pages := make([][]meli.ResultadoML, opts.pages)
+	totals := make([]int, opts.pages)
+	group, ctx := errgroup.WithContext(ctx)
+	group.SetLimit(opts.pageConcurrency)
+	for i := range pages {
+		i := i
+		group.Go(func() error {
+			page, total, err := searchPage(ctx, searchCriteria, site, opts.sort, opts.filters, pageSize, i*pageSize)
+			if err != nil {
+				return fmt.Errorf("fetching page %d: %w", i+1, err)
+			}
+			pages[i], totals[i] = page, total
+			return nil
+		})
+	}
+	if err := group.Wait(); err != nil {
+		return nil, 0, err
+	}
+
+	results := make([]meli.ResultadoML, 0, opts.pages*pageSize)
+	for _, page := range pages {
+		results = append(results, page...)
+	}
+	return results, totals[0], nil
+}
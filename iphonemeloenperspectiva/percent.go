@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// percentValue es un flag.Value para porcentajes, acepta tanto "10%" como "10".
+type percentValue struct {
+	percent decimal.Decimal
+}
+
+// String devuelve el porcentaje con el signo %, o vacío si es cero.
+func (p *percentValue) String() string {
+	if p == nil || p.percent.IsZero() {
+		return ""
+	}
+	return p.percent.String() + "%"
+}
+
+// Set interpreta un porcentaje, que no puede ser negativo.
+func (p *percentValue) Set(value string) error {
+	percent, err := decimal.NewFromString(strings.TrimSuffix(strings.TrimSpace(value), "%"))
+	if err != nil {
+		return fmt.Errorf("invalid percentage %q", value)
+	}
+	if percent.IsNegative() {
+		return fmt.Errorf("percentage cannot be negative, got %s", value)
+	}
+	p.percent = percent
+	return nil
+}
+
+// percentChange devuelve cuanto cambió current respecto de previous, en porcentaje, cero si
+// previous es cero.
+func percentChange(previous, current decimal.Decimal) decimal.Decimal {
+	if previous.IsZero() {
+		return decimal.Zero
+	}
+	return current.Sub(previous).Div(previous).Mul(decimal.New(100, 0))
+}
@@ -0,0 +1,5 @@
+// Package pricepb contiene los tipos y el servicio gRPC PriceService generados a partir de
+// price.proto, no se editan a mano: luego de modificar price.proto se regeneran con go generate.
+package pricepb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative price.proto
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"runtime"
+	runtimepprof "runtime/pprof"
+)
+
+// profiler escribe los perfiles de CPU y memoria que se pidieron con -cpuprofile y
+// -memprofile. Se inicia al terminar de procesar los flags y se detiene cuando termina el
+// comando, así los perfiles muestran solo el trabajo del comando.
+type profiler struct {
+	cpuPath string
+	memPath string
+	cpuFile *os.File
+}
+
+// profiling es el profiler del comando que se está ejecutando.
+var profiling profiler
+
+// start empieza el perfil de CPU, si se pidió.
+func (p *profiler) start() error {
+	if p.cpuPath == "" {
+		return nil
+	}
+	f, err := os.Create(p.cpuPath)
+	if err != nil {
+		return fmt.Errorf("creating cpu profile: %v", err)
+	}
+	if err := runtimepprof.StartCPUProfile(f); err != nil {
+		f.Close()
+		return fmt.Errorf("starting cpu profile: %v", err)
+	}
+	p.cpuFile = f
+	return nil
+}
+
+// stop termina el perfil de CPU y escribe el de memoria, si se pidieron.
+func (p *profiler) stop() error {
+	if p.cpuFile != nil {
+		runtimepprof.StopCPUProfile()
+		err := p.cpuFile.Close()
+		p.cpuFile = nil
+		if err != nil {
+			return fmt.Errorf("writing cpu profile: %v", err)
+		}
+	}
+	if p.memPath == "" {
+		return nil
+	}
+	f, err := os.Create(p.memPath)
+	if err != nil {
+		return fmt.Errorf("creating memory profile: %v", err)
+	}
+	defer f.Close()
+	// el perfil de memoria muestra lo que quedó en uso luego de la última recolección.
+	runtime.GC()
+	if err := runtimepprof.WriteHeapProfile(f); err != nil {
+		return fmt.Errorf("writing memory profile: %v", err)
+	}
+	return f.Close()
+}
+
+// registerPprof agrega a mux los endpoints de net/http/pprof bajo /debug/pprof/, cada uno
+// envuelto por wrap, por ejemplo para exigir una clave de API.
+func registerPprof(mux *http.ServeMux, wrap func(http.Handler) http.Handler) {
+	mux.Handle("/debug/pprof/", wrap(http.HandlerFunc(pprof.Index)))
+	mux.Handle("/debug/pprof/cmdline", wrap(http.HandlerFunc(pprof.Cmdline)))
+	mux.Handle("/debug/pprof/profile", wrap(http.HandlerFunc(pprof.Profile)))
+	mux.Handle("/debug/pprof/symbol", wrap(http.HandlerFunc(pprof.Symbol)))
+	mux.Handle("/debug/pprof/trace", wrap(http.HandlerFunc(pprof.Trace)))
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/perrito666/tutoriales_go/internal/meli"
+)
+
+// Listing es una publicación encontrada por un Provider, con los datos que nos interesan sin
+// importar de que mercado venga.
+type Listing struct {
+	// ID identifica la publicación, no debe repetirse entre publicaciones distintas de
+	// ningún mercado.
+	ID    string
+	Title string
+	// Permalink es la URL de la publicación.
+	Permalink string
+	// SellerID identifica al vendedor, vacío si el mercado no lo indica.
+	SellerID string
+	// Price es el precio, en la moneda en que está publicado.
+	Price Money
+	// FreeShipping indica que el envío es gratis, solo lo indica Mercado Libre.
+	FreeShipping bool
+	// OfficialStoreID identifica la tienda oficial que publica, vacío si no es una tienda
+	// oficial o el mercado no lo indica.
+	OfficialStoreID string
+	// Installments es la financiación en cuotas, nil si el mercado no la indica.
+	Installments *Installments
+	// CatalogProductID identifica el producto de catálogo de la publicación, vacío si no
+	// está asociada a uno o el mercado no lo indica.
+	CatalogProductID string
+	// Attributes son las características del producto por ID, por ejemplo INTERNAL_MEMORY,
+	// cada una con su valor y el ID del valor si lo tiene. Vacío si el mercado no las indica.
+	Attributes map[string][]string
+	// Province y City son la provincia (o estado) y la ciudad desde donde se vende, vacíos si
+	// el mercado no los indica.
+	Province string
+	City     string
+}
+
+// Provider es un sitio donde se pueden buscar publicaciones: un sitio de Mercado Libre, un
+// mercado de eBay o de Amazon o cualquier otro que se registre. Cada Provider se crea con las
+// opciones de búsqueda, así Search solo necesita el criterio.
+type Provider interface {
+	// Name devuelve el nombre del sitio.
+	Name() string
+	// Search devuelve las publicaciones que corresponden al criterio, en el orden en que se
+	// deben considerar, la búsqueda se cancela si el contexto expira.
+	Search(ctx context.Context, query string) ([]Listing, error)
+}
+
+// totalSearcher lo implementan los Provider que, además de las publicaciones que devuelven,
+// saben cuantas coinciden en total con el criterio, así el reporte puede avisar que solo se
+// vio una parte.
+type totalSearcher interface {
+	searchWithTotal(ctx context.Context, query string) ([]Listing, int, error)
+}
+
+// providerSource devuelve los sitios de un tipo de Provider que participan de la comparación
+// según opts, cada uno con su Provider.
+type providerSource func(opts searchOptions) ([]mlSite, error)
+
+// registeredProvider es un tipo de Provider que se puede habilitar por nombre.
+type registeredProvider struct {
+	name  string
+	sites providerSource
+}
+
+// providerRegistry contiene los tipos de Provider disponibles, en el orden en que se agregan
+// sus sitios a la comparación.
+var providerRegistry = []registeredProvider{
+	{"mercadolibre", mercadoLibreSites},
+	{"ebay", ebayProviderSites},
+	{"amazon", amazonProviderSites},
+}
+
+// registerProvider agrega un tipo de Provider al registro, por ejemplo un scraper propio, para
+// que se pueda habilitar con -providers.
+func registerProvider(name string, sites providerSource) {
+	providerRegistry = append(providerRegistry, registeredProvider{name: name, sites: sites})
+}
+
+// providerNames devuelve los nombres de todos los tipos de Provider registrados.
+func providerNames() []string {
+	names := make([]string, 0, len(providerRegistry))
+	for _, p := range providerRegistry {
+		names = append(names, p.name)
+	}
+	return names
+}
+
+// validProviders verifica que todos los nombres correspondan a un Provider registrado.
+func validProviders(names []string) error {
+	for _, name := range names {
+		found := false
+		for _, p := range providerRegistry {
+			if strings.EqualFold(p.name, name) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("unknown provider %q, must be one of %s", name, strings.Join(providerNames(), ", "))
+		}
+	}
+	return nil
+}
+
+// providerSites devuelve los sitios de todos los tipos de Provider habilitados en opts.
+func providerSites(opts searchOptions) ([]mlSite, error) {
+	sites := []mlSite{}
+	for _, p := range providerRegistry {
+		enabled := false
+		for _, name := range opts.providers {
+			if strings.EqualFold(p.name, name) {
+				enabled = true
+				break
+			}
+		}
+		if !enabled {
+			continue
+		}
+		providerSites, err := p.sites(opts)
+		if err != nil {
+			return nil, err
+		}
+		sites = append(sites, providerSites...)
+	}
+	return sites, nil
+}
+
+// mlProvider busca en un sitio de Mercado Libre.
+type mlProvider struct {
+	site mlSite
+	opts searchOptions
+}
+
+// mercadoLibreSites devuelve los sitios de Mercado Libre que eligió el usuario con -sites y
+// -exclude-sites.
+func mercadoLibreSites(opts searchOptions) ([]mlSite, error) {
+	sites, err := fetchSites()
+	if err != nil {
+		return nil, fmt.Errorf("could not obtain mercado libre sites: %w", err)
+	}
+	sites = filterSites(sites, opts.onlySites, opts.excludeSites)
+	for i := range sites {
+		sites[i].provider = mlProvider{site: sites[i], opts: opts}
+	}
+	return sites, nil
+}
+
+// Name devuelve el nombre del sitio.
+func (p mlProvider) Name() string {
+	return p.site.Name
+}
+
+// Search busca el criterio en el sitio, entre los mas vendidos si opts.bestSellers o en el
+// catálogo de productos si opts.catalog.
+func (p mlProvider) Search(ctx context.Context, query string) ([]Listing, error) {
+	listings, _, err := p.searchWithTotal(ctx, query)
+	return listings, err
+}
+
+// searchWithTotal es Search, pero además devuelve cuantas publicaciones coinciden en total
+// según la paginación de ML, cero entre los mas vendidos y en el catálogo donde no se sabe.
+func (p mlProvider) searchWithTotal(ctx context.Context, query string) ([]Listing, int, error) {
+	var results []meli.ResultadoML
+	var total int
+	var err error
+	switch {
+	case p.opts.bestSellers:
+		results, err = searchBestSellers(ctx, query, p.site, p.opts)
+	case p.opts.catalog:
+		results, err = searchCatalog(ctx, query, p.site)
+	default:
+		results, total, err = searchPages(ctx, query, p.site, p.opts)
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	listings := make([]Listing, 0, len(results))
+	for _, r := range results {
+		listings = append(listings, mlListing(r))
+	}
+	return listings, total, nil
+}
+
+// mlListing convierte un resultado de Mercado Libre en un Listing.
+func mlListing(r meli.ResultadoML) Listing {
+	sellerID := ""
+	if r.Seller.ID != 0 {
+		sellerID = strconv.FormatInt(r.Seller.ID, 10)
+	}
+	listing := Listing{
+		ID:        r.ID,
+		Title:     r.Title,
+		Permalink: r.Permalink,
+		SellerID:  sellerID,
+		Price:     NewMoney(r.GetPrice(), r.CurrencyID),
+
+		FreeShipping:     r.Shipping.FreeShipping,
+		CatalogProductID: r.CatalogProductID,
+	}
+	listing.Province, listing.City = r.Location()
+	if len(r.Attributes) > 0 {
+		listing.Attributes = map[string][]string{}
+		for _, a := range r.Attributes {
+			listing.Attributes[a.ID] = append(listing.Attributes[a.ID], a.ValueName)
+			if a.ValueID != "" {
+				listing.Attributes[a.ID] = append(listing.Attributes[a.ID], a.ValueID)
+			}
+		}
+	}
+	if r.OfficialStoreID != nil {
+		listing.OfficialStoreID = strconv.FormatInt(*r.OfficialStoreID, 10)
+	}
+	if r.Installments != nil {
+		currency := r.Installments.CurrencyID
+		if currency == "" {
+			currency = r.CurrencyID
+		}
+		listing.Installments = &Installments{
+			Quantity: r.Installments.Quantity,
+			Amount:   NewMoney(r.Installments.Amount, currency),
+			Rate:     r.Installments.Rate,
+		}
+	}
+	return listing
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/perrito666/tutoriales_go/internal/i18n"
+	"github.com/perrito666/tutoriales_go/internal/qr"
+)
+
+// qrScale es cuantos pixeles de lado ocupa cada módulo de los PNG de -qr-dir.
+const qrScale = 8
+
+// selectedListings devuelve la publicación elegida en cada sitio del reporte, la primera según
+// -sort, sin las que no tienen link.
+func selectedListings(report *runReport) []reportResult {
+	var selected []reportResult
+	for _, r := range report.Results {
+		if r.Rank == 1 && r.Permalink != "" {
+			selected = append(selected, r)
+		}
+	}
+	return selected
+}
+
+// writeTextQR escribe en w el código QR del link de la publicación elegida en cada sitio,
+// para abrirla con el teléfono cuando la comparación corre en un servidor.
+func writeTextQR(w io.Writer, report *runReport) error {
+	for _, r := range selectedListings(report) {
+		code, err := qr.Encode(r.Permalink)
+		if err != nil {
+			return fmt.Errorf("qr code for %s: %v", r.SiteName, err)
+		}
+		fmt.Fprintf(w, i18n.T("\nCódigo QR de %q en %q, %s:\n"), report.Query, r.SiteName, r.Permalink)
+		fmt.Fprint(w, code.Text())
+	}
+	return nil
+}
+
+// writeQRFiles guarda en dir un PNG con el código QR del link de la publicación elegida en
+// cada sitio, llamado como el sitio y la publicación, y crea dir si no existe.
+func writeQRFiles(dir string, report *runReport) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("creating qr dir: %v", err)
+	}
+	for _, r := range selectedListings(report) {
+		code, err := qr.Encode(r.Permalink)
+		if err != nil {
+			return fmt.Errorf("qr code for %s: %v", r.SiteName, err)
+		}
+		path := filepath.Join(dir, qrFileName(r))
+		if err := writePNG(path, code); err != nil {
+			return fmt.Errorf("saving qr code for %s: %v", r.SiteName, err)
+		}
+	}
+	return nil
+}
+
+// qrFileName devuelve el nombre del PNG de r, con los caracteres que no pueden ir en un nombre
+// de archivo reemplazados, como las barras de los IDs de eBay.
+func qrFileName(r reportResult) string {
+	name := strings.Map(func(c rune) rune {
+		if c == '-' || c == '_' || (c >= '0' && c <= '9') || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') {
+			return c
+		}
+		return '_'
+	}, r.SiteID+"-"+r.ItemID)
+	return name + ".png"
+}
+
+// writePNG guarda code como PNG en path.
+func writePNG(path string, code *qr.Code) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, code.Image(qrScale)); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/perrito666/tutoriales_go/internal/i18n"
+)
+
+const (
+	// mlQuestionsURL es el endpoint de búsqueda de preguntas.
+	mlQuestionsURL = "https://api.mercadolibre.com/questions/search"
+	// questionsLimit es la cantidad de preguntas, las mas recientes, que se piden por
+	// publicación.
+	questionsLimit = 50
+	// questionUnanswered es el estado de una pregunta que el vendedor no respondió.
+	questionUnanswered = "UNANSWERED"
+)
+
+// mlQuestions imita la estructura JSON de la búsqueda de preguntas de una publicación.
+type mlQuestions struct {
+	Total     int          `json:"total"`
+	Questions []mlQuestion `json:"questions"`
+}
+
+// mlQuestion es una pregunta a una publicación, con su respuesta si la tiene.
+type mlQuestion struct {
+	Status      string    `json:"status"`
+	DateCreated time.Time `json:"date_created"`
+	Answer      *struct {
+		DateCreated time.Time `json:"date_created"`
+	} `json:"answer"`
+}
+
+// reportQuestions resume las preguntas a una publicación, sirve para saber si una publicación
+// demasiado buena para ser cierta está activa y el vendedor responde.
+type reportQuestions struct {
+	// Total es la cantidad de preguntas que recibió la publicación.
+	Total int `json:"total"`
+	// Unanswered es cuantas de las mas recientes, hasta questionsLimit, no tienen respuesta.
+	Unanswered int `json:"unanswered"`
+	// LastActivity es la última pregunta o respuesta, no está si no hay preguntas.
+	LastActivity *time.Time `json:"last_activity,omitempty"`
+}
+
+// questionsURL devuelve la URL de las preguntas mas recientes de itemID.
+func questionsURL(itemID string) (string, error) {
+	questionsURL, err := url.Parse(mlQuestionsURL)
+	if err != nil {
+		return "", fmt.Errorf("parsing mercado libre questions url: %w", err)
+	}
+	queryValues := questionsURL.Query()
+	queryValues.Set("item", itemID)
+	queryValues.Set("sort_fields", "date_created")
+	queryValues.Set("sort_types", "DESC")
+	queryValues.Set("limit", strconv.Itoa(questionsLimit))
+	questionsURL.RawQuery = queryValues.Encode()
+	return questionsURL.String(), nil
+}
+
+// summarizeQuestions resume las preguntas de una publicación.
+func summarizeQuestions(questions mlQuestions) *reportQuestions {
+	summary := &reportQuestions{Total: questions.Total}
+	var last time.Time
+	for _, q := range questions.Questions {
+		if q.Status == questionUnanswered {
+			summary.Unanswered++
+		}
+		if q.DateCreated.After(last) {
+			last = q.DateCreated
+		}
+		if q.Answer != nil && q.Answer.DateCreated.After(last) {
+			last = q.Answer.DateCreated
+		}
+	}
+	if !last.IsZero() {
+		summary.LastActivity = &last
+	}
+	return summary
+}
+
+// formatQuestions describe el resumen de preguntas, por ejemplo "Tiene 12 preguntas, 3 sin
+// responder, la última actividad fue el 2026-10-10".
+func formatQuestions(q reportQuestions) string {
+	if q.Total == 0 {
+		return i18n.T("No tiene preguntas")
+	}
+	text := fmt.Sprintf(i18n.T("Tiene %d preguntas, %d sin responder"), q.Total, q.Unanswered)
+	if q.Total > questionsLimit {
+		text += fmt.Sprintf(i18n.T(" entre las últimas %d"), questionsLimit)
+	}
+	if q.LastActivity != nil {
+		text += fmt.Sprintf(i18n.T(", la última actividad fue el %s"), q.LastActivity.Local().Format("2006-01-02"))
+	}
+	return text
+}
+
+// fetchQuestions devuelve el resumen de las preguntas de itemID.
+func fetchQuestions(ctx context.Context, itemID string) (*reportQuestions, error) {
+	questionsURL, err := questionsURL(itemID)
+	if err != nil {
+		return nil, err
+	}
+	questions := mlQuestions{}
+	if err := getML(ctx, questionsURL, &questions, &mlQuestionsSchema{}); err != nil {
+		return nil, fmt.Errorf("fetching questions: %w", err)
+	}
+	return summarizeQuestions(questions), nil
+}
+
+// applyQuestions completa el resumen de preguntas de cada resultado de Mercado Libre del
+// reporte, cada pedido tiene timeout de plazo. Las preguntas que no se pudieron obtener se
+// informan en el log y el resultado queda sin ellas.
+func applyQuestions(ctx context.Context, report *runReport, timeout time.Duration) {
+	forEachMLResult(ctx, report, timeout, func(ctx context.Context, r *reportResult) {
+		questions, err := fetchQuestions(ctx, r.ItemID)
+		if err != nil {
+			log.Printf("search: questions of %s: %v", r.ItemID, err)
+			return
+		}
+		r.Questions = questions
+	})
+}
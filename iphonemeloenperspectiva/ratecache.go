@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"golang.org/x/sync/singleflight"
+)
+
+// rateCache evita pedir la misma cotización mas de una vez por ejecución, varios sitios
+// comparten moneda (o publican en USD) y no tiene sentido que cada gorutina haga su pedido.
+// Los pedidos concurrentes de la misma moneda se unifican con singleflight y una vez
+// obtenida la cotización se guarda para los pedidos posteriores.
+type rateCache struct {
+	group singleflight.Group
+	// timeout es el plazo del pedido compartido de cada moneda.
+	timeout time.Duration
+
+	mu    sync.Mutex
+	rates map[string]decimal.Decimal
+}
+
+// newRateCache devuelve un rateCache vacío listo para usar, cuyos pedidos tienen el plazo
+// timeout, normalmente el de un sitio.
+func newRateCache(timeout time.Duration) *rateCache {
+	return &rateCache{timeout: timeout, rates: map[string]decimal.Decimal{}}
+}
+
+// get devuelve la cotización de sourceCurrency a USD, pidiéndola a rateSource solo si nadie lo hizo
+// antes. Si hay un pedido en curso para la misma moneda se espera su resultado. El pedido es de
+// todos los que esperan esa moneda, así que no se cancela con el contexto de quien lo inició: si
+// ese sitio se cancela o vence los demás siguen esperando, y cada uno deja de esperar cuando
+// vence su propio ctx.
+func (c *rateCache) get(ctx context.Context, sourceCurrency string) (decimal.Decimal, error) {
+	c.mu.Lock()
+	rate, ok := c.rates[sourceCurrency]
+	c.mu.Unlock()
+	if ok {
+		return rate, nil
+	}
+
+	fetched := c.group.DoChan(sourceCurrency, func() (interface{}, error) {
+		fetchCtx, cancel := context.WithTimeout(detachedContext{ctx}, c.timeout)
+		defer cancel()
+		rate, err := rateSource.ToUSD(fetchCtx, sourceCurrency)
+		if err != nil {
+			// los errores no se guardan, el próximo pedido lo volverá a intentar.
+			return decimal.Zero, err
+		}
+		c.mu.Lock()
+		c.rates[sourceCurrency] = rate
+		c.mu.Unlock()
+		return rate, nil
+	})
+	select {
+	case <-ctx.Done():
+		return decimal.Zero, ctx.Err()
+	case result := <-fetched:
+		if result.Err != nil {
+			return decimal.Zero, result.Err
+		}
+		return result.Val.(decimal.Decimal), nil
+	}
+}
+
+// detachedContext conserva los valores de un contexto, como el ID de pedido del log y la traza,
+// sin su plazo ni su cancelación.
+type detachedContext struct {
+	context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}       { return nil }
+func (detachedContext) Err() error                  { return nil }
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// blockingSource es una fuente de cotizaciones que responde recién cuando se cierra release.
+type blockingSource struct {
+	started chan struct{}
+	release chan struct{}
+	calls   *int32
+	once    *sync.Once
+}
+
+func (s blockingSource) ToUSD(ctx context.Context, currency string) (decimal.Decimal, error) {
+	atomic.AddInt32(s.calls, 1)
+	s.once.Do(func() { close(s.started) })
+	select {
+	case <-s.release:
+		return decimal.NewFromFloat(0.001), nil
+	case <-ctx.Done():
+		return decimal.Zero, ctx.Err()
+	}
+}
+
+func (blockingSource) URL(currency string) (string, error) { return "", nil }
+
+func TestRateCacheSharedFetchOutlivesFirstCaller(t *testing.T) {
+	source := blockingSource{started: make(chan struct{}), release: make(chan struct{}), calls: new(int32), once: &sync.Once{}}
+	previous := rateSource
+	rateSource = source
+	defer func() { rateSource = previous }()
+
+	cache := newRateCache(time.Minute)
+	firstCtx, cancelFirst := context.WithCancel(context.Background())
+	firstErr := make(chan error)
+	go func() {
+		_, err := cache.get(firstCtx, "ARS")
+		firstErr <- err
+	}()
+	<-source.started
+
+	second := make(chan error)
+	var rate decimal.Decimal
+	go func() {
+		var err error
+		rate, err = cache.get(context.Background(), "ARS")
+		second <- err
+	}()
+
+	// le damos tiempo al segundo sitio a sumarse al pedido en curso.
+	time.Sleep(50 * time.Millisecond)
+	// el sitio que inició el pedido se cancela, solo él deja de esperar.
+	cancelFirst()
+	if err := <-firstErr; err != context.Canceled {
+		t.Fatalf("first caller error = %v, want %v", err, context.Canceled)
+	}
+	close(source.release)
+	if err := <-second; err != nil {
+		t.Fatalf("second caller error = %v, want the shared rate", err)
+	}
+	if calls := atomic.LoadInt32(source.calls); calls != 1 {
+		t.Errorf("rate fetched %d times, want once", calls)
+	}
+	if !rate.Equal(decimal.NewFromFloat(0.001)) {
+		t.Errorf("rate = %s, want 0.001", rate)
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// clientIdleTimeout es cuanto tiempo sin pedidos tiene que pasar para olvidar el límite de
+// un cliente, así la tabla no crece para siempre.
+const clientIdleTimeout = 10 * time.Minute
+
+// clientLimiter limita la cantidad de pedidos que puede hacer cada cliente, identificado por
+// su clave de API o, si no usa una, por su IP. Cada búsqueda dispara un pedido a cada sitio,
+// sin un límite un solo cliente puede hacer que Mercado Libre nos bloquee a todos.
+type clientLimiter struct {
+	limit rate.Limit
+	burst int
+
+	mu        sync.Mutex
+	clients   map[string]*clientRate
+	lastSweep time.Time
+}
+
+// clientRate es el limitador de un cliente y la última vez que hizo un pedido.
+type clientRate struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// newClientLimiter devuelve un clientLimiter que permite perMinute pedidos por minuto a cada
+// cliente, con ráfagas de hasta burst pedidos. Si perMinute es 0 devuelve nil, sin límite.
+func newClientLimiter(perMinute float64, burst int) *clientLimiter {
+	if perMinute <= 0 {
+		return nil
+	}
+	return &clientLimiter{
+		limit:   rate.Limit(perMinute / 60),
+		burst:   burst,
+		clients: map[string]*clientRate{},
+	}
+}
+
+// allow indica si el cliente puede hacer un pedido ahora o, si no, cuanto debe esperar.
+func (l *clientLimiter) allow(client string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	if now.Sub(l.lastSweep) > clientIdleTimeout {
+		for id, c := range l.clients {
+			if now.Sub(c.lastSeen) > clientIdleTimeout {
+				delete(l.clients, id)
+			}
+		}
+		l.lastSweep = now
+	}
+	c, ok := l.clients[client]
+	if !ok {
+		c = &clientRate{limiter: rate.NewLimiter(l.limit, l.burst)}
+		l.clients[client] = c
+	}
+	c.lastSeen = now
+
+	reservation := c.limiter.ReserveN(now, 1)
+	if delay := reservation.DelayFrom(now); delay > 0 {
+		// no vamos a esperar, devolvemos el lugar para no castigar de mas al cliente.
+		reservation.CancelAt(now)
+		return false, delay
+	}
+	return true, 0
+}
+
+// clientID identifica al cliente de un pedido: la etiqueta de su clave de API si se
+// autenticó o su IP.
+func clientID(ctx context.Context, remoteAddr string) string {
+	if label, ok := apiKeyLabel(ctx); ok {
+		return "key:" + label
+	}
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	return "ip:" + host
+}
+
+// retryAfter devuelve los segundos enteros que hay que esperar, como los espera Retry-After.
+func retryAfter(delay time.Duration) int {
+	return int(math.Ceil(delay.Seconds()))
+}
+
+// limitHTTP responde 429 a los pedidos de un cliente que superó su límite, indicando en
+// Retry-After cuando puede volver a intentar. Un limitador nil no limita.
+func (l *clientLimiter) limitHTTP(next http.Handler) http.Handler {
+	if l == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		client := clientID(r.Context(), r.RemoteAddr)
+		if ok, delay := l.allow(client); !ok {
+			log.Printf("rate limited %s: %s %s", client, r.Method, r.URL.Path)
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfter(delay)))
+			writeError(w, http.StatusTooManyRequests, fmt.Errorf("rate limit exceeded, retry in %ds", retryAfter(delay)))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// limitGRPC verifica el límite del cliente de una llamada gRPC.
+func (l *clientLimiter) limitGRPC(ctx context.Context, method string) error {
+	remoteAddr := ""
+	if p, ok := peer.FromContext(ctx); ok {
+		remoteAddr = p.Addr.String()
+	}
+	client := clientID(ctx, remoteAddr)
+	if ok, delay := l.allow(client); !ok {
+		log.Printf("rate limited %s: grpc %s", client, method)
+		return status.Errorf(codes.ResourceExhausted, "rate limit exceeded, retry in %ds", retryAfter(delay))
+	}
+	return nil
+}
+
+// grpcInterceptors devuelve los interceptores que limitan cada llamada gRPC, nil si el
+// limitador es nil.
+func (l *clientLimiter) grpcInterceptors() (grpc.UnaryServerInterceptor, grpc.StreamServerInterceptor) {
+	if l == nil {
+		return nil, nil
+	}
+	unary := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := l.limitGRPC(ctx, info.FullMethod); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+	stream := func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := l.limitGRPC(ss.Context(), info.FullMethod); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+	return unary, stream
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	// mlReviewsURL es el endpoint de las opiniones de una publicación.
+	mlReviewsURL = "https://api.mercadolibre.com/reviews/item/%s"
+	// maxRatingLookups es la cantidad máxima de productos distintos cuyas opiniones se piden por
+	// sitio buscando publicaciones que alcancen -min-rating.
+	maxRatingLookups = 10
+	// maxRating es la calificación máxima de ML, en estrellas.
+	maxRating = 5
+)
+
+// Rating es la calificación de los compradores del producto de una publicación.
+type Rating struct {
+	// Average es el promedio de las calificaciones, de 1 a maxRating estrellas.
+	Average decimal.Decimal
+	// Count es la cantidad de opiniones.
+	Count int
+}
+
+// mlReviews imita la estructura JSON de las opiniones de una publicación.
+type mlReviews struct {
+	Paging struct {
+		Total int `json:"total"`
+	} `json:"paging"`
+	RatingAverage decimal.Decimal `json:"rating_average"`
+}
+
+// reviewsURL devuelve la URL de las opiniones de itemID, las del producto de catálogo
+// catalogProductID si no está vacío, que junta las de todas sus publicaciones.
+func reviewsURL(itemID, catalogProductID string) (string, error) {
+	reviewsURL, err := url.Parse(fmt.Sprintf(mlReviewsURL, url.PathEscape(itemID)))
+	if err != nil {
+		return "", fmt.Errorf("parsing mercado libre reviews url: %w", err)
+	}
+	if catalogProductID != "" {
+		queryValues := reviewsURL.Query()
+		queryValues.Set("catalog_product_id", catalogProductID)
+		reviewsURL.RawQuery = queryValues.Encode()
+	}
+	return reviewsURL.String(), nil
+}
+
+// fetchRating devuelve la calificación del producto de la publicación itemID.
+func fetchRating(ctx context.Context, itemID, catalogProductID string) (Rating, error) {
+	reviewsURL, err := reviewsURL(itemID, catalogProductID)
+	if err != nil {
+		return Rating{}, err
+	}
+	reviews := mlReviews{}
+	if err := getML(ctx, reviewsURL, &reviews, &mlReviewsSchema{}); err != nil {
+		return Rating{}, fmt.Errorf("fetching reviews of %s: %w", itemID, err)
+	}
+	return Rating{Average: reviews.RatingAverage, Count: reviews.Paging.Total}, nil
+}
+
+// atLeast indica si la calificación tiene opiniones y su promedio alcanza min.
+func (r Rating) atLeast(min decimal.Decimal) bool {
+	return r.Count > 0 && r.Average.GreaterThanOrEqual(min)
+}
+
+// rateResults completa la calificación de los primeros opts.perSite resultados, que ya deben
+// estar en el orden de opts.sort. Con opts.minRating descarta los que no la alcanzan, o no
+// tienen opiniones, y sigue con los siguientes hasta completar opts.perSite o haber pedido las
+// opiniones de maxRatingLookups productos. Las opiniones de un mismo producto se piden una sola
+// vez.
+func rateResults(ctx context.Context, results []siteSearchResult, opts searchOptions) ([]siteSearchResult, error) {
+	ratings := map[string]Rating{}
+	rated := make([]siteSearchResult, 0, opts.perSite)
+	for _, r := range results {
+		if len(rated) == opts.perSite {
+			break
+		}
+		// sin producto de catálogo las opiniones son las de la publicación.
+		product := r.catalogProductID
+		if product == "" {
+			product = r.itemID
+		}
+		rating, ok := ratings[product]
+		if !ok {
+			if len(ratings) == maxRatingLookups {
+				break
+			}
+			var err error
+			if rating, err = fetchRating(ctx, r.itemID, r.catalogProductID); err != nil {
+				return nil, err
+			}
+			ratings[product] = rating
+		}
+		if opts.minRating.IsPositive() && !rating.atLeast(opts.minRating) {
+			continue
+		}
+		r.rating = &rating
+		rated = append(rated, r)
+	}
+	if len(rated) == 0 {
+		return nil, notRetryableError{fmt.Errorf("no results rated at least %s", opts.minRating)}
+	}
+	return rated, nil
+}
@@ -0,0 +1,380 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/perrito666/tutoriales_go/internal/i18n"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	// outputText es el formato de salida pensado para leer en la terminal.
+	outputText = "text"
+	// outputJSON es el formato de salida pensado para otros programas.
+	outputJSON = "json"
+	// outputNDJSON es un objeto JSON por linea, uno por cada resultado apenas llega, pensado
+	// para encadenar con otros programas sin esperar a que terminen todos los sitios.
+	outputNDJSON = "ndjson"
+	// outputStream es texto que se escribe a medida que responde cada sitio, seguido de los
+	// resultados ordenados, pensado para leer en la terminal en conexiones lentas.
+	outputStream = "stream"
+)
+
+// runReport es el resultado de una comparación entre sitios, es lo que se imprime, lo que
+// se guarda en el historial y lo que devuelve el servidor.
+type runReport struct {
+	// Query es el criterio de búsqueda.
+	Query string `json:"query"`
+	// Time es el momento en que terminó la comparación.
+	Time time.Time `json:"time"`
+	// Results contiene un elemento por cada publicación encontrada.
+	Results []reportResult `json:"results"`
+	// Failed contiene un elemento por cada sitio que falló.
+	Failed []reportFailure `json:"failed,omitempty"`
+	// Errors contiene el error de cada sitio que falló, precedido por el sitio.
+	Errors []string `json:"errors,omitempty"`
+	// Sampled indica que en algún sitio solo se analizó una parte de las publicaciones que
+	// coinciden, así que Summary resume una muestra.
+	Sampled bool `json:"sampled,omitempty"`
+	// Groups resume los precios de Results por ubicación, solo si se pidió -group-by.
+	Groups []reportGroup `json:"groups,omitempty"`
+	// Summary resume los precios de Results, no está si no hay resultados.
+	Summary *reportSummary `json:"summary,omitempty"`
+	// Diff son los cambios respecto de la comparación anterior del historial, solo si se pidió.
+	Diff *reportDiff `json:"diff,omitempty"`
+	// Home es el sitio del país al que se calcula el costo de traer cada publicación, solo
+	// si se indicó un modelo de costos.
+	Home string `json:"home,omitempty"`
+	// Timings son los tiempos de cada sitio, del que mas tardó al que menos, solo si se pidió.
+	Timings []reportTiming `json:"timings,omitempty"`
+
+	// siteErrs contiene un *SiteError por cada sitio que falló.
+	siteErrs []error
+}
+
+// reportResult es una publicación dentro de un runReport.
+type reportResult struct {
+	SiteID   string `json:"site_id"`
+	SiteName string `json:"site_name"`
+	Currency string `json:"currency"`
+	// Rank es la posición de la publicación dentro de las de su sitio.
+	Rank      int             `json:"rank"`
+	Price     decimal.Decimal `json:"price"`
+	PriceUSD  decimal.Decimal `json:"price_usd"`
+	Ratio     decimal.Decimal `json:"ratio"`
+	Title     string          `json:"title"`
+	ItemID    string          `json:"item_id"`
+	Permalink string          `json:"permalink"`
+	// SiteTotal es cuantas publicaciones del sitio coinciden con el criterio, no está si el
+	// sitio no lo indica.
+	SiteTotal int `json:"site_total,omitempty"`
+	// SiteSeen es cuantas de ellas se analizaron y Sampled indica que fueron menos que
+	// SiteTotal, por ejemplo porque solo se pidió la primera página.
+	SiteSeen int  `json:"site_seen,omitempty"`
+	Sampled  bool `json:"sampled,omitempty"`
+	// AlsoOn contiene los nombres de otros sitios con la misma publicación.
+	AlsoOn []string `json:"also_on,omitempty"`
+	// Province y City son la ubicación de la publicación, no están si el sitio no la indica.
+	Province string `json:"province,omitempty"`
+	City     string `json:"city,omitempty"`
+	// Rating es la calificación del producto de la publicación, solo si se pidió.
+	Rating *reportRating `json:"rating,omitempty"`
+	// Questions resume las preguntas a la publicación, solo si se pidió y se pudieron obtener.
+	Questions *reportQuestions `json:"questions,omitempty"`
+	// Description es la descripción de la publicación en texto y recortada, solo si se pidió y
+	// se pudo obtener.
+	Description string `json:"description,omitempty"`
+	// Shipping es lo que cuesta enviar la publicación al código postal de -zip, solo si se
+	// pidió y se pudo estimar.
+	Shipping *reportShipping `json:"shipping,omitempty"`
+	// LandedUSD es el costo estimado de traer la publicación al país de runReport.Home, con el
+	// envío de Shipping si está.
+	LandedUSD *decimal.Decimal `json:"landed_usd,omitempty"`
+	// MinWageMonths es cuantos salarios mínimos mensuales del país del sitio cuesta la
+	// publicación, solo si se pidió.
+	MinWageMonths *decimal.Decimal `json:"min_wage_months,omitempty"`
+	// ARSDollars es el precio de una publicación en pesos argentinos en cada cotización del
+	// dólar en Argentina, solo si se pidió.
+	ARSDollars []reportDollar `json:"ars_dollars,omitempty"`
+	// CardARS es lo que cuesta en pesos una publicación de otro país pagada con tarjeta desde
+	// Argentina, con el dólar tarjeta, solo si se pidió.
+	CardARS *decimal.Decimal `json:"card_ars,omitempty"`
+	// AlsoIn es el precio de la publicación en cada moneda de -also-in, solo si se pidió.
+	AlsoIn []convertedPrice `json:"also_in,omitempty"`
+	// Crypto es el precio de la publicación en cada criptomoneda de -crypto, solo si se pidió.
+	Crypto []convertedPrice `json:"crypto,omitempty"`
+	// ConstantARS es el precio de una publicación en pesos argentinos expresado en pesos de otro
+	// mes según la inflación, solo en el historial y si se pidió.
+	ConstantARS *decimal.Decimal `json:"constant_ars,omitempty"`
+	// Installments es la financiación en cuotas de la publicación, solo si se pidió.
+	Installments *reportInstallments `json:"installments,omitempty"`
+
+	// installments es la financiación que indicó el sitio, aunque no se haya pedido.
+	installments *Installments
+	// mercadoLibre indica que la publicación es de un sitio de Mercado Libre.
+	mercadoLibre bool
+}
+
+// reportRating es la calificación de los compradores del producto de una publicación.
+type reportRating struct {
+	Average decimal.Decimal `json:"average"`
+	Count   int             `json:"count"`
+}
+
+// reportFailure es un sitio que falló dentro de un runReport.
+type reportFailure struct {
+	SiteID   string `json:"site_id"`
+	SiteName string `json:"site_name"`
+	Error    string `json:"error"`
+	TimedOut bool   `json:"timed_out"`
+	Attempts int    `json:"attempts"`
+	// RequestID es el ID con el que aparecen en el log los intentos del sitio.
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// local devuelve el precio de la publicación en la moneda del sitio.
+func (r reportResult) local() Money {
+	return NewMoney(r.Price, r.Currency)
+}
+
+// extraPrices devuelve los precios de la publicación en las monedas de -also-in seguidos de los
+// de -crypto.
+func (r reportResult) extraPrices() []convertedPrice {
+	return append(append([]convertedPrice{}, r.AlsoIn...), r.Crypto...)
+}
+
+// usd devuelve el precio de la publicación en USD.
+func (r reportResult) usd() Money {
+	return NewMoney(r.PriceUSD, usdCurrencyCode)
+}
+
+// newReportResult convierte un resultado interno en un reportResult.
+func newReportResult(r siteSearchResult) reportResult {
+	alsoOn := make([]string, 0, len(r.alsoOn))
+	for _, site := range r.alsoOn {
+		alsoOn = append(alsoOn, site.Name)
+	}
+	_, mercadoLibre := r.site.provider.(mlProvider)
+	var rating *reportRating
+	if r.rating != nil {
+		rating = &reportRating{Average: r.rating.Average, Count: r.rating.Count}
+	}
+	return reportResult{
+		SiteID:    r.site.ID,
+		SiteName:  r.site.Name,
+		Currency:  r.site.DefaultCurrencyID,
+		Rank:      r.rank,
+		Price:     r.price.Amount,
+		PriceUSD:  r.priceUSD.Amount,
+		Ratio:     r.ratio.Ratio,
+		Title:     r.item,
+		ItemID:    r.itemID,
+		Permalink: r.permalink,
+		SiteTotal: r.total,
+		SiteSeen:  r.seen,
+		Sampled:   r.total > r.seen,
+		AlsoOn:    alsoOn,
+		Rating:    rating,
+		Province:  r.province,
+		City:      r.city,
+
+		installments: r.installments,
+		mercadoLibre: mercadoLibre,
+	}
+}
+
+// newReportFailure convierte un sitio que falló en un reportFailure.
+func newReportFailure(f siteSearchResult) reportFailure {
+	return reportFailure{
+		SiteID:    f.site.ID,
+		SiteName:  f.site.Name,
+		Error:     f.err.Error(),
+		TimedOut:  f.timedOut,
+		Attempts:  f.attempts,
+		RequestID: f.requestID,
+	}
+}
+
+// newRunReport arma el runReport de una comparación a partir de los resultados internos.
+func newRunReport(query string, results, failed []siteSearchResult) *runReport {
+	report := &runReport{
+		Query:   query,
+		Time:    time.Now(),
+		Results: make([]reportResult, 0, len(results)),
+	}
+	for _, r := range results {
+		result := newReportResult(r)
+		report.Sampled = report.Sampled || result.Sampled
+		report.Results = append(report.Results, result)
+	}
+	for _, f := range failed {
+		report.Failed = append(report.Failed, newReportFailure(f))
+		siteErr := newSiteError(f)
+		report.siteErrs = append(report.siteErrs, siteErr)
+		report.Errors = append(report.Errors, siteErr.Error())
+	}
+	report.Summary = summarize(report)
+	return report
+}
+
+// streamEvent es una linea de la salida ndjson, tiene un resultado o un sitio que falló.
+type streamEvent struct {
+	Query   string         `json:"query"`
+	Result  *reportResult  `json:"result,omitempty"`
+	Failure *reportFailure `json:"failure,omitempty"`
+}
+
+// streamResults devuelve una función que escribe en w cada resultado que recibe como una linea
+// de JSON, para usar como searchOptions.onResult.
+func streamResults(w io.Writer, query string) func(siteSearchResult) {
+	encoder := json.NewEncoder(w)
+	return func(r siteSearchResult) {
+		event := streamEvent{Query: query}
+		if r.err != nil {
+			failure := newReportFailure(r)
+			event.Failure = &failure
+		} else {
+			result := newReportResult(r)
+			event.Result = &result
+		}
+		if err := encoder.Encode(event); err != nil {
+			log.Printf("writing result: %v", err)
+		}
+	}
+}
+
+// validOutput indica si output es un formato de salida conocido.
+func validOutput(output string) bool {
+	return output == outputText || output == outputJSON
+}
+
+// writeReport escribe el reporte en w en el formato indicado.
+func writeReport(w io.Writer, report *runReport, output string) error {
+	if output == outputJSON {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	}
+	writeTextReport(w, report)
+	return nil
+}
+
+// writeTextReport escribe el reporte de manera legible, primero los sitios con datos, luego
+// el resumen de precios, los cambios si los hay, los sitios que fallaron y por último los
+// tiempos de cada sitio si se pidieron.
+func writeTextReport(w io.Writer, report *runReport) {
+	// agrupado por ubicación la lista de publicaciones sería demasiado larga.
+	if len(report.Groups) > 0 {
+		writeTextGroups(w, report)
+	} else {
+		writeTextResults(w, report)
+	}
+	if report.Sampled {
+		fmt.Fprintln(w, i18n.T("\nAviso: en algunos sitios solo se vio una parte de las publicaciones que coinciden, los precios son de una muestra (ver -pages)"))
+	}
+	if report.Summary != nil {
+		writeTextSummary(w, report.Summary)
+	}
+	if report.Diff != nil {
+		writeTextDiff(w, report.Diff)
+	}
+	if len(report.Failed) > 0 {
+		fmt.Fprintf(w, i18n.T("\nSitios que fallaron (%d):\n"), len(report.Failed))
+		for _, v := range report.Failed {
+			// el ID de pedido permite encontrar los intentos del sitio en el log.
+			request := ""
+			if v.RequestID != "" {
+				request = " [req=" + v.RequestID + "]"
+			}
+			if v.TimedOut {
+				fmt.Fprintf(w, i18n.T("El sitio %q no respondió a tiempo (%d intentos): %s%s\n"), v.SiteName, v.Attempts, v.Error, request)
+				continue
+			}
+			fmt.Fprintf(w, i18n.T("El sitio %q falló luego de %d intentos: %s%s\n"), v.SiteName, v.Attempts, v.Error, request)
+		}
+	}
+	if len(report.Timings) > 0 {
+		writeTextTimings(w, report.Timings)
+	}
+}
+
+// writeTextResults escribe cada publicación del reporte con sus detalles.
+func writeTextResults(w io.Writer, report *runReport) {
+	// si hay mas de un resultado por sitio indicamos cual es cada uno.
+	ranked := false
+	for _, v := range report.Results {
+		if v.Rank > 1 {
+			ranked = true
+		}
+	}
+
+	fmt.Fprintf(w, i18n.T("Sitios con datos (%d):\n"), len(report.Results))
+	for _, v := range report.Results {
+		siteName := v.SiteName
+		if ranked {
+			siteName = fmt.Sprintf("%s #%d", v.SiteName, v.Rank)
+		}
+		fmt.Fprintf(w, i18n.T("Comprar %q en %q cuesta %s (son %s a cambio %s):\n"),
+			report.Query, siteName, v.usd(), v.local(), formatRate(v.Ratio))
+		fmt.Fprintf(w, i18n.T("--> Publicado como %q\n"), v.Title)
+		// el total es del sitio, alcanza con indicarlo en su primera publicación.
+		if v.SiteTotal > 0 && v.Rank == 1 {
+			seen := ""
+			if v.Sampled {
+				seen = fmt.Sprintf(i18n.T(", solo se vieron %d"), v.SiteSeen)
+			}
+			fmt.Fprintf(w, i18n.T("--> Coinciden %d publicaciones en el sitio%s\n"), v.SiteTotal, seen)
+		}
+		if len(v.AlsoOn) > 0 {
+			fmt.Fprintf(w, i18n.T("--> La misma publicación aparece en %s\n"), strings.Join(v.AlsoOn, ", "))
+		}
+		if len(v.ARSDollars) > 0 {
+			fmt.Fprintf(w, i18n.T("--> Según el dólar: %s\n"), formatARSDollars(v.ARSDollars))
+		}
+		if len(v.AlsoIn) > 0 {
+			fmt.Fprintf(w, i18n.T("--> En otras monedas: %s\n"), formatConverted(v.AlsoIn))
+		}
+		if len(v.Crypto) > 0 {
+			fmt.Fprintf(w, i18n.T("--> En cripto: %s\n"), formatConverted(v.Crypto))
+		}
+		if v.CardARS != nil {
+			fmt.Fprintf(w, i18n.T("--> Pagándolo con tarjeta desde Argentina cuesta %s\n"), NewMoney(*v.CardARS, arsCurrencyCode))
+		}
+		if v.Installments != nil {
+			fmt.Fprintf(w, i18n.T("--> En %s\n"), formatInstallments(*v.Installments))
+		}
+		if v.MinWageMonths != nil {
+			fmt.Fprintf(w, i18n.T("--> Son %s meses de salario mínimo\n"), formatNumber(*v.MinWageMonths))
+		}
+		if v.Rating != nil {
+			if v.Rating.Count == 0 {
+				fmt.Fprintln(w, i18n.T("--> El producto todavía no tiene opiniones"))
+			} else {
+				fmt.Fprintf(w, i18n.T("--> Calificación: %s de %d (%d opiniones)\n"), formatDecimal(v.Rating.Average, 1), maxRating, v.Rating.Count)
+			}
+		}
+		if v.Questions != nil {
+			fmt.Fprintf(w, "--> %s\n", formatQuestions(*v.Questions))
+		}
+		if v.Description != "" {
+			fmt.Fprintf(w, i18n.T("--> Descripción:\n    %s\n"), strings.ReplaceAll(v.Description, "\n", "\n    "))
+		}
+		if v.Shipping != nil {
+			delivery := ""
+			if v.Shipping.EstimatedDelivery != nil {
+				delivery = fmt.Sprintf(i18n.T(", llega el %s"), v.Shipping.EstimatedDelivery.Local().Format("2006-01-02"))
+			}
+			fmt.Fprintf(w, i18n.T("--> Enviarlo al código postal %s cuesta %s (%s%s), en total %s\n"), v.Shipping.Zip,
+				NewMoney(v.Shipping.Cost, v.Shipping.Currency), v.Shipping.Name, delivery,
+				NewMoney(v.withShippingUSD(), usdCurrencyCode))
+		}
+		if v.LandedUSD != nil && v.SiteID != report.Home {
+			fmt.Fprintf(w, i18n.T("--> Traerlo a %s cuesta aproximadamente %s\n"), report.Home, NewMoney(*v.LandedUSD, usdCurrencyCode))
+		}
+	}
+}
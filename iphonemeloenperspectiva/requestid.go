@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"sync/atomic"
+)
+
+// requestIDHeader es el encabezado con el que los pedidos salientes llevan su ID de pedido.
+const requestIDHeader = "X-Request-Id"
+
+// runID identifica a esta ejecución del programa, encabeza cada linea del log.
+var runID = newRunID()
+
+// requestSeq numera los IDs de pedido, un mismo sitio se busca varias veces en watch o serve.
+var requestSeq int64
+
+// newRunID devuelve un ID al azar de 8 caracteres hexadecimales.
+func newRunID() string {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		// sin azar igual necesitamos algo que distinga las lineas del log.
+		return "00000000"
+	}
+	return hex.EncodeToString(b)
+}
+
+// newRequestID devuelve un ID para la búsqueda en un sitio, por ejemplo 3fa2c1d0-MLA-7: el ID
+// de la ejecución, el sitio y un número que no se repite.
+func newRequestID(siteID string) string {
+	return fmt.Sprintf("%s-%s-%d", runID, siteID, atomic.AddInt64(&requestSeq, 1))
+}
+
+// requestIDKey es la clave con la que se guarda el ID de pedido en el contexto.
+type requestIDKey struct{}
+
+// withRequestID devuelve un contexto cuyos pedidos y lineas de log llevan id.
+func withRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// requestIDFrom devuelve el ID de pedido de ctx, vacío si no tiene.
+func requestIDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// logf escribe una linea en el log precedida por el ID de pedido de ctx, si tiene.
+func logf(ctx context.Context, format string, args ...interface{}) {
+	if id := requestIDFrom(ctx); id != "" {
+		format = "req=" + id + " " + format
+	}
+	log.Printf(format, args...)
+}
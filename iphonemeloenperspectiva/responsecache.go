@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// cachedResponse es una respuesta JSON ya codificada, con lo necesario para que los clientes
+// la puedan cachear.
+type cachedResponse struct {
+	body     []byte
+	etag     string
+	modified time.Time
+	expires  time.Time
+}
+
+// responseCache guarda las respuestas de un endpoint durante ttl, así los clientes que
+// consultan seguido no generan un pedido a cada sitio por consulta. Los pedidos iguales
+// concurrentes se unifican con singleflight. Con ttl 0 no se guarda nada, pero las respuestas
+// igual llevan ETag.
+type responseCache struct {
+	ttl   time.Duration
+	group singleflight.Group
+
+	mu      sync.Mutex
+	entries map[string]*cachedResponse
+}
+
+// newResponseCache devuelve un responseCache vacío que guarda las respuestas durante ttl.
+func newResponseCache(ttl time.Duration) *responseCache {
+	return &responseCache{ttl: ttl, entries: map[string]*cachedResponse{}}
+}
+
+// get devuelve la respuesta guardada para key o, si no hay o venció, la arma con build, que
+// devuelve el valor a codificar y el momento en que se obtuvo.
+func (c *responseCache) get(key string, build func() (interface{}, time.Time, error)) (*cachedResponse, error) {
+	now := time.Now()
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	c.mu.Unlock()
+	if ok && now.Before(entry.expires) {
+		return entry, nil
+	}
+
+	v, err, _ := c.group.Do(key, func() (interface{}, error) {
+		value, modified, err := build()
+		if err != nil {
+			return nil, err
+		}
+		body, err := json.Marshal(value)
+		if err != nil {
+			return nil, fmt.Errorf("encoding response: %v", err)
+		}
+		sum := sha256.Sum256(body)
+		entry := &cachedResponse{
+			body:     append(body, '\n'),
+			etag:     `"` + hex.EncodeToString(sum[:16]) + `"`,
+			modified: modified,
+			expires:  time.Now().Add(c.ttl),
+		}
+		if c.ttl > 0 {
+			c.store(key, entry)
+		}
+		return entry, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.(*cachedResponse), nil
+}
+
+// store guarda la respuesta y de paso descarta las vencidas.
+func (c *responseCache) store(key string, entry *cachedResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	for k, e := range c.entries {
+		if now.After(e.expires) {
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = entry
+}
+
+// serveCached responde la respuesta con su ETag, Last-Modified y un Cache-Control que
+// permite cachearla hasta que vence. http.ServeContent responde 304 si el cliente ya la
+// tiene según If-None-Match o If-Modified-Since.
+func serveCached(w http.ResponseWriter, r *http.Request, response *cachedResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", response.etag)
+	if maxAge := int(time.Until(response.expires).Seconds()); maxAge > 0 {
+		w.Header().Set("Cache-Control", fmt.Sprintf("max-age=%d", maxAge))
+	} else {
+		w.Header().Set("Cache-Control", "no-cache")
+	}
+	http.ServeContent(w, r, "", response.modified, bytes.NewReader(response.body))
+}
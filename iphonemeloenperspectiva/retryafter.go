@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// mlPause recuerda hasta cuando nos pidió Mercado Libre que no le hagamos pedidos, con un
+// 429 o un 5xx con el encabezado Retry-After. Lo comparten todos los pedidos: si ML nos
+// pidió esperar no tiene sentido que otro sitio o página insista mientras tanto.
+type mlPause struct {
+	mu    sync.Mutex
+	until time.Time
+}
+
+// mlBackoff es la pausa que respetan todos los pedidos a Mercado Libre.
+var mlBackoff = &mlPause{}
+
+// extend posterga los pedidos hasta dentro de delay, salvo que ya estén postergados mas.
+func (p *mlPause) extend(delay time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if until := time.Now().Add(delay); until.After(p.until) {
+		p.until = until
+	}
+}
+
+// remaining devuelve cuanto falta para que termine la pausa, 0 si no hay ninguna.
+func (p *mlPause) remaining() time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if remaining := time.Until(p.until); remaining > 0 {
+		return remaining
+	}
+	return 0
+}
+
+// wait espera a que termine la pausa. Si el contexto vence antes de que termine falla
+// enseguida, sin esperar en vano.
+func (p *mlPause) wait(ctx context.Context) error {
+	remaining := p.remaining()
+	if remaining == 0 {
+		return nil
+	}
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < remaining {
+		return fmt.Errorf("mercado libre asked to wait %s, longer than the time left: %w", remaining.Round(time.Second), ErrRateLimited)
+	}
+	select {
+	case <-time.After(remaining):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// parseRetryAfter interpreta el encabezado Retry-After, que puede indicar una cantidad de
+// segundos o una fecha HTTP.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	when, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	if delay := when.Sub(now); delay > 0 {
+		return delay, true
+	}
+	return 0, true
+}
+
+// doML hace un pedido a Mercado Libre respetando la pausa que nos haya pedido. Si la
+// respuesta es un 429 o un 5xx con Retry-After, posterga los próximos pedidos lo indicado.
+func doML(request *http.Request) (*http.Response, error) {
+	if err := mlBackoff.wait(request.Context()); err != nil {
+		return nil, err
+	}
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode == http.StatusTooManyRequests || response.StatusCode >= 500 {
+		if delay, ok := parseRetryAfter(response.Header.Get("Retry-After"), time.Now()); ok {
+			mlBackoff.extend(delay)
+		}
+	}
+	return response, nil
+}
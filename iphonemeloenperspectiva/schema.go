@@ -0,0 +1,425 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// strictDecode indica que las respuestas de Mercado Libre se validan contra las estructuras
+// completas de este archivo, se activa con -strict.
+var strictDecode bool
+
+// decodeML de-serializa en v una respuesta de Mercado Libre que se lee de body, what indica
+// que se está de-serializando para el error. Con -strict además se de-serializa la respuesta
+// en schema rechazando los campos desconocidos, así un cambio en la API se informa como un
+// error en lugar de terminar en campos vacíos. schema nil indica que v ya describe la
+// respuesta completa. Lo que tarda, incluida la lectura de body, se suma a los tiempos del
+// sitio de ctx.
+func decodeML(ctx context.Context, body io.Reader, what string, v, schema interface{}) (err error) {
+	_, span := tracer.Start(ctx, "decode", trace.WithAttributes(attribute.String("decode.what", what)))
+	defer func(start time.Time) {
+		addDecodeTime(ctx, time.Since(start))
+		endSpan(span, err)
+	}(time.Now())
+
+	if !strictDecode {
+		if err := json.NewDecoder(body).Decode(v); err != nil {
+			return &DecodeError{What: what, Err: err}
+		}
+		return nil
+	}
+
+	// la respuesta se de-serializa dos veces, hay que tenerla entera en memoria.
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return &DecodeError{What: what, Err: err}
+	}
+	if err := json.NewDecoder(bytes.NewReader(data)).Decode(v); err != nil {
+		return &DecodeError{What: what, Err: err}
+	}
+	if schema == nil {
+		schema = v
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(schema); err != nil {
+		return &DecodeError{What: what, Err: fmt.Errorf("%w: %v", ErrSchemaDrift, err)}
+	}
+	return nil
+}
+
+// Las estructuras que siguen describen las respuestas de Mercado Libre con todos los campos
+// que conocemos, aunque no los usemos, y solo sirven para detectar cambios con -strict. Los
+// campos cuyo contenido no nos interesa validar son json.RawMessage, aceptan cualquier valor.
+
+// mlSearchSchema es la respuesta de la búsqueda de un sitio.
+type mlSearchSchema struct {
+	SiteID                 string           `json:"site_id"`
+	CountryDefaultTimeZone string           `json:"country_default_time_zone"`
+	Query                  string           `json:"query"`
+	Paging                 mlPagingSchema   `json:"paging"`
+	Results                []mlResultSchema `json:"results"`
+	Sort                   mlSortSchema     `json:"sort"`
+	AvailableSorts         []mlSortSchema   `json:"available_sorts"`
+	Filters                []mlFilterSchema `json:"filters"`
+	AvailableFilters       []mlFilterSchema `json:"available_filters"`
+	PDPTracking            json.RawMessage  `json:"pdp_tracking"`
+	UserContext            json.RawMessage  `json:"user_context"`
+}
+
+// mlPagingSchema es la paginación de la búsqueda.
+type mlPagingSchema struct {
+	Total          int64 `json:"total"`
+	PrimaryResults int64 `json:"primary_results"`
+	Offset         int64 `json:"offset"`
+	Limit          int64 `json:"limit"`
+}
+
+// mlSortSchema es un orden de la búsqueda.
+type mlSortSchema struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// mlFilterSchema es un filtro de la búsqueda.
+type mlFilterSchema struct {
+	ID     string          `json:"id"`
+	Name   string          `json:"name"`
+	Type   string          `json:"type"`
+	Values json.RawMessage `json:"values"`
+}
+
+// mlResultSchema es un resultado de la búsqueda.
+type mlResultSchema struct {
+	ID                  string               `json:"id"`
+	Title               string               `json:"title"`
+	SanitizedTitle      string               `json:"sanitized_title"`
+	Condition           string               `json:"condition"`
+	ThumbnailID         string               `json:"thumbnail_id"`
+	Thumbnail           string               `json:"thumbnail"`
+	UseThumbnailID      bool                 `json:"use_thumbnail_id"`
+	CatalogProductID    *string              `json:"catalog_product_id"`
+	CatalogListing      bool                 `json:"catalog_listing"`
+	ListingTypeID       string               `json:"listing_type_id"`
+	Permalink           string               `json:"permalink"`
+	BuyingMode          string               `json:"buying_mode"`
+	SiteID              string               `json:"site_id"`
+	CategoryID          string               `json:"category_id"`
+	DomainID            string               `json:"domain_id"`
+	VariationID         string               `json:"variation_id"`
+	CurrencyID          string               `json:"currency_id"`
+	OrderBackend        int                  `json:"order_backend"`
+	Price               float64              `json:"price"`
+	OriginalPrice       *float64             `json:"original_price"`
+	SalePrice           json.RawMessage      `json:"sale_price"`
+	Prices              json.RawMessage      `json:"prices"`
+	ReferencePrices     json.RawMessage      `json:"reference_prices"`
+	AvailableQuantity   int64                `json:"available_quantity"`
+	SoldQuantity        int64                `json:"sold_quantity"`
+	OfficialStoreID     *int64               `json:"official_store_id"`
+	OfficialStoreName   string               `json:"official_store_name"`
+	AcceptsMercadopago  bool                 `json:"accepts_mercadopago"`
+	Tags                []string             `json:"tags"`
+	Shipping            mlShippingSchema     `json:"shipping"`
+	StopTime            string               `json:"stop_time"`
+	Seller              mlSellerSchema       `json:"seller"`
+	SellerAddress       json.RawMessage      `json:"seller_address"`
+	Address             mlAddressSchema      `json:"address"`
+	Location            json.RawMessage      `json:"location"`
+	Attributes          json.RawMessage      `json:"attributes"`
+	Installments        *mlInstallmentSchema `json:"installments"`
+	WinnerItemID        *string              `json:"winner_item_id"`
+	Discounts           json.RawMessage      `json:"discounts"`
+	Promotions          json.RawMessage      `json:"promotions"`
+	DifferentialPricing json.RawMessage      `json:"differential_pricing"`
+	InventoryID         *string              `json:"inventory_id"`
+	VariationFilters    json.RawMessage      `json:"variation_filters"`
+	VariationsData      json.RawMessage      `json:"variations_data"`
+}
+
+// mlShippingSchema es el envío de un resultado de la búsqueda.
+type mlShippingSchema struct {
+	StorePickUp  bool            `json:"store_pick_up"`
+	FreeShipping bool            `json:"free_shipping"`
+	LogisticType string          `json:"logistic_type"`
+	Mode         string          `json:"mode"`
+	Tags         []string        `json:"tags"`
+	Benefits     json.RawMessage `json:"benefits"`
+	Promise      json.RawMessage `json:"promise"`
+}
+
+// mlSellerSchema es el vendedor de un resultado de la búsqueda.
+type mlSellerSchema struct {
+	ID               int64           `json:"id"`
+	Nickname         string          `json:"nickname"`
+	Permalink        string          `json:"permalink"`
+	RegistrationDate string          `json:"registration_date"`
+	CarDealer        bool            `json:"car_dealer"`
+	CarDealerLogo    string          `json:"car_dealer_logo"`
+	RealEstateAgency bool            `json:"real_estate_agency"`
+	Tags             []string        `json:"tags"`
+	SellerReputation json.RawMessage `json:"seller_reputation"`
+	Eshop            json.RawMessage `json:"eshop"`
+}
+
+// mlAddressSchema es la ubicación de un resultado de la búsqueda.
+type mlAddressSchema struct {
+	StateID   string `json:"state_id"`
+	StateName string `json:"state_name"`
+	CityID    string `json:"city_id"`
+	CityName  string `json:"city_name"`
+}
+
+// mlInstallmentSchema son las cuotas de un resultado de la búsqueda.
+type mlInstallmentSchema struct {
+	Quantity   int             `json:"quantity"`
+	Amount     float64         `json:"amount"`
+	Rate       float64         `json:"rate"`
+	CurrencyID string          `json:"currency_id"`
+	Metadata   json.RawMessage `json:"metadata"`
+}
+
+// mlRateSchema es la respuesta de la API de cambio.
+type mlRateSchema struct {
+	CurrencyBase  string  `json:"currency_base"`
+	CurrencyQuote string  `json:"currency_quote"`
+	Ratio         float64 `json:"ratio"`
+	Rate          float64 `json:"rate"`
+	InvRate       float64 `json:"inv_rate"`
+	CreationDate  string  `json:"creation_date"`
+	ValidUntil    string  `json:"valid_until"`
+}
+
+// mlShippingOptionsSchema es la respuesta de las opciones de envío de una publicación.
+type mlShippingOptionsSchema struct {
+	Destination   json.RawMessage          `json:"destination"`
+	Options       []mlShippingOptionSchema `json:"options"`
+	CustomMessage json.RawMessage          `json:"custom_message"`
+}
+
+// mlShippingOptionSchema es una de las opciones de envío de una publicación.
+type mlShippingOptionSchema struct {
+	ID                        int64           `json:"id"`
+	Name                      string          `json:"name"`
+	CurrencyID                string          `json:"currency_id"`
+	ListCost                  float64         `json:"list_cost"`
+	Cost                      float64         `json:"cost"`
+	BaseCost                  float64         `json:"base_cost"`
+	Display                   string          `json:"display"`
+	ShippingMethodID          int64           `json:"shipping_method_id"`
+	ShippingMethodType        string          `json:"shipping_method_type"`
+	ShippingOptionType        string          `json:"shipping_option_type"`
+	EstimatedDeliveryTime     json.RawMessage `json:"estimated_delivery_time"`
+	EstimatedScheduleLimit    json.RawMessage `json:"estimated_schedule_limit"`
+	EstimatedDeliveryLimit    json.RawMessage `json:"estimated_delivery_limit"`
+	EstimatedDeliveryFinal    json.RawMessage `json:"estimated_delivery_final"`
+	EstimatedDeliveryExtended json.RawMessage `json:"estimated_delivery_extended"`
+	Speed                     json.RawMessage `json:"speed"`
+	Discount                  json.RawMessage `json:"discount"`
+	Tags                      []string        `json:"tags"`
+}
+
+// mlDescriptionSchema es la descripción de una publicación.
+type mlDescriptionSchema struct {
+	Text        string          `json:"text"`
+	PlainText   string          `json:"plain_text"`
+	LastUpdated string          `json:"last_updated"`
+	DateCreated string          `json:"date_created"`
+	Snapshot    json.RawMessage `json:"snapshot"`
+}
+
+// mlReviewsSchema son las opiniones de una publicación.
+type mlReviewsSchema struct {
+	Paging            mlPagingSchema  `json:"paging"`
+	Reviews           json.RawMessage `json:"reviews"`
+	HelpfulReviews    json.RawMessage `json:"helpful_reviews"`
+	RatingAverage     float64         `json:"rating_average"`
+	RatingLevels      json.RawMessage `json:"rating_levels"`
+	Attributes        json.RawMessage `json:"attributes"`
+	AttributesSummary json.RawMessage `json:"attributes_summary"`
+}
+
+// mlQuestionsSchema es la búsqueda de preguntas de una publicación.
+type mlQuestionsSchema struct {
+	Total            int                `json:"total"`
+	Limit            int                `json:"limit"`
+	Questions        []mlQuestionSchema `json:"questions"`
+	Filters          json.RawMessage    `json:"filters"`
+	AvailableFilters json.RawMessage    `json:"available_filters"`
+	AvailableSorts   json.RawMessage    `json:"available_sorts"`
+}
+
+// mlQuestionSchema es una pregunta a una publicación.
+type mlQuestionSchema struct {
+	ID                 int64           `json:"id"`
+	SellerID           int64           `json:"seller_id"`
+	ItemID             string          `json:"item_id"`
+	Text               string          `json:"text"`
+	Status             string          `json:"status"`
+	DateCreated        string          `json:"date_created"`
+	Deleted            bool            `json:"deleted_from_listing"`
+	Hold               bool            `json:"hold"`
+	Tags               []string        `json:"tags"`
+	Answer             json.RawMessage `json:"answer"`
+	From               json.RawMessage `json:"from"`
+	SuspectedSpam      bool            `json:"suspected_spam"`
+	AppID              json.RawMessage `json:"app_id"`
+	ImportedFromItemID json.RawMessage `json:"imported_from_item_id"`
+}
+
+// mlProductsSearchSchema es la búsqueda en el catálogo de productos.
+type mlProductsSearchSchema struct {
+	Keywords       string                   `json:"keywords"`
+	Paging         mlPagingSchema           `json:"paging"`
+	Results        []mlProductSummarySchema `json:"results"`
+	UsedAttributes json.RawMessage          `json:"used_attributes"`
+	QueryType      string                   `json:"query_type"`
+	DomainID       json.RawMessage          `json:"domain_id"`
+	Suggestions    json.RawMessage          `json:"suggestions"`
+}
+
+// mlProductSummarySchema es un producto en la búsqueda en el catálogo.
+type mlProductSummarySchema struct {
+	ID              string          `json:"id"`
+	Name            string          `json:"name"`
+	Status          string          `json:"status"`
+	DomainID        string          `json:"domain_id"`
+	SiteID          string          `json:"site_id"`
+	Settings        json.RawMessage `json:"settings"`
+	MainFeatures    json.RawMessage `json:"main_features"`
+	Attributes      json.RawMessage `json:"attributes"`
+	Pictures        json.RawMessage `json:"pictures"`
+	ParentID        *string         `json:"parent_id"`
+	ChildrenIDs     []string        `json:"children_ids"`
+	QualityType     string          `json:"quality_type"`
+	Priority        json.RawMessage `json:"priority"`
+	Type            string          `json:"type"`
+	Description     json.RawMessage `json:"description"`
+	BuyBoxWinner    json.RawMessage `json:"buy_box_winner"`
+	CatalogProducts json.RawMessage `json:"catalog_products"`
+}
+
+// mlProductSchema es un producto del catálogo.
+type mlProductSchema struct {
+	ID                            string          `json:"id"`
+	Status                        string          `json:"status"`
+	SoldQuantity                  int64           `json:"sold_quantity"`
+	DomainID                      string          `json:"domain_id"`
+	Permalink                     string          `json:"permalink"`
+	Name                          string          `json:"name"`
+	FamilyName                    string          `json:"family_name"`
+	Type                          string          `json:"type"`
+	BuyBoxWinner                  json.RawMessage `json:"buy_box_winner"`
+	BuyBoxWinnerPriceRange        json.RawMessage `json:"buy_box_winner_price_range"`
+	Pickers                       json.RawMessage `json:"pickers"`
+	Pictures                      json.RawMessage `json:"pictures"`
+	DescriptionPictures           json.RawMessage `json:"description_pictures"`
+	MainFeatures                  json.RawMessage `json:"main_features"`
+	Disclaimers                   json.RawMessage `json:"disclaimers"`
+	Attributes                    json.RawMessage `json:"attributes"`
+	ShortDescription              json.RawMessage `json:"short_description"`
+	ParentID                      *string         `json:"parent_id"`
+	UserProduct                   json.RawMessage `json:"user_product"`
+	ChildrenIDs                   []string        `json:"children_ids"`
+	Settings                      json.RawMessage `json:"settings"`
+	QualityType                   string          `json:"quality_type"`
+	ReleaseInfo                   json.RawMessage `json:"release_info"`
+	PresaleInfo                   json.RawMessage `json:"presale_info"`
+	EnhancedContent               json.RawMessage `json:"enhanced_content"`
+	Tags                          []string        `json:"tags"`
+	DateCreated                   string          `json:"date_created"`
+	AuthorizedStores              json.RawMessage `json:"authorized_stores"`
+	BoostedListingsCount          json.RawMessage `json:"boosted_listings_count"`
+	LastUpdated                   string          `json:"last_updated"`
+	Grouper                       json.RawMessage `json:"grouper"`
+	SiteID                        string          `json:"site_id"`
+	CatalogProductsAvailableSites json.RawMessage `json:"catalog_products_available_sites"`
+}
+
+// mlDomainSchema es una categoría sugerida por domain discovery.
+type mlDomainSchema struct {
+	DomainID     string          `json:"domain_id"`
+	DomainName   string          `json:"domain_name"`
+	CategoryID   string          `json:"category_id"`
+	CategoryName string          `json:"category_name"`
+	Attributes   json.RawMessage `json:"attributes"`
+}
+
+// mlHighlightsSchema es la respuesta de los mas vendidos de una categoría.
+type mlHighlightsSchema struct {
+	QueryData struct {
+		HighlightType string `json:"highlight_type"`
+		Criteria      string `json:"criteria"`
+		ID            string `json:"id"`
+	} `json:"query_data"`
+	Content []struct {
+		ID       string `json:"id"`
+		Position int    `json:"position"`
+		Type     string `json:"type"`
+	} `json:"content"`
+}
+
+// mlItemSchema es una publicación.
+type mlItemSchema struct {
+	ID                           string          `json:"id"`
+	SiteID                       string          `json:"site_id"`
+	Title                        string          `json:"title"`
+	Subtitle                     *string         `json:"subtitle"`
+	SellerID                     int64           `json:"seller_id"`
+	CategoryID                   string          `json:"category_id"`
+	DomainID                     string          `json:"domain_id"`
+	OfficialStoreID              *int64          `json:"official_store_id"`
+	Price                        float64         `json:"price"`
+	BasePrice                    float64         `json:"base_price"`
+	OriginalPrice                *float64        `json:"original_price"`
+	CurrencyID                   string          `json:"currency_id"`
+	InitialQuantity              int64           `json:"initial_quantity"`
+	AvailableQuantity            int64           `json:"available_quantity"`
+	SoldQuantity                 int64           `json:"sold_quantity"`
+	SaleTerms                    json.RawMessage `json:"sale_terms"`
+	BuyingMode                   string          `json:"buying_mode"`
+	ListingTypeID                string          `json:"listing_type_id"`
+	ListingSource                string          `json:"listing_source"`
+	StartTime                    string          `json:"start_time"`
+	StopTime                     string          `json:"stop_time"`
+	Condition                    string          `json:"condition"`
+	Permalink                    string          `json:"permalink"`
+	ThumbnailID                  string          `json:"thumbnail_id"`
+	Thumbnail                    string          `json:"thumbnail"`
+	SecureThumbnail              string          `json:"secure_thumbnail"`
+	Pictures                     json.RawMessage `json:"pictures"`
+	VideoID                      *string         `json:"video_id"`
+	Descriptions                 json.RawMessage `json:"descriptions"`
+	AcceptsMercadopago           bool            `json:"accepts_mercadopago"`
+	NonMercadoPagoPaymentMethods json.RawMessage `json:"non_mercado_pago_payment_methods"`
+	Shipping                     json.RawMessage `json:"shipping"`
+	InternationalDeliveryMode    string          `json:"international_delivery_mode"`
+	SellerAddress                json.RawMessage `json:"seller_address"`
+	SellerContact                json.RawMessage `json:"seller_contact"`
+	Location                     json.RawMessage `json:"location"`
+	CoverageAreas                json.RawMessage `json:"coverage_areas"`
+	Attributes                   json.RawMessage `json:"attributes"`
+	Warnings                     json.RawMessage `json:"warnings"`
+	Variations                   json.RawMessage `json:"variations"`
+	Status                       string          `json:"status"`
+	SubStatus                    []string        `json:"sub_status"`
+	Tags                         []string        `json:"tags"`
+	Warranty                     *string         `json:"warranty"`
+	CatalogProductID             *string         `json:"catalog_product_id"`
+	CatalogListing               bool            `json:"catalog_listing"`
+	ParentItemID                 *string         `json:"parent_item_id"`
+	DifferentialPricing          json.RawMessage `json:"differential_pricing"`
+	DealIDs                      []string        `json:"deal_ids"`
+	AutomaticRelist              bool            `json:"automatic_relist"`
+	DateCreated                  string          `json:"date_created"`
+	LastUpdated                  string          `json:"last_updated"`
+	Health                       *float64        `json:"health"`
+	Channels                     []string        `json:"channels"`
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/perrito666/tutoriales_go/internal/bodylimit"
+)
+
+const (
+	// sheetsAPIURL es la base de la API de Google Sheets.
+	sheetsAPIURL = "https://sheets.googleapis.com/v4/spreadsheets/"
+	// sheetsScope es el alcance que necesita el token para escribir en una planilla.
+	sheetsScope = "https://www.googleapis.com/auth/spreadsheets"
+	// googleTokenURL es donde se canjea la firma de la cuenta de servicio por un token, si el
+	// archivo de credenciales no indica otro.
+	googleTokenURL = "https://oauth2.googleapis.com/token"
+	// sheetsTimeLayout es el formato de la fecha de cada fila, como texto se ordena bien.
+	sheetsTimeLayout = "2006-01-02 15:04:05"
+)
+
+// serviceAccountKey imita la estructura del archivo JSON de credenciales de una cuenta de
+// servicio de Google, solo lo que nos interesa.
+type serviceAccountKey struct {
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+}
+
+// sheetsExporter agrega los resultados de cada comparación como filas al final de una
+// planilla de Google Sheets. Se autentica como una cuenta de servicio, la planilla debe estar
+// compartida con el email de la cuenta.
+type sheetsExporter struct {
+	spreadsheetID string
+	sheetRange    string
+	email         string
+	tokenURL      string
+	key           *rsa.PrivateKey
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// newSheetsExporter devuelve un sheetsExporter que escribe en la hoja sheetRange de la
+// planilla spreadsheetID con las credenciales del archivo credentialsPath.
+func newSheetsExporter(credentialsPath, spreadsheetID, sheetRange string) (*sheetsExporter, error) {
+	data, err := os.ReadFile(credentialsPath)
+	if err != nil {
+		return nil, fmt.Errorf("reading sheets credentials: %v", err)
+	}
+	account := serviceAccountKey{}
+	if err := json.Unmarshal(data, &account); err != nil {
+		return nil, fmt.Errorf("decoding sheets credentials %s: %v", credentialsPath, err)
+	}
+	if account.ClientEmail == "" || account.PrivateKey == "" {
+		return nil, fmt.Errorf("sheets credentials %s are not a service account key", credentialsPath)
+	}
+	key, err := parseRSAKey(account.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("parsing sheets credentials %s: %v", credentialsPath, err)
+	}
+	tokenURL := account.TokenURI
+	if tokenURL == "" {
+		tokenURL = googleTokenURL
+	}
+	return &sheetsExporter{
+		spreadsheetID: spreadsheetID,
+		sheetRange:    sheetRange,
+		email:         account.ClientEmail,
+		tokenURL:      tokenURL,
+		key:           key,
+	}, nil
+}
+
+// parseRSAKey lee una clave privada RSA en formato PEM, Google las entrega en PKCS#8.
+func parseRSAKey(pemKey string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(pemKey))
+	if block == nil {
+		return nil, fmt.Errorf("private key is not PEM encoded")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parsing private key: %v", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("private key is not an RSA key")
+	}
+	return key, nil
+}
+
+// assertion arma el JWT firmado con el que la cuenta de servicio pide un token.
+func (e *sheetsExporter) assertion(now time.Time) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]interface{}{
+		"iss":   e.email,
+		"scope": sheetsScope,
+		"aud":   e.tokenURL,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+	encoding := base64.RawURLEncoding
+	unsigned := encoding.EncodeToString(header) + "." + encoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, e.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("signing sheets token request: %v", err)
+	}
+	return unsigned + "." + encoding.EncodeToString(signature), nil
+}
+
+// accessToken devuelve un token válido, pidiendo uno nuevo si no hay o está por vencer.
+func (e *sheetsExporter) accessToken(ctx context.Context) (string, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.token != "" && time.Now().Before(e.expires) {
+		return e.token, nil
+	}
+
+	assertion, err := e.assertion(time.Now())
+	if err != nil {
+		return "", err
+	}
+	form := url.Values{}
+	form.Set("grant_type", "urn:ietf:params:oauth:grant-type:jwt-bearer")
+	form.Set("assertion", assertion)
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, e.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("building sheets token request: %v", err)
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return "", fmt.Errorf("requesting sheets token: %v", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("requesting sheets token: %s", response.Status)
+	}
+	token := struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}{}
+	if err := json.NewDecoder(bodylimit.Body(response)).Decode(&token); err != nil {
+		return "", fmt.Errorf("decoding sheets token: %v", err)
+	}
+	e.token = token.AccessToken
+	// renovamos el token un minuto antes de que venza.
+	e.expires = time.Now().Add(time.Duration(token.ExpiresIn)*time.Second - time.Minute)
+	return e.token, nil
+}
+
+// sheetsRows devuelve una fila por cada publicación del reporte con la fecha, el criterio, el
+// ID y nombre del sitio, la moneda, el precio, el precio en USD, el título y el link. Los
+// precios van como números para que se puedan graficar.
+func sheetsRows(report *runReport) [][]interface{} {
+	rows := make([][]interface{}, 0, len(report.Results))
+	when := report.Time.Local().Format(sheetsTimeLayout)
+	for _, r := range report.Results {
+		price, _ := r.Price.Float64()
+		priceUSD, _ := r.PriceUSD.Float64()
+		rows = append(rows, []interface{}{
+			when, report.Query, r.SiteID, r.SiteName, r.Currency, price, priceUSD, r.Title, r.Permalink,
+		})
+	}
+	return rows
+}
+
+// export agrega los resultados del reporte al final de la hoja.
+func (e *sheetsExporter) export(ctx context.Context, report *runReport) error {
+	rows := sheetsRows(report)
+	if len(rows) == 0 {
+		return nil
+	}
+	token, err := e.accessToken(ctx)
+	if err != nil {
+		return err
+	}
+
+	// RAW evita que Sheets interprete un título que empieza con = como una fórmula.
+	appendURL := sheetsAPIURL + url.PathEscape(e.spreadsheetID) + "/values/" + url.PathEscape(e.sheetRange) +
+		":append?valueInputOption=RAW&insertDataOption=INSERT_ROWS"
+	body, err := json.Marshal(map[string]interface{}{"values": rows})
+	if err != nil {
+		return fmt.Errorf("encoding sheets rows: %v", err)
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, appendURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("building sheets request: %v", err)
+	}
+	request.Header.Set("Authorization", "Bearer "+token)
+	request.Header.Set("Content-Type", "application/json")
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return fmt.Errorf("appending to sheet: %v", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("appending to sheet: %s", response.Status)
+	}
+	return nil
+}
+
+// sheetsFlags contiene los flags de la exportación a Google Sheets, los registran los
+// subcomandos que generan reportes.
+type sheetsFlags struct {
+	spreadsheetID *string
+	credentials   *string
+	sheetRange    *string
+}
+
+// addSheetsFlags registra en fs los flags de la exportación a Google Sheets.
+func addSheetsFlags(fs *flag.FlagSet) *sheetsFlags {
+	return &sheetsFlags{
+		spreadsheetID: fs.String("sheets-id", "", "ID de una planilla de Google Sheets a la que se agregan los resultados de cada comparación"),
+		credentials:   fs.String("sheets-credentials", "", "archivo JSON con la clave de la cuenta de servicio de Google, necesario para -sheets-id"),
+		sheetRange:    fs.String("sheets-range", "A1", "hoja o rango de la planilla al final del cual se agregan las filas, por ejemplo Precios!A1"),
+	}
+}
+
+// exporter devuelve el sheetsExporter indicado por los flags o nil si no se pidió exportar.
+func (f *sheetsFlags) exporter() (*sheetsExporter, error) {
+	if *f.spreadsheetID == "" {
+		return nil, nil
+	}
+	if *f.credentials == "" {
+		return nil, fmt.Errorf("-sheets-id needs the service account key in -sheets-credentials")
+	}
+	return newSheetsExporter(*f.credentials, *f.spreadsheetID, *f.sheetRange)
+}
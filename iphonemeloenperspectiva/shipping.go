@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// mlShippingOptionsURL es el endpoint de las opciones de envío de una publicación a un código
+// postal.
+const mlShippingOptionsURL = "https://api.mercadolibre.com/items/%s/shipping_options"
+
+// mlShippingOptions imita la estructura JSON de las opciones de envío de una publicación.
+type mlShippingOptions struct {
+	Options []mlShippingOption `json:"options"`
+}
+
+// mlShippingOption es una de las formas de enviar una publicación.
+type mlShippingOption struct {
+	Name                  string          `json:"name"`
+	Cost                  decimal.Decimal `json:"cost"`
+	CurrencyID            string          `json:"currency_id"`
+	EstimatedDeliveryTime struct {
+		Date *time.Time `json:"date"`
+	} `json:"estimated_delivery_time"`
+}
+
+// reportShipping es lo que cuesta enviar una publicación al código postal del usuario, con la
+// opción de envío mas barata.
+type reportShipping struct {
+	Zip      string          `json:"zip"`
+	Name     string          `json:"name"`
+	Cost     decimal.Decimal `json:"cost"`
+	Currency string          `json:"currency"`
+	CostUSD  decimal.Decimal `json:"cost_usd"`
+	// EstimatedDelivery es cuando se estima que llega, no está si ML no lo indica.
+	EstimatedDelivery *time.Time `json:"estimated_delivery,omitempty"`
+}
+
+// shippingOptionsURL devuelve la URL de las opciones de envío de itemID a zip.
+func shippingOptionsURL(itemID, zip string) (string, error) {
+	optionsURL, err := url.Parse(fmt.Sprintf(mlShippingOptionsURL, url.PathEscape(itemID)))
+	if err != nil {
+		return "", fmt.Errorf("parsing mercado libre shipping options url: %w", err)
+	}
+	queryValues := optionsURL.Query()
+	queryValues.Set("zip_code", zip)
+	optionsURL.RawQuery = queryValues.Encode()
+	return optionsURL.String(), nil
+}
+
+// cheapestShipping devuelve la opción de envío de itemID a zip que menos cuesta, entre dos
+// igual de baratas la que llega antes.
+func cheapestShipping(ctx context.Context, itemID, zip string) (mlShippingOption, error) {
+	optionsURL, err := shippingOptionsURL(itemID, zip)
+	if err != nil {
+		return mlShippingOption{}, err
+	}
+	options := mlShippingOptions{}
+	if err := getML(ctx, optionsURL, &options, &mlShippingOptionsSchema{}); err != nil {
+		return mlShippingOption{}, fmt.Errorf("fetching shipping options: %w", err)
+	}
+	if len(options.Options) == 0 {
+		return mlShippingOption{}, fmt.Errorf("no shipping options to zip code %s", zip)
+	}
+	cheapest := options.Options[0]
+	for _, option := range options.Options[1:] {
+		if option.Cost.LessThan(cheapest.Cost) ||
+			option.Cost.Equal(cheapest.Cost) && arrivesBefore(option, cheapest) {
+			cheapest = option
+		}
+	}
+	return cheapest, nil
+}
+
+// arrivesBefore indica si a tiene fecha de entrega estimada y llega antes que b.
+func arrivesBefore(a, b mlShippingOption) bool {
+	if a.EstimatedDeliveryTime.Date == nil {
+		return false
+	}
+	return b.EstimatedDeliveryTime.Date == nil || a.EstimatedDeliveryTime.Date.Before(*b.EstimatedDeliveryTime.Date)
+}
+
+// newReportShipping convierte la opción de envío de r en un reportShipping, con su costo en
+// USD a la cotización de r.
+func newReportShipping(r reportResult, zip string, option mlShippingOption) (*reportShipping, error) {
+	currency := option.CurrencyID
+	if currency == "" {
+		currency = r.Currency
+	}
+	rate := Rate{From: r.Currency, To: usdCurrencyCode, Ratio: r.Ratio}
+	costUSD, err := NewMoney(option.Cost, currency).In(usdCurrencyCode, rate)
+	if err != nil {
+		return nil, fmt.Errorf("converting shipping cost: %v", err)
+	}
+	return &reportShipping{
+		Zip:               zip,
+		Name:              option.Name,
+		Cost:              option.Cost,
+		Currency:          currency,
+		CostUSD:           costUSD.Amount,
+		EstimatedDelivery: option.EstimatedDeliveryTime.Date,
+	}, nil
+}
+
+// applyShipping completa el envío a zip de cada resultado de Mercado Libre del reporte, cada
+// pedido tiene timeout de plazo. Un envío que no se pudo estimar, por ejemplo porque zip no es
+// un código postal del país del sitio, se informa en el log y el resultado queda sin envío.
+func applyShipping(ctx context.Context, report *runReport, zip string, timeout time.Duration) {
+	forEachMLResult(ctx, report, timeout, func(ctx context.Context, r *reportResult) {
+		option, err := cheapestShipping(ctx, r.ItemID, zip)
+		if err == nil {
+			r.Shipping, err = newReportShipping(*r, zip, option)
+		}
+		if err != nil {
+			log.Printf("search: shipping %s to %s: %v", r.ItemID, zip, err)
+		}
+	})
+}
+
+// forEachMLResult llama a f a la vez con cada resultado de Mercado Libre del reporte, para
+// completarlo con otro pedido a ML que tiene timeout de plazo, y espera a que terminen todos.
+func forEachMLResult(ctx context.Context, report *runReport, timeout time.Duration, f func(context.Context, *reportResult)) {
+	var wg sync.WaitGroup
+	for i := range report.Results {
+		if !report.Results[i].mercadoLibre {
+			continue
+		}
+		wg.Add(1)
+		go func(r *reportResult) {
+			defer wg.Done()
+			resultCtx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			f(resultCtx, r)
+		}(&report.Results[i])
+	}
+	wg.Wait()
+}
+
+// withShippingUSD devuelve el precio en USD de r mas su envío, si se estimó.
+func (r reportResult) withShippingUSD() decimal.Decimal {
+	if r.Shipping == nil {
+		return r.PriceUSD
+	}
+	return r.PriceUSD.Add(r.Shipping.CostUSD)
+}
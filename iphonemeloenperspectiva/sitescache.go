@@ -0,0 +1,177 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/perrito666/tutoriales_go/internal/conditional"
+)
+
+const (
+	// sitesCacheFileName es el nombre del archivo donde se guarda la lista de sitios.
+	sitesCacheFileName = "sites.json"
+	// defaultSitesTTL es el tiempo por defecto durante el cual se usa la lista de sitios
+	// guardada, los sitios de Mercado Libre prácticamente no cambian.
+	defaultSitesTTL = 24 * time.Hour
+)
+
+// cachePath devuelve la ubicación de un archivo de cache de la aplicación, dentro de
+// $XDG_CACHE_HOME/iphonemelo o, si no está definido, de ~/.cache/iphonemelo.
+func cachePath(fileName string) string {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		// sin directorio de cache usamos el directorio actual.
+		return fileName
+	}
+	return filepath.Join(cacheDir, "iphonemelo", fileName)
+}
+
+// sitesCacheFile es el contenido del archivo donde se guarda la lista de sitios.
+type sitesCacheFile struct {
+	Fetched time.Time `json:"fetched"`
+	// Validators son los de la respuesta de la que salió la lista, para pedirla
+	// condicionalmente cuando vence.
+	Validators conditional.Validators `json:"validators"`
+	Sites      []mlSite               `json:"sites"`
+}
+
+// sitesCache guarda la lista de sitios de Mercado Libre en memoria y en disco, así no se
+// pide en cada ejecución y, si Mercado Libre no responde, se puede seguir usando la última.
+type sitesCache struct {
+	mu sync.Mutex
+	// path es el archivo donde se guarda la lista, si está vacío solo se guarda en memoria.
+	path string
+	// ttl es el tiempo durante el cual se usa la lista guardada sin volver a pedirla.
+	ttl time.Duration
+	// refresh pide la lista a Mercado Libre la próxima vez aunque la guardada no haya vencido.
+	refresh bool
+	// offline usa la lista guardada aunque esté vencida y nunca la pide, lo usa -dry-run.
+	offline bool
+	cached  *sitesCacheFile
+}
+
+// siteList es la lista de sitios que usan todos los subcomandos, los flags comunes indican
+// su vigencia.
+var siteList = &sitesCache{path: cachePath(sitesCacheFileName), ttl: defaultSitesTTL}
+
+// fetchSites devuelve la lista de sites de Mercado Libre, la guardada si no venció o si
+// Mercado Libre no la pudo devolver.
+func fetchSites() ([]mlSite, error) {
+	return siteList.sites()
+}
+
+// sites devuelve la lista guardada en memoria o en disco si tiene menos de ttl, si no la pide
+// a Mercado Libre y la guarda. Si el pedido falla y hay una lista guardada, aunque esté
+// vencida, se usa esa.
+func (c *sitesCache) sites() ([]mlSite, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.cached == nil {
+		c.cached = c.load()
+	}
+	if !c.refresh && c.cached != nil && time.Since(c.cached.Fetched) < c.ttl {
+		return c.copy(), nil
+	}
+	if c.offline {
+		if c.cached == nil {
+			return nil, fmt.Errorf("no saved sites list, run the sites command once to save it")
+		}
+		return c.copy(), nil
+	}
+
+	// si ya hay una lista se pide condicionalmente, si no cambió no se descarga de nuevo.
+	var validators conditional.Validators
+	if c.cached != nil {
+		validators = c.cached.Validators
+	}
+	sites, validators, notModified, err := requestSites(validators)
+	if err == nil && notModified && c.cached != nil {
+		c.refresh = false
+		c.cached.Fetched = time.Now()
+		if err := c.save(); err != nil {
+			log.Printf("could not save mercado libre sites: %v", err)
+		}
+		return c.copy(), nil
+	}
+	if err != nil {
+		if c.cached == nil {
+			return nil, err
+		}
+		log.Printf("using mercado libre sites from %s: %v", c.cached.Fetched.Format(time.RFC3339), err)
+		return c.copy(), nil
+	}
+	// solo se fuerza el primer pedido, un servidor no pide la lista en cada búsqueda.
+	c.refresh = false
+	c.cached = &sitesCacheFile{Fetched: time.Now(), Validators: validators, Sites: sites}
+	if err := c.save(); err != nil {
+		log.Printf("could not save mercado libre sites: %v", err)
+	}
+	return c.copy(), nil
+}
+
+// stale indica si la próxima vez que se pida la lista habrá que pedirla a Mercado Libre.
+func (c *sitesCache) stale() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.cached == nil {
+		c.cached = c.load()
+	}
+	return c.refresh || c.cached == nil || time.Since(c.cached.Fetched) >= c.ttl
+}
+
+// copy devuelve una copia de la lista guardada, quien la recibe puede modificarla.
+func (c *sitesCache) copy() []mlSite {
+	return append([]mlSite(nil), c.cached.Sites...)
+}
+
+// load lee la lista guardada en disco, nil si no hay o no se puede leer.
+func (c *sitesCache) load() *sitesCacheFile {
+	if c.path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(c.path)
+	if err != nil {
+		return nil
+	}
+	cached := &sitesCacheFile{}
+	if err := json.Unmarshal(data, cached); err != nil || len(cached.Sites) == 0 {
+		return nil
+	}
+	return cached
+}
+
+// save guarda la lista en disco, primero en un archivo temporal así otro proceso nunca lee
+// una lista a medio escribir.
+func (c *sitesCache) save() error {
+	if c.path == "" {
+		return nil
+	}
+	data, err := json.Marshal(c.cached)
+	if err != nil {
+		return fmt.Errorf("encoding sites cache: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil {
+		return fmt.Errorf("creating cache directory: %v", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), sitesCacheFileName+".*")
+	if err != nil {
+		return fmt.Errorf("creating sites cache: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing sites cache: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("writing sites cache: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), c.path); err != nil {
+		return fmt.Errorf("writing sites cache: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+)
+
+// Tipos de evento de GET /search/stream.
+const (
+	// sseResult es una publicación de un sitio, se envía apenas llega.
+	sseResult = "result"
+	// sseFailure es un sitio que falló luego de todos sus intentos.
+	sseFailure = "failure"
+	// sseSummary es el último evento, con el reporte completo ya ordenado y su resumen.
+	sseSummary = "summary"
+	// sseError indica que la comparación no se pudo hacer.
+	sseError = "error"
+)
+
+// eventStream escribe Server-Sent Events, cada uno con su tipo y un JSON como datos, y los
+// envía al cliente apenas se escriben.
+type eventStream struct {
+	w       io.Writer
+	flusher http.Flusher
+	// id numera los eventos, el cliente lo recibe como lastEventId.
+	id int
+}
+
+// newEventStream devuelve un eventStream que escribe en w.
+func newEventStream(w io.Writer, flusher http.Flusher) *eventStream {
+	return &eventStream{w: w, flusher: flusher}
+}
+
+// send escribe un evento, JSON no contiene saltos de linea así que entra en un solo "data:".
+func (e *eventStream) send(event string, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		log.Printf("encoding %s event: %v", event, err)
+		return
+	}
+	e.id++
+	if _, err := fmt.Fprintf(e.w, "id: %d\nevent: %s\ndata: %s\n\n", e.id, event, data); err != nil {
+		// el cliente se fue, la búsqueda se corta sola con el contexto del pedido.
+		return
+	}
+	e.flusher.Flush()
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/perrito666/tutoriales_go/internal/i18n"
+)
+
+// streamText devuelve una función para usar como searchOptions.onResult que escribe en w una
+// linea por cada resultado o sitio que falló apenas llega, con el tiempo transcurrido desde
+// start, así en una conexión lenta se ven las primeras respuestas sin esperar a las demás.
+func streamText(w io.Writer, start time.Time) func(siteSearchResult) {
+	return func(r siteSearchResult) {
+		elapsed := time.Since(start).Seconds()
+		if r.err != nil {
+			fmt.Fprintf(w, i18n.T("[%5.1fs] %s: falló luego de %d intentos: %v\n"), elapsed, r.site.Name, r.attempts, r.err)
+			return
+		}
+		siteName := r.site.Name
+		if r.rank > 1 {
+			siteName = fmt.Sprintf("%s #%d", r.site.Name, r.rank)
+		}
+		fmt.Fprintf(w, "[%5.1fs] %s: %s (%s) %q\n", elapsed, siteName, r.priceUSD, r.price, r.item)
+	}
+}
+
+// writeStreamSummary escribe, luego de las lineas de streamText, los resultados ya ordenados
+// y agrupados en una tabla seguidos del resumen de precios, los cambios y los tiempos.
+func writeStreamSummary(w io.Writer, report *runReport) {
+	fmt.Fprintf(w, i18n.T("\nOrdenado (%d resultados, %d sitios fallaron):\n"), len(report.Results), len(report.Failed))
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	// con -also-in y -crypto cada moneda es una columna mas, todos los resultados tienen las
+	// mismas.
+	var extra []convertedPrice
+	if len(report.Results) > 0 {
+		extra = report.Results[0].extraPrices()
+	}
+	fmt.Fprint(tw, i18n.T("Sitio\tPrecio (USD)\tPrecio local\t"))
+	for _, c := range extra {
+		fmt.Fprintf(tw, i18n.T("Precio (%s)\t"), c.Currency)
+	}
+	fmt.Fprintln(tw, i18n.T("Título"))
+	for _, v := range report.Results {
+		siteName := v.SiteName
+		if v.Rank > 1 {
+			siteName = fmt.Sprintf("%s #%d", v.SiteName, v.Rank)
+		}
+		// las publicaciones repetidas en otros sitios ya se agruparon en esta.
+		title := v.Title
+		if len(v.AlsoOn) > 0 {
+			title = fmt.Sprintf(i18n.T("%s (también en %s)"), v.Title, strings.Join(v.AlsoOn, ", "))
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t", siteName, v.usd().AmountString(), v.local())
+		for _, c := range v.extraPrices() {
+			fmt.Fprintf(tw, "%s\t", formatAmount(c.Amount, c.Currency))
+		}
+		fmt.Fprintln(tw, title)
+	}
+	tw.Flush()
+	if report.Summary != nil {
+		writeTextSummary(w, report.Summary)
+	}
+	if report.Diff != nil {
+		writeTextDiff(w, report.Diff)
+	}
+	if len(report.Timings) > 0 {
+		writeTextTimings(w, report.Timings)
+	}
+}
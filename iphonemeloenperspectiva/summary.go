@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/perrito666/tutoriales_go/internal/i18n"
+	"github.com/shopspring/decimal"
+)
+
+// summarySite es un sitio destacado en el resumen de una comparación.
+type summarySite struct {
+	SiteID   string          `json:"site_id"`
+	SiteName string          `json:"site_name"`
+	PriceUSD decimal.Decimal `json:"price_usd"`
+}
+
+// reportSummary resume una comparación: donde conviene comprar, donde no y cuanta diferencia
+// hay entre ambos, todo en USD.
+type reportSummary struct {
+	Cheapest      summarySite `json:"cheapest"`
+	MostExpensive summarySite `json:"most_expensive"`
+	// Spread es la diferencia entre el mas caro y el mas barato.
+	Spread decimal.Decimal `json:"spread"`
+	// SpreadPercent es Spread como porcentaje del precio mas barato.
+	SpreadPercent decimal.Decimal `json:"spread_percent"`
+	// Median es la mediana de los precios de todas las publicaciones.
+	Median decimal.Decimal `json:"median"`
+}
+
+// summarize calcula el resumen de un reporte, devuelve nil si no tiene resultados.
+func summarize(report *runReport) *reportSummary {
+	min, max, ok := priceRange(report)
+	if !ok {
+		return nil
+	}
+	summary := &reportSummary{
+		Cheapest:      summarySite{SiteID: min.SiteID, SiteName: min.SiteName, PriceUSD: min.PriceUSD},
+		MostExpensive: summarySite{SiteID: max.SiteID, SiteName: max.SiteName, PriceUSD: max.PriceUSD},
+		Spread:        max.PriceUSD.Sub(min.PriceUSD),
+		Median:        medianUSD(report.Results),
+	}
+	if !min.PriceUSD.IsZero() {
+		summary.SpreadPercent = summary.Spread.Div(min.PriceUSD).Mul(decimal.New(100, 0))
+	}
+	return summary
+}
+
+// medianUSD devuelve la mediana de los precios en USD de results, que no debe estar vacío.
+func medianUSD(results []reportResult) decimal.Decimal {
+	prices := make([]decimal.Decimal, 0, len(results))
+	for _, r := range results {
+		prices = append(prices, r.PriceUSD)
+	}
+	sortDecimals(prices)
+	return median(prices)
+}
+
+// sortDecimals ordena prices de menor a mayor.
+func sortDecimals(prices []decimal.Decimal) {
+	sort.Slice(prices, func(i, j int) bool {
+		return prices[i].LessThan(prices[j])
+	})
+}
+
+// median devuelve la mediana de prices, que debe estar ordenado y no vacío.
+func median(prices []decimal.Decimal) decimal.Decimal {
+	middle := len(prices) / 2
+	if len(prices)%2 == 1 {
+		return prices[middle]
+	}
+	return prices[middle-1].Add(prices[middle]).Div(decimal.New(2, 0))
+}
+
+// writeTextSummary escribe el resumen de manera legible.
+func writeTextSummary(w io.Writer, summary *reportSummary) {
+	usd := func(d decimal.Decimal) Money {
+		return NewMoney(d, usdCurrencyCode)
+	}
+	fmt.Fprintln(w, i18n.T("\nResumen:"))
+	fmt.Fprintf(w, i18n.T("Mas barato: %s, %s\n"), summary.Cheapest.SiteName, usd(summary.Cheapest.PriceUSD))
+	fmt.Fprintf(w, i18n.T("Mas caro: %s, %s\n"), summary.MostExpensive.SiteName, usd(summary.MostExpensive.PriceUSD))
+	fmt.Fprintf(w, i18n.T("Diferencia: %s (%s%%)\n"), usd(summary.Spread), formatNumber(summary.SpreadPercent))
+	fmt.Fprintf(w, i18n.T("Mediana: %s\n"), usd(summary.Median))
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"sync/atomic"
+	"text/tabwriter"
+	"time"
+
+	"github.com/perrito666/tutoriales_go/internal/i18n"
+)
+
+// siteTiming mide cuanto tardó cada parte del último intento de búsqueda en un sitio.
+type siteTiming struct {
+	// search es lo que tardó la búsqueda, incluida la de-serialización de sus respuestas.
+	search time.Duration
+	// currency es lo que tardó en obtenerse la cotización, casi nada si ya estaba en el
+	// cache de cotizaciones.
+	currency time.Duration
+	// decode son los nanosegundos que se pasaron leyendo y de-serializando respuestas de
+	// Mercado Libre, las páginas se de-serializan concurrentemente así que se suman con atomic.
+	decode int64
+}
+
+// siteTimingKey es la clave con la que se guarda el *siteTiming de un intento en su contexto.
+type siteTimingKey struct{}
+
+// withSiteTiming devuelve un contexto en el que se registran los tiempos en timing.
+func withSiteTiming(ctx context.Context, timing *siteTiming) context.Context {
+	return context.WithValue(ctx, siteTimingKey{}, timing)
+}
+
+// siteTimingFrom devuelve el *siteTiming de ctx, o uno que no se usa si ctx no tiene.
+func siteTimingFrom(ctx context.Context) *siteTiming {
+	if timing, ok := ctx.Value(siteTimingKey{}).(*siteTiming); ok {
+		return timing
+	}
+	return &siteTiming{}
+}
+
+// addDecodeTime suma d al tiempo de de-serialización del intento de ctx, si lo hay.
+func addDecodeTime(ctx context.Context, d time.Duration) {
+	if timing, ok := ctx.Value(siteTimingKey{}).(*siteTiming); ok {
+		atomic.AddInt64(&timing.decode, int64(d))
+	}
+}
+
+// reportTiming son los tiempos de un sitio dentro de un runReport, en milisegundos.
+type reportTiming struct {
+	SiteID     string  `json:"site_id"`
+	SiteName   string  `json:"site_name"`
+	SearchMS   float64 `json:"search_ms"`
+	CurrencyMS float64 `json:"currency_ms"`
+	DecodeMS   float64 `json:"decode_ms"`
+	// Failed indica que el sitio falló, los tiempos son los de su último intento.
+	Failed bool `json:"failed,omitempty"`
+}
+
+// milliseconds devuelve d en milisegundos con precisión de microsegundos.
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// timingCollector junta los tiempos de cada sitio a medida que llegan sus resultados.
+type timingCollector struct {
+	timings []reportTiming
+	seen    map[string]bool
+}
+
+// collect devuelve una función para usar como searchOptions.onResult que registra los tiempos
+// de cada sitio y luego llama a next, si no es nil.
+func (c *timingCollector) collect(next func(siteSearchResult)) func(siteSearchResult) {
+	c.seen = map[string]bool{}
+	return func(r siteSearchResult) {
+		// un sitio envía un resultado por publicación, todos con los mismos tiempos.
+		if !c.seen[r.site.ID] {
+			c.seen[r.site.ID] = true
+			c.timings = append(c.timings, reportTiming{
+				SiteID:     r.site.ID,
+				SiteName:   r.site.Name,
+				SearchMS:   milliseconds(r.timing.search),
+				CurrencyMS: milliseconds(r.timing.currency),
+				DecodeMS:   milliseconds(time.Duration(r.timing.decode)),
+				Failed:     r.err != nil,
+			})
+		}
+		if next != nil {
+			next(r)
+		}
+	}
+}
+
+// result devuelve los tiempos de todos los sitios, del que mas tardó en buscar al que menos.
+func (c *timingCollector) result() []reportTiming {
+	sort.SliceStable(c.timings, func(i, j int) bool {
+		return c.timings[i].SearchMS > c.timings[j].SearchMS
+	})
+	return c.timings
+}
+
+// writeTextTimings escribe la tabla de tiempos de cada sitio.
+func writeTextTimings(w io.Writer, timings []reportTiming) {
+	fmt.Fprint(w, i18n.T("\nTiempos por sitio:\n"))
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, i18n.T("Sitio\tBúsqueda\tCotización\tDe-serialización"))
+	for _, t := range timings {
+		site := fmt.Sprintf("%s (%s)", t.SiteName, t.SiteID)
+		if t.Failed {
+			site += i18n.T(" (falló)")
+		}
+		fmt.Fprintf(tw, "%s\t%.1fms\t%.1fms\t%.1fms\n", site, t.SearchMS, t.CurrencyMS, t.DecodeMS)
+	}
+	tw.Flush()
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer crea los spans de la comparación: uno por búsqueda, uno por sitio y dentro de cada
+// sitio la búsqueda, la cotización, cada pedido y cada de-serialización. Mientras no se
+// configure un exportador los spans no hacen nada.
+var tracer = otel.Tracer("github.com/perrito666/tutoriales_go")
+
+// tracingShutdownTimeout es lo que se espera al terminar a que se envíen los spans pendientes.
+const tracingShutdownTimeout = 5 * time.Second
+
+// tracingSetup configura el envío de spans por OTLP, se activa con -otlp-endpoint o con las
+// variables de entorno estándar de OpenTelemetry.
+type tracingSetup struct {
+	endpoint string
+	provider *sdktrace.TracerProvider
+}
+
+// tracing es la configuración de trazas del comando que se está ejecutando.
+var tracing tracingSetup
+
+// enabled indica si se pidió enviar spans.
+func (t *tracingSetup) enabled() bool {
+	return t.endpoint != "" || os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "" ||
+		os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") != ""
+}
+
+// start crea el exportador OTLP por HTTP y lo instala como el TracerProvider global.
+func (t *tracingSetup) start() error {
+	if !t.enabled() {
+		return nil
+	}
+	options := []otlptracehttp.Option{}
+	if t.endpoint != "" {
+		endpoint, err := url.Parse(t.endpoint)
+		if err != nil || endpoint.Host == "" || (endpoint.Scheme != "http" && endpoint.Scheme != "https") {
+			return fmt.Errorf("-otlp-endpoint must be an http or https URL, for example http://localhost:4318, got %q", t.endpoint)
+		}
+		options = append(options, otlptracehttp.WithEndpoint(endpoint.Host))
+		if endpoint.Scheme == "http" {
+			options = append(options, otlptracehttp.WithInsecure())
+		}
+		if endpoint.Path != "" && endpoint.Path != "/" {
+			options = append(options, otlptracehttp.WithURLPath(endpoint.Path))
+		}
+	}
+	// el exportador envía los spans por su cuenta, no por el cliente de los pedidos salientes.
+	exporter, err := otlptracehttp.New(context.Background(), options...)
+	if err != nil {
+		return fmt.Errorf("creating otlp exporter: %v", err)
+	}
+	service, err := resource.Merge(resource.Default(), resource.NewSchemaless(
+		attribute.String("service.name", programName),
+		attribute.String("service.version", programVersion()),
+	))
+	if err != nil {
+		return fmt.Errorf("describing trace resource: %v", err)
+	}
+	t.provider = sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(service))
+	otel.SetTracerProvider(t.provider)
+	return nil
+}
+
+// stop envía los spans pendientes, así también se exportan los del final de la comparación.
+func (t *tracingSetup) stop() error {
+	if t.provider == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), tracingShutdownTimeout)
+	defer cancel()
+	if err := t.provider.Shutdown(ctx); err != nil {
+		return fmt.Errorf("exporting traces: %v", err)
+	}
+	return nil
+}
+
+// endSpan registra err en span, si no es nil, y lo termina.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// tracingTransport crea un span por cada pedido que pasa por base, desde que se envía hasta
+// que llegan los encabezados de la respuesta, leer el cuerpo es parte de la de-serialización.
+type tracingTransport struct {
+	base http.RoundTripper
+}
+
+func (t tracingTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	ctx, span := tracer.Start(request.Context(), request.Method+" "+request.URL.Host+request.URL.Path,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("http.method", request.Method),
+			attribute.String("http.url", request.URL.Redacted()),
+		))
+	response, err := t.base.RoundTrip(request.WithContext(ctx))
+	if err != nil {
+		endSpan(span, err)
+		return nil, err
+	}
+	span.SetAttributes(attribute.Int("http.status_code", response.StatusCode))
+	if response.StatusCode >= http.StatusBadRequest {
+		span.SetStatus(codes.Error, response.Status)
+	}
+	span.End()
+	return response, nil
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/perrito666/tutoriales_go/internal/bodylimit"
+)
+
+// mlTrendsEndpoint es el endpoint de tendencias de búsqueda de un sitio de Mercado Libre.
+const mlTrendsEndpoint = "https://api.mercadolibre.com/trends/%s"
+
+// mlTrend imita la estructura JSON de una tendencia de búsqueda de Mercado Libre.
+type mlTrend struct {
+	// Keyword es lo que la gente está buscando.
+	Keyword string `json:"keyword"`
+	// URL es la búsqueda en el sitio de Mercado Libre.
+	URL string `json:"url"`
+}
+
+// fetchTrends devuelve las búsquedas en tendencia de un sitio, de la mas buscada a la menos,
+// el pedido se cancela si el contexto expira.
+func fetchTrends(ctx context.Context, site mlSite) ([]mlTrend, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf(mlTrendsEndpoint, site.ID), nil)
+	if err != nil {
+		return nil, fmt.Errorf("building mercado libre trends request: %w", err)
+	}
+	response, err := doML(request)
+	if err != nil {
+		return nil, fmt.Errorf("querying mercado libre trends url: %w", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("requesting trends to mercado libre: %w", newStatusError(response))
+	}
+
+	trends := []mlTrend{}
+	if err := decodeML(ctx, bodylimit.Body(response), "mercado libre trends", &trends, nil); err != nil {
+		return nil, err
+	}
+	return trends, nil
+}
+
+// siteTrends son las tendencias de un sitio, o el error que impidió obtenerlas.
+type siteTrends struct {
+	SiteID   string    `json:"site_id"`
+	SiteName string    `json:"site_name"`
+	Trends   []mlTrend `json:"trends,omitempty"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// fetchSitesTrends pide concurrentemente las tendencias de cada sitio, cada uno con su propio
+// plazo, y devuelve a lo sumo limit tendencias por sitio en el orden de sites.
+func fetchSitesTrends(ctx context.Context, sites []mlSite, timeout time.Duration, limit int) []siteTrends {
+	all := make([]siteTrends, len(sites))
+	wg := &sync.WaitGroup{}
+	wg.Add(len(sites))
+	for i := range sites {
+		// cada gorutina escribe solo su posición del slice, no hace falta sincronizar.
+		go func(i int) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			all[i] = siteTrends{SiteID: sites[i].ID, SiteName: sites[i].Name}
+			trends, err := fetchTrends(ctx, sites[i])
+			if err != nil {
+				all[i].Error = err.Error()
+				return
+			}
+			if len(trends) > limit {
+				trends = trends[:limit]
+			}
+			all[i].Trends = trends
+		}(i)
+	}
+	wg.Wait()
+	return all
+}
+
+// topTrend devuelve la tendencia principal: la primera del primer sitio que tenga alguna.
+func topTrend(all []siteTrends) (string, bool) {
+	for _, st := range all {
+		if len(st.Trends) > 0 {
+			return st.Trends[0].Keyword, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/shopspring/decimal"
+)
+
+// storageAttribute es el atributo de ML con la capacidad de almacenamiento de un producto.
+const storageAttribute = "INTERNAL_MEMORY"
+
+// storageSize es una capacidad de almacenamiento, por ejemplo 256 GB o 1 TB.
+type storageSize struct {
+	// GB es la capacidad en GB, con la que se calcula el precio por GB.
+	GB int
+	// Label es la capacidad como la escribe ML, por ejemplo "256 GB" o "1 TB".
+	Label string
+}
+
+// parseStorageSizes lee una lista de capacidades separadas por coma, por ejemplo
+// "128,256,512,1TB", sin unidad se entiende GB. Las devuelve de menor a mayor.
+func parseStorageSizes(list string) ([]storageSize, error) {
+	sizes := []storageSize{}
+	for _, size := range parseKeywords(list) {
+		value, unit, multiplier := strings.ToUpper(strings.ReplaceAll(size, " ", "")), "GB", 1
+		switch {
+		case strings.HasSuffix(value, "TB"):
+			value, unit, multiplier = strings.TrimSuffix(value, "TB"), "TB", 1024
+		case strings.HasSuffix(value, "GB"):
+			value = strings.TrimSuffix(value, "GB")
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid storage size %q, must be a number of GB or TB, for example 256 or 1TB", size)
+		}
+		sizes = append(sizes, storageSize{GB: n * multiplier, Label: fmt.Sprintf("%d %s", n, unit)})
+	}
+	if len(sizes) < 2 {
+		return nil, fmt.Errorf("at least two storage sizes are needed to compare variants, got %d", len(sizes))
+	}
+	sort.Slice(sizes, func(i, j int) bool {
+		return sizes[i].GB < sizes[j].GB
+	})
+	for i := 1; i < len(sizes); i++ {
+		if sizes[i].GB == sizes[i-1].GB {
+			return nil, fmt.Errorf("storage size %s given more than once", sizes[i].Label)
+		}
+	}
+	return sizes, nil
+}
+
+// storageVariant es una variante de un modelo con su búsqueda: el criterio incluye la
+// capacidad y las opciones exigen el atributo, así una publicación de otra capacidad no se
+// cuela en los resultados de la variante.
+type storageVariant struct {
+	Size  storageSize
+	Query string
+	opts  searchOptions
+}
+
+// expandStorageVariants arma una variante de model por cada capacidad de sizes, a partir de las
+// opciones de búsqueda opts.
+func expandStorageVariants(model string, sizes []storageSize, opts searchOptions) ([]storageVariant, error) {
+	for _, a := range opts.attributes {
+		if a.Name == storageAttribute {
+			return nil, fmt.Errorf("-attr %s cannot be used with storage variants", storageAttribute)
+		}
+	}
+	variants := make([]storageVariant, 0, len(sizes))
+	for _, size := range sizes {
+		variantOpts := opts
+		variantOpts.attributes = append(append([]attributeFilter{}, opts.attributes...),
+			attributeFilter{Name: storageAttribute, Value: size.Label})
+		variantOpts.filters = map[string]string{}
+		for name, value := range opts.filters {
+			variantOpts.filters[name] = value
+		}
+		variantOpts.filters[storageAttribute] = size.Label
+		variants = append(variants, storageVariant{
+			Size:  size,
+			Query: fmt.Sprintf("%s %s", model, strings.ReplaceAll(size.Label, " ", "")),
+			opts:  variantOpts,
+		})
+	}
+	return variants, nil
+}
+
+// searchVariants compara cada variante en todos los sitios y devuelve los resultados de cada
+// una en el orden de variants.
+func searchVariants(ctx context.Context, variants []storageVariant, sites []mlSite) [][]siteSearchResult {
+	queries := make([]string, 0, len(variants))
+	opts := make([]searchOptions, 0, len(variants))
+	for _, variant := range variants {
+		queries = append(queries, variant.Query)
+		opts = append(opts, variant.opts)
+	}
+	return compareQueries(ctx, queries, opts, sites)
+}
+
+// compareQueries compara cada criterio de queries, con sus opciones en opts, en todos los
+// sitios, todos a la vez, y devuelve los resultados de cada uno en el orden de queries. Los
+// sitios que fallan se omiten.
+func compareQueries(ctx context.Context, queries []string, opts []searchOptions, sites []mlSite) [][]siteSearchResult {
+	results := make([][]siteSearchResult, len(queries))
+	wg := &sync.WaitGroup{}
+	wg.Add(len(queries))
+	for i := range queries {
+		go func(i int) {
+			defer wg.Done()
+			results[i], _ = compareSites(ctx, queries[i], sites, opts[i])
+		}(i)
+	}
+	wg.Wait()
+	return results
+}
+
+// variantPrice es el precio de una variante en un sitio.
+type variantPrice struct {
+	Storage  string          `json:"storage"`
+	PriceUSD decimal.Decimal `json:"price_usd"`
+	// PerGBUSD es el precio por GB de almacenamiento.
+	PerGBUSD decimal.Decimal `json:"per_gb_usd"`
+	// PremiumPercent es cuanto mas cara es que la variante anterior con precio en el sitio,
+	// no está en la primera.
+	PremiumPercent *decimal.Decimal `json:"premium_percent,omitempty"`
+}
+
+// siteVariants son los precios de las variantes que se encontraron en un sitio.
+type siteVariants struct {
+	SiteID   string         `json:"site_id"`
+	SiteName string         `json:"site_name"`
+	Variants []variantPrice `json:"variants"`
+}
+
+// variantsReport es el resultado del subcomando variants.
+type variantsReport struct {
+	Model string         `json:"model"`
+	Sites []siteVariants `json:"sites"`
+}
+
+// correlateVariants junta por sitio los precios de cada variante, tomando de cada sitio la
+// primera publicación de cada variante. Los sitios sin ninguna variante se omiten.
+func correlateVariants(model string, sites []mlSite, variants []storageVariant, results [][]siteSearchResult) *variantsReport {
+	prices := make([]map[string]decimal.Decimal, len(results))
+	for i := range results {
+		prices[i] = firstBySite(results[i])
+	}
+	report := &variantsReport{Model: model}
+	for _, site := range sites {
+		sv := siteVariants{SiteID: site.ID, SiteName: site.Name}
+		var previous *decimal.Decimal
+		for i, variant := range variants {
+			price, ok := prices[i][site.ID]
+			if !ok {
+				continue
+			}
+			vp := variantPrice{
+				Storage:  variant.Size.Label,
+				PriceUSD: price,
+				PerGBUSD: price.Div(decimal.New(int64(variant.Size.GB), 0)),
+			}
+			if previous != nil {
+				premium := percentChange(*previous, price)
+				vp.PremiumPercent = &premium
+			}
+			previous = &vp.PriceUSD
+			sv.Variants = append(sv.Variants, vp)
+		}
+		if len(sv.Variants) > 0 {
+			report.Sites = append(report.Sites, sv)
+		}
+	}
+	return report
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/shopspring/decimal"
+	"gopkg.in/yaml.v3"
+)
+
+// monthlyIncome es un ingreso mensual de un país, por ejemplo su salario mínimo, en la moneda
+// en que se publica.
+type monthlyIncome struct {
+	Currency string          `yaml:"currency"`
+	Monthly  decimal.Decimal `yaml:"monthly"`
+}
+
+// bundledWagesDate indica a que momento corresponden los salarios de bundledWages.
+const bundledWagesDate = "2025-01"
+
+// bundledWages son los salarios mínimos mensuales aproximados de los países de Mercado Libre,
+// por ID de sitio, cambian seguido (sobre todo con inflación alta) así que se pueden
+// reemplazar con -wages-file.
+var bundledWages = map[string]monthlyIncome{
+	"MLA": {"ARS", decimal.New(279718, 0)},
+	"MLB": {"BRL", decimal.New(1518, 0)},
+	"MLC": {"CLP", decimal.New(510000, 0)},
+	"MCO": {"COP", decimal.New(1423500, 0)},
+	"MLM": {"MXN", decimal.New(8364, 0)},
+	"MLU": {"UYU", decimal.New(23604, 0)},
+	"MPE": {"PEN", decimal.New(1130, 0)},
+	"MEC": {"USD", decimal.New(470, 0)},
+	"MPY": {"PYG", decimal.New(2798309, 0)},
+	"MBO": {"BOB", decimal.New(2750, 0)},
+	"MCR": {"CRC", decimal.New(365015, 0)},
+	"MGT": {"GTQ", decimal.New(3723, 0)},
+	"MSV": {"USD", decimal.New(40880, -2)},
+}
+
+// loadWages devuelve los salarios mínimos incluidos, reemplazados por los de path si no está
+// vacío. El archivo es YAML con un salario por ID de sitio, por ejemplo:
+//
+//	MLA: {currency: ARS, monthly: 296832}
+func loadWages(path string) (map[string]monthlyIncome, error) {
+	wages := map[string]monthlyIncome{}
+	for id, wage := range bundledWages {
+		wages[id] = wage
+	}
+	if path == "" {
+		return wages, nil
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading wages file: %v", err)
+	}
+	overrides := map[string]monthlyIncome{}
+	if err := yaml.Unmarshal(data, &overrides); err != nil {
+		return nil, fmt.Errorf("parsing wages file %s: %v", path, err)
+	}
+	for id, wage := range overrides {
+		if wage.Currency == "" || !wage.Monthly.IsPositive() {
+			return nil, fmt.Errorf("invalid wages file %s: %s needs a currency and a positive monthly wage", path, id)
+		}
+		wages[id] = wage
+	}
+	return wages, nil
+}
+
+// applyWages completa, para cada resultado del reporte cuyo país tiene un salario mínimo
+// conocido en la moneda del sitio, cuantos meses de salario mínimo cuesta.
+func applyWages(report *runReport, wages map[string]monthlyIncome) {
+	for i, r := range report.Results {
+		wage, ok := wages[r.SiteID]
+		if !ok || wage.Currency != r.Currency || !wage.Monthly.IsPositive() {
+			continue
+		}
+		months := r.Price.Div(wage.Monthly)
+		report.Results[i].MinWageMonths = &months
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/shopspring/decimal"
+)
+
+// watchlistFileName es el nombre del archivo donde se guarda la lista de productos vigilados.
+const watchlistFileName = "watchlist.json"
+
+// defaultWatchlistPath devuelve la ubicación por defecto de la lista de productos vigilados.
+func defaultWatchlistPath() string {
+	return dataPath(watchlistFileName)
+}
+
+// watchedProduct es un producto que el modo watch compara en cada vuelta.
+type watchedProduct struct {
+	// Query es el criterio de búsqueda del producto.
+	Query string `json:"query"`
+	// Below es el precio en USD por debajo del cual se emite una alerta, cero no alerta.
+	Below decimal.Decimal `json:"below,omitempty"`
+}
+
+// watchlist es la lista de productos vigilados, se guarda entera como JSON.
+type watchlist struct {
+	path     string
+	Products []watchedProduct `json:"products"`
+}
+
+// loadWatchlist lee la lista de productos de path, si no existe devuelve una lista vacía.
+func loadWatchlist(path string) (*watchlist, error) {
+	list := &watchlist{path: path}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return list, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading watchlist: %v", err)
+	}
+	if err := json.Unmarshal(data, list); err != nil {
+		return nil, fmt.Errorf("decoding watchlist %s: %v", path, err)
+	}
+	return list, nil
+}
+
+// save guarda la lista, primero en un archivo temporal para no dejarla a medio escribir.
+func (l *watchlist) save() error {
+	if err := os.MkdirAll(filepath.Dir(l.path), 0755); err != nil {
+		return fmt.Errorf("creating watchlist dir: %v", err)
+	}
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding watchlist: %v", err)
+	}
+	tmp := l.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("writing watchlist: %v", err)
+	}
+	if err := os.Rename(tmp, l.path); err != nil {
+		return fmt.Errorf("replacing watchlist: %v", err)
+	}
+	return nil
+}
+
+// find devuelve la posición del producto con el criterio query o -1 si no está.
+func (l *watchlist) find(query string) int {
+	for i, product := range l.Products {
+		if sameQuery(product.Query, query) {
+			return i
+		}
+	}
+	return -1
+}
+
+// add agrega un producto o, si ya estaba, actualiza su umbral de alerta.
+func (l *watchlist) add(product watchedProduct) {
+	if i := l.find(product.Query); i >= 0 {
+		l.Products[i] = product
+		return
+	}
+	l.Products = append(l.Products, product)
+}
+
+// remove quita el producto con el criterio query y devuelve false si no estaba.
+func (l *watchlist) remove(query string) bool {
+	i := l.find(query)
+	if i < 0 {
+		return false
+	}
+	l.Products = append(l.Products[:i], l.Products[i+1:]...)
+	return true
+}
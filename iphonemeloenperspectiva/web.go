@@ -0,0 +1,23 @@
+package main
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+)
+
+// webFiles contiene el tablero web, se compila dentro del binario así serve no depende de
+// archivos sueltos.
+//
+//go:embed web
+var webFiles embed.FS
+
+// dashboard devuelve el handler que sirve el tablero web.
+func dashboard() http.Handler {
+	root, err := fs.Sub(webFiles, "web")
+	if err != nil {
+		// web está embebido, solo puede fallar si el nombre está mal escrito.
+		panic(err)
+	}
+	return http.FileServer(http.FS(root))
+}
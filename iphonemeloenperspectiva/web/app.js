@@ -0,0 +1,217 @@
+// Tablero de iphonemeloenperspectiva: muestra la última comparación de un criterio con la
+// evolución del precio de cada sitio según el historial y permite lanzar búsquedas nuevas,
+// que se muestran sitio por sitio a medida que llegan.
+"use strict";
+
+const form = document.getElementById("search");
+const queryInput = document.getElementById("query");
+const statusLine = document.getElementById("status");
+const table = document.getElementById("results");
+const failedList = document.getElementById("failed");
+const summaryLine = document.getElementById("summary");
+
+// saved contiene las comparaciones guardadas del criterio actual, de la mas vieja a la mas
+// nueva, de ahí salen las líneas de cada sitio.
+let saved = [];
+// apiKey es la clave de API indicada en la dirección del tablero, si el servidor la exige.
+const apiKey = new URLSearchParams(location.search).get("api_key");
+let source = null;
+let socket = null;
+
+// apiURL agrega la clave de API, si hay, a la dirección de un endpoint.
+function apiURL(url) {
+  if (!apiKey) {
+    return url;
+  }
+  return url + (url.includes("?") ? "&" : "?") + "api_key=" + encodeURIComponent(apiKey);
+}
+
+// cell agrega a row una celda con el texto indicado.
+function cell(row, text, className) {
+  const td = row.insertCell();
+  td.textContent = text;
+  if (className) {
+    td.className = className;
+  }
+  return td;
+}
+
+// siteSeries devuelve el precio en USD mas barato del sitio en cada comparación del historial.
+function siteSeries(siteID) {
+  const series = [];
+  for (const report of saved) {
+    const prices = report.results
+      .filter((r) => r.site_id === siteID)
+      .map((r) => Number(r.price_usd));
+    if (prices.length > 0) {
+      series.push(Math.min(...prices));
+    }
+  }
+  return series;
+}
+
+// sparkline dibuja los valores como una línea SVG.
+function sparkline(values) {
+  const width = 120;
+  const height = 24;
+  const svg = document.createElementNS("http://www.w3.org/2000/svg", "svg");
+  svg.setAttribute("class", "sparkline");
+  svg.setAttribute("width", width);
+  svg.setAttribute("height", height);
+  if (values.length < 2) {
+    return svg;
+  }
+  const min = Math.min(...values);
+  const range = Math.max(...values) - min || 1;
+  const points = values.map((v, i) => {
+    const x = (i / (values.length - 1)) * (width - 2) + 1;
+    const y = height - 1 - ((v - min) / range) * (height - 2);
+    return x.toFixed(1) + "," + y.toFixed(1);
+  });
+  const line = document.createElementNS("http://www.w3.org/2000/svg", "polyline");
+  line.setAttribute("points", points.join(" "));
+  const title = document.createElementNS("http://www.w3.org/2000/svg", "title");
+  title.textContent = values.map((v) => v.toFixed(2)).join(" → ");
+  svg.appendChild(title);
+  svg.appendChild(line);
+  return svg;
+}
+
+// clear vacía la tabla y los mensajes.
+function clear() {
+  table.tBodies[0].replaceChildren();
+  failedList.replaceChildren();
+  summaryLine.textContent = "";
+}
+
+// addResult agrega una publicación a la tabla.
+function addResult(result) {
+  const row = table.tBodies[0].insertRow();
+  row.dataset.site = result.site_id;
+  cell(row, result.site_name);
+  cell(row, result.currency + " " + result.price, "number");
+  cell(row, Number(result.price_usd).toFixed(2), "number");
+  cell(row, "").appendChild(sparkline(siteSeries(result.site_id)));
+  const link = document.createElement("a");
+  link.href = result.permalink;
+  link.textContent = result.title;
+  cell(row, "").appendChild(link);
+  table.hidden = false;
+}
+
+// addFailure agrega un sitio que falló a la lista de fallidos.
+function addFailure(failure) {
+  const item = document.createElement("li");
+  item.textContent = failure.site_name + ": " + failure.error;
+  failedList.appendChild(item);
+}
+
+// showReport muestra una comparación completa, ya ordenada y con su resumen.
+function showReport(report) {
+  clear();
+  for (const result of report.results) {
+    addResult(result);
+  }
+  for (const failure of report.failed || []) {
+    addFailure(failure);
+  }
+  const summary = report.summary;
+  if (summary) {
+    const cheapest = table.querySelector('tr[data-site="' + summary.cheapest.site_id + '"]');
+    if (cheapest) {
+      cheapest.className = "cheapest";
+    }
+    summaryLine.textContent =
+      "Mas barato: " + summary.cheapest.site_name + ", mas caro: " + summary.most_expensive.site_name +
+      ", diferencia USD " + Number(summary.spread).toFixed(2) +
+      " (" + Number(summary.spread_percent).toFixed(2) + "%), mediana USD " + Number(summary.median).toFixed(2);
+  }
+  statusLine.textContent = "Comparación del " + new Date(report.time).toLocaleString();
+}
+
+// follow recibe por WebSocket las comparaciones nuevas del criterio que se guarden en el
+// historial, por ejemplo desde watch.
+function follow(query, since) {
+  if (socket) {
+    socket.close();
+  }
+  const scheme = location.protocol === "https:" ? "wss:" : "ws:";
+  let url = scheme + "//" + location.host + "/ws?q=" + encodeURIComponent(query);
+  if (since) {
+    url += "&since=" + encodeURIComponent(since);
+  }
+  const ws = new WebSocket(apiURL(url));
+  ws.onmessage = (event) => {
+    const report = JSON.parse(event.data);
+    saved.push(report);
+    since = report.time;
+    showReport(report);
+  };
+  // al reconectar pedimos lo que nos perdimos desde el último reporte.
+  ws.onclose = () => {
+    if (socket === ws) {
+      setTimeout(() => follow(query, since), 5000);
+    }
+  };
+  socket = ws;
+}
+
+// load muestra la última comparación guardada del criterio, o la última de todas si no se
+// indica ninguno, y sigue las nuevas.
+async function load(query) {
+  const response = await fetch(apiURL("/history?limit=50&q=" + encodeURIComponent(query || "")));
+  if (!response.ok) {
+    statusLine.textContent = "No se pudo leer el historial";
+    return;
+  }
+  let reports = await response.json();
+  if (!query && reports.length > 0) {
+    query = reports[reports.length - 1].query;
+    reports = reports.filter((r) => r.query === query);
+  }
+  saved = reports;
+  if (!query) {
+    statusLine.textContent = "Todavía no hay comparaciones guardadas, busque algo.";
+    return;
+  }
+  queryInput.value = query;
+  if (reports.length > 0) {
+    showReport(reports[reports.length - 1]);
+    follow(query, reports[reports.length - 1].time);
+  } else {
+    clear();
+    statusLine.textContent = "No hay comparaciones guardadas de " + query;
+    follow(query);
+  }
+}
+
+// search lanza una comparación nueva y muestra cada sitio apenas responde.
+function search(query) {
+  if (source) {
+    source.close();
+  }
+  clear();
+  table.hidden = true;
+  statusLine.textContent = "Buscando " + query + "…";
+  source = new EventSource(apiURL("/search/stream?q=" + encodeURIComponent(query)));
+  source.addEventListener("result", (event) => addResult(JSON.parse(event.data)));
+  source.addEventListener("failure", (event) => addFailure(JSON.parse(event.data)));
+  source.addEventListener("summary", (event) => {
+    source.close();
+    showReport(JSON.parse(event.data));
+  });
+  source.addEventListener("error", (event) => {
+    source.close();
+    statusLine.textContent = event.data ? JSON.parse(event.data).error : "La búsqueda falló";
+  });
+}
+
+form.addEventListener("submit", async (event) => {
+  event.preventDefault();
+  const query = queryInput.value.trim();
+  history.replaceState(null, "", apiURL("?q=" + encodeURIComponent(query)));
+  await load(query);
+  search(query);
+});
+
+load(new URLSearchParams(location.search).get("q"));
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/perrito666/tutoriales_go/internal/bodylimit"
+)
+
+const (
+	// webhookSignatureHeader es el encabezado con la firma HMAC-SHA256 del cuerpo, el receptor
+	// la calcula con el mismo secreto para verificar que el pedido viene de nosotros.
+	webhookSignatureHeader = "X-Melo-Signature"
+	// webhookRetryDelay es la espera antes del primer reintento, cada reintento espera un poco
+	// mas que el anterior.
+	webhookRetryDelay = time.Second
+	// webhookTimeout es el plazo de cada intento.
+	webhookTimeout = 10 * time.Second
+)
+
+// webhook envía el reporte de cada comparación como JSON a una URL arbitraria.
+type webhook struct {
+	url     string
+	secret  string
+	retries int
+	client  *http.Client
+}
+
+// newWebhook devuelve un webhook que envía a url, firmando con secret si no está vacío y
+// reintentando hasta retries veces.
+func newWebhook(url, secret string, retries int) *webhook {
+	return &webhook{
+		url:     url,
+		secret:  secret,
+		retries: retries,
+		client:  &http.Client{Transport: outgoing, Timeout: webhookTimeout},
+	}
+}
+
+// sign devuelve la firma del cuerpo, como "sha256=" seguido del HMAC en hexadecimal.
+func (w *webhook) sign(body []byte) string {
+	mac := hmac.New(sha256.New, []byte(w.secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// post hace un intento de envío, los errores del receptor que no se arreglan reintentando
+// (un 4xx salvo 429) se devuelven como notRetryableError.
+func (w *webhook) post(ctx context.Context, body []byte) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return notRetryableError{fmt.Errorf("building webhook request: %v", err)}
+	}
+	request.Header.Set("Content-Type", "application/json")
+	if w.secret != "" {
+		request.Header.Set(webhookSignatureHeader, w.sign(body))
+	}
+	response, err := w.client.Do(request)
+	if err != nil {
+		return fmt.Errorf("posting to webhook: %v", err)
+	}
+	defer response.Body.Close()
+	// leemos (y descartamos) la respuesta para poder reutilizar la conexión.
+	io.Copy(ioutil.Discard, bodylimit.Body(response))
+	switch {
+	case response.StatusCode >= 200 && response.StatusCode < 300:
+		return nil
+	case response.StatusCode >= 500 || response.StatusCode == http.StatusTooManyRequests:
+		return fmt.Errorf("posting to webhook: %s", response.Status)
+	}
+	return notRetryableError{fmt.Errorf("posting to webhook: %s", response.Status)}
+}
+
+// export envía el reporte, reintentando si el receptor no está disponible.
+func (w *webhook) export(ctx context.Context, report *runReport) error {
+	body, err := json.Marshal(report)
+	if err != nil {
+		return fmt.Errorf("encoding webhook body: %v", err)
+	}
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(time.Duration(attempt) * webhookRetryDelay):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		err = w.post(ctx, body)
+		if _, ok := err.(notRetryableError); err == nil || ok || attempt >= w.retries {
+			return err
+		}
+	}
+}
+
+// webhookFlags contiene los flags del webhook.
+type webhookFlags struct {
+	url     *string
+	secret  *string
+	retries *int
+}
+
+// addWebhookFlags registra en fs los flags del webhook.
+func addWebhookFlags(fs *flag.FlagSet) *webhookFlags {
+	return &webhookFlags{
+		url:     fs.String("webhook-url", "", "URL a la que se envía por POST el reporte JSON de cada comparación"),
+		secret:  fs.String("webhook-secret", "", "secreto con el que se firma cada envío del webhook en el encabezado "+webhookSignatureHeader),
+		retries: fs.Int("webhook-retries", 3, "cantidad de veces que se reintenta un envío del webhook que falló"),
+	}
+}
+
+// exporter devuelve el webhook indicado por los flags o nil si no se pidió.
+func (f *webhookFlags) exporter() (*webhook, error) {
+	if *f.url == "" {
+		return nil, nil
+	}
+	if *f.retries < 0 {
+		return nil, fmt.Errorf("-webhook-retries cannot be negative, got %d", *f.retries)
+	}
+	return newWebhook(*f.url, *f.secret, *f.retries), nil
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// wsWriteTimeout es el plazo para escribir un mensaje a un cliente.
+	wsWriteTimeout = 10 * time.Second
+	// wsPingInterval es cada cuanto se verifica que el cliente siga conectado.
+	wsPingInterval = 30 * time.Second
+)
+
+// handleWS envía por WebSocket, como un mensaje JSON por reporte, cada comparación que se
+// agrega al historial del criterio q (o de todos si no se indica). Al conectarse primero
+// envía la última comparación o, si se indica since, todas las posteriores a ese momento, así
+// un cliente que se reconecta con el momento del último reporte que recibió no pierde nada.
+func (s *server) handleWS(w http.ResponseWriter, r *http.Request) {
+	if !onlyGET(w, r) {
+		return
+	}
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	var since time.Time
+	if raw := r.URL.Query().Get("since"); raw != "" {
+		var err error
+		if since, err = time.Parse(time.RFC3339Nano, raw); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid since %q, must be RFC 3339", raw))
+			return
+		}
+	}
+
+	// nos suscribimos antes de leer el historial para no perder lo que se agregue mientras
+	// tanto, lo repetido se descarta por fecha.
+	updates := s.hub.subscribe(query)
+	defer s.hub.unsubscribe(updates)
+	reports, err := s.history.load(query)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if since.IsZero() {
+		reports = lastReports(reports, 1)
+	}
+
+	// solo aceptamos páginas del mismo origen que el servidor o de los permitidos por CORS.
+	upgrader := websocket.Upgrader{CheckOrigin: s.cors.checkWebSocketOrigin}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade ya respondió con el error.
+		log.Printf("upgrading to websocket: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	// sent tiene la fecha del último reporte enviado de cada criterio.
+	sent := map[string]time.Time{}
+	send := func(report *runReport) error {
+		key := normalizeTitle(report.Query)
+		if !report.Time.After(sent[key]) || !report.Time.After(since) {
+			return nil
+		}
+		sent[key] = report.Time
+		conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+		return conn.WriteJSON(report)
+	}
+	for _, report := range reports {
+		if err := send(report); err != nil {
+			return
+		}
+	}
+
+	// el cliente no nos envía nada, pero hay que leer para enterarse de que cerró y para
+	// responder sus pings.
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+	ping := time.NewTicker(wsPingInterval)
+	defer ping.Stop()
+	for {
+		select {
+		case <-closed:
+			return
+		case <-s.stopping:
+			// el servidor se apaga, avisamos para que el cliente se reconecte a otro.
+			conn.WriteControl(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down"),
+				time.Now().Add(wsWriteTimeout))
+			return
+		case report := <-updates:
+			if err := send(report); err != nil {
+				return
+			}
+		case <-ping.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteTimeout)); err != nil {
+				return
+			}
+		}
+	}
+}